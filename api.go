@@ -0,0 +1,1726 @@
+// Code generated by gen_api.go from the v2 package; DO NOT EDIT.
+
+package cwalk
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	v2 "github.com/iafan/cwalk/v2"
+)
+
+// WithAlternateDataStreams makes the walk enumerate the alternate data
+// streams of every regular file on NTFS, and pass each of them to the
+// callback as a synthetic entry named "path:stream", which shares the
+// OrderToken of its file. Scanners need this, as payloads can be hidden
+// in alternate data streams. This option has no effect on other platforms.
+func WithAlternateDataStreams() Option {
+	return v2.WithAlternateDataStreams()
+}
+
+// DefaultAgeBounds are the age bucket bounds used by AgeReport()
+// when none are given: a week, a month, a quarter, a year and 3 years
+var DefaultAgeBounds = v2.DefaultAgeBounds
+
+// AgeHistogram is the distribution of files by age: bucket i counts
+// the files younger than the bound i (and not younger than the bound
+// i-1), the last bucket counts the files older than all the bounds
+type AgeHistogram = v2.AgeHistogram
+
+// SubtreeAges holds the age histograms of the files of a subtree,
+// see AgeReport()
+type SubtreeAges = v2.SubtreeAges
+
+// AgeReport walks root and returns the distribution of the files by
+// last modification and last access time (see AgeHistogram) for the
+// root and for every directory up to depth levels below it (0 for the
+// root only, negative for all of them), in lexical order, to plan the
+// archival of cold data. Each subtree counts all the files under it.
+// Ages are relative to the start of the walk and bucketed by bounds,
+// in increasing order (DefaultAgeBounds if nil). The histograms are accumulated by each
+// worker and merged once the walk is complete, so the memory used only
+// depends on the number of subtrees reported.
+//
+// Access times are only as accurate as the file system keeps them:
+// with the relatime mount option (the default on Linux), they are only
+// updated once a day, and not at all with noatime. Files which access
+// time is not available on the platform are left out of Accessed.
+// Errors are returned after the walk along with the histograms of the
+// files that could be read.
+func AgeReport(root string, depth int, bounds []time.Duration, opts ...Option) ([]SubtreeAges, error) {
+	return v2.AgeReport(root, depth, bounds, withGlobals(opts)...)
+}
+
+// ErrUnsupportedArchive is reported by WithArchiveDescent()
+// for archive formats that can't be read
+var ErrUnsupportedArchive = v2.ErrUnsupportedArchive
+
+// ErrUnsafeArchiveMember is reported by WithArchiveDescent() for archive
+// members with absolute names or names containing "..", which are skipped
+var ErrUnsafeArchiveMember = v2.ErrUnsafeArchiveMember
+
+// archive formats supported by WithArchiveDescent()
+const (
+	ArchiveZip   = v2.ArchiveZip
+	ArchiveTar   = v2.ArchiveTar
+	ArchiveTarGz = v2.ArchiveTarGz
+)
+
+// WithArchiveDescent makes the walk look inside the archives of the
+// given formats (all supported formats if none are given) and pass
+// their members to the callback as synthetic entries named
+// "path!/member", which share the OrderToken of the archive. Archives are
+// recognized by their extensions, and at most four of them
+// are read at the same time. The member names are cleaned, and members
+// with absolute names or names containing ".." are skipped and reported
+// with ErrUnsafeArchiveMember; the AbsPath of the members is the path
+// of the archive. The members are not filtered, archives nested in
+// archives are not descended into, and returning SkipDir for a member
+// directory skips its members. Errors reading an archive are reported
+// for its path.
+func WithArchiveDescent(formats ...string) Option {
+	return v2.WithArchiveDescent(formats...)
+}
+
+// FileKey identifies a version of a directory: its device and inode
+// numbers, and its modification time in nanoseconds, which changes
+// whenever entries are added, removed or renamed
+type FileKey = v2.FileKey
+
+// CachedDir is a directory listing stored in a MetadataCache;
+// Infos holds the file info of each entry in Names, or nil
+// if it is not known, which is only reused for immutable trees
+// (see WithImmutableTree())
+type CachedDir = v2.CachedDir
+
+// MetadataCache stores directory listings along with the file info
+// of their entries between walks, see WithMetadataCache().
+// Implementations must be safe for concurrent use
+type MetadataCache = v2.MetadataCache
+
+// WithMetadataCache makes the walk reuse the directory listings stored
+// in c, as long as the directory itself hasn't changed (see FileKey),
+// skipping the readdir calls. This is meant for repeated walks in
+// long-running processes. The entries of a cached directory are still
+// stat'ed, as changes to files (e.g. writing to an existing file) don't
+// update their directory; their file info is only reused as well
+// with WithImmutableTree(). A cache should not be shared between walks
+// with different symlink following settings. Caching is not available
+// on Windows.
+func WithMetadataCache(c MetadataCache) Option {
+	return v2.WithMetadataCache(c)
+}
+
+// CacheStats holds the statistics of an LRUCache
+type CacheStats = v2.CacheStats
+
+// LRUCache is a MetadataCache which keeps up to a given number
+// of directories, evicting the least recently used ones
+type LRUCache = v2.LRUCache
+
+// NewLRUCache creates an LRUCache keeping up to maxDirs directories
+func NewLRUCache(maxDirs int) *LRUCache {
+	return v2.NewLRUCache(maxDirs)
+}
+
+// ErrOwnerChanged is returned by ApplyChown() for the files
+// which owner has changed since the operations were planned
+var ErrOwnerChanged = v2.ErrOwnerChanged
+
+// IDMap maps user and group IDs, e.g. from one identity domain
+// to another, see PlanChown(); IDs not in the maps are kept
+type IDMap = v2.IDMap
+
+// ChownOp is a change of the owner of a file, see PlanChown()
+type ChownOp = v2.ChownOp
+
+// PlanChown walks root and returns the changes of owner needed to map
+// the user and group IDs of the files with m, without changing anything
+// (the list is a dry-run diff, see ChownOp.String()). The operations
+// are in post-order: the contents of every directory come before the
+// directory itself, and they can be applied with ApplyChown(). Files
+// with multiple hard links are only listed once. Symbolic links are
+// changed themselves rather than their targets. On platforms without
+// numeric owners (Windows, Plan 9), nothing is planned. Errors are
+// returned after the walk along with the operations planned for the
+// entries that could be read.
+func PlanChown(root string, m IDMap, opts ...Option) ([]ChownOp, error) {
+	return v2.PlanChown(root, m, withGlobals(opts)...)
+}
+
+// ApplyChown applies the operations planned by PlanChown() to the tree
+// at root, deepest directory levels first, so that every directory is
+// changed after its contents; the operations of each level are applied
+// by as many goroutines as a walk with opts has workers (see WithWorkers()).
+// To avoid clobbering concurrent changes, the files which owner is
+// no longer the one planned are left alone and reported with
+// ErrOwnerChanged. On Linux, the paths are resolved without following
+// symlinks and the files are changed through file descriptors, so that
+// the tree can't be modified during the run to redirect a change outside
+// of it; elsewhere, the symlinks among the parent directories are
+// followed, so don't run ApplyChown() there with more privileges than
+// the users who can modify the tree. The failed operations are returned
+// as a WalkerErrorList.
+func ApplyChown(root string, ops []ChownOp, opts ...Option) error {
+	return v2.ApplyChown(root, ops, withGlobals(opts)...)
+}
+
+// Chunk is a content-defined chunk of a file, see WithChunking()
+type Chunk = v2.Chunk
+
+// ChunkParams are the minimum, average and maximum chunk sizes
+// of content-defined chunking, in bytes
+type ChunkParams = v2.ChunkParams
+
+// DefaultChunkParams are the chunk sizes commonly used by FastCDC
+var DefaultChunkParams = v2.DefaultChunkParams
+
+// ChunkFunc receives the chunks of a file, see WithChunking()
+type ChunkFunc = v2.ChunkFunc
+
+// WithChunking adds a content stage (see WithContentStage()) which
+// splits every regular file into content-defined chunks with FastCDC
+// (a gear rolling hash with normalized chunking), and passes the list
+// of chunks, with their SHA-256 digests, to fn, e.g. for delta-sync
+// engines: an insertion into a file only changes the chunks around it.
+// The files are chunked by the workers, so fn must be safe for
+// concurrent use. The chunk boundaries only depend on the content
+// and on p, which must satisfy 64 <= Min <= Avg <= Max.
+func WithChunking(p ChunkParams, fn ChunkFunc) Option {
+	return v2.WithChunking(p, fn)
+}
+
+// Chunks splits the content read from r into content-defined chunks,
+// like WithChunking() does
+func Chunks(r io.Reader, p ChunkParams) ([]Chunk, error) {
+	return v2.Chunks(r, p)
+}
+
+// Sample defines the part of a file passed to a classifier,
+// see WithClassifier()
+type Sample = v2.Sample
+
+// ClassifyFunc inspects a sample of the file content (e.g. looks
+// for personal data); entry is the one passed to the callback.
+// It is called by the workers, so it must be safe for concurrent use.
+type ClassifyFunc = v2.ClassifyFunc
+
+// WithClassifier adds a content stage (see WithContentStage()) which
+// reads a sample of every regular file and passes it to fn: the first
+// sample.Head bytes, followed by sample.Chunks chunks of sample.ChunkSize
+// bytes read at random offsets in the rest of the file (in the order
+// of their offsets). Files not larger than the sample are read entirely.
+// The offsets are derived from the path, so repeated walks read the same
+// chunks. If bytesPerSec is positive, the reads of all workers are
+// throttled to this rate. As the sample is a small part of large files,
+// consider disabling the read-ahead hints with WithoutPrefetch().
+func WithClassifier(fn ClassifyFunc, sample Sample, bytesPerSec int64) Option {
+	return v2.WithClassifier(fn, sample, bytesPerSec)
+}
+
+// Config is a serializable description of the walker settings, meant
+// to be embedded into the configuration files of services (it has both
+// JSON and YAML field tags); Options() converts it to walker options.
+// The zero value means the defaults.
+type Config = v2.Config
+
+// ConsistencyError reports a violation of the walker's internal
+// invariants detected when the walk is run with WithConsistencyChecks()
+type ConsistencyError = v2.ConsistencyError
+
+// WithConsistencyChecks enables a debug mode which verifies that
+// each path is passed to the callback exactly once per walk.
+// A violation is reported as a *ConsistencyError in the returned
+// WalkerErrorList, and the duplicate is not passed to the callback.
+// This mode keeps every visited path in memory, so it is intended
+// for testing and debugging only.
+func WithConsistencyChecks() Option {
+	return v2.WithConsistencyChecks()
+}
+
+// ContentFunc processes the content of a regular file (e.g. hashes it);
+// entry is the one passed to the callback, and r reads the file from
+// the beginning. It is called by the workers, so it must be safe
+// for concurrent use.
+type ContentFunc = v2.ContentFunc
+
+// WithContentStage makes the walk read every regular file passed to the
+// callback (unless the callback returned an error or SkipDir for it)
+// and pass its content to fn, so that content processing, such as
+// hashing, is spread over the workers. Multiple stages may be added:
+// the file is opened once, and each stage reads it from the beginning.
+// Errors (including the ones returned by fn) are collected as usual.
+// Read-ahead hints are given for the opened files, see WithoutPrefetch().
+func WithContentStage(fn ContentFunc) Option {
+	return v2.WithContentStage(fn)
+}
+
+// WithoutPrefetch disables the read-ahead hints (posix_fadvise(2) with
+// POSIX_FADV_SEQUENTIAL and POSIX_FADV_WILLNEED on Linux) given for the
+// files opened by the content stages, which improve the throughput of
+// sequential reads on spinning disks, but may waste I/O if the stages
+// only read the beginning of the files
+func WithoutPrefetch() Option {
+	return v2.WithoutPrefetch()
+}
+
+// WithNoAtime makes the content stages open files with O_NOATIME
+// on Linux, so that reading them doesn't update their access times
+// (which matters for forensic use, and avoids a write for every file
+// read on file systems mounted without noatime or relatime). The flag
+// is only permitted for the owner of the file or a privileged user;
+// other files are silently opened without it. Other platforms don't
+// have an equivalent open flag, so this option has no effect there.
+func WithNoAtime() Option {
+	return v2.WithNoAtime()
+}
+
+// ErrFileReplaced is reported for the files which are no longer the
+// regular files that were listed when they are opened for the content
+// stages (see WithContentStage() and WithArchiveDescent())
+var ErrFileReplaced = v2.ErrFileReplaced
+
+// ErrNotDir indicates that the path, which is being passed
+// to a walker function, does not point to a directory
+// (only reported when the WithStrictRoot() option is used)
+var ErrNotDir = v2.ErrNotDir
+
+// ErrStopped is returned by the walk when it was stopped with Stop()
+var ErrStopped = v2.ErrStopped
+
+// ErrTooManyErrors indicates that the walk has reported more errors
+// than allowed by WithMaxErrors(); the WalkerErrorList
+// returned by Walk() satisfies errors.Is(err, ErrTooManyErrors)
+// in this case
+var ErrTooManyErrors = v2.ErrTooManyErrors
+
+// WalkerError struct stores individual errors reported from each worker routine
+type WalkerError = v2.WalkerError
+
+// WalkerErrorList struct store a list of errors reported from all worker routines
+type WalkerErrorList = v2.WalkerErrorList
+
+// OrderToken identifies the position of an entry in the tree,
+// so that entries processed concurrently can be put back
+// into a deterministic order once the walk is complete
+type OrderToken = v2.OrderToken
+
+// Entry describes a single file or directory visited by the walker
+type Entry = v2.Entry
+
+// EntryFunc is the type of the function called for each
+// file or directory visited by WalkEntries(). The err argument
+// has the same meaning as in filepath.WalkFunc
+type EntryFunc = v2.EntryFunc
+
+// Walker is constructed for each Walk() function invocation
+type Walker = v2.Walker
+
+// Usage is the disk usage of a file, or of a directory
+// including its contents, see DiskUsage()
+type Usage = v2.Usage
+
+// UsageConfig selects the entries listed by DiskUsage()
+type UsageConfig = v2.UsageConfig
+
+// LinkSet is the set of the files with multiple hard links counted
+// by DiskUsage(), see UsageConfig; the zero value is an empty set
+type LinkSet = v2.LinkSet
+
+// DiskUsage walks root and returns the disk usage of the root and of
+// the entries under it selected by cfg, listed like du does: the
+// entries of each directory (in lexical order, as the concurrent walk
+// has no directory order) followed by the directory itself, so the
+// root comes last. The usage of the entries which are not listed is
+// added up as the walk goes, so only the listed entries are kept in
+// memory. Like du, it counts the files with multiple hard links only
+// once (and lists only the first path found for them). Errors are
+// returned after the walk along with the usage of the entries that
+// could be read.
+func DiskUsage(root string, cfg UsageConfig, opts ...Option) ([]Usage, error) {
+	return v2.DiskUsage(root, cfg, withGlobals(opts)...)
+}
+
+// FormatUsage formats the usage the way du does: the number of blocks
+// of blockSize bytes, rounded up, or if human is set, a value rounded up
+// to two significant digits with a binary unit suffix (du -h)
+func FormatUsage(bytes, blockSize int64, human bool) string {
+	return v2.FormatUsage(bytes, blockSize, human)
+}
+
+// ErrSequentialOption indicates that an option
+// is not supported by the Sequential() engine
+var ErrSequentialOption = v2.ErrSequentialOption
+
+// TreeWalker is a walk engine, so that applications can choose the
+// engine at runtime, e.g. the sequential one for spinning disks, where
+// concurrent reads cause seeks, and the concurrent one otherwise
+type TreeWalker = v2.TreeWalker
+
+// Concurrent returns the concurrent TreeWalker,
+// which walks with the options like Walk() does
+func Concurrent(opts ...Option) TreeWalker {
+	return v2.Concurrent(withGlobals(opts)...)
+}
+
+// Sequential returns a TreeWalker built on filepath.Walk, so that walkFn
+// is called from one goroutine at a time, in lexical order, and only
+// one directory is read at a time. It calls walkFn with the same paths,
+// relative to the root, as the concurrent engine, and follows the same
+// rules: the filters of the options apply (see WithFilter()), walkFn
+// may return SkipDir just like with filepath.Walk, and the other errors
+// it returns, as well as the directory read errors, are returned as a
+// WalkerErrorList once the walk is complete. Besides the filters,
+// it only supports WithContext() and WithMaxErrors(), as well as
+// WithWorkers() and WithQueueCapacity(), which have no effect on it;
+// the walk fails with ErrSequentialOption if any other option is set.
+// As it shares no code with the concurrent engine beyond the filters,
+// it is the reference for differential tests (see walktest.Differential()).
+func Sequential(opts ...Option) TreeWalker {
+	return v2.Sequential(withGlobals(opts)...)
+}
+
+// DefaultEntropyThreshold is the entropy, in bits per byte, above which
+// content is considered high-entropy: compressed or encrypted data is
+// close to 8, while text and most executables stay well below 7
+const DefaultEntropyThreshold = v2.DefaultEntropyThreshold
+
+// FileEntropy is the entropy measured for a file, see WithEntropy()
+type FileEntropy = v2.FileEntropy
+
+// EntropySnapshot holds the entropy of the files measured by a walk
+// with WithEntropy(), so that it can be compared with the one measured
+// by the next walk (see CompareEntropy()). It can be saved between
+// walks with Save() and LoadEntropySnapshot(). EntropySnapshot is safe
+// for concurrent use.
+type EntropySnapshot = v2.EntropySnapshot
+
+// NewEntropySnapshot creates an empty EntropySnapshot
+func NewEntropySnapshot() *EntropySnapshot {
+	return v2.NewEntropySnapshot()
+}
+
+// WithEntropy adds a content stage (see WithContentStage()) which
+// measures the Shannon entropy of a sample of every regular file
+// (see WithClassifier() for the sample and the throttling of the
+// reads) and records it in s, replacing what s has recorded before.
+// Encrypting files raises their entropy close to the maximum, so
+// that a sudden rise between walks is a sign of ransomware activity,
+// see CompareEntropy().
+func WithEntropy(s *EntropySnapshot, sample Sample, bytesPerSec int64) Option {
+	return v2.WithEntropy(s, sample, bytesPerSec)
+}
+
+// LoadEntropySnapshot reads the files written by EntropySnapshot.Save()
+func LoadEntropySnapshot(r io.Reader) (*EntropySnapshot, error) {
+	return v2.LoadEntropySnapshot(r)
+}
+
+// EntropyDiff is the change of the entropy of the files between
+// two snapshots, see CompareEntropy()
+type EntropyDiff = v2.EntropyDiff
+
+// CompareEntropy compares the entropy of the files recorded in the
+// snapshots of two walks, with the entropy threshold in bits per byte
+// (DefaultEntropyThreshold if zero)
+func CompareEntropy(prev, cur *EntropySnapshot, threshold float64) EntropyDiff {
+	return v2.CompareEntropy(prev, cur, threshold)
+}
+
+// WithSuppressedErrors makes the walk drop the errors matching any
+// of the classes (as per errors.Is(), e.g. fs.ErrPermission or
+// fs.ErrNotExist), which are expected when scanning system directories
+// as an unprivileged user: they are counted in Report.Failed and
+// Report.Suppressed, and passed to the outcome function, but not stored
+// or returned (nor do they count towards the error limit or stop
+// a WithFailFast() walk). The callback still gets these errors.
+func WithSuppressedErrors(classes ...error) Option {
+	return v2.WithSuppressedErrors(classes...)
+}
+
+// Interval is an estimated quantity along with
+// the bounds of its 95% confidence interval
+type Interval = v2.Interval
+
+// EstimateResult holds the estimated size of a tree, see Estimate()
+type EstimateResult = v2.EstimateResult
+
+// Estimate estimates the number of files and directories in the tree
+// at root, and the total size of the files, spending about the given
+// time, for capacity planning. If the tree can be walked completely in
+// a quarter of the budget, the result is exact. Otherwise, the rest of
+// the budget is spent on random descents from the root (Knuth's
+// estimator): each descent picks a random subdirectory at every level
+// and scales what it finds by the fan-out of the directories above,
+// which gives an unbiased estimate of the whole tree; the result is
+// the mean of the descents. The estimate converges slowly for very
+// unbalanced trees, which shows in wide confidence intervals.
+// Symlinks are not followed.
+//
+// The descents stop reading as soon as the budget is spent, even in
+// the middle of a huge directory; the descents which didn't complete
+// are not counted. If not even the root directory could be read within
+// the budget, Estimate() fails with context.DeadlineExceeded.
+//
+// Unlike WithSampling(), which reads every directory, the estimate
+// only reads the directories along the descents, so its cost is
+// bounded by the budget however large the tree is. The options apply
+// to the walk; of them, only the number of workers (see WithWorkers())
+// applies to the descents.
+func Estimate(root string, budget time.Duration, opts ...Option) (EstimateResult, error) {
+	return v2.Estimate(root, budget, withGlobals(opts)...)
+}
+
+// ExplainPlan returns a human-readable description of the effective
+// walk settings for the configuration, including the ones set
+// by its presets
+func ExplainPlan(cfg Config) (string, error) {
+	return v2.ExplainPlan(cfg)
+}
+
+// FDBudget is a Limiter which caps the number of open file descriptors:
+// once the budget is used up, the walk queues the directory reads (and
+// the content reads) until descriptors are released, instead of failing
+// with EMFILE. The same budget can be passed to any number of walks,
+// and used by the application for its own files, e.g. by the content
+// stages opening other files than the one they are passed. The waiters
+// are served in FIFO order. FDBudget is safe for concurrent use.
+type FDBudget = v2.FDBudget
+
+// FDBudgetStats holds the current usage of an FDBudget
+type FDBudgetStats = v2.FDBudgetStats
+
+// NewFDBudget creates an FDBudget of n descriptors (at least one)
+func NewFDBudget(n int) *FDBudget {
+	return v2.NewFDBudget(n)
+}
+
+// WithFDBudget makes the walk acquire a descriptor from b for every
+// directory it reads and for every file read by the content stages,
+// as long as they are open; this is WithLimiter(b)
+func WithFDBudget(b *FDBudget) Option {
+	return v2.WithFDBudget(b)
+}
+
+// WalkList is a wrapper function for the Walker object
+// that works like Walker.WalkList()
+func WalkList(root string, list io.Reader, fn EntryFunc, opts ...Option) error {
+	return v2.WalkList(root, list, fn, withGlobals(opts)...)
+}
+
+// FilterAction tells the walker what to do with an entry
+type FilterAction = v2.FilterAction
+
+const (
+	// FilterInclude passes the entry to the callback
+	FilterInclude = v2.FilterInclude
+	// FilterExclude doesn't pass the entry to the callback,
+	// but still descends into it if it is a directory
+	FilterExclude = v2.FilterExclude
+	// FilterPrune neither passes the entry to the callback,
+	// nor descends into it
+	FilterPrune = v2.FilterPrune
+)
+
+// FilterFunc decides what to do with an entry before it is passed
+// to the callback; relpath is relative to the walker root, and info
+// is nil if the entry could not be stat'ed
+type FilterFunc = v2.FilterFunc
+
+// WithFilter adds a filter to the walk. Filters are called in the order
+// they were added, and the first one that returns anything other than
+// FilterInclude decides what happens to the entry. Filters apply to the
+// root of the walk too (unlike the built-in filters, such as the one of
+// WithSkipHidden(), which never skip the root).
+// Filters must be safe for concurrent use.
+func WithFilter(fn FilterFunc) Option {
+	return v2.WithFilter(fn)
+}
+
+// DevTreeIgnoredDirs lists the names of the directories
+// pruned by WithDevTreeDefaults(): VCS internals, dependency
+// directories and common build outputs
+var DevTreeIgnoredDirs = v2.DevTreeIgnoredDirs
+
+// WithDevTreeDefaults prunes the directories listed in DevTreeIgnoredDirs,
+// except for the ones named in keep, which is what most developer tools
+// want when walking source trees
+func WithDevTreeDefaults(keep ...string) Option {
+	return v2.WithDevTreeDefaults(keep...)
+}
+
+// ErrFindSyntax is returned by ParseFindExpr() for invalid expressions
+var ErrFindSyntax = v2.ErrFindSyntax
+
+// FindExpr is a find(1) expression, see ParseFindExpr()
+type FindExpr = v2.FindExpr
+
+// ParseFindExpr parses a subset of the find(1) expression syntax:
+//
+//	-name pattern    the base name matches the shell pattern
+//	-type c          the file is of type c: f, d, l, p, s, c or b
+//	-size [+-]n[ckMGwb]  the size, rounded up to the units (512-byte blocks by default)
+//	-mtime [+-]n     the file was modified n*24 hours ago (rounded down)
+//	-newer file      the file was modified more recently than file
+//	-maxdepth n      descend at most n levels below the root
+//	-prune           true; don't descend into the directory
+//	-print           true (entries are passed to the callback if the expression is true)
+//	! expr, -not expr, expr -a expr, expr -and expr, expr expr,
+//	expr -o expr, expr -or expr, ( expr )
+//
+// The time of the call is used as the current time for -mtime,
+// and the file given to -newer is stat'ed by the call.
+func ParseFindExpr(args []string) (*FindExpr, error) {
+	return v2.ParseFindExpr(args)
+}
+
+// WithFindExpr adds a filter which passes the entries matching
+// the expression to the callback, and doesn't descend into the
+// directories pruned by it. Unlike find(1), a directory for which -prune
+// is evaluated is not passed to the callback, even if the expression
+// is true for it. Entries which could not be stat'ed are passed to the
+// callback along with the error.
+func WithFindExpr(e *FindExpr) Option {
+	return v2.WithFindExpr(e)
+}
+
+// ErrForensicConflict is returned by the walk when WithForensicMode()
+// is combined with a setting which could modify the file system
+var ErrForensicConflict = v2.ErrForensicConflict
+
+// WithForensicMode guarantees that the walk doesn't modify the walked
+// tree in any way, for evidence handling:
+//
+//   - the content stages, archive descent and the ignore files (see
+//     WithGitignore()) open files with O_NOATIME (see WithNoAtime()),
+//     and report an error for the files that can't be opened this way
+//     instead of updating their access times; on platforms without
+//     O_NOATIME, these features are not allowed;
+//   - the content stages never open anything but the regular files
+//     which were listed: files replaced in the meantime (e.g. by a FIFO
+//     or a device, which opening could affect) are reported with
+//     ErrFileReplaced;
+//   - the content of cloud placeholder files (e.g. OneDrive Files
+//     On-Demand) is never read, as that would download it;
+//   - symlinks are never followed (so neither are magic links,
+//     such as /proc/[pid]/fd/*).
+//
+// The walker itself never writes to the walked tree. If another option
+// conflicts with these rules, the walk fails with ErrForensicConflict.
+func WithForensicMode() Option {
+	return v2.WithForensicMode()
+}
+
+// FuzzyHasher is a similarity (fuzzy) hashing algorithm, such as
+// ssdeep or TLSH, plugged into the walk with WithFuzzyHashing();
+// implementations typically wrap a third-party package
+type FuzzyHasher = v2.FuzzyHasher
+
+// FuzzyHash computes the similarity digest of the content written to it
+type FuzzyHash = v2.FuzzyHash
+
+// FuzzyDigest is the similarity digest of a file computed by a hasher
+type FuzzyDigest = v2.FuzzyDigest
+
+// FuzzyFunc receives the similarity digests of a file, in the order
+// of the hashers passed to WithFuzzyHashing()
+type FuzzyFunc = v2.FuzzyFunc
+
+// FuzzyLimits are the sizes of the files WithFuzzyHashing() hashes:
+// files smaller than MinSize carry too little content to be compared,
+// and files larger than MaxSize (if not zero) would take too long
+type FuzzyLimits = v2.FuzzyLimits
+
+// WithFuzzyHashing adds a content stage (see WithContentStage()) which
+// computes the similarity digests of the regular files within limits
+// with every hasher, reading each file once, and passes them to fn, e.g.
+// for malware triage on file servers. The files are hashed by the
+// workers, so fn must be safe for concurrent use; it isn't called for
+// the files out of limits. A hasher failing to compute a digest for
+// a file doesn't fail the file, see FuzzyDigest.Err.
+func WithFuzzyHashing(limits FuzzyLimits, fn FuzzyFunc, hashers ...FuzzyHasher) Option {
+	return v2.WithFuzzyHashing(limits, fn, hashers...)
+}
+
+// GitMode selects the files passed to the callback by WithGitFilter()
+type GitMode = v2.GitMode
+
+const (
+	// GitTracked selects the files tracked in the git index
+	GitTracked = v2.GitTracked
+	// GitUntracked selects the files not tracked in the git index
+	// (including the ignored ones)
+	GitUntracked = v2.GitUntracked
+	// GitModified selects the tracked files which differ from the HEAD
+	// commit (like git diff HEAD): the files which changes are staged,
+	// and the ones which size or modification time differs from the
+	// ones recorded in the git index. The contents are not compared,
+	// so files which are modified within the timestamp granularity of
+	// the index write (whose contents git would compare) are not
+	// selected, unless their size changed.
+	GitModified = v2.GitModified
+)
+
+// ErrNoGitRepo indicates that WithGitFilter() could not find
+// a git repository containing the walker root
+var ErrNoGitRepo = v2.ErrNoGitRepo
+
+// WithGitFilter makes the walk pass to the callback only the files
+// selected by mode, based on the index of the git repository containing
+// the walker root (the index file, and for GitModified the objects of
+// the HEAD commit, are parsed directly, so git doesn't need to be
+// installed). Directories are always passed to the callback with
+// GitUntracked, and only if they contain tracked files otherwise;
+// the .git directory itself is pruned. If the repository can't
+// be read, the walk fails with the corresponding error.
+func WithGitFilter(mode GitMode) Option {
+	return v2.WithGitFilter(mode)
+}
+
+// WithGitignore prunes the entries ignored by the .gitignore files
+// (and the .ignore files used by ripgrep and fd, which take precedence),
+// both in the walked tree and in its parent directories up to the top
+// of the git work tree containing the root, if any. The files are read
+// as the walk reaches their directories, through the same file system
+// access as the walk (see WithConfineToRoot() and WithTraceReplay()),
+// and only if they are regular files; the ones in the parent
+// directories are not read by confined walks and replays. Global
+// excludes and .git/info/exclude are not taken into account.
+func WithGitignore() Option {
+	return v2.WithGitignore()
+}
+
+// ErrGoroutineLeak is returned by the walk when WithGoroutineCheck()
+// is used and some of the goroutines started by the walk are still
+// running after it has completed, which indicates a bug in the walker
+var ErrGoroutineLeak = v2.ErrGoroutineLeak
+
+// WithGoroutineCheck is a debug mode which verifies that every goroutine
+// started by the walk has exited by the time it returns, on all paths
+// (including errors, aborts and cancellation); if that is not the case,
+// the walk returns ErrGoroutineLeak. The number of goroutines started
+// is available as Report.Goroutines.
+func WithGoroutineCheck() Option {
+	return v2.WithGoroutineCheck()
+}
+
+// Quota limits the resources used by a single walk, see WithQuota();
+// zero fields mean no limit other than the Governor's own
+type Quota = v2.Quota
+
+// Governor shares the workers and the file descriptors of a process
+// between many walks running at the same time, e.g. scanning the
+// mounts of different customers, so that one huge tree can't starve
+// the other walks: each walk gets its own Quota, and the walks take
+// turns (in FIFO order) for the workers and descriptors of the process.
+// Governor is safe for concurrent use.
+type Governor = v2.Governor
+
+// GovernorStats holds the current usage of a Governor
+type GovernorStats = v2.GovernorStats
+
+// NewGovernor creates a Governor letting at most the given number
+// of workers process directories, and at most fds descriptors be open,
+// at the same time in all governed walks
+func NewGovernor(workers, fds int) *Governor {
+	return v2.NewGovernor(workers, fds)
+}
+
+// WithQuota makes the walk run under the governor g with the quota q.
+// The descriptors are acquired from g as with WithFDBudget(), which
+// replaces the Limiter set by WithLimiter(), if any.
+func WithQuota(g *Governor, q Quota) Option {
+	return v2.WithQuota(g, q)
+}
+
+// Index is a sorted list of the paths recorded during a walk
+// (see WithIndex()), which answers prefix, extension and glob
+// queries without touching the file system, e.g. for interactive
+// file pickers over large trees. Paths are slash-separated and
+// relative to the walker root. Index is safe for concurrent use.
+type Index = v2.Index
+
+// NewIndex creates an empty Index
+func NewIndex() *Index {
+	return v2.NewIndex()
+}
+
+// WithIndex makes the walk record every visited path in ix,
+// replacing what ix has recorded before
+func WithIndex(ix *Index) Option {
+	return v2.WithIndex(ix)
+}
+
+// IOClass is an I/O scheduling class, see IOPriority
+type IOClass = v2.IOClass
+
+// I/O scheduling classes, as in ionice(1)
+const (
+	IOClassNone       = v2.IOClassNone
+	IOClassRealtime   = v2.IOClassRealtime
+	IOClassBestEffort = v2.IOClassBestEffort
+	IOClassIdle       = v2.IOClassIdle
+)
+
+// IOPriority is the I/O and CPU scheduling priority of the workers,
+// see WithIOPriority()
+type IOPriority = v2.IOPriority
+
+// WithIOPriority makes the workers run with the I/O scheduling class
+// (like ionice(1)) and the niceness of p, so that background scans on
+// production hosts stay out of the way of serving traffic. Only the
+// workers are affected, not the rest of the process: each of them
+// runs on its own thread for the duration of the walk, which is
+// discarded afterwards. The walk fails if the priority can't be set.
+//
+// Note that the I/O classes are only honored by some I/O schedulers
+// (BFQ on Linux; Report.Hints tells if the root's device uses another
+// one): on devices shared with other workloads, limiting the walk with
+// the io.max file of a cgroup v2 is more effective, see CgroupIOMax().
+// On Windows, the idle class puts the workers into the background
+// mode, which lowers their I/O and memory priority, so that e.g.
+// desktop indexing doesn't make the machine stutter, and Nice maps
+// to the closest thread priority level; the other classes have no
+// equivalent there. The option has no effect on other platforms.
+func WithIOPriority(p IOPriority) Option {
+	return v2.WithIOPriority(p)
+}
+
+// IOMax is a limit from the io.max file of a cgroup v2, for the
+// device identified by its major and minor numbers; negative
+// values mean no limit
+type IOMax = v2.IOMax
+
+// CgroupIOMax returns the directory of the cgroup v2 of the process
+// and the limits set in its io.max file, so that background scans can
+// check (or log) whether they are throttled. To throttle a scan,
+// move the process to a cgroup and write its limits to io.max, e.g.
+// "8:0 rbps=10485760 riops=500" (see the cgroup v2 documentation).
+func CgroupIOMax() (string, []IOMax, error) {
+	return v2.CgroupIOMax()
+}
+
+// LatencyFunc returns the latency to inject into
+// a single file system operation, see WithInjectedLatency()
+type LatencyFunc = v2.LatencyFunc
+
+// FixedLatency returns a LatencyFunc which always returns d
+func FixedLatency(d time.Duration) LatencyFunc {
+	return v2.FixedLatency(d)
+}
+
+// UniformLatency returns a LatencyFunc with latencies
+// distributed uniformly between min and max
+func UniformLatency(min, max time.Duration) LatencyFunc {
+	return v2.UniformLatency(min, max)
+}
+
+// LogNormalLatency returns a LatencyFunc with latencies following
+// a log-normal distribution with the given median, which models the
+// long tail of network storage; sigma is the standard deviation of
+// the logarithm of the latency (e.g. 0.5 for a moderate tail, 1.5
+// for occasional stalls two orders of magnitude above the median)
+func LogNormalLatency(median time.Duration, sigma float64) LatencyFunc {
+	return v2.LogNormalLatency(median, sigma)
+}
+
+// WithInjectedLatency delays every directory listing by the duration
+// returned by readDir, and every stat call by the one returned by stat
+// (either may be nil), which is meant for modeling how the walk, e.g.
+// with a given number of workers, would behave on slower storage.
+// The delays block the workers just like the real latencies would.
+func WithInjectedLatency(readDir, stat LatencyFunc) Option {
+	return v2.WithInjectedLatency(readDir, stat)
+}
+
+// WithLazyStat skips stat'ing the directory entries which type is
+// reported by the directory listing (d_type): their Entry.Info only
+// knows the name and the type, and lstat is called the first time any
+// other file info is asked for, such as the size, the permissions or
+// the modification time. The entries of a directory, their paths and
+// their file info are allocated along with the listing, so walks that
+// mostly look at the names, such as WalkMap() with a callback checking
+// d.Name() and d.Type(), take a few allocations per directory rather than
+// several per entry (see also WalkBytes()). The FileInfo also
+// implements fs.DirEntry, which Info() returns the stat'ed file info or
+// error.
+//
+// Directories are still stat'ed during the walk, and so are symlinks
+// when they are followed (see WithFollowSymlinks()), and all entries
+// whose type is unknown. If a file can't be stat'ed later (e.g. it was
+// removed in the meantime), no error is reported by the walk: its
+// Size() and ModTime() are zero, and Mode() reports the type only. The
+// file info should not be used once the walk has returned, as the root
+// may no longer be accessible (see WithConfineToRoot()). The types are
+// listed on Linux only, with the directory cache disabled (see
+// WithCache()); elsewhere, the option has no effect.
+func WithLazyStat() Option {
+	return v2.WithLazyStat()
+}
+
+// Limiter is an external concurrency limit shared with the rest of the
+// application, such as a global file descriptor budget; its method set
+// matches *semaphore.Weighted from golang.org/x/sync/semaphore
+type Limiter = v2.Limiter
+
+// WithLimiter makes every worker acquire a unit of l for the time
+// a directory, or a file read by the content stages, is open, so that
+// the walk composes with application-wide I/O budgets instead of
+// competing with them (see FDBudget). Acquire is called with a context
+// derived from the one set by WithContext() (if any), which is also
+// canceled by Stop(); if it fails, the walk is aborted and returns
+// the error.
+func WithLimiter(l Limiter) Option {
+	return v2.WithLimiter(l)
+}
+
+// DetectType returns the type of the file which content starts with
+// head (of which 512 bytes are enough), detected by its magic bytes,
+// such as "pdf", "zip" or "elf"; "" if unknown
+func DetectType(head []byte) string {
+	return v2.DetectType(head)
+}
+
+// TypeMismatch is a file which type contradicts its extension,
+// see TypeCensus()
+type TypeMismatch = v2.TypeMismatch
+
+// TypeReport is the result of TypeCensus()
+type TypeReport = v2.TypeReport
+
+// TypeCensus walks root and counts the regular files by their true type
+// detected from their magic bytes (see DetectType()) and by extension,
+// and lists the files which type contradicts their extension, such as
+// executables renamed to .jpg, or documents which no longer start with
+// the bytes of their format (e.g. once encrypted). Only the first bytes
+// of every file are read, by the content stages, so the reads are bounded
+// like those of other stages (see WithLimiter() and WithFDBudget());
+// read-ahead is disabled (see WithoutPrefetch()). Errors are returned
+// after the walk along with the report of the files that could be read.
+func TypeCensus(root string, opts ...Option) (TypeReport, error) {
+	return v2.TypeCensus(root, withGlobals(opts)...)
+}
+
+// Metadata holds the extended file metadata collected
+// when the WithEnrichedMetadata() option is used; the fields
+// not supported on the platform are left empty
+type Metadata = v2.Metadata
+
+// FileFlags are the file flags reported in Metadata.Flags
+type FileFlags = v2.FileFlags
+
+const (
+	// FlagImmutable is set for files which can't be modified, renamed
+	// or deleted (chattr +i on Linux, the BSD uchg and schg flags)
+	FlagImmutable = v2.FlagImmutable
+	// FlagAppendOnly is set for files which can only be appended to
+	// (chattr +a on Linux, the BSD uappnd and sappnd flags)
+	FlagAppendOnly = v2.FlagAppendOnly
+	// FlagHidden is set for hidden files: the ones with names starting
+	// with a dot on Unix, or with the UF_HIDDEN flag on macOS and FreeBSD,
+	// and the ones with the hidden attribute on Windows
+	FlagHidden = v2.FlagHidden
+	// FlagSystem is set for the files with the system attribute (Windows only)
+	FlagSystem = v2.FlagSystem
+)
+
+// ACE is a summary of a single access control entry of a DACL
+type ACE = v2.ACE
+
+// WithEnrichedMetadata makes the walk collect extended metadata
+// for every entry, available as Entry.Meta
+func WithEnrichedMetadata() Option {
+	return v2.WithEnrichedMetadata()
+}
+
+// WithWindowsACL makes the walk collect the owner SID and a summary
+// of the DACL of every entry into Entry.Meta on Windows (where the owner
+// account name is also reported as Metadata.User); it implies
+// WithEnrichedMetadata(). The security information is read
+// by the workers, so this scales with the number of workers.
+// This option has no effect on other platforms.
+func WithWindowsACL() Option {
+	return v2.WithWindowsACL()
+}
+
+// WithSecurityLabels makes the walk collect the security label of
+// every entry into Metadata.SecurityLabel on Linux: the SELinux context
+// (the security.selinux extended attribute), or the Smack label on
+// systems using Smack instead; it implies WithEnrichedMetadata().
+// AppArmor confines programs by path rather than by labeling files, so
+// there is nothing to collect for it. The labels are read by the
+// workers without opening the files, so this scales with their number;
+// see SecurityLabelCounts() for policy audits of large trees.
+// This option has no effect on other platforms.
+func WithSecurityLabels() Option {
+	return v2.WithSecurityLabels()
+}
+
+// SecurityLabelCounts walks root and returns the number of entries
+// having each security label (see WithSecurityLabels()), counting the
+// entries without a label under "". The counts are accumulated by each
+// worker and merged once the walk is complete. The entries which label
+// could not be read are reported as errors, which are returned after
+// the walk along with the counts.
+func SecurityLabelCounts(root string, opts ...Option) (map[string]int64, error) {
+	return v2.SecurityLabelCounts(root, withGlobals(opts)...)
+}
+
+// WithSkipHidden prunes hidden files and directories, using the same
+// rules as FlagHidden, except that on Windows the files with the system
+// attribute are skipped as well. The root itself is never skipped.
+func WithSkipHidden() Option {
+	return v2.WithSkipHidden()
+}
+
+// NamePolicy tells which names CheckNames() reports as invalid
+// on the system a tree is to be migrated to
+type NamePolicy = v2.NamePolicy
+
+// WindowsNamePolicy reports the names which can't be created
+// on NTFS by Windows applications not opted into long paths
+var WindowsNamePolicy = v2.WindowsNamePolicy
+
+// SharePointNamePolicy reports the names which can't be uploaded to
+// SharePoint Online and OneDrive, which allow decoded paths of up to
+// 400 characters, the length of the site and library URL included
+// (set BaseLength accordingly)
+var SharePointNamePolicy = v2.SharePointNamePolicy
+
+// MacOSNamePolicy reports the names which collide on the default,
+// case-insensitive file systems of macOS, such as when checking out
+// a repository created on Linux
+var MacOSNamePolicy = v2.MacOSNamePolicy
+
+// NameProblem tells why a name violates a NamePolicy
+type NameProblem = v2.NameProblem
+
+const (
+	// NameReserved means the name is reserved by Windows
+	NameReserved = v2.NameReserved
+	// NameInvalidChars means the name contains invalid characters
+	NameInvalidChars = v2.NameInvalidChars
+	// NameTrailing means the name ends with a space or a period
+	NameTrailing = v2.NameTrailing
+	// NameInvalidUTF8 means the name is not valid UTF-8
+	NameInvalidUTF8 = v2.NameInvalidUTF8
+	// NameCaseCollision means other names of the same
+	// directory only differ from the name by case
+	NameCaseCollision = v2.NameCaseCollision
+	// NameNormalizationCollision means other names of the same directory
+	// only differ from the name by Unicode normalization form
+	NameNormalizationCollision = v2.NameNormalizationCollision
+	// NameDuplicate means the directory lists the name more than once,
+	// which only happens on corrupted or inconsistent file systems
+	NameDuplicate = v2.NameDuplicate
+	// NameTooLong means the name is longer than MaxNameLength
+	NameTooLong = v2.NameTooLong
+	// PathTooLong means the path is longer than MaxPathLength
+	PathTooLong = v2.PathTooLong
+)
+
+// NameViolation is a name found by CheckNames()
+type NameViolation = v2.NameViolation
+
+// CheckNames walks root and returns the names which violate the policy,
+// ordered by path and problem, e.g. to find what has to be renamed before
+// migrating a share to NTFS or SharePoint (see WindowsNamePolicy and
+// SharePointNamePolicy). A name may have several problems, which are
+// reported separately. Collisions are found among all the names of every
+// directory, including those excluded by filters, along with the names
+// listed twice (if either kind of collision is checked); the other
+// problems are only reported for the entries passed to the callback.
+// Errors are returned after the walk along with the violations found.
+func CheckNames(root string, p NamePolicy, opts ...Option) ([]NameViolation, error) {
+	return v2.CheckNames(root, p, withGlobals(opts)...)
+}
+
+// Option configures a Walker
+type Option = v2.Option
+
+// MaxErrorsAction defines what happens once
+// the limit set by WithMaxErrors() is reached
+type MaxErrorsAction = v2.MaxErrorsAction
+
+const (
+	// StopCollecting continues the walk, but further errors
+	// are only counted in WalkerErrorList.Truncated
+	StopCollecting = v2.StopCollecting
+	// AbortWalk stops the walk as soon as possible
+	AbortWalk = v2.AbortWalk
+)
+
+// WithWorkers sets the number of workers for the walk; by default,
+// or if n is not positive, it is runtime.GOMAXPROCS(0)
+func WithWorkers(n int) Option {
+	return v2.WithWorkers(n)
+}
+
+// WithQueueCapacity sets the initial capacity of the job queue. The
+// queue grows and shrinks as needed, and a Walker reused for another
+// walk starts with the queue capacity observed during the previous
+// one, so this is only worth setting for the first walk of a Walker
+// over a tree known to be very wide.
+func WithQueueCapacity(n int) Option {
+	return v2.WithQueueCapacity(n)
+}
+
+// WithFollowSymlinks makes the walk follow directory symlinks
+func WithFollowSymlinks() Option {
+	return v2.WithFollowSymlinks()
+}
+
+// WithContext makes the walk abort as soon as possible once ctx
+// is canceled, in which case the walk returns ctx.Err(). To run
+// a walk as part of an errgroup, pass the group's context here.
+func WithContext(ctx context.Context) Option {
+	return v2.WithContext(ctx)
+}
+
+// WithMaxErrors limits the number of errors stored during the walk
+// to n (there is no limit by default); if n is zero, the limit set
+// before is kept, and only the action is changed. Once the limit
+// is reached, the walk is either aborted or continues without storing
+// further errors, depending on action. In both cases, the returned
+// error satisfies errors.Is(err, ErrTooManyErrors).
+func WithMaxErrors(n int, action MaxErrorsAction) Option {
+	return v2.WithMaxErrors(n, action)
+}
+
+// WithFailFast makes the walk stop at the first error, like
+// filepath.Walk does, and return that error as a WalkerError rather
+// than a WalkerErrorList, so that errors.Is(err, fs.ErrPermission),
+// errors.As() and the like work on it directly; the path the error
+// was reported for is available with errors.As() and WalkerError.Path().
+// Other errors which occur while the walk is stopping are dropped.
+func WithFailFast() Option {
+	return v2.WithFailFast()
+}
+
+// WithStrictRoot makes the walk fail with ErrNotDir if the root
+// is not a directory (by default, walkFn is called for the root
+// file, and the walk returns nil, just like filepath.Walk does)
+func WithStrictRoot() Option {
+	return v2.WithStrictRoot()
+}
+
+// WithConfineToRoot makes sure that no path opened during the walk
+// resolves outside of the root directory, even if the tree contains
+// symlinks pointing outside of it or is modified during the walk,
+// and that magic links (such as /proc/[pid]/fd/*) are never followed.
+// Such paths are reported as errors, and the walk fails if the root
+// can't be opened as a directory. This is intended for services
+// walking untrusted user-supplied trees. It requires openat2(2),
+// i.e. Linux 5.6+; on other systems, the walk fails
+// with ErrConfineUnsupported.
+func WithConfineToRoot() Option {
+	return v2.WithConfineToRoot()
+}
+
+// WithPinnedRoot makes the walk resolve a relative root against
+// the working directory once, at the start of the walk, and access all
+// paths by their absolute names, so that a concurrent os.Chdir() elsewhere
+// in the process can't make the walk continue in another tree. The walk
+// fails if the working directory can't be determined. Walks of open
+// directories (see WalkDirFile()) and confined walks (see
+// WithConfineToRoot()) on Linux are not affected by the working
+// directory in the first place.
+func WithPinnedRoot() Option {
+	return v2.WithPinnedRoot()
+}
+
+// WithSlashPaths makes the walk present paths with forward slashes
+// as separators on all platforms, wherever it presents them (to the
+// callback, in errors, outcomes and timing), so that inventories
+// produced on Windows can be consumed elsewhere; the path mapper
+// (see WithPathMapper()) gets the converted paths. This has no effect
+// on platforms which use forward slashes already.
+func WithSlashPaths() Option {
+	return v2.WithSlashPaths()
+}
+
+// WithPathMapper sets a function which rewrites the paths passed to
+// the callback and reported in errors (e.g. to strip a staging prefix
+// or to map container paths to host paths), so that every callback
+// doesn't have to do that itself. The walker keeps using the original
+// paths internally.
+func WithPathMapper(fn func(path string) string) Option {
+	return v2.WithPathMapper(fn)
+}
+
+// OutcomeKind tells what happened to a path during the walk
+type OutcomeKind = v2.OutcomeKind
+
+const (
+	// Visited means the path was passed to the callback,
+	// which didn't return an error
+	Visited = v2.Visited
+	// Skipped means the path was deliberately left out
+	// of the walk, see SkipReason
+	Skipped = v2.Skipped
+	// Failed means an error was reported for the path
+	Failed = v2.Failed
+)
+
+// SkipReason tells why a path was skipped
+type SkipReason = v2.SkipReason
+
+const (
+	// SkipExcluded means a filter has excluded the entry
+	// (its subdirectories are still visited)
+	SkipExcluded = v2.SkipExcluded
+	// SkipPruned means a filter has pruned the entry
+	// along with everything beneath it
+	SkipPruned = v2.SkipPruned
+	// SkipOtherShard means the entry belongs to another shard,
+	// see WithShard()
+	SkipOtherShard = v2.SkipOtherShard
+	// SkipAborted means the directory was queued, but not read
+	// because the walk was aborted, canceled or stopped
+	SkipAborted = v2.SkipAborted
+	// SkipSampled means the file was left out of
+	// the sample, see WithSampling()
+	SkipSampled = v2.SkipSampled
+)
+
+// Outcome describes what happened to a single path
+type Outcome = v2.Outcome
+
+// WithOutcomeFunc sets a function which is called with the outcome
+// of every path, so that audit reports can tell the skipped paths from
+// the failed ones. Note that a directory may have two outcomes: it may
+// be visited, and then fail to be read. The function must be safe
+// for concurrent use.
+func WithOutcomeFunc(fn func(o Outcome)) Option {
+	return v2.WithOutcomeFunc(fn)
+}
+
+// ErrInvalidCursor indicates that the cursor passed
+// to WalkPage() was not produced by it
+var ErrInvalidCursor = v2.ErrInvalidCursor
+
+// WalkPage returns up to limit entries of the tree under root,
+// starting after the position identified by cursor (use an empty cursor
+// to start from the root), and the cursor to get the next page with,
+// which is empty once the whole tree has been listed. The options
+// configure the Walker reading the tree, e.g. its filters.
+//
+// Unlike Walk(), WalkPage() lists the tree sequentially in a stable
+// depth-first order with directory entries sorted by name, so that
+// repeated calls produce consistent pages without keeping any state
+// between them: the cursor is the last path visited, and the listing
+// resumes right after it even if that path has been removed since.
+// Directory IDs are derived from the paths, so they (and the
+// OrderToken values) are the same across pages.
+//
+// Errors (e.g. for unreadable directories) don't stop the listing,
+// and are returned as a WalkerErrorList along with the entries, unless
+// the walk is aborted (see WithFailFast() and WithMaxErrors()).
+func WalkPage(root, cursor string, limit int, opts ...Option) (entries []Entry, nextCursor string, err error) {
+	return v2.WalkPage(root, cursor, limit, withGlobals(opts)...)
+}
+
+// WalkBytes walks the tree like WalkMap(), passing every entry that was
+// read without errors to fn, with its path as a byte slice: the slice is
+// only valid during the call, and is overwritten by the next entry the
+// worker finds, so fn must copy whatever it keeps (e.g. appending it to
+// an index buffer). In exchange, the paths are joined once per directory
+// listing rather than once per entry, so that throughput-critical
+// consumers, such as indexers, don't pay a string allocation per entry;
+// combined with WithLazyStat(), walks that only look at the names and
+// the types of the entries make a few allocations per directory.
+//
+// fn is called concurrently from multiple goroutines, just like the
+// callback of Walk(). fn may return filepath.SkipDir to skip a directory;
+// other errors are collected as usual and returned after the walk.
+func WalkBytes(root string, fn func(path []byte, d fs.DirEntry) error, opts ...Option) error {
+	return v2.WalkBytes(root, fn, withGlobals(opts)...)
+}
+
+// PathLimits are the thresholds of PathBudget()
+type PathLimits = v2.PathLimits
+
+// PathReport is the result of PathBudget()
+type PathReport = v2.PathReport
+
+// PathBudget walks root and returns the maximum length and depth of
+// its paths, along with all the paths exceeding the limits, e.g. the
+// 260 characters of MAX_PATH on Windows, in a single pass. The longest
+// and the deepest paths are the first ones in lexical order in case
+// of a tie, so that reports of the same tree can be compared. Errors
+// are returned after the walk along with the report of the entries
+// that could be read.
+func PathBudget(root string, limits PathLimits, opts ...Option) (PathReport, error) {
+	return v2.PathBudget(root, limits, withGlobals(opts)...)
+}
+
+// PathStream writes the paths of a walk (see WithPathStream()) to
+// a writer as they are found, separated with NUL bytes, so that tools
+// such as tar --null --files-from or rsync --from0 --files-from can
+// consume them live. The paths are buffered, and flushed at least
+// every 100ms and at the end of the walk. If the writer blocks (e.g.
+// the consumer of a pipe is slower than the walk), the workers block
+// as well, so the walk proceeds at the pace of the consumer.
+type PathStream = v2.PathStream
+
+// NewPathStream creates a PathStream writing to out
+func NewPathStream(out io.Writer) *PathStream {
+	return v2.NewPathStream(out)
+}
+
+// OpenPathStream opens the file (usually a named pipe, created
+// with mkfifo(1)) for writing and creates a PathStream writing
+// to it; opening a named pipe blocks until a reader opens it.
+// Close() closes the file.
+func OpenPathStream(name string) (*PathStream, error) {
+	return v2.OpenPathStream(name)
+}
+
+// WithPathStream makes the walk write the path of every entry passed
+// to the callback (as seen by the callback, so relative to the root)
+// to s, except for the root itself, which path is empty. As directories
+// are listed along with their contents, use tar --no-recursion
+// (or rsync --files-from, which doesn't recurse by default).
+// If writing fails (e.g. the consumer has exited), the walk
+// is aborted with the error.
+func WithPathStream(s *PathStream) Option {
+	return v2.WithPathStream(s)
+}
+
+// ErrUnknownPreset is returned by the walk when WithPreset()
+// names a preset which hasn't been registered
+var ErrUnknownPreset = v2.ErrUnknownPreset
+
+// RegisterPreset registers a named set of options (typically filters),
+// which can then be selected with WithPreset(), in Config.Presets,
+// or with the -preset flag of the command line tools, so that standard
+// scan profiles can be shared across tools. Registering a preset
+// with an existing name replaces it. The "dev-tree" and "skip-hidden"
+// presets are registered by default.
+func RegisterPreset(name string, opts ...Option) {
+	v2.RegisterPreset(name, withGlobals(opts)...)
+}
+
+// Presets returns the sorted names of the registered presets
+func Presets() []string {
+	return v2.Presets()
+}
+
+// WithPreset applies the options of the named preset registered
+// with RegisterPreset(); the walk fails with ErrUnknownPreset
+// if there is no such preset
+func WithPreset(name string) Option {
+	return v2.WithPreset(name)
+}
+
+// kinds of read-only snapshots found by FindSnapshots()
+const (
+	SnapshotZFS     = v2.SnapshotZFS
+	SnapshotSnapper = v2.SnapshotSnapper
+)
+
+// ReadOnlySnapshot is a read-only file system snapshot
+type ReadOnlySnapshot = v2.ReadOnlySnapshot
+
+// FindSnapshots returns the read-only snapshots of the file system
+// mounted at dir: ZFS snapshots (available in the .zfs directory,
+// even if it is hidden) and Btrfs snapshots managed by snapper,
+// ordered by kind and name
+func FindSnapshots(dir string) ([]ReadOnlySnapshot, error) {
+	return v2.FindSnapshots(dir)
+}
+
+// WithImmutableTree declares that the walked tree doesn't change (such
+// as a read-only snapshot), so that the metadata cache (see
+// WithMetadataCache()) can be trusted entirely: cached subdirectories
+// are not stat'ed again, and a walk of a fully cached tree makes no
+// file system calls besides stat'ing the root. Without a cache,
+// this option has no effect.
+func WithImmutableTree() Option {
+	return v2.WithImmutableTree()
+}
+
+// WalkSnapshot walks the snapshot with WithImmutableTree()
+// and the given options
+func WalkSnapshot(s ReadOnlySnapshot, walkFn filepath.WalkFunc, opts ...Option) error {
+	return v2.WalkSnapshot(s, walkFn, withGlobals(opts)...)
+}
+
+// WalkReduce walks the tree like WalkEntries(), passing every entry
+// that was read without errors to mapFn, and combines the results with
+// reduceFn. The results are accumulated by each worker separately and
+// merged once the walk is complete, so neither function needs any
+// synchronization. reduceFn must be both commutative and associative
+// (like a sum, a maximum or a set union), as the entries are spread
+// over the workers in any order, and the partial results of the
+// workers are merged in any order as well: otherwise, the result
+// may differ from one walk to the next. If no entries were mapped,
+// the zero value of T is returned. Errors are collected as usual and
+// returned along with the result.
+func WalkReduce[T any](root string, mapFn func(Entry) T, reduceFn func(a, b T) T, opts ...Option) (T, error) {
+	return v2.WalkReduce(root, mapFn, reduceFn, withGlobals(opts)...)
+}
+
+// Report describes how the walk went and what resources it used,
+// to help tune the number of workers for the environment.
+// The walker itself never creates temporary files (except for
+// the runs spilled by a SortedSink), so the memory figures
+// cover everything the walk needs (except that they are
+// process-wide, and thus include allocations made by the callback
+// and by any other goroutines running at the same time)
+type Report = v2.Report
+
+// WithReport makes the walk fill r once it is complete.
+// Note that collecting memory statistics briefly stops the world
+// at the beginning and at the end of the walk
+func WithReport(r *Report) Option {
+	return v2.WithReport(r)
+}
+
+// Summary describes the outcome of a walk, see WalkWithSummary()
+type Summary = v2.Summary
+
+// WalkWithSummary works like Walk(), but also returns the Summary
+// of the walk, so that the statistics and the collected errors are
+// available without type-asserting the returned error. The error
+// is the same one Walk() would return. WithReport() should not be
+// passed, as the Report is filled in the Summary instead.
+func WalkWithSummary(root string, walkFn filepath.WalkFunc, opts ...Option) (Summary, error) {
+	return v2.WalkWithSummary(root, walkFn, withGlobals(opts)...)
+}
+
+// WithSampling makes the walk pass only a sample of the files to the
+// callback, about rate of them (0 < rate <= 1), for quick estimates of
+// the composition of huge trees; all directories are still traversed.
+// Whether a file is sampled only depends on its path relative to the
+// root and on seed, so walks with the same seed sample the same files.
+// The files left out are reported with SkipSampled.
+//
+// Just like find(1) does, the walk assumes that directories with
+// a link count of 2 have no subdirectories, so that the entries of
+// such directories which are not sampled are not even stat'ed
+// (unless symlinks are followed). This is only done on the file
+// systems known to count subdirectories in the link counts (ext4,
+// xfs, tmpfs and f2fs on Linux); others, such as btrfs or overlayfs,
+// may report a link count of 2 for any directory.
+func WithSampling(rate float64, seed int64) Option {
+	return v2.WithSampling(rate, seed)
+}
+
+// ErrScheduleSyntax indicates an invalid schedule of a ScheduledWalk
+var ErrScheduleSyntax = v2.ErrScheduleSyntax
+
+// ScheduledWalk is a walk run periodically by a Scheduler
+type ScheduledWalk = v2.ScheduledWalk
+
+// ScheduledResult is the result of a single run of a ScheduledWalk
+type ScheduledResult = v2.ScheduledResult
+
+// Scheduler runs walks on their schedules, which turns cwalk into
+// the core of an inventory agent. Each walk runs in its own goroutine,
+// and never overlaps with itself.
+type Scheduler = v2.Scheduler
+
+// NewScheduler creates a Scheduler without walks
+func NewScheduler() *Scheduler {
+	return v2.NewScheduler()
+}
+
+// ErrScrubUnsupported is returned by Scrub() on platforms
+// where checksums can't be stored in extended attributes
+var ErrScrubUnsupported = v2.ErrScrubUnsupported
+
+// ScrubConfig configures Scrub()
+type ScrubConfig = v2.ScrubConfig
+
+// ScrubReport is the result of Scrub()
+type ScrubReport = v2.ScrubReport
+
+// Scrub walks root and detects silent data corruption (bitrot): the
+// first time a regular file is scrubbed, its SHA-256 checksum is stored
+// in its user.cwalk.checksum extended attribute along with its size and
+// modification time; later scrubs report the files which content no
+// longer matches the checksum although their size and modification
+// time haven't changed. The checksums of the files modified in the
+// meantime are updated. The files are read by the content stages (see
+// WithContentStage()), so their access times are preserved with
+// WithNoAtime(); storing the attributes changes the inode change time
+// (ctime) of the files, and is not allowed in forensic mode.
+//
+// Scrubs are meant to run continuously in the background: the reads
+// can be rate limited with cfg.Rate, and combined with WithIOPriority()
+// and WithThrottle(), or run periodically by a Scheduler. Checksums are
+// stored on Linux only, on file systems supporting user extended
+// attributes; elsewhere, Scrub() fails with ErrScrubUnsupported. Errors
+// (such as files which attributes can't be written) are returned after
+// the walk along with the report.
+func Scrub(root string, cfg ScrubConfig, opts ...Option) (ScrubReport, error) {
+	return v2.Scrub(root, cfg, withGlobals(opts)...)
+}
+
+// WithSeedDirs pre-seeds the job queue with the given directories
+// (relative to the root, e.g. taken from a snapshot of a previous walk),
+// so that all their subtrees can be processed immediately instead of
+// being discovered one level at a time, which reduces the ramp-up time
+// on high-latency storage. Seeded directories are not queued again
+// when they are found in their parent directories, and the ones
+// that no longer exist are silently ignored.
+//
+// Note that the entries of a seeded directory may be passed to the
+// callback before the directory itself, and even if the callback
+// skips one of its parent directories.
+func WithSeedDirs(dirs ...string) Option {
+	return v2.WithSeedDirs(dirs...)
+}
+
+// WithShard makes the walk visit only the part of the tree
+// assigned to shard i of n (0 <= i < n), so that multiple machines
+// can each walk a disjoint part of the same tree without coordination.
+// Entries located directly in the root directory (along with everything
+// beneath them) are assigned to shards by the hash of their names;
+// the root itself is visited by every shard. The walk fails if i
+// is out of range.
+func WithShard(i, n int) Option {
+	return v2.WithShard(i, n)
+}
+
+// Snapshot is an in-memory copy of the tree metadata recorded during
+// a walk (see WithSnapshot()). It implements fs.FS, fs.ReadDirFS and
+// fs.StatFS, so that subsequent lookups, globs (fs.Glob) and re-walks
+// (fs.WalkDir) are served from memory; only reading the contents of
+// files goes to the underlying file system. Snapshot is safe
+// for concurrent use.
+type Snapshot = v2.Snapshot
+
+// NewSnapshot creates an empty Snapshot
+func NewSnapshot() *Snapshot {
+	return v2.NewSnapshot()
+}
+
+// WithSnapshot makes the walk record the metadata of every visited
+// entry in s, replacing what s has recorded before
+func WithSnapshot(s *Snapshot) Option {
+	return v2.WithSnapshot(s)
+}
+
+// SortedSink collects the paths of a walk (see WithSortedSink())
+// or arbitrary records added with Add(), and produces them in lexical
+// order with bounded memory: once the records held in memory exceed
+// the limit, they are sorted and spilled to a temporary file, and the
+// sorted runs are merged when the results are read. As new records are
+// added while a batch is spilled, up to one batch per goroutine adding
+// records may be held in memory. SortedSink is safe for concurrent use;
+// Close() removes the temporary files.
+type SortedSink = v2.SortedSink
+
+// NewSortedSink creates a SortedSink which keeps up to maxBytes
+// of records in memory, spilling the rest to temporary files in dir
+// (the default directory for temporary files if dir is empty)
+func NewSortedSink(dir string, maxBytes int) *SortedSink {
+	return v2.NewSortedSink(dir, maxBytes)
+}
+
+// WithSortedSink makes the walk add the path of every entry passed
+// to the callback (as seen by the callback) to s
+func WithSortedSink(s *SortedSink) Option {
+	return v2.WithSortedSink(s)
+}
+
+// Throttle slows down or pauses a walk, see WithThrottle()
+type Throttle = v2.Throttle
+
+// WithThrottle makes every worker consult t once every n file system
+// operations (directory reads and stat calls; 1 if n is not positive),
+// so that applications can slow down or pause the walk without
+// tearing it down and starting over (see Pacer). The context passed
+// to t is derived from the one set by WithContext(), and is canceled
+// once the walk is stopped or aborted.
+func WithThrottle(t Throttle, n int) Option {
+	return v2.WithThrottle(t, n)
+}
+
+// Pacer is a Throttle controlled by the application: it delays every
+// operation it is consulted for by a given duration, or pauses the
+// walks until resumed. Pacer is safe for concurrent use, and can be
+// shared by any number of walks.
+type Pacer = v2.Pacer
+
+// NewPacer creates a Pacer which doesn't slow the walks down
+func NewPacer() *Pacer {
+	return v2.NewPacer()
+}
+
+// Histogram counts operation latencies: Buckets[i] holds the number
+// of operations which took from 2^i to 2^(i+1) microseconds (the first
+// bucket also holds the faster ones, and the last one the slower ones)
+type Histogram = v2.Histogram
+
+// DirTiming holds the time spent on a directory: reading its listing,
+// and stat'ing its entries (which includes e.g. network round trips
+// on NFS, or antivirus filters inspecting the files)
+type DirTiming = v2.DirTiming
+
+// Timing records the latency of the file system operations of a walk,
+// see WithTiming(). Large directories processed in parts (by multiple
+// workers) may be reported as several DirTimings with the same path.
+type Timing = v2.Timing
+
+// NewTiming creates a Timing which keeps the n slowest directories
+func NewTiming(n int) *Timing {
+	return v2.NewTiming(n)
+}
+
+// WithTiming makes the walk record the latency of every readdir
+// and stat call in t, along with the slowest directories, to help
+// find the parts of the tree (such as a slow network export) that make
+// the walk slow. The timing itself adds two clock reads per call.
+func WithTiming(t *Timing) Option {
+	return v2.WithTiming(t)
+}
+
+// ErrNotInTrace indicates that the result of a file system call
+// requested during a replayed walk was not recorded in the trace
+var ErrNotInTrace = v2.ErrNotInTrace
+
+// WithTraceRecording makes the walk write the results of the
+// directory listings, stat and readlink calls to out, one JSON object
+// per line, so that the walk can be replayed later (see LoadTrace()).
+// Each line is written with a single Write call; use a bufio.Writer to
+// reduce the number of system calls, and flush it once the walk is done.
+// If writing fails, the walk is aborted with the error.
+func WithTraceRecording(out io.Writer) Option {
+	return v2.WithTraceRecording(out)
+}
+
+// Trace holds the results of the file system calls recorded
+// during a walk (see WithTraceRecording()), for replaying the walk
+// with WithTraceReplay(). Trace is read-only, so it can be used
+// by any number of walks at the same time.
+type Trace = v2.Trace
+
+// LoadTrace reads a trace written with WithTraceRecording()
+func LoadTrace(r io.Reader) (*Trace, error) {
+	return v2.LoadTrace(r)
+}
+
+// WithTraceReplay makes the walk serve the directory listings, stat
+// and readlink calls from t instead of the file system, which is left
+// alone, so that the scheduling of walks can be benchmarked reproducibly.
+// The calls which were not recorded fail with ErrNotInTrace, including
+// all attempts to open files, so the enriched metadata read from open
+// files (e.g. the creation time on Linux) is not available either.
+func WithTraceReplay(t *Trace) Option {
+	return v2.WithTraceReplay(t)
+}
+
+// ErrManifestSyntax indicates an invalid manifest passed to Verify()
+var ErrManifestSyntax = v2.ErrManifestSyntax
+
+// VerifyReport is the result of Verify(); the paths are relative
+// to the root, with forward slashes, in lexical order
+type VerifyReport = v2.VerifyReport
+
+// VerifyProgress is the progress of Verify()
+type VerifyProgress = v2.VerifyProgress
+
+// Verify walks root, hashes the regular files listed in the manifest in
+// parallel (with the content stages, see WithContentStage()), and reports
+// the differences with the manifest, which has the format of the output
+// of sha256sum(1): a line per file with its hex digest, two spaces (or a
+// space and an asterisk) and its path relative to root (with forward
+// slashes, "./" prefixes allowed). The hash function is chosen by the
+// length of the digests: MD5, SHA-1, SHA-256 or SHA-512. The files which
+// are not listed are reported from their metadata, without being opened
+// (so the content stages passed in opts don't see them either). The files
+// which could not be read are reported as errors, and are neither verified
+// nor modified. Errors are returned after the walk along with the report.
+func Verify(root string, manifest io.Reader, opts ...Option) (VerifyReport, error) {
+	return v2.Verify(root, manifest, withGlobals(opts)...)
+}
+
+// VerifyWithProgress is like Verify(), and calls progress every second
+// while the files are hashed, and once more when the walk is complete
+func VerifyWithProgress(root string, manifest io.Reader, progress func(VerifyProgress), opts ...Option) (VerifyReport, error) {
+	return v2.VerifyWithProgress(root, manifest, progress, withGlobals(opts)...)
+}
+
+// ErrRootMoved indicates that the directory passed to WalkDirFile()
+// can no longer be found under its original name (only reported
+// on platforms where the walk can't be done relative to an open
+// file descriptor)
+var ErrRootMoved = v2.ErrRootMoved
+
+// ErrConfineUnsupported indicates that the WithConfineToRoot()
+// option is not supported on this platform
+var ErrConfineUnsupported = v2.ErrConfineUnsupported
+
+// WalkDirFile works like Walk(), but walks the directory f,
+// which the caller already holds open. On Linux, all paths are
+// opened relative to f using openat(2), so the root directory
+// can't be swapped between the moment the caller has checked it
+// and the traversal (note that symlinks inside the tree are still
+// followed when resolving intermediate path components, unless
+// the WithConfineToRoot() option is used).
+// The paths passed to walkFn are relative to f.
+func WalkDirFile(f *os.File, walkFn filepath.WalkFunc, opts ...Option) error {
+	return v2.WalkDirFile(f, walkFn, withGlobals(opts)...)
+}
+
+// WalkMap walks the tree like WalkEntries(), passing every entry that
+// was read without errors to fn, and collects the values for which fn
+// returns true into a map keyed by the entry path. Each worker fills
+// its own map, and the maps are merged once the walk is complete,
+// so fn needs no synchronization. fn may return filepath.SkipDir
+// to skip a directory; other errors are collected as usual
+// and returned along with the map.
+func WalkMap[T any](root string, fn func(path string, d fs.DirEntry) (T, bool, error), opts ...Option) (map[string]T, error) {
+	return v2.WalkMap(root, fn, withGlobals(opts)...)
+}
+
+// ErrWatchUnsupported indicates that directory watches
+// are not supported on this platform
+var ErrWatchUnsupported = v2.ErrWatchUnsupported
+
+// ErrWatchEvicted is the reason reported for the directories which
+// watch was removed to stay within the watch limit
+var ErrWatchEvicted = v2.ErrWatchEvicted
+
+// UnwatchedDir is a directory which is not watched,
+// so that changes of its entries go unnoticed
+type UnwatchedDir = v2.UnwatchedDir
+
+// Watches registers inotify watches on the directories as they are
+// discovered by walks (see WithWatches()), which is much faster
+// than adding them in a separate pass after the walk. The number of
+// watches is kept within a limit, which defaults to the system-wide
+// per-user limit: once it is reached (or the kernel refuses new watches),
+// the least recently used watch is removed. Directories become recently
+// used when they are watched and when Touch() is called for them,
+// typically when their events are handled.
+//
+// The events are read from the inotify descriptor returned by Fd(),
+// and Path() maps their watch descriptors back to the directories.
+// Watches is safe for concurrent use.
+type Watches = v2.Watches
+
+// WithWatches makes the walk add a watch to ws for every directory
+// passed to the callback, unless the callback skips it
+func WithWatches(ws *Watches) Option {
+	return v2.WithWatches(ws)
+}
+
+// WatchMask is the default set of inotify events watched
+// for the directories, see NewWatches()
+const WatchMask = v2.WatchMask
+
+// NewWatches creates an inotify instance watching for the events
+// in mask (WatchMask if zero), with at most limit watches (the
+// system-wide limit from /proc/sys/fs/inotify/max_user_watches if
+// not positive). The instance must be closed with Close().
+func NewWatches(mask uint32, limit int) (*Watches, error) {
+	return v2.NewWatches(mask, limit)
+}
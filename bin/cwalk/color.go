@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/iafan/cwalk"
+)
+
+// defaultColors are the colors used if LS_COLORS is not set,
+// which are the defaults of dircolors(1)
+const defaultColors = "di=01;34:ln=01;36:pi=40;33:so=01;35:bd=40;33;01:cd=40;33;01:" +
+	"or=40;31;01:su=37;41:sg=30;43:tw=30;42:ow=34;42:st=37;44:ex=01;32"
+
+// colorizer renders the paths printed by the commands,
+// coloring them as configured by LS_COLORS
+type colorizer struct {
+	enabled bool
+	types   map[string]string // type codes (e.g. "di") to SGR sequences
+	exts    map[string]string // lowercase "*.ext" patterns to SGR sequences
+}
+
+// newColorizer creates a colorizer for the -color flag value: "always",
+// "never", or "auto", which enables colors if the standard output
+// is a terminal and the NO_COLOR environment variable is not set
+func newColorizer(when string) (*colorizer, error) {
+	c := &colorizer{}
+	switch when {
+	case "always":
+		c.enabled = true
+	case "never":
+	case "auto":
+		_, noColor := os.LookupEnv("NO_COLOR")
+		c.enabled = !noColor && isTerminal(os.Stdout)
+	default:
+		return nil, fmt.Errorf("Invalid color mode: %s", when)
+	}
+	if c.enabled {
+		spec, ok := os.LookupEnv("LS_COLORS")
+		if !ok {
+			spec = defaultColors
+		}
+		c.parse(spec)
+	}
+	return c, nil
+}
+
+// parse parses the LS_COLORS value: colon-separated key=value
+// pairs, where keys are type codes or *.ext patterns
+func (c *colorizer) parse(spec string) {
+	c.types = map[string]string{}
+	c.exts = map[string]string{}
+	for _, item := range strings.Split(spec, ":") {
+		i := strings.IndexByte(item, '=')
+		if i < 0 {
+			continue
+		}
+		key, value := item[:i], item[i+1:]
+		if strings.HasPrefix(key, "*") {
+			c.exts[strings.ToLower(key[1:])] = value
+		} else {
+			c.types[key] = value
+		}
+	}
+}
+
+// isTerminal reports whether f is a character device
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// format returns the path with its directory part colored as
+// a directory, and its name colored by the file type and extension
+func (c *colorizer) format(path string, info os.FileInfo) string {
+	if !c.enabled || info == nil {
+		return path
+	}
+	dir, name := filepath.Split(path)
+	return c.paint(dir, c.types["di"]) + c.paint(name, c.code(name, info))
+}
+
+// paint wraps s in the SGR sequence
+func (c *colorizer) paint(s, code string) string {
+	if s == "" || code == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// code returns the SGR sequence for the file,
+// following the precedence used by ls(1)
+func (c *colorizer) code(name string, info os.FileInfo) string {
+	mode := info.Mode()
+	typeCode := ""
+	switch {
+	case mode.IsDir():
+		switch {
+		case mode&os.ModeSticky != 0 && mode&0002 != 0:
+			typeCode = "tw"
+		case mode&0002 != 0:
+			typeCode = "ow"
+		case mode&os.ModeSticky != 0:
+			typeCode = "st"
+		default:
+			typeCode = "di"
+		}
+	case mode&os.ModeSymlink != 0:
+		typeCode = "ln"
+	case mode&os.ModeNamedPipe != 0:
+		typeCode = "pi"
+	case mode&os.ModeSocket != 0:
+		typeCode = "so"
+	case mode&os.ModeCharDevice != 0:
+		typeCode = "cd"
+	case mode&os.ModeDevice != 0:
+		typeCode = "bd"
+	case mode&os.ModeSetuid != 0:
+		typeCode = "su"
+	case mode&os.ModeSetgid != 0:
+		typeCode = "sg"
+	case mode&0111 != 0:
+		typeCode = "ex"
+	}
+	if code, ok := c.types[typeCode]; ok && typeCode != "" {
+		return code
+	}
+	lower := strings.ToLower(name)
+	code, longest := c.types["fi"], 0
+	for ext, extCode := range c.exts {
+		if len(ext) > longest && strings.HasSuffix(lower, ext) {
+			code, longest = extCode, len(ext)
+		}
+	}
+	return code
+}
+
+// longFormat renders the entry in the ls -l format (without
+// the link count), using the metadata collected by the walk
+// (see cwalk.WithEnrichedMetadata()), so no extra stats are needed
+func (c *colorizer) longFormat(path string, entry *cwalk.Entry) string {
+	info := entry.Info
+	if info == nil {
+		return path
+	}
+	user, group := "?", "?"
+	target := ""
+	if m := entry.Meta; m != nil {
+		if m.User != "" {
+			user, group = m.User, m.Group
+		}
+		if m.LinkTarget != "" {
+			target = " -> " + m.LinkTarget
+		}
+	}
+	return fmt.Sprintf("%s %-8s %-8s %10d %s %s%s", lsMode(info.Mode()), user, group,
+		info.Size(), lsTime(info.ModTime()), c.format(path, info), target)
+}
+
+// lsMode formats the file mode like ls(1) does
+func lsMode(mode os.FileMode) string {
+	b := []byte("----------")
+	switch {
+	case mode.IsDir():
+		b[0] = 'd'
+	case mode&os.ModeSymlink != 0:
+		b[0] = 'l'
+	case mode&os.ModeNamedPipe != 0:
+		b[0] = 'p'
+	case mode&os.ModeSocket != 0:
+		b[0] = 's'
+	case mode&os.ModeCharDevice != 0:
+		b[0] = 'c'
+	case mode&os.ModeDevice != 0:
+		b[0] = 'b'
+	}
+	const rwx = "rwxrwxrwx"
+	for i := 0; i < 9; i++ {
+		if mode&(1<<uint(8-i)) != 0 {
+			b[i+1] = rwx[i]
+		}
+	}
+	special := func(i int, set bool, c byte) {
+		if !set {
+			return
+		}
+		if b[i] == 'x' {
+			b[i] = c
+		} else {
+			b[i] = c - 'a' + 'A'
+		}
+	}
+	special(3, mode&os.ModeSetuid != 0, 's')
+	special(6, mode&os.ModeSetgid != 0, 's')
+	special(9, mode&os.ModeSticky != 0, 't')
+	return string(b)
+}
+
+// lsTime formats the modification time like ls(1) does:
+// with the year instead of the time for files older
+// than six months or in the future
+func lsTime(t time.Time) string {
+	now := time.Now()
+	if t.Before(now.AddDate(0, -6, 0)) || t.After(now.Add(time.Hour)) {
+		return t.Format("Jan _2  2006")
+	}
+	return t.Format("Jan _2 15:04")
+}
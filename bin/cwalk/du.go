@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/iafan/cwalk"
+)
+
+// du implements the du command, which output is identical to that
+// of du(1) for the supported options, except for the order of the
+// entries listed with -a or without -s (see cwalk.DiskUsage())
+func du(fs *flag.FlagSet) func(args []string) int {
+	summarize := fs.Bool("s", false, "Display only a total for each argument")
+	all := fs.Bool("a", false, "Write counts for all files, not just directories")
+	depth := fs.Int("d", -1, "Print the total for a directory only if it is at most this many levels below the argument")
+	total := fs.Bool("c", false, "Produce a grand total")
+	human := fs.Bool("h", false, "Print sizes in human readable format (e.g. 1.5K, 234M, 2.0G)")
+	kilo := fs.Bool("k", false, "Like -B 1K")
+	mega := fs.Bool("m", false, "Like -B 1M")
+	bytes := fs.Bool("b", false, "Like -apparent-size -B 1")
+	apparent := fs.Bool("apparent-size", false, "Print apparent sizes rather than disk usage")
+	blockSize := fs.String("B", "", "Scale sizes by the given block size (e.g. 512, 4K, 1M)")
+
+	return func(paths []string) int {
+		// the default block size is 1K, or 512 bytes in POSIX mode
+		size := int64(1024)
+		if _, ok := os.LookupEnv("POSIXLY_CORRECT"); ok {
+			size = 512
+		}
+		if s := os.Getenv("BLOCKSIZE"); s != "" {
+			if n, err := parseBlockSize(s); err == nil {
+				size = n
+			}
+		}
+		switch {
+		case *blockSize != "":
+			n, err := parseBlockSize(*blockSize)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "du: invalid block size %q\n", *blockSize)
+				return exitFatal
+			}
+			size = n
+		case *bytes:
+			size = 1
+			*apparent = true
+		case *kilo:
+			size = 1024
+		case *mega:
+			size = 1 << 20
+		}
+
+		if len(paths) == 0 {
+			paths = []string{"."}
+		}
+		format := func(u cwalk.Usage) string {
+			n := u.Bytes
+			if *apparent {
+				n = u.Apparent
+			}
+			return cwalk.FormatUsage(n, size, *human)
+		}
+
+		// the hard links are counted once for all the arguments
+		cfg := cwalk.UsageConfig{MaxDepth: *depth, All: *all, Links: &cwalk.LinkSet{}}
+		if *summarize {
+			cfg.MaxDepth = 0
+		}
+		var grand cwalk.Usage
+		for _, root := range paths {
+			list, err := cwalk.DiskUsage(root, cfg)
+			reportErrors("du", root, err, "cannot read %s: %v")
+			for _, u := range list {
+				if u.Path == "" {
+					grand.Bytes += u.Bytes
+					grand.Apparent += u.Apparent
+				}
+				fmt.Fprintf(stdout, "%s\t%s\n", format(u), joinPath(root, u.Path))
+			}
+		}
+		if *total {
+			fmt.Fprintf(stdout, "%s\ttotal\n", format(grand))
+		}
+		return exitCode()
+	}
+}
+
+// parseBlockSize parses a block size, which is a number optionally
+// followed by a unit: K, M, G, T, P, E (powers of 1024), optionally
+// followed by iB, or KB, MB... (powers of 1000)
+func parseBlockSize(s string) (int64, error) {
+	num := strings.TrimRight(s, "KMGTPEiB")
+	unit := s[len(num):]
+	n := int64(1)
+	if num != "" {
+		var err error
+		n, err = strconv.ParseInt(num, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("Invalid block size: %s", s)
+		}
+	}
+	if unit == "" {
+		return n, nil
+	}
+	base := int64(1024)
+	switch unit[1:] {
+	case "", "iB":
+	case "B":
+		base = 1000
+	default:
+		return 0, fmt.Errorf("Invalid block size: %s", s)
+	}
+	i := strings.IndexByte("KMGTPE", unit[0])
+	if i < 0 {
+		return 0, fmt.Errorf("Invalid block size: %s", s)
+	}
+	for ; i >= 0; i-- {
+		n *= base
+	}
+	return n, nil
+}
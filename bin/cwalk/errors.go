@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/iafan/cwalk"
+)
+
+// exit codes of the commands
+const (
+	exitOK      = 0 // the walk completed without errors
+	exitFatal   = 1 // the command failed (e.g. invalid arguments, missing root)
+	exitPartial = 2 // the walk completed, but some paths couldn't be read
+)
+
+// maxPartialErrors is the number of errors above which a walk
+// that completed is considered failed, set by the -max-partial-errors
+// flag (-1 for no limit)
+var maxPartialErrors = -1
+
+// the outcome of the walks of the command, for exitCode()
+var (
+	walkErrors int
+	walkFailed bool
+)
+
+// exitCode returns the exit code for the outcome of the walks
+func exitCode() int {
+	switch {
+	case walkFailed, maxPartialErrors >= 0 && walkErrors > maxPartialErrors:
+		return exitFatal
+	case walkErrors > 0:
+		return exitPartial
+	}
+	return exitOK
+}
+
+// errorsFormat is the format of the errors printed to the standard
+// error, set by the -errors-format flag: "text" or "json"
+var errorsFormat = "text"
+
+// jsonError is an error printed as a JSON line
+type jsonError struct {
+	Command string `json:"command"`
+	Path    string `json:"path,omitempty"`  // the path as printed in the results
+	Op      string `json:"op,omitempty"`    // the failed operation, e.g. "open" or "lstat"
+	Errno   int    `json:"errno,omitempty"` // the system error number
+	Error   string `json:"error"`
+}
+
+// setErrorsFormat validates and sets the errors format
+func setErrorsFormat(format string) error {
+	switch format {
+	case "text", "json":
+		errorsFormat = format
+		return nil
+	}
+	return fmt.Errorf("Invalid errors format: %s", format)
+}
+
+// reportErrors prints the errors returned by the walk of root and
+// records them for exitCode(); in the text format, the errors for paths
+// are printed with textFormat, which gets the path and the error, and
+// other errors are printed as is. The errors for paths under the root
+// mean that the walk completed partially, while any other error
+// (such as a missing root) fails the command.
+func reportErrors(cmd, root string, err error, textFormat string) {
+	if err == nil {
+		return
+	}
+	var errList cwalk.WalkerErrorList
+	if !errors.As(err, &errList) {
+		walkFailed = true
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			printError(cmd, root, err, textFormat)
+		} else {
+			printError(cmd, "", err, "")
+		}
+		return
+	}
+	walkErrors += len(errList.ErrorList) + errList.Truncated
+	for _, e := range errList.ErrorList {
+		printError(cmd, joinPath(root, e.Path()), e.Unwrap(), textFormat)
+	}
+	if errList.Truncated > 0 {
+		printError(cmd, "", fmt.Errorf("%d more errors not shown", errList.Truncated), "")
+	}
+}
+
+// printError prints a single error in the errors format
+func printError(cmd, path string, err error, textFormat string) {
+	var pathErr *os.PathError
+	if errorsFormat != "json" {
+		if path == "" {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", cmd, err)
+			return
+		}
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err // the path is printed already
+		}
+		fmt.Fprintf(os.Stderr, "%s: "+textFormat+"\n", cmd, path, err)
+		return
+	}
+
+	je := jsonError{Command: cmd, Path: path, Error: err.Error()}
+	var linkErr *os.LinkError
+	var sysErr *os.SyscallError
+	switch {
+	case errors.As(err, &pathErr):
+		je.Op = pathErr.Op
+		je.Error = pathErr.Err.Error()
+	case errors.As(err, &linkErr):
+		je.Op = linkErr.Op
+		je.Error = linkErr.Err.Error()
+	case errors.As(err, &sysErr):
+		je.Op = sysErr.Syscall
+		je.Error = sysErr.Err.Error()
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		je.Errno = int(errno)
+	}
+	line, _ := json.Marshal(je)
+	fmt.Fprintln(os.Stderr, string(line))
+}
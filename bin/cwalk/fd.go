@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/iafan/cwalk"
+)
+
+// fd implements the fd command, which mimics fd(1): the pattern is
+// a regular expression matched against the file names, case-insensitively
+// unless it contains uppercase letters, and the hidden and ignored files
+// (see cwalk.WithGitignore()) are skipped unless requested
+func fd(fs *flag.FlagSet) func(args []string) int {
+	var hidden, noIgnore, caseSensitive, ignoreCase, glob, fullPath, long bool
+	var types, extension, color string
+	var maxDepth int
+	fs.BoolVar(&hidden, "H", false, "Include hidden files and directories")
+	fs.BoolVar(&hidden, "hidden", false, "Same as -H")
+	fs.BoolVar(&noIgnore, "I", false, "Don't respect .gitignore and .ignore files")
+	fs.BoolVar(&noIgnore, "no-ignore", false, "Same as -I")
+	fs.BoolVar(&caseSensitive, "s", false, "Case-sensitive search (default: smart case)")
+	fs.BoolVar(&caseSensitive, "case-sensitive", false, "Same as -s")
+	fs.BoolVar(&ignoreCase, "i", false, "Case-insensitive search (default: smart case)")
+	fs.BoolVar(&ignoreCase, "ignore-case", false, "Same as -i")
+	fs.BoolVar(&glob, "g", false, "Glob-based search instead of a regular expression")
+	fs.BoolVar(&glob, "glob", false, "Same as -g")
+	fs.BoolVar(&fullPath, "p", false, "Match the pattern against the full path")
+	fs.BoolVar(&fullPath, "full-path", false, "Same as -p")
+	fs.StringVar(&types, "t", "", "Filter by type: f (file), d (directory), l (symlink), x (executable); comma-separated")
+	fs.StringVar(&types, "type", "", "Same as -t")
+	fs.StringVar(&extension, "e", "", "Filter by file extension")
+	fs.StringVar(&extension, "extension", "", "Same as -e")
+	fs.IntVar(&maxDepth, "d", 0, "Maximum search depth (0 for unlimited)")
+	fs.IntVar(&maxDepth, "max-depth", 0, "Same as -d")
+	fs.BoolVar(&long, "l", false, "Use a long listing format with file metadata")
+	fs.BoolVar(&long, "list-details", false, "Same as -l")
+	fs.StringVar(&color, "c", "auto", "When to use colors: auto, always, never (see LS_COLORS)")
+	fs.StringVar(&color, "color", "auto", "Same as -c")
+
+	return func(args []string) int {
+		pattern, paths := "", args
+		if len(paths) > 0 {
+			pattern, paths = paths[0], paths[1:]
+		}
+		match, err := fdMatcher(pattern, glob, caseSensitive || (!ignoreCase && hasUpper(pattern)))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fd: %v\n", err)
+			return exitFatal
+		}
+		colors, err := newColorizer(color)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fd: %v\n", err)
+			return exitFatal
+		}
+
+		var opts []cwalk.Option
+		if long {
+			opts = append(opts, cwalk.WithEnrichedMetadata())
+		}
+		if !hidden {
+			opts = append(opts, cwalk.WithSkipHidden())
+		}
+		if !noIgnore {
+			opts = append(opts, cwalk.WithGitignore())
+		}
+		opts = append(opts, cwalk.WithFilter(func(relpath string, info os.FileInfo) cwalk.FilterAction {
+			if info == nil {
+				return cwalk.FilterInclude
+			}
+			if maxDepth > 0 && strings.Count(relpath, string(filepath.Separator)) >= maxDepth {
+				return cwalk.FilterPrune
+			}
+			name := info.Name()
+			if fullPath {
+				name = filepath.ToSlash(relpath)
+			}
+			if !match(name) || !fdType(types, info) ||
+				(extension != "" && !strings.EqualFold(filepath.Ext(relpath), "."+strings.TrimPrefix(extension, "."))) {
+				return cwalk.FilterExclude
+			}
+			return cwalk.FilterInclude
+		}))
+
+		prefixed := len(paths) > 0
+		if !prefixed {
+			paths = []string{"."}
+		}
+		var mu sync.Mutex
+		for _, root := range paths {
+			err := cwalk.WalkEntries(root, func(entry *cwalk.Entry, err error) error {
+				if err != nil {
+					return err
+				}
+				path := entry.Path
+				if path == "" {
+					return nil // the root is not a search result
+				}
+				if prefixed {
+					path = joinPath(root, path)
+				}
+				line := colors.format(path, entry.Info)
+				if long {
+					line = colors.longFormat(path, entry)
+				}
+				mu.Lock()
+				fmt.Fprintln(stdout, line)
+				mu.Unlock()
+				return nil
+			}, opts...)
+
+			reportErrors("fd", root, err, "%s: %v")
+		}
+		return exitCode()
+	}
+}
+
+// fdMatcher returns the function matching the names against the pattern
+func fdMatcher(pattern string, glob, caseSensitive bool) (func(name string) bool, error) {
+	if glob {
+		if !caseSensitive {
+			pattern = strings.ToLower(pattern)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+		return func(name string) bool {
+			if !caseSensitive {
+				name = strings.ToLower(name)
+			}
+			ok, _ := filepath.Match(pattern, name)
+			return ok || pattern == ""
+		}, nil
+	}
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
+
+// fdType reports whether the entry is of one of the types
+func fdType(types string, info os.FileInfo) bool {
+	if types == "" {
+		return true
+	}
+	mode := info.Mode()
+	for _, t := range strings.Split(types, ",") {
+		switch t {
+		case "f", "file":
+			if mode.IsRegular() {
+				return true
+			}
+		case "d", "directory":
+			if mode.IsDir() {
+				return true
+			}
+		case "l", "symlink":
+			if mode&os.ModeSymlink != 0 {
+				return true
+			}
+		case "x", "executable":
+			if mode.IsRegular() && mode&0111 != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasUpper reports whether s contains uppercase letters
+func hasUpper(s string) bool {
+	return strings.IndexFunc(s, unicode.IsUpper) >= 0
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/iafan/cwalk"
+)
+
+// find implements the find command: the paths (the current directory
+// if none are given) are followed by an expression, see
+// cwalk.ParseFindExpr(). The matching paths are printed in the order
+// they are found, which is not the order of find(1).
+func find(_ *flag.FlagSet) func(args []string) int {
+	return func(args []string) int {
+		n := 0
+		for n < len(args) && !isFindExprStart(args[n]) {
+			n++
+		}
+		paths, exprArgs := args[:n], args[n:]
+		if len(paths) == 0 {
+			paths = []string{"."}
+		}
+		expr, err := cwalk.ParseFindExpr(exprArgs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "find: %v\n", err)
+			return exitFatal
+		}
+
+		var mu sync.Mutex
+		for _, root := range paths {
+			err := cwalk.Walk(root, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				fmt.Fprintln(stdout, joinPath(root, path))
+				mu.Unlock()
+				return nil
+			}, cwalk.WithFindExpr(expr))
+
+			reportErrors("find", root, err, "'%s': %v")
+		}
+		return exitCode()
+	}
+}
+
+// isFindExprStart reports whether the argument
+// starts the expression rather than being a path
+func isFindExprStart(arg string) bool {
+	return len(arg) > 0 && (arg[0] == '-' || arg == "!" || arg == "(")
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// commandFlags returns the flags defined by the command
+func commandFlags(name string) []*flag.Flag {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	commands[name].setup(fs)
+	if !commands[name].rawArgs {
+		defineCommonFlags(fs, new(string))
+	}
+	var flags []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+	return flags
+}
+
+// completion implements the completion command
+func completion(_ *flag.FlagSet) func(args []string) int {
+	return func(args []string) int {
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "completion: expected one of: bash, zsh, fish")
+			return exitFatal
+		}
+		switch args[0] {
+		case "bash":
+			bashCompletion()
+		case "zsh":
+			zshCompletion()
+		case "fish":
+			fishCompletion()
+		default:
+			fmt.Fprintf(os.Stderr, "completion: unsupported shell %q\n", args[0])
+			return exitFatal
+		}
+		return exitOK
+	}
+}
+
+// bashCompletion prints the bash completion script; install it
+// with: cwalk completion bash > /etc/bash_completion.d/cwalk
+func bashCompletion() {
+	fmt.Println("# bash completion for cwalk, generated by: cwalk completion bash")
+	fmt.Println("_cwalk() {")
+	fmt.Println(`	local cur="${COMP_WORDS[COMP_CWORD]}"`)
+	fmt.Println(`	if [ "$COMP_CWORD" -eq 1 ]; then`)
+	fmt.Printf("\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	fmt.Println("\t\treturn")
+	fmt.Println("\tfi")
+	fmt.Println(`	local flags=""`)
+	fmt.Println(`	case "${COMP_WORDS[1]}" in`)
+	for _, name := range commandNames() {
+		var flags []string
+		for _, f := range commandFlags(name) {
+			flags = append(flags, "-"+f.Name)
+		}
+		if name == "completion" {
+			flags = append(flags, "bash", "zsh", "fish")
+		}
+		if len(flags) > 0 {
+			fmt.Printf("\t%s) flags=%q ;;\n", name, strings.Join(flags, " "))
+		}
+	}
+	fmt.Println("\tesac")
+	fmt.Println(`	if [[ "$cur" == -* || "${COMP_WORDS[1]}" == completion ]]; then`)
+	fmt.Println(`		COMPREPLY=($(compgen -W "$flags" -- "$cur"))`)
+	fmt.Println("\telse")
+	fmt.Println(`		COMPREPLY=($(compgen -f -- "$cur"))`)
+	fmt.Println("\tfi")
+	fmt.Println("}")
+	fmt.Println("complete -o filenames -F _cwalk cwalk")
+}
+
+// zshCompletion prints the zsh completion script; install it
+// as _cwalk in a directory listed in $fpath
+func zshCompletion() {
+	fmt.Println("#compdef cwalk")
+	fmt.Println("# zsh completion for cwalk, generated by: cwalk completion zsh")
+	fmt.Println("_cwalk() {")
+	fmt.Println("\tlocal -a commands")
+	fmt.Println("\tcommands=(")
+	for _, name := range commandNames() {
+		fmt.Printf("\t\t%s\n", zshQuote(name+":"+commands[name].summary))
+	}
+	fmt.Println("\t)")
+	fmt.Println("\tif (( CURRENT == 2 )); then")
+	fmt.Println("\t\t_describe command commands")
+	fmt.Println("\t\treturn")
+	fmt.Println("\tfi")
+	fmt.Println("\tshift words")
+	fmt.Println("\t(( CURRENT-- ))")
+	fmt.Println("\tcase $words[1] in")
+	for _, name := range commandNames() {
+		fmt.Printf("\t%s)\n\t\t_arguments", name)
+		for _, f := range commandFlags(name) {
+			spec := "-" + f.Name + "[" + zshEscape(f.Usage) + "]"
+			if !isBoolFlag(f) {
+				spec += ":value:"
+			}
+			fmt.Printf(" \\\n\t\t\t%s", zshQuote(spec))
+		}
+		if name == "completion" {
+			fmt.Printf(" \\\n\t\t\t%s", zshQuote("1:shell:(bash zsh fish)"))
+		} else if name != "man" {
+			fmt.Printf(" \\\n\t\t\t%s", zshQuote("*:file:_files"))
+		}
+		fmt.Println("\n\t\t;;")
+	}
+	fmt.Println("\tesac")
+	fmt.Println("}")
+	fmt.Println(`_cwalk "$@"`)
+}
+
+// zshQuote quotes s for zsh
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// zshEscape escapes the characters special
+// in _arguments descriptions
+func zshEscape(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
+
+// fishCompletion prints the fish completion script; install it
+// with: cwalk completion fish > ~/.config/fish/completions/cwalk.fish
+func fishCompletion() {
+	fmt.Println("# fish completion for cwalk, generated by: cwalk completion fish")
+	fmt.Println("complete -c cwalk -f")
+	for _, name := range commandNames() {
+		fmt.Printf("complete -c cwalk -n __fish_use_subcommand -a %s -d %s\n", name, fishQuote(commands[name].summary))
+	}
+	for _, name := range commandNames() {
+		cond := fishQuote("__fish_seen_subcommand_from " + name)
+		for _, f := range commandFlags(name) {
+			value := ""
+			if !isBoolFlag(f) {
+				value = " -r"
+			}
+			fmt.Printf("complete -c cwalk -n %s -o %s%s -d %s\n", cond, f.Name, value, fishQuote(f.Usage))
+		}
+		switch name {
+		case "completion":
+			fmt.Printf("complete -c cwalk -n %s -a 'bash zsh fish'\n", cond)
+		case "man":
+		default:
+			fmt.Printf("complete -c cwalk -n %s -F\n", cond)
+		}
+	}
+}
+
+// fishQuote quotes s for fish
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// man implements the man command, which prints the man page in
+// the roff format; install it with: cwalk man > /usr/share/man/man1/cwalk.1
+func man(_ *flag.FlagSet) func(args []string) int {
+	return func(args []string) int {
+		fmt.Println(`.TH CWALK 1`)
+		fmt.Println(`.SH NAME`)
+		fmt.Println(`cwalk \- concurrent replacements for tree walking tools`)
+		fmt.Println(`.SH SYNOPSIS`)
+		for _, name := range commandNames() {
+			fmt.Printf(".B cwalk %s\n", name)
+			if len(commandFlags(name)) > 0 {
+				fmt.Println(`[\fIoptions\fR]`)
+			}
+			if args := commands[name].args; args != "" {
+				fmt.Println(roffEscape(args))
+			}
+			fmt.Println(".br")
+		}
+		fmt.Println(`.SH DESCRIPTION`)
+		fmt.Println(`.B cwalk`)
+		fmt.Println(`walks directory trees with multiple workers, which makes it faster than`)
+		fmt.Println(`the standard tools on large trees; the order of the results is not defined.`)
+		fmt.Println(`.SH COMMANDS`)
+		for _, name := range commandNames() {
+			fmt.Printf(".SS %s\n", name)
+			fmt.Println(roffEscape(commands[name].summary))
+			for _, f := range commandFlags(name) {
+				fmt.Println(".TP")
+				arg := ""
+				if !isBoolFlag(f) {
+					arg = ` \fIvalue\fR`
+				}
+				fmt.Printf("\\fB\\-%s\\fR%s\n", roffEscape(f.Name), arg)
+				fmt.Println(roffEscape(f.Usage))
+			}
+		}
+		fmt.Println(`.SH ENVIRONMENT`)
+		fmt.Println(`.TP`)
+		fmt.Println(`.B LS_COLORS`)
+		fmt.Println(`colors of the paths printed by fd`)
+		fmt.Println(`.TP`)
+		fmt.Println(`.B NO_COLOR`)
+		fmt.Println(`disables the colors in the auto mode`)
+		fmt.Println(`.TP`)
+		fmt.Println(`.B POSIXLY_CORRECT, BLOCKSIZE`)
+		fmt.Println(`the default block size of du`)
+		return exitOK
+	}
+}
+
+// roffEscape escapes the characters special in roff
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
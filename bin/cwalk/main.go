@@ -0,0 +1,240 @@
+// Command cwalk provides drop-in replacements for common tree walking
+// tools, built on the concurrent walker:
+//
+//	cwalk du [-s] [-a] [-d depth] [-c] [-h] [-k] [-m] [-b] [-B size] [path ...]
+//	cwalk find [path ...] [expression]
+//	cwalk fd [options] [pattern] [path ...]
+//
+// The options may also follow the arguments, except for find, whose
+// expression comes last; use -- to pass arguments starting with a dash.
+//
+// Shell completions and the man page are generated
+// from the flag definitions of the commands:
+//
+//	cwalk completion bash|zsh|fish
+//	cwalk man
+//
+// The commands exit with 0 if the walk completed without errors, 1 if
+// it failed (or if more paths than allowed by -max-partial-errors can't
+// be read), and 2 if it completed, but some paths couldn't be read.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// command is a subcommand of the tool
+type command struct {
+	summary string
+	args    string // the arguments following the options, for the usage
+
+	// setup defines the flags of the command in fs and returns the
+	// function running the command with the remaining arguments,
+	// which returns the exit code
+	setup func(fs *flag.FlagSet) func(args []string) int
+
+	// rawArgs passes all arguments to the command unparsed
+	rawArgs bool
+}
+
+// stdout buffers the results printed by the commands,
+// which is flushed once the command is done
+var stdout = bufio.NewWriter(os.Stdout)
+
+// commands maps the subcommand names to their implementations;
+// it is filled in init() as the generators refer to it
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"du": {
+			summary: "estimate file space usage, like du(1)",
+			args:    "[path ...]",
+			setup:   du,
+		},
+		"fd": {
+			summary: "search for files with smart defaults, like fd(1)",
+			args:    "[pattern] [path ...]",
+			setup:   fd,
+		},
+		"find": {
+			summary: "search for files, like find(1)",
+			args:    "[path ...] [expression]",
+			setup:   find,
+			rawArgs: true,
+		},
+		"completion": {
+			summary: "print the shell completion script for bash, zsh or fish",
+			args:    "bash|zsh|fish",
+			setup:   completion,
+		},
+		"man": {
+			summary: "print the man page",
+			setup:   man,
+		},
+	}
+}
+
+// commandNames returns the sorted names of the commands
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  cwalk <command> [options] [path ...]")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range commandNames() {
+		fmt.Fprintf(os.Stderr, "  %-10s  %s\n", name, commands[name].summary)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitFatal)
+	}
+	name := os.Args[1]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "cwalk: unknown command %q\n", name)
+		usage()
+		os.Exit(exitFatal)
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n  cwalk %s [options] %s\nOptions:\n", name, cmd.args)
+		fs.PrintDefaults()
+	}
+	run := cmd.setup(fs)
+	args := os.Args[2:]
+	format := "text"
+	if cmd.rawArgs {
+		// only the common flags are accepted, before the arguments
+		for len(args) >= 2 && isCommonFlag(args[0]) {
+			if strings.TrimLeft(args[0], "-") == "errors-format" {
+				format = args[1]
+			} else if n, err := strconv.Atoi(args[1]); err == nil {
+				maxPartialErrors = n
+			}
+			args = args[2:]
+		}
+	} else {
+		defineCommonFlags(fs, &format)
+		var err error
+		args, err = parseArgs(fs, splitShortFlags(args, shortBoolFlags(fs)))
+		if err == flag.ErrHelp {
+			os.Exit(exitOK)
+		} else if err != nil {
+			os.Exit(exitFatal)
+		}
+	}
+	if err := setErrorsFormat(format); err != nil {
+		fmt.Fprintf(os.Stderr, "cwalk: %v\n", err)
+		os.Exit(exitFatal)
+	}
+	code := run(args)
+	if err := stdout.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "cwalk: %v\n", err)
+		code = exitFatal
+	}
+	os.Exit(code)
+}
+
+// parseArgs parses the flags, which may follow the positional arguments
+// (e.g. "cwalk fd pattern -H"), unlike with the flag package alone,
+// and returns the positional arguments; the arguments following "--"
+// are never taken for flags
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// defineCommonFlags defines the flags accepted by all commands
+func defineCommonFlags(fs *flag.FlagSet, errorsFormat *string) {
+	fs.StringVar(errorsFormat, "errors-format", "text", "Format of the errors printed to stderr: text, or json (one object per line)")
+	fs.IntVar(&maxPartialErrors, "max-partial-errors", -1, "Exit with 1 instead of 2 if more paths than this can't be read (-1 for no limit)")
+}
+
+// isCommonFlag reports whether the argument is one of the
+// common flags, which are accepted before raw arguments
+func isCommonFlag(arg string) bool {
+	switch strings.TrimLeft(arg, "-") {
+	case "errors-format", "max-partial-errors":
+		return strings.HasPrefix(arg, "-")
+	}
+	return false
+}
+
+// joinPath returns the path as the tools print it: the argument
+// followed by the path relative to it
+func joinPath(root, relpath string) string {
+	if relpath == "" {
+		return root
+	}
+	if strings.HasSuffix(root, string(os.PathSeparator)) {
+		return root + relpath
+	}
+	return root + string(os.PathSeparator) + relpath
+}
+
+// shortBoolFlags returns the names of the single-letter boolean flags
+func shortBoolFlags(fs *flag.FlagSet) string {
+	var letters string
+	fs.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) == 1 && isBoolFlag(f) {
+			letters += f.Name
+		}
+	})
+	return letters
+}
+
+// isBoolFlag reports whether the flag doesn't take a value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// splitShortFlags splits the combined single-letter flags (e.g. "-sh")
+// into separate ones, as the flag package doesn't support combining them;
+// letters lists the flags which can be combined
+func splitShortFlags(args []string, letters string) []string {
+	var out []string
+	for i, arg := range args {
+		if arg == "--" {
+			return append(out, args[i:]...)
+		}
+		if len(arg) > 2 && arg[0] == '-' && arg[1] != '-' && strings.Trim(arg[1:], letters) == "" {
+			for _, c := range arg[1:] {
+				out = append(out, "-"+string(c))
+			}
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	for _, c := range []struct {
+		args   []string
+		hidden bool
+		depth  int
+		want   []string
+	}{
+		{[]string{"pattern", "dir"}, false, 0, []string{"pattern", "dir"}},
+		{[]string{"-H", "pattern", "dir"}, true, 0, []string{"pattern", "dir"}},
+		{[]string{"pattern", "-H", "dir", "-d", "2"}, true, 2, []string{"pattern", "dir"}},
+		{[]string{"pattern", "dir", "-H"}, true, 0, []string{"pattern", "dir"}},
+		{[]string{"-d", "1", "--", "-H", "dir"}, false, 1, []string{"-H", "dir"}},
+		{[]string{"pattern", "--", "-H"}, false, 0, []string{"pattern", "-H"}},
+		{nil, false, 0, nil},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		hidden := fs.Bool("H", false, "")
+		depth := fs.Int("d", 0, "")
+		args, err := parseArgs(fs, c.args)
+		if err != nil {
+			t.Fatalf("%q: %v", c.args, err)
+		}
+		if !reflect.DeepEqual(args, c.want) || *hidden != c.hidden || *depth != c.depth {
+			t.Errorf("%q: got %q, -H %v, -d %d; want %q, -H %v, -d %d",
+				c.args, args, *hidden, *depth, c.want, c.hidden, c.depth)
+		}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if _, err := parseArgs(fs, []string{"pattern", "-x"}); err == nil {
+		t.Error("no error for an unknown flag after the pattern")
+	}
+}
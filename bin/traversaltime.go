@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -16,6 +17,7 @@ var folderCount int32
 var errorCount int32
 
 var followSymlinks bool
+var preset string
 var processingTime time.Duration
 
 // This callback simply counts files and folders.
@@ -44,6 +46,8 @@ func init() {
 
 	flag.DurationVar(&processingTime, "file-processing-time", 0, "An artificial delay, for each file processed, to imitate actual work. Omitting this parameter means no delay. Example: 50ms")
 	flag.DurationVar(&processingTime, "t", 0, "Shorthand for -file-processing-time")
+
+	flag.StringVar(&preset, "preset", "", "A comma-separated list of named filter presets to apply to the concurrent version. Available: "+strings.Join(cwalk.Presets(), ", "))
 }
 
 func main() {
@@ -51,7 +55,7 @@ func main() {
 
 	if len(flag.Args()) < 1 || flag.Args()[0] == "" {
 		fmt.Println("Usage:")
-		fmt.Println("  traversaltime [-f] [-t N] <directory-to-scan>")
+		fmt.Println("  traversaltime [-f] [-t N] [-preset name,...] <directory-to-scan>")
 		fmt.Println("Options:")
 		flag.PrintDefaults()
 		os.Exit(0)
@@ -65,15 +69,22 @@ func main() {
 	fileCount = 0
 	errorCount = 0
 
+	var opts []cwalk.Option
+	if preset != "" {
+		for _, name := range strings.Split(preset, ",") {
+			opts = append(opts, cwalk.WithPreset(strings.TrimSpace(name)))
+		}
+	}
+
 	start := time.Now()
 	var err error
 
 	if followSymlinks {
 		fmt.Printf("Running a concurrent version that follows symlinks with %d workers and %s file processing time... ", cwalk.NumWorkers, processingTime)
-		err = cwalk.WalkWithSymlinks(dir, callback)
+		err = cwalk.WalkWithSymlinks(dir, callback, opts...)
 	} else {
 		fmt.Printf("Running a concurrent version that doesn't follow symlinks with %d workers and %s file processing time... ", cwalk.NumWorkers, processingTime)
-		err = cwalk.Walk(dir, callback)
+		err = cwalk.Walk(dir, callback, opts...)
 	}
 
 	fmt.Printf("done in %s\n", time.Since(start))
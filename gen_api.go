@@ -0,0 +1,235 @@
+//go:build ignore
+// +build ignore
+
+// gen_api generates api.go, which makes the API of the v2 package
+// available in v1: the types, constants and variables are aliases,
+// and the functions are thin wrappers which apply the v1 package
+// variables to the options they take. Run it with go generate.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// handwritten are the v2 identifiers which v1 defines itself (see
+// cwalk.go), and the ones of the v2 API which v1 doesn't have
+var handwritten = map[string]bool{
+	"NewWalker":   true,
+	"Walk":        true,
+	"WalkEntries": true,
+	"WalkConfig":  true,
+	"WalkerOf":    true,
+	"PathError":   true,
+	"WalkError":   true,
+}
+
+// generator accumulates the output
+type generator struct {
+	fset    *token.FileSet
+	out     bytes.Buffer
+	imports map[string]bool
+	seen    map[string]bool
+}
+
+func main() {
+	g := &generator{fset: token.NewFileSet(), imports: map[string]bool{}, seen: map[string]bool{}}
+	files, err := filepath.Glob("v2/*.go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	sort.Strings(files)
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(g.fset, name, nil, parser.ParseComments)
+		if err != nil {
+			log.Fatal(err)
+		}
+		g.file(f)
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by gen_api.go from the v2 package; DO NOT EDIT.\n\npackage cwalk\n\nimport (\n")
+	var imports []string
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	for _, path := range imports {
+		fmt.Fprintf(&src, "\t%q\n", path)
+	}
+	src.WriteString("\n\tv2 \"github.com/iafan/cwalk/v2\"\n)\n")
+	src.Write(g.out.Bytes())
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		log.Fatalf("%v\n%s", err, src.Bytes())
+	}
+	if err := os.WriteFile("api.go", formatted, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// file generates the aliases and wrappers for the exported
+// identifiers of a file; the identifiers declared in more than
+// one file (for different platforms) are generated once
+func (g *generator) file(f *ast.File) {
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && g.exported(d.Name.Name) {
+				g.fn(f, d)
+			}
+		case *ast.GenDecl:
+			g.gen(d)
+		}
+	}
+}
+
+// exported reports whether the identifier is exported and not
+// generated yet, and marks it as generated
+func (g *generator) exported(name string) bool {
+	if !ast.IsExported(name) || handwritten[name] || g.seen[name] {
+		return false
+	}
+	g.seen[name] = true
+	return true
+}
+
+// doc writes the doc comment
+func (g *generator) doc(doc *ast.CommentGroup) {
+	if doc == nil {
+		return
+	}
+	for _, c := range doc.List {
+		if strings.HasPrefix(c.Text, "//go:") {
+			continue
+		}
+		g.out.WriteString(c.Text + "\n")
+	}
+}
+
+// gen generates the aliases of the types, constants and variables
+func (g *generator) gen(d *ast.GenDecl) {
+	if d.Tok == token.IMPORT {
+		return
+	}
+	var specs bytes.Buffer
+	n := 0
+	for _, spec := range d.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			if !g.exported(s.Name.Name) {
+				continue
+			}
+			if len(d.Specs) == 1 {
+				g.doc(d.Doc)
+			} else {
+				g.doc(s.Doc)
+			}
+			if s.TypeParams != nil {
+				log.Fatalf("generic type %s can't be aliased", s.Name.Name)
+			}
+			fmt.Fprintf(&g.out, "type %s = v2.%s\n\n", s.Name.Name, s.Name.Name)
+		case *ast.ValueSpec:
+			for _, name := range s.Names {
+				if !g.exported(name.Name) {
+					continue
+				}
+				if s.Doc != nil {
+					g.docTo(&specs, s.Doc)
+				}
+				fmt.Fprintf(&specs, "%s = v2.%s\n", name.Name, name.Name)
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		return
+	}
+	if n == 1 && len(d.Specs) == 1 {
+		g.doc(d.Doc)
+		fmt.Fprintf(&g.out, "%s %s\n", d.Tok, specs.String())
+		return
+	}
+	g.doc(d.Doc)
+	fmt.Fprintf(&g.out, "%s (\n%s)\n\n", d.Tok, specs.String())
+}
+
+// docTo writes the doc comment of a spec in a group
+func (g *generator) docTo(b *bytes.Buffer, doc *ast.CommentGroup) {
+	for _, c := range doc.List {
+		b.WriteString(c.Text + "\n")
+	}
+}
+
+// fn generates the wrapper of a function; the options it takes
+// are preceded by the ones applying the package variables
+func (g *generator) fn(f *ast.File, d *ast.FuncDecl) {
+	g.doc(d.Doc)
+	g.useImports(f, d.Type)
+
+	var args []string
+	for _, field := range d.Type.Params.List {
+		for _, name := range field.Names {
+			arg := name.Name
+			if _, ok := field.Type.(*ast.Ellipsis); ok {
+				if g.expr(field.Type) == "...Option" {
+					arg = "withGlobals(" + arg + ")"
+				}
+				arg += "..."
+			}
+			args = append(args, arg)
+		}
+	}
+	sig := g.expr(d.Type)
+	sig = "func " + d.Name.Name + strings.TrimPrefix(sig, "func")
+	call := "v2." + d.Name.Name + "(" + strings.Join(args, ", ") + ")"
+	if d.Type.Results != nil {
+		call = "return " + call
+	}
+	fmt.Fprintf(&g.out, "%s {\n%s\n}\n\n", sig, call)
+}
+
+// expr formats the expression
+func (g *generator) expr(e ast.Expr) string {
+	var b bytes.Buffer
+	if err := printer.Fprint(&b, g.fset, e); err != nil {
+		log.Fatal(err)
+	}
+	return b.String()
+}
+
+// useImports records the imports of the file used by the node
+func (g *generator) useImports(f *ast.File, node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if pkg, ok := sel.X.(*ast.Ident); ok {
+			for _, imp := range f.Imports {
+				path := strings.Trim(imp.Path.Value, `"`)
+				name := filepath.Base(path)
+				if imp.Name != nil {
+					name = imp.Name.Name
+				}
+				if name == pkg.Name {
+					g.imports[path] = true
+				}
+			}
+		}
+		return true
+	})
+}
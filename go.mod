@@ -1,3 +1,9 @@
 module github.com/iafan/cwalk
 
-go 1.15
+go 1.18
+
+require github.com/iafan/cwalk/v2 v2.0.0
+
+// v2 is developed alongside in the v2 directory; the replacement only
+// applies to builds of this module itself, others use the tagged release
+replace github.com/iafan/cwalk/v2 => ./v2
@@ -0,0 +1,218 @@
+// Package oci walks the merged file system of a container image:
+// its layers, given as unpacked directories or as layer tarballs,
+// are stacked with the OCI whiteout rules applied, as a container
+// runtime would see the root file system.
+package oci
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/iafan/cwalk"
+)
+
+// whiteout markers, see the OCI image layer specification
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// image is the merged file system being built from the layers
+type image struct {
+	entries  map[string]os.FileInfo     // keyed by slash-separated paths
+	children map[string]map[string]bool // names in each directory ("" for the root)
+}
+
+// newImage creates an empty image
+func newImage() *image {
+	return &image{entries: map[string]os.FileInfo{}, children: map[string]map[string]bool{}}
+}
+
+// WalkLayers walks the merged file system of the unpacked layer
+// directories, given from the bottom layer up (an unpacked root file
+// system is a single layer). Each layer is walked with cwalk using opts;
+// the merged tree is then passed to walkFn sequentially, in lexical order,
+// with paths relative to the image root. Whiteout files are applied
+// and never passed to walkFn. Returning SkipDir for a directory skips
+// its contents, and any other error stops the walk and is returned.
+func WalkLayers(layers []string, walkFn filepath.WalkFunc, opts ...cwalk.Option) error {
+	img := newImage()
+	for _, layer := range layers {
+		var mu sync.Mutex
+		entries := map[string]os.FileInfo{}
+		err := cwalk.Walk(layer, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if path == "" || path == "." {
+				return nil // the image root
+			}
+			mu.Lock()
+			entries[filepath.ToSlash(path)] = info
+			mu.Unlock()
+			return nil
+		}, opts...)
+		if err != nil {
+			return err
+		}
+		img.apply(entries)
+	}
+	return img.walk(walkFn)
+}
+
+// WalkLayerTarballs is like WalkLayers(), but reads the layers from
+// tarballs (optionally gzip-compressed), given from the bottom layer up
+func WalkLayerTarballs(tarballs []string, walkFn filepath.WalkFunc) error {
+	img := newImage()
+	for _, name := range tarballs {
+		entries, err := readLayer(name)
+		if err != nil {
+			return err
+		}
+		img.apply(entries)
+	}
+	return img.walk(walkFn)
+}
+
+// readLayer returns the entries of the layer tarball
+func readLayer(name string) (map[string]os.FileInfo, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	entries := map[string]os.FileInfo{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		p := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if p == "." {
+			continue
+		}
+		entries[p] = hdr.FileInfo()
+	}
+}
+
+// apply stacks the layer entries on top of the image
+func (img *image) apply(entries map[string]os.FileInfo) {
+	for p := range entries {
+		dir, name := path.Split(p)
+		switch {
+		case name == whiteoutOpaque:
+			// hide the contents of the directory in the lower layers
+			img.remove(strings.TrimSuffix(dir, "/"), false)
+		case strings.HasPrefix(name, whiteoutPrefix):
+			img.remove(dir+strings.TrimPrefix(name, whiteoutPrefix), true)
+		}
+	}
+	for p, info := range entries {
+		if !strings.HasPrefix(path.Base(p), whiteoutPrefix) {
+			img.set(p, info)
+		}
+	}
+}
+
+// set adds the entry p to the image, replacing the lower one; if it
+// isn't a directory, the lower contents of the directory p are removed
+// (like in overlayfs, a directory replaced by a file hides its subtree)
+func (img *image) set(p string, info os.FileInfo) {
+	if !info.IsDir() {
+		img.remove(p, false)
+	}
+	img.entries[p] = info
+	// index the ancestors too, as layer tarballs may not list them
+	for p != "" {
+		dir, name := path.Split(p)
+		dir = strings.TrimSuffix(dir, "/")
+		names := img.children[dir]
+		if names == nil {
+			names = map[string]bool{}
+			img.children[dir] = names
+		}
+		if names[name] {
+			break
+		}
+		names[name] = true
+		p = dir
+	}
+}
+
+// remove removes the contents of the directory p
+// (the root if p is empty), and p itself if self is set
+func (img *image) remove(p string, self bool) {
+	for name := range img.children[p] {
+		child := path.Join(p, name)
+		img.remove(child, false)
+		delete(img.entries, child)
+	}
+	delete(img.children, p)
+	if self && p != "" {
+		delete(img.entries, p)
+		dir, name := path.Split(p)
+		delete(img.children[strings.TrimSuffix(dir, "/")], name)
+	}
+}
+
+// walk passes the image entries to walkFn in lexical order
+func (img *image) walk(walkFn filepath.WalkFunc) error {
+	paths := make([]string, 0, len(img.entries))
+	for p := range img.entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var skipped []string // directories skipped by walkFn
+	for _, p := range paths {
+		if isSkipped(p, skipped) {
+			continue
+		}
+		info := img.entries[p]
+		err := walkFn(filepath.FromSlash(p), info, nil)
+		if err == filepath.SkipDir {
+			if info.IsDir() {
+				skipped = append(skipped, p+"/")
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isSkipped reports whether p is in one of the skipped directories
+func isSkipped(p string, skipped []string) bool {
+	for _, dir := range skipped {
+		if strings.HasPrefix(p, dir) {
+			return true
+		}
+	}
+	return false
+}
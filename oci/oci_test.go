@@ -0,0 +1,91 @@
+package oci
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// layers are the layers of the test image, from the bottom up;
+// the names ending with "/" are directories
+var layers = [][]string{
+	{"d/", "d/x", "d/sub/", "d/sub/y", "f", "o/", "o/gone", "w/", "w/gone", "w/kept", "s/", "s/z"},
+	{"d", "f/", "f/new", "o/", "o/.wh..wh..opq", "o/added", "w/", "w/.wh.gone", "s/", "s/added"},
+}
+
+const merged = "d,f,f/new,o,o/added,s,s/added,s/z,w,w/kept"
+
+func TestWalkLayers(t *testing.T) {
+	var dirs []string
+	for _, entries := range layers {
+		dir := t.TempDir()
+		for _, name := range entries {
+			p := filepath.Join(dir, filepath.FromSlash(name))
+			var err error
+			if strings.HasSuffix(name, "/") {
+				err = os.MkdirAll(p, 0755)
+			} else {
+				err = os.WriteFile(p, nil, 0644)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+	var visited []string
+	err := WalkLayers(dirs, func(path string, info os.FileInfo, err error) error {
+		visited = append(visited, filepath.ToSlash(path))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(visited, ","); got != merged {
+		t.Errorf("got %s, want %s", got, merged)
+	}
+}
+
+func TestWalkLayerTarballs(t *testing.T) {
+	var tarballs []string
+	for i, entries := range layers {
+		name := filepath.Join(t.TempDir(), "layer.tar")
+		f, err := os.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tw := tar.NewWriter(f)
+		for _, entry := range entries {
+			if i == 0 && strings.HasSuffix(entry, "/") {
+				continue // the parent directories may be missing
+			}
+			hdr := &tar.Header{Name: entry, Typeflag: tar.TypeReg, Mode: 0644}
+			if strings.HasSuffix(entry, "/") {
+				hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		tarballs = append(tarballs, name)
+	}
+	var visited []string
+	err := WalkLayerTarballs(tarballs, func(path string, info os.FileInfo, err error) error {
+		visited = append(visited, filepath.ToSlash(path))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(visited, ","); got != merged {
+		t.Errorf("got %s, want %s", got, merged)
+	}
+}
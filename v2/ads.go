@@ -0,0 +1,55 @@
+package cwalk
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WithAlternateDataStreams makes the walk enumerate the alternate data
+// streams of every regular file on NTFS, and pass each of them to the
+// callback as a synthetic entry named "path:stream", which shares the
+// OrderToken of its file. Scanners need this, as payloads can be hidden
+// in alternate data streams. This option has no effect on other platforms.
+func WithAlternateDataStreams() Option {
+	return func(w *Walker) {
+		w.streams = true
+	}
+}
+
+// streamInfo is the os.FileInfo of an alternate data stream
+type streamInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (si *streamInfo) Name() string       { return si.name }
+func (si *streamInfo) Size() int64        { return si.size }
+func (si *streamInfo) Mode() os.FileMode  { return 0 }
+func (si *streamInfo) ModTime() time.Time { return si.modTime }
+func (si *streamInfo) IsDir() bool        { return false }
+func (si *streamInfo) Sys() interface{}   { return nil }
+
+// emitStream passes an alternate data stream of the file at relpath
+// to the callback
+func (w *Walker) emitStream(ws *workerState, relpath string, file os.FileInfo, name string, size int64, order OrderToken) {
+	path := relpath + ":" + name
+	entry := &Entry{
+		Path: path,
+		Info: &streamInfo{
+			name:    file.Name() + ":" + name,
+			size:    size,
+			modTime: file.ModTime(),
+		},
+		Order: order,
+	}
+	err := w.emit(ws, entry, nil)
+	switch err {
+	case nil, filepath.SkipDir:
+		w.outcome(path, Visited, 0, nil)
+	case errDuplicate:
+	default:
+		w.addError(path, err)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package cwalk
+
+import "os"
+
+// emitStreams does nothing, as alternate data streams
+// are only supported on Windows
+func (w *Walker) emitStreams(ws *workerState, relpath string, info os.FileInfo, order OrderToken) {
+}
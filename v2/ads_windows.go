@@ -0,0 +1,62 @@
+package cwalk
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// FindFirstStreamW definitions missing from the syscall package
+const (
+	findStreamInfoStandard = 0
+	maxStreamName          = syscall.MAX_PATH + 36
+	errorInvalidParameter  = syscall.Errno(87)
+)
+
+var (
+	modKernel32          = syscall.NewLazyDLL("kernel32.dll")
+	procFindFirstStreamW = modKernel32.NewProc("FindFirstStreamW")
+	procFindNextStreamW  = modKernel32.NewProc("FindNextStreamW")
+)
+
+// win32FindStreamData is the WIN32_FIND_STREAM_DATA structure
+type win32FindStreamData struct {
+	streamSize int64
+	streamName [maxStreamName]uint16
+}
+
+// emitStreams passes the alternate data streams of the file
+// at relpath to the callback
+func (w *Walker) emitStreams(ws *workerState, relpath string, info os.FileInfo, order OrderToken) {
+	path, err := syscall.UTF16PtrFromString(w.osPath(relpath))
+	if err != nil {
+		return
+	}
+	var data win32FindStreamData
+	h, _, errno := procFindFirstStreamW.Call(uintptr(unsafe.Pointer(path)),
+		findStreamInfoStandard, uintptr(unsafe.Pointer(&data)), 0)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		// ERROR_HANDLE_EOF means there are no streams, and
+		// ERROR_INVALID_PARAMETER that the file system doesn't support them
+		if errno != syscall.ERROR_HANDLE_EOF && errno != errorInvalidParameter {
+			w.addError(relpath, &os.PathError{Op: "FindFirstStream", Path: relpath, Err: errno})
+		}
+		return
+	}
+	defer syscall.FindClose(syscall.Handle(h))
+
+	for {
+		// stream names look like ":name:$DATA",
+		// and the default stream is "::$DATA"
+		name := syscall.UTF16ToString(data.streamName[:])
+		name = strings.TrimSuffix(strings.TrimPrefix(name, ":"), ":$DATA")
+		if name != "" {
+			w.emitStream(ws, relpath, info, name, data.streamSize, order)
+		}
+		ret, _, _ := procFindNextStreamW.Call(h, uintptr(unsafe.Pointer(&data)))
+		if ret == 0 {
+			return
+		}
+	}
+}
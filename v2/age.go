@@ -0,0 +1,183 @@
+package cwalk
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultAgeBounds are the age bucket bounds used by AgeReport()
+// when none are given: a week, a month, a quarter, a year and 3 years
+var DefaultAgeBounds = []time.Duration{
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+	90 * 24 * time.Hour,
+	365 * 24 * time.Hour,
+	3 * 365 * 24 * time.Hour,
+}
+
+// AgeHistogram is the distribution of files by age: bucket i counts
+// the files younger than the bound i (and not younger than the bound
+// i-1), the last bucket counts the files older than all the bounds
+type AgeHistogram struct {
+	Files []int64
+	Bytes []int64 // apparent size of the files
+}
+
+// add counts a file in the bucket
+func (h *AgeHistogram) add(bucket int, size int64) {
+	h.Files[bucket]++
+	h.Bytes[bucket] += size
+}
+
+// merge adds the counts of o
+func (h *AgeHistogram) merge(o *AgeHistogram) {
+	for i := range o.Files {
+		h.Files[i] += o.Files[i]
+		h.Bytes[i] += o.Bytes[i]
+	}
+}
+
+// SubtreeAges holds the age histograms of the files of a subtree,
+// see AgeReport()
+type SubtreeAges struct {
+	Path  string // path relative to the root ("" for the root itself)
+	Files int64  // number of files in the subtree, including symlinks and the like
+	Bytes int64  // apparent size of the files
+
+	Modified AgeHistogram // by last modification time
+	Accessed AgeHistogram // by last access time, where available
+}
+
+// newSubtreeAges returns empty histograms for the given number of bounds
+func newSubtreeAges(path string, bounds int) *SubtreeAges {
+	hist := func() AgeHistogram {
+		return AgeHistogram{Files: make([]int64, bounds+1), Bytes: make([]int64, bounds+1)}
+	}
+	return &SubtreeAges{Path: path, Modified: hist(), Accessed: hist()}
+}
+
+// merge adds the counts of o
+func (s *SubtreeAges) merge(o *SubtreeAges) {
+	s.Files += o.Files
+	s.Bytes += o.Bytes
+	s.Modified.merge(&o.Modified)
+	s.Accessed.merge(&o.Accessed)
+}
+
+// AgeReport walks root and returns the distribution of the files by
+// last modification and last access time (see AgeHistogram) for the
+// root and for every directory up to depth levels below it (0 for the
+// root only, negative for all of them), in lexical order, to plan the
+// archival of cold data. Each subtree counts all the files under it.
+// Ages are relative to the start of the walk and bucketed by bounds,
+// in increasing order (DefaultAgeBounds if nil). The histograms are accumulated by each
+// worker and merged once the walk is complete, so the memory used only
+// depends on the number of subtrees reported.
+//
+// Access times are only as accurate as the file system keeps them:
+// with the relatime mount option (the default on Linux), they are only
+// updated once a day, and not at all with noatime. Files which access
+// time is not available on the platform are left out of Accessed.
+// Errors are returned after the walk along with the histograms of the
+// files that could be read.
+func AgeReport(root string, depth int, bounds []time.Duration, opts ...Option) ([]SubtreeAges, error) {
+	if bounds == nil {
+		bounds = DefaultAgeBounds
+	}
+	now := time.Now()
+	bucket := func(t time.Time) int {
+		age := now.Sub(t)
+		return sort.Search(len(bounds), func(i int) bool { return age < bounds[i] })
+	}
+	// subtree returns the subtree at most depth levels deep the path belongs to
+	subtree := func(path string) string {
+		if path == "" {
+			return ""
+		}
+		n := 0
+		for i := 0; i < len(path); i++ {
+			if path[i] == filepath.Separator {
+				if n++; n == depth {
+					return path[:i]
+				}
+			}
+		}
+		if depth == 0 {
+			return ""
+		}
+		return path
+	}
+
+	w := NewWalker(root, opts...)
+	rootState := map[string]*SubtreeAges{}
+	err := w.walk("", func(ws *workerState, entry *Entry, err error) error {
+		if err != nil || entry.Info == nil {
+			return err
+		}
+		state := rootState
+		if ws != nil {
+			if ws.local == nil {
+				ws.local = map[string]*SubtreeAges{}
+			}
+			state = ws.local.(map[string]*SubtreeAges)
+		}
+		key := entry.Path
+		if !entry.Info.IsDir() {
+			key = parentPath(key)
+		}
+		key = subtree(key)
+		s := state[key]
+		if s == nil {
+			s = newSubtreeAges(key, len(bounds))
+			state[key] = s
+		}
+		if entry.Info.IsDir() {
+			return nil
+		}
+		size := entry.Info.Size()
+		s.Files++
+		s.Bytes += size
+		s.Modified.add(bucket(entry.Info.ModTime()), size)
+		if atime, ok := accessTime(entry.Info); ok {
+			s.Accessed.add(bucket(atime), size)
+		}
+		return nil
+	})
+
+	// the workers have exited, so their states can be read here
+	w.mu.Lock()
+	states := []map[string]*SubtreeAges{rootState}
+	for _, ws := range w.workers {
+		if state, ok := ws.local.(map[string]*SubtreeAges); ok {
+			states = append(states, state)
+		}
+	}
+	w.mu.Unlock()
+
+	// add the counts of every subtree to its parents
+	total := map[string]*SubtreeAges{}
+	for _, state := range states {
+		for key, s := range state {
+			for path := key; ; path = parentPath(path) {
+				t := total[path]
+				if t == nil {
+					t = newSubtreeAges(path, len(bounds))
+					total[path] = t
+				}
+				t.merge(s)
+				if path == "" {
+					break
+				}
+			}
+		}
+	}
+	list := make([]SubtreeAges, 0, len(total))
+	for _, s := range total {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Path < list[j].Path
+	})
+	return list, err
+}
@@ -0,0 +1,185 @@
+package cwalk
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupportedArchive is reported by WithArchiveDescent()
+// for archive formats that can't be read
+var ErrUnsupportedArchive = errors.New("Unsupported archive format")
+
+// ErrUnsafeArchiveMember is reported by WithArchiveDescent() for archive
+// members with absolute names or names containing "..", which are skipped
+var ErrUnsafeArchiveMember = errors.New("Unsafe archive member name")
+
+// archive formats supported by WithArchiveDescent()
+const (
+	ArchiveZip   = "zip"
+	ArchiveTar   = "tar"
+	ArchiveTarGz = "tar.gz" // also matches .tgz files
+)
+
+// maxArchiveReaders is the number of archives read at the same time
+const maxArchiveReaders = 4
+
+// WithArchiveDescent makes the walk look inside the archives of the
+// given formats (all supported formats if none are given) and pass
+// their members to the callback as synthetic entries named
+// "path!/member", which share the OrderToken of the archive. Archives are
+// recognized by their extensions, and at most four of them
+// are read at the same time. The member names are cleaned, and members
+// with absolute names or names containing ".." are skipped and reported
+// with ErrUnsafeArchiveMember; the AbsPath of the members is the path
+// of the archive. The members are not filtered, archives nested in
+// archives are not descended into, and returning SkipDir for a member
+// directory skips its members. Errors reading an archive are reported
+// for its path.
+func WithArchiveDescent(formats ...string) Option {
+	return func(w *Walker) {
+		if len(formats) == 0 {
+			formats = []string{ArchiveZip, ArchiveTar, ArchiveTarGz}
+		}
+		for _, format := range formats {
+			switch format {
+			case ArchiveZip, ArchiveTar, ArchiveTarGz:
+			default:
+				w.setOptionError(fmt.Errorf("%w: %s", ErrUnsupportedArchive, format))
+				return
+			}
+		}
+		w.archiveFormats = formats
+		w.archiveReaders = make(chan struct{}, maxArchiveReaders)
+	}
+}
+
+// archiveFormat returns the format of the archive
+// by its name, or "" if it is not descended into
+func (w *Walker) archiveFormat(name string) string {
+	name = strings.ToLower(name)
+	for _, format := range w.archiveFormats {
+		switch {
+		case format == ArchiveTarGz && strings.HasSuffix(name, ".tgz"),
+			strings.HasSuffix(name, "."+format):
+			return format
+		}
+	}
+	return ""
+}
+
+// emitArchive passes the members of the archive
+// at relpath to the callback
+func (w *Walker) emitArchive(ws *workerState, relpath string, info os.FileInfo, format string, order OrderToken) error {
+	if w.forensic && isPlaceholder(info) {
+		return nil
+	}
+	w.archiveReaders <- struct{}{}
+	defer func() { <-w.archiveReaders }()
+	if !w.acquire() {
+		return nil
+	}
+	defer w.release()
+
+	f, err := w.openContentFile(relpath, info)
+	if err != nil {
+		return err
+	}
+	defer w.closeFD()
+	defer f.Close()
+
+	absPath := filepath.Join(w.absRoot, relpath)
+	var skipped []string // member directories skipped by the callback
+	member := func(name string, info os.FileInfo) bool {
+		name, err := cleanMemberName(name)
+		if err != nil {
+			w.addError(relpath+"!/"+name, err)
+			return !w.isAborted()
+		}
+		if name == "." {
+			return true // the archive itself
+		}
+		for _, dir := range skipped {
+			if strings.HasPrefix(name, dir+"/") {
+				return true
+			}
+		}
+		path := relpath + "!/" + name
+		entry := &Entry{Path: path, AbsPath: absPath, Info: info, Order: order}
+		err = w.emit(ws, entry, nil)
+		switch err {
+		case nil:
+			w.outcome(path, Visited, 0, nil)
+		case filepath.SkipDir:
+			w.outcome(path, Visited, 0, nil)
+			if !info.IsDir() {
+				return false
+			}
+			skipped = append(skipped, name)
+		case errDuplicate:
+		default:
+			w.addError(path, err)
+		}
+		return !w.isAborted()
+	}
+
+	if format == ArchiveZip {
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return err
+		}
+		for _, zf := range zr.File {
+			if !member(zf.Name, zf.FileInfo()) {
+				break
+			}
+		}
+		return nil
+	}
+
+	var r io.Reader = f
+	if format == ArchiveTarGz {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !member(hdr.Name, hdr.FileInfo()) {
+			return nil
+		}
+	}
+}
+
+// cleanMemberName cleans the name of an archive member, which is
+// always slash-separated; names which would resolve outside of the
+// archive (absolute, or containing "..") are rejected
+func cleanMemberName(name string) (string, error) {
+	unsafe := strings.HasPrefix(name, "/") || strings.HasPrefix(name, "\\") ||
+		filepath.VolumeName(name) != "" || len(name) > 1 && name[1] == ':'
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			unsafe = true
+		}
+	}
+	if unsafe {
+		return strings.TrimLeft(name, "/"), fmt.Errorf("%w: %s", ErrUnsafeArchiveMember, name)
+	}
+	return path.Clean(name), nil
+}
@@ -0,0 +1,58 @@
+package cwalk
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestArchiveUnsafeMembers(t *testing.T) {
+	root := t.TempDir()
+	f, err := os.Create(filepath.Join(root, "x.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"../../etc/passwd", "/abs", "a/../b", "a/./b/", "ok.txt"} {
+		if _, err := zw.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	var mu sync.Mutex
+	var members []string
+	w := NewWalker(root, WithArchiveDescent())
+	err = w.WalkEntries("", func(entry *Entry, err error) error {
+		if strings.Contains(entry.Path, "!/") {
+			mu.Lock()
+			members = append(members, entry.Path)
+			mu.Unlock()
+			if entry.AbsPath != filepath.Join(w.absRoot, "x.zip") {
+				t.Errorf("AbsPath of %s is %s, want the archive path", entry.Path, entry.AbsPath)
+			}
+		}
+		return nil
+	})
+	sort.Strings(members)
+	if want := []string{"x.zip!/a/b", "x.zip!/ok.txt"}; strings.Join(members, ",") != strings.Join(want, ",") {
+		t.Errorf("members = %v, want %v", members, want)
+	}
+	var list WalkerErrorList
+	if !errors.As(err, &list) || len(list.ErrorList) != 3 {
+		t.Fatalf("Walk returned %v, want 3 errors", err)
+	}
+	for _, e := range list.ErrorList {
+		if !errors.Is(e, ErrUnsafeArchiveMember) {
+			t.Errorf("unexpected error: %v", e)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package cwalk
+
+import (
+	"container/list"
+	"os"
+	"sync"
+)
+
+// FileKey identifies a version of a directory: its device and inode
+// numbers, and its modification time in nanoseconds, which changes
+// whenever entries are added, removed or renamed
+type FileKey struct {
+	Dev     uint64
+	Ino     uint64
+	ModTime int64
+}
+
+// CachedDir is a directory listing stored in a MetadataCache;
+// Infos holds the file info of each entry in Names, or nil
+// if it is not known, which is only reused for immutable trees
+// (see WithImmutableTree())
+type CachedDir struct {
+	Names []string
+	Infos []os.FileInfo
+}
+
+// MetadataCache stores directory listings along with the file info
+// of their entries between walks, see WithMetadataCache().
+// Implementations must be safe for concurrent use
+type MetadataCache interface {
+	Get(key FileKey) (*CachedDir, bool)
+	Put(key FileKey, dir *CachedDir)
+}
+
+// WithMetadataCache makes the walk reuse the directory listings stored
+// in c, as long as the directory itself hasn't changed (see FileKey),
+// skipping the readdir calls. This is meant for repeated walks in
+// long-running processes. The entries of a cached directory are still
+// stat'ed, as changes to files (e.g. writing to an existing file) don't
+// update their directory; their file info is only reused as well
+// with WithImmutableTree(). A cache should not be shared between walks
+// with different symlink following settings. Caching is not available
+// on Windows.
+func WithMetadataCache(c MetadataCache) Option {
+	return func(w *Walker) {
+		w.cache = c
+	}
+}
+
+// readDir returns the names of the directory entries, either from
+// the metadata cache, along with the file info of the entries,
+// or from the file system; the returned function stores the file
+// info collected during processing in the cache
+func (w *Walker) readDir(j job) (names []string, infos []os.FileInfo, store func(), err error) {
+	store = func() {}
+	key, ok := fileKey(j.info)
+	if w.cache == nil || !ok {
+		if w.lazyStat && w.cache == nil {
+			if names, infos, ok, err := w.readDirLazy(j.path); ok {
+				return names, infos, store, err
+			}
+		}
+		names, err = w.readDirNames(j.path)
+		return names, nil, store, err
+	}
+	if dir, ok := w.cache.Get(key); ok {
+		// the entries are stat'ed again unless the tree is immutable,
+		// as files can change without their directory changing; the
+		// entries not stat'ed are filled in during processing, so
+		// copy the cached listing
+		infos = make([]os.FileInfo, len(dir.Names))
+		if w.immutable {
+			copy(infos, dir.Infos)
+		}
+		return dir.Names, infos, store, nil
+	}
+
+	names, err = w.readDirNames(j.path)
+	if err != nil {
+		return names, nil, store, err
+	}
+	infos = make([]os.FileInfo, len(names))
+	store = func() {
+		w.cache.Put(key, &CachedDir{Names: names, Infos: infos})
+	}
+	return names, infos, store, nil
+}
+
+// CacheStats holds the statistics of an LRUCache
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	Dirs   int // number of directories currently stored
+}
+
+// LRUCache is a MetadataCache which keeps up to a given number
+// of directories, evicting the least recently used ones
+type LRUCache struct {
+	mu      sync.Mutex
+	maxDirs int
+	items   map[FileKey]*list.Element
+	order   *list.List // front is the most recently used
+	stats   CacheStats
+}
+
+// lruItem is an element of LRUCache.order
+type lruItem struct {
+	key FileKey
+	dir *CachedDir
+}
+
+// NewLRUCache creates an LRUCache keeping up to maxDirs directories
+func NewLRUCache(maxDirs int) *LRUCache {
+	return &LRUCache{
+		maxDirs: maxDirs,
+		items:   map[FileKey]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// Get returns the cached directory listing
+func (c *LRUCache) Get(key FileKey) (*CachedDir, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*lruItem).dir, true
+}
+
+// Put stores the directory listing, evicting the least
+// recently used directory if the cache is full
+func (c *LRUCache) Put(key FileKey, dir *CachedDir) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruItem).dir = dir
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruItem{key: key, dir: dir})
+	for c.maxDirs > 0 && c.order.Len() > c.maxDirs {
+		el := c.order.Back()
+		c.order.Remove(el)
+		delete(c.items, el.Value.(*lruItem).key)
+	}
+}
+
+// Stats returns the hit and miss counters
+// and the number of cached directories
+func (c *LRUCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Dirs = c.order.Len()
+	return stats
+}
@@ -0,0 +1,62 @@
+package cwalk
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestMetadataCacheFileChanged(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("caching is not available on Windows")
+	}
+	root := t.TempDir()
+	name := filepath.Join(root, "f")
+	if err := os.WriteFile(name, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache := NewLRUCache(0)
+	size := func(opts ...Option) int64 {
+		var mu sync.Mutex
+		var size int64 = -1
+		err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+			if path == "f" {
+				mu.Lock()
+				size = info.Size()
+				mu.Unlock()
+			}
+			return err
+		}, append([]Option{WithMetadataCache(cache)}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return size
+	}
+
+	if got := size(); got != 1 {
+		t.Fatalf("got size %d, want 1", got)
+	}
+	// writing to the file doesn't change the directory
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("bc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := size(); got != 3 {
+		t.Errorf("got size %d, want 3", got)
+	}
+	if stats := cache.Stats(); stats.Hits != 1 {
+		t.Errorf("got %d cache hits, want 1", stats.Hits)
+	}
+	// the file info is trusted for immutable trees
+	if got := size(WithImmutableTree()); got != 1 {
+		t.Errorf("got size %d with WithImmutableTree(), want 1", got)
+	}
+}
@@ -0,0 +1,157 @@
+package cwalk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ErrOwnerChanged is returned by ApplyChown() for the files
+// which owner has changed since the operations were planned
+var ErrOwnerChanged = errors.New("Owner changed since the chown was planned")
+
+// IDMap maps user and group IDs, e.g. from one identity domain
+// to another, see PlanChown(); IDs not in the maps are kept
+type IDMap struct {
+	UIDs map[uint32]uint32
+	GIDs map[uint32]uint32
+}
+
+// ChownOp is a change of the owner of a file, see PlanChown()
+type ChownOp struct {
+	Path           string // path relative to the root
+	IsDir          bool
+	UID, GID       uint32 // current owner
+	NewUID, NewGID uint32
+}
+
+// String formats the operation as a line of a dry-run diff
+// ("." stands for the root)
+func (op ChownOp) String() string {
+	path := op.Path
+	if path == "" {
+		path = "."
+	}
+	return fmt.Sprintf("%s: %d:%d -> %d:%d", path, op.UID, op.GID, op.NewUID, op.NewGID)
+}
+
+// PlanChown walks root and returns the changes of owner needed to map
+// the user and group IDs of the files with m, without changing anything
+// (the list is a dry-run diff, see ChownOp.String()). The operations
+// are in post-order: the contents of every directory come before the
+// directory itself, and they can be applied with ApplyChown(). Files
+// with multiple hard links are only listed once. Symbolic links are
+// changed themselves rather than their targets. On platforms without
+// numeric owners (Windows, Plan 9), nothing is planned. Errors are
+// returned after the walk along with the operations planned for the
+// entries that could be read.
+func PlanChown(root string, m IDMap, opts ...Option) ([]ChownOp, error) {
+	var mu sync.Mutex
+	var ops []ChownOp
+	seen := map[FileKey]bool{}
+	err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		uid, gid, ok := fileOwner(info)
+		if !ok {
+			return nil
+		}
+		op := ChownOp{Path: path, IsDir: info.IsDir(), UID: uid, GID: gid, NewUID: uid, NewGID: gid}
+		if id, ok := m.UIDs[uid]; ok {
+			op.NewUID = id
+		}
+		if id, ok := m.GIDs[gid]; ok {
+			op.NewGID = id
+		}
+		if op.NewUID == uid && op.NewGID == gid {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !info.IsDir() && linkCount(info) > 1 {
+			if key, ok := fileKey(info); ok {
+				key.ModTime = 0
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+		}
+		ops = append(ops, op)
+		return nil
+	}, opts...)
+
+	// the entries of a directory sort before it, as no
+	// valid UTF-8 name starts with the 0xff byte
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].Path+"\xff" < ops[j].Path+"\xff"
+	})
+	return ops, err
+}
+
+// ApplyChown applies the operations planned by PlanChown() to the tree
+// at root, deepest directory levels first, so that every directory is
+// changed after its contents; the operations of each level are applied
+// by as many goroutines as a walk with opts has workers (see WithWorkers()).
+// To avoid clobbering concurrent changes, the files which owner is
+// no longer the one planned are left alone and reported with
+// ErrOwnerChanged. On Linux, the paths are resolved without following
+// symlinks and the files are changed through file descriptors, so that
+// the tree can't be modified during the run to redirect a change outside
+// of it; elsewhere, the symlinks among the parent directories are
+// followed, so don't run ApplyChown() there with more privileges than
+// the users who can modify the tree. The failed operations are returned
+// as a WalkerErrorList.
+func ApplyChown(root string, ops []ChownOp, opts ...Option) error {
+	workers := NewWalker(root, opts...).workerCount()
+	levels := map[int][]ChownOp{}
+	var depths []int
+	for _, op := range ops {
+		depth := 0
+		if op.Path != "" {
+			depth = pathDepth(op.Path)
+		}
+		if levels[depth] == nil {
+			depths = append(depths, depth)
+		}
+		levels[depth] = append(levels[depth], op)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(depths)))
+
+	var mu sync.Mutex
+	var errs WalkerErrorList
+	for _, depth := range depths {
+		level := levels[depth]
+		next := make(chan ChownOp)
+		var wg sync.WaitGroup
+		for n := 0; n < workers; n++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for op := range next {
+					if err := applyChown(root, op); err != nil {
+						mu.Lock()
+						errs.ErrorList = append(errs.ErrorList, WalkerError{error: err, path: op.Path})
+						mu.Unlock()
+					}
+				}
+			}()
+		}
+		for _, op := range level {
+			next <- op
+		}
+		close(next)
+		wg.Wait()
+	}
+
+	if len(errs.ErrorList) == 0 {
+		return nil
+	}
+	sort.Slice(errs.ErrorList, func(i, j int) bool {
+		return errs.ErrorList[i].path < errs.ErrorList[j].path
+	})
+	return errs
+}
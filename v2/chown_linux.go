@@ -0,0 +1,63 @@
+package cwalk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// applyChown changes the owner of the file, unless it has changed.
+// The path is resolved one component at a time, relative to the parent
+// directory and without following symlinks, and the file is changed
+// through its file descriptor, so that the tree can't be modified to
+// redirect the change outside of it (e.g. by swapping a directory
+// for a symlink while running as root)
+func applyChown(root string, op ChownOp) error {
+	dir, err := syscall.Open(root, oPath|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: root, Err: err}
+	}
+	name := "."
+	if op.Path != "" {
+		parts := strings.Split(op.Path, string(filepath.Separator))
+		for _, part := range parts[:len(parts)-1] {
+			fd, err := openat(dir, part, oPath|syscall.O_DIRECTORY|syscall.O_NOFOLLOW)
+			syscall.Close(dir)
+			if err != nil {
+				return &os.PathError{Op: "openat", Path: op.Path, Err: err}
+			}
+			dir = fd
+		}
+		name = parts[len(parts)-1]
+	}
+
+	// O_PATH opens symlinks (and devices) without side effects
+	fd, err := openat(dir, name, oPath|syscall.O_NOFOLLOW)
+	syscall.Close(dir)
+	if err != nil {
+		return &os.PathError{Op: "openat", Path: op.Path, Err: err}
+	}
+	defer syscall.Close(fd)
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		return &os.PathError{Op: "fstat", Path: op.Path, Err: err}
+	}
+	if st.Uid != op.UID || st.Gid != op.GID {
+		return ErrOwnerChanged
+	}
+	if err := syscall.Fchownat(fd, "", int(op.NewUID), int(op.NewGID), atEmptyPath); err != nil {
+		return &os.PathError{Op: "fchownat", Path: op.Path, Err: err}
+	}
+	return nil
+}
+
+// openat is a wrapper for openat(2) which retries on EINTR
+func openat(dir int, name string, flags int) (int, error) {
+	for {
+		fd, err := syscall.Openat(dir, name, flags|syscall.O_CLOEXEC, 0)
+		if err != syscall.EINTR {
+			return fd, err
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package cwalk
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestApplyChown(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing owners requires root")
+	}
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "d", "f"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc/passwd", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	m := IDMap{UIDs: map[uint32]uint32{0: 1234}, GIDs: map[uint32]uint32{0: 1234}}
+	ops, err := PlanChown(root, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyChown(root, ops); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", "d", "d/f", "link"} {
+		if uid := ownerOf(t, filepath.Join(root, filepath.FromSlash(name))); uid != 1234 {
+			t.Errorf("%s: got owner %d, want 1234", name, uid)
+		}
+	}
+	if uid := ownerOf(t, "/etc/passwd"); uid != 0 {
+		t.Errorf("the symlink target was changed")
+	}
+}
+
+func TestApplyChownSwappedParent(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing owners requires root")
+	}
+	root, outside := t.TempDir(), t.TempDir()
+	for _, dir := range []string{root, outside} {
+		if err := os.MkdirAll(filepath.Join(dir, "d"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "d", "f"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := IDMap{UIDs: map[uint32]uint32{0: 1234}}
+	ops, err := PlanChown(root, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the directory is replaced by a symlink after the planning
+	if err := os.Rename(filepath.Join(root, "d"), filepath.Join(root, "old")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "d"), filepath.Join(root, "d")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyChown(root, ops); err == nil {
+		t.Error("the swapped directory is not reported")
+	}
+	if uid := ownerOf(t, filepath.Join(outside, "d", "f")); uid != 0 {
+		t.Error("a file outside of the tree was changed")
+	}
+}
+
+// ownerOf returns the user ID of the file, not following symlinks
+func ownerOf(t *testing.T, path string) uint32 {
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Sys().(*syscall.Stat_t).Uid
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package cwalk
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// applyChown changes the owner of the file, unless it has changed.
+// On this platform, the symlinks among the parent directories
+// are followed, see ApplyChown()
+func applyChown(root string, op ChownOp) error {
+	path := filepath.Join(root, op.Path)
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if uid, gid, ok := fileOwner(info); !ok || uid != op.UID || gid != op.GID {
+		return ErrOwnerChanged
+	}
+	return os.Lchown(path, int(op.NewUID), int(op.NewGID))
+}
@@ -0,0 +1,149 @@
+package cwalk
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// Chunk is a content-defined chunk of a file, see WithChunking()
+type Chunk struct {
+	Offset int64
+	Size   int
+	Sum    [sha256.Size]byte
+}
+
+// ChunkParams are the minimum, average and maximum chunk sizes
+// of content-defined chunking, in bytes
+type ChunkParams struct {
+	Min, Avg, Max int
+}
+
+// DefaultChunkParams are the chunk sizes commonly used by FastCDC
+var DefaultChunkParams = ChunkParams{Min: 2 << 10, Avg: 8 << 10, Max: 64 << 10}
+
+// ChunkFunc receives the chunks of a file, see WithChunking()
+type ChunkFunc func(entry *Entry, chunks []Chunk) error
+
+// gearTable holds the random values of the gear rolling hash;
+// they are generated (with splitmix64) rather than listed, and
+// must never change, as they define the chunk boundaries
+var gearTable = func() (table [256]uint64) {
+	x := uint64(0x63776b6c) // "cwlk"
+	for i := range table {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// WithChunking adds a content stage (see WithContentStage()) which
+// splits every regular file into content-defined chunks with FastCDC
+// (a gear rolling hash with normalized chunking), and passes the list
+// of chunks, with their SHA-256 digests, to fn, e.g. for delta-sync
+// engines: an insertion into a file only changes the chunks around it.
+// The files are chunked by the workers, so fn must be safe for
+// concurrent use. The chunk boundaries only depend on the content
+// and on p, which must satisfy 64 <= Min <= Avg <= Max.
+func WithChunking(p ChunkParams, fn ChunkFunc) Option {
+	return func(w *Walker) {
+		if err := p.validate(); err != nil {
+			w.setOptionError(err)
+			return
+		}
+		w.stages = append(w.stages, func(entry *Entry, r io.Reader) error {
+			chunks, err := Chunks(r, p)
+			if err != nil {
+				return err
+			}
+			return fn(entry, chunks)
+		})
+	}
+}
+
+// validate checks the chunk sizes
+func (p ChunkParams) validate() error {
+	if p.Min < 64 || p.Avg < p.Min || p.Max < p.Avg {
+		return fmt.Errorf("Invalid chunk sizes: %+v", p)
+	}
+	return nil
+}
+
+// Chunks splits the content read from r into content-defined chunks,
+// like WithChunking() does
+func Chunks(r io.Reader, p ChunkParams) ([]Chunk, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	// the masks have more bits than the average before reaching it,
+	// and fewer after, so that chunk sizes cluster around the average
+	avgBits := bits.Len(uint(p.Avg)) - 1
+	maskS := highBits(avgBits + 2)
+	maskL := highBits(avgBits - 2)
+
+	var chunks []Chunk
+	var offset int64
+	buf := make([]byte, 2*p.Max)
+	n, eof := 0, false
+	for {
+		if !eof && n < p.Max {
+			m, err := io.ReadFull(r, buf[n:])
+			n += m
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				eof = true
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if n == 0 {
+			return chunks, nil
+		}
+		size := cutChunk(buf[:n], p, maskS, maskL)
+		chunks = append(chunks, Chunk{Offset: offset, Size: size, Sum: sha256.Sum256(buf[:size])})
+		offset += int64(size)
+		n = copy(buf, buf[size:n])
+	}
+}
+
+// highBits returns a mask of the n high bits, which
+// carry the most entropy in the gear hash
+func highBits(n int) uint64 {
+	if n < 1 {
+		n = 1
+	}
+	return ^uint64(0) << (64 - n)
+}
+
+// cutChunk returns the size of the chunk at the beginning of data
+func cutChunk(data []byte, p ChunkParams, maskS, maskL uint64) int {
+	n := len(data)
+	if n <= p.Min {
+		return n
+	}
+	if n > p.Max {
+		n = p.Max
+	}
+	normal := p.Avg
+	if n < normal {
+		normal = n
+	}
+	var fp uint64
+	i := p.Min
+	for ; i < normal; i++ {
+		fp = fp<<1 + gearTable[data[i]]
+		if fp&maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fp = fp<<1 + gearTable[data[i]]
+		if fp&maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
@@ -0,0 +1,120 @@
+package cwalk
+
+import (
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Sample defines the part of a file passed to a classifier,
+// see WithClassifier()
+type Sample struct {
+	Head      int // bytes read from the beginning of the file
+	Chunks    int // number of chunks read at random offsets after the head
+	ChunkSize int // size of each chunk
+}
+
+// size returns the total size of the sample
+func (s Sample) size() int64 {
+	return int64(s.Head) + int64(s.Chunks)*int64(s.ChunkSize)
+}
+
+// ClassifyFunc inspects a sample of the file content (e.g. looks
+// for personal data); entry is the one passed to the callback.
+// It is called by the workers, so it must be safe for concurrent use.
+type ClassifyFunc func(entry *Entry, sample []byte) error
+
+// WithClassifier adds a content stage (see WithContentStage()) which
+// reads a sample of every regular file and passes it to fn: the first
+// sample.Head bytes, followed by sample.Chunks chunks of sample.ChunkSize
+// bytes read at random offsets in the rest of the file (in the order
+// of their offsets). Files not larger than the sample are read entirely.
+// The offsets are derived from the path, so repeated walks read the same
+// chunks. If bytesPerSec is positive, the reads of all workers are
+// throttled to this rate. As the sample is a small part of large files,
+// consider disabling the read-ahead hints with WithoutPrefetch().
+func WithClassifier(fn ClassifyFunc, sample Sample, bytesPerSec int64) Option {
+	var throttle *byteRate
+	if bytesPerSec > 0 {
+		throttle = &byteRate{rate: bytesPerSec}
+	}
+	return WithContentStage(func(entry *Entry, r io.Reader) error {
+		buf, err := readSample(entry, r, sample, throttle)
+		if err != nil {
+			return err
+		}
+		return fn(entry, buf)
+	})
+}
+
+// readSample reads the sample of the file
+func readSample(entry *Entry, r io.Reader, sample Sample, throttle *byteRate) ([]byte, error) {
+	size := entry.Info.Size()
+	ra, ok := r.(io.ReaderAt)
+	if size <= sample.size() || !ok || sample.Chunks <= 0 || sample.ChunkSize <= 0 {
+		n := sample.size()
+		if size < n {
+			n = size
+		}
+		throttle.wait(n)
+		buf, err := io.ReadAll(io.LimitReader(r, n))
+		return buf, err
+	}
+
+	offsets := make([]int64, sample.Chunks)
+	h := fnv.New64a()
+	h.Write([]byte(entry.Path))
+	rnd := rand.New(rand.NewSource(int64(h.Sum64())))
+	span := size - int64(sample.Head) - int64(sample.ChunkSize)
+	for i := range offsets {
+		offsets[i] = int64(sample.Head) + rnd.Int63n(span+1)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	buf := make([]byte, sample.size())
+	throttle.wait(int64(sample.Head))
+	if _, err := io.ReadFull(r, buf[:sample.Head]); err != nil {
+		return nil, err
+	}
+	pos := sample.Head
+	for _, off := range offsets {
+		throttle.wait(int64(sample.ChunkSize))
+		n, err := ra.ReadAt(buf[pos:pos+sample.ChunkSize], off)
+		pos += n
+		if err == io.EOF {
+			// the file was truncated meanwhile
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf[:pos], nil
+}
+
+// byteRate throttles reads to the rate (in bytes per second)
+// shared by all workers
+type byteRate struct {
+	mu   sync.Mutex
+	rate int64
+	next time.Time // when the bytes reserved so far are read at the rate
+}
+
+// wait blocks until n bytes may be read; a nil byteRate doesn't throttle
+func (b *byteRate) wait(n int64) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	now := time.Now()
+	if b.next.Before(now) {
+		b.next = now
+	}
+	delay := b.next.Sub(now)
+	b.next = b.next.Add(time.Duration(n) * time.Second / time.Duration(b.rate))
+	b.mu.Unlock()
+	time.Sleep(delay)
+}
@@ -0,0 +1,153 @@
+package cwalk
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Config is a serializable description of the walker settings, meant
+// to be embedded into the configuration files of services (it has both
+// JSON and YAML field tags); Options() converts it to walker options.
+// The zero value means the defaults.
+type Config struct {
+	Workers        int  `json:"workers,omitempty" yaml:"workers,omitempty"` // GOMAXPROCS if zero
+	FollowSymlinks bool `json:"follow_symlinks,omitempty" yaml:"follow_symlinks,omitempty"`
+	StrictRoot     bool `json:"strict_root,omitempty" yaml:"strict_root,omitempty"`
+	ConfineToRoot  bool `json:"confine_to_root,omitempty" yaml:"confine_to_root,omitempty"`
+
+	// error limit, see WithMaxErrors(); no limit if zero
+	MaxErrors       int             `json:"max_errors,omitempty" yaml:"max_errors,omitempty"`
+	MaxErrorsAction MaxErrorsAction `json:"max_errors_action,omitempty" yaml:"max_errors_action,omitempty"`
+	FailFast        bool            `json:"fail_fast,omitempty" yaml:"fail_fast,omitempty"` // see WithFailFast()
+
+	// filters; Exclude and Prune are path.Match patterns matched against
+	// entry names, or against slash-separated paths relative to the root
+	// if they contain a slash (see FilterExclude and FilterPrune)
+	SkipHidden      bool     `json:"skip_hidden,omitempty" yaml:"skip_hidden,omitempty"`
+	DevTreeDefaults bool     `json:"dev_tree_defaults,omitempty" yaml:"dev_tree_defaults,omitempty"`
+	DevTreeKeep     []string `json:"dev_tree_keep,omitempty" yaml:"dev_tree_keep,omitempty"`
+	Exclude         []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+	Prune           []string `json:"prune,omitempty" yaml:"prune,omitempty"`
+	Presets         []string `json:"presets,omitempty" yaml:"presets,omitempty"` // see RegisterPreset()
+
+	EnrichedMetadata bool `json:"enriched_metadata,omitempty" yaml:"enriched_metadata,omitempty"`
+	SlashPaths       bool `json:"slash_paths,omitempty" yaml:"slash_paths,omitempty"` // see WithSlashPaths()
+}
+
+// Options validates the configuration and converts it to walker options
+func (c *Config) Options() ([]Option, error) {
+	if c.Workers < 0 {
+		return nil, fmt.Errorf("Invalid number of workers: %d", c.Workers)
+	}
+	if c.MaxErrors < 0 {
+		return nil, fmt.Errorf("Invalid error limit: %d", c.MaxErrors)
+	}
+	for _, pattern := range append(append([]string{}, c.Exclude...), c.Prune...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("Invalid pattern %q: %w", pattern, err)
+		}
+	}
+	for _, name := range c.Presets {
+		if _, err := lookupPreset(name); err != nil {
+			return nil, err
+		}
+	}
+
+	var opts []Option
+	if c.Workers > 0 {
+		opts = append(opts, WithWorkers(c.Workers))
+	}
+	if c.FollowSymlinks {
+		opts = append(opts, WithFollowSymlinks())
+	}
+	if c.StrictRoot {
+		opts = append(opts, WithStrictRoot())
+	}
+	if c.ConfineToRoot {
+		opts = append(opts, WithConfineToRoot())
+	}
+	if c.MaxErrors > 0 || c.MaxErrorsAction != StopCollecting {
+		opts = append(opts, WithMaxErrors(c.MaxErrors, c.MaxErrorsAction))
+	}
+	if c.FailFast {
+		opts = append(opts, WithFailFast())
+	}
+	if c.SlashPaths {
+		opts = append(opts, WithSlashPaths())
+	}
+	if c.SkipHidden {
+		opts = append(opts, WithSkipHidden())
+	}
+	if c.DevTreeDefaults {
+		opts = append(opts, WithDevTreeDefaults(c.DevTreeKeep...))
+	}
+	if len(c.Exclude) > 0 {
+		opts = append(opts, withPatternFilter("exclude", c.Exclude, FilterExclude))
+	}
+	if len(c.Prune) > 0 {
+		opts = append(opts, withPatternFilter("prune", c.Prune, FilterPrune))
+	}
+	for _, name := range c.Presets {
+		opts = append(opts, WithPreset(name))
+	}
+	if c.EnrichedMetadata {
+		opts = append(opts, WithEnrichedMetadata())
+	}
+	return opts, nil
+}
+
+// withPatternFilter adds a filter applying action to the entries
+// matching any of the (already validated) patterns
+func withPatternFilter(name string, patterns []string, action FilterAction) Option {
+	name = fmt.Sprintf("%s patterns %q", name, patterns)
+	return func(w *Walker) {
+		w.addFilter(name, w.exceptRoot(patternFilter(patterns, action)))
+	}
+}
+
+// patternFilter returns a filter applying action to the entries
+// matching any of the patterns
+func patternFilter(patterns []string, action FilterAction) FilterFunc {
+	return func(relpath string, info os.FileInfo) FilterAction {
+		relpath = filepath.ToSlash(relpath)
+		name := path.Base(relpath)
+		for _, pattern := range patterns {
+			subject := name
+			if strings.Contains(pattern, "/") {
+				subject = relpath
+			}
+			if ok, _ := path.Match(pattern, subject); ok {
+				return action
+			}
+		}
+		return FilterInclude
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler,
+// so that the action is serialized as "stop" or "abort"
+func (a MaxErrorsAction) MarshalText() ([]byte, error) {
+	switch a {
+	case StopCollecting:
+		return []byte("stop"), nil
+	case AbortWalk:
+		return []byte("abort"), nil
+	}
+	return nil, fmt.Errorf("Invalid MaxErrorsAction: %d", int(a))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (a *MaxErrorsAction) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "stop":
+		*a = StopCollecting
+	case "abort":
+		*a = AbortWalk
+	default:
+		return fmt.Errorf("Invalid max errors action %q (want \"stop\" or \"abort\")", text)
+	}
+	return nil
+}
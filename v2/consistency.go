@@ -0,0 +1,46 @@
+package cwalk
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errDuplicate is returned by emit() instead of calling
+// the user callback for a path that has already been visited
+var errDuplicate = errors.New("duplicate path")
+
+// ConsistencyError reports a violation of the walker's internal
+// invariants detected when the walk is run with WithConsistencyChecks()
+type ConsistencyError struct {
+	Path   string
+	Reason string
+}
+
+// Implement the error interface for ConsistencyError
+func (ce *ConsistencyError) Error() string {
+	return fmt.Sprintf("Internal consistency error: %s: %s", ce.Path, ce.Reason)
+}
+
+// WithConsistencyChecks enables a debug mode which verifies that
+// each path is passed to the callback exactly once per walk.
+// A violation is reported as a *ConsistencyError in the returned
+// WalkerErrorList, and the duplicate is not passed to the callback.
+// This mode keeps every visited path in memory, so it is intended
+// for testing and debugging only.
+func WithConsistencyChecks() Option {
+	return func(w *Walker) {
+		w.checkConsistency = true
+	}
+}
+
+// checkVisited records the path as visited and reports
+// whether it has already been visited during this walk
+func (w *Walker) checkVisited(path string) (duplicate bool) {
+	w.visitedMu.Lock()
+	defer w.visitedMu.Unlock()
+	if _, ok := w.visited[path]; ok {
+		return true
+	}
+	w.visited[path] = struct{}{}
+	return false
+}
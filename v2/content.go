@@ -0,0 +1,162 @@
+package cwalk
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// ContentFunc processes the content of a regular file (e.g. hashes it);
+// entry is the one passed to the callback, and r reads the file from
+// the beginning. It is called by the workers, so it must be safe
+// for concurrent use.
+type ContentFunc func(entry *Entry, r io.Reader) error
+
+// WithContentStage makes the walk read every regular file passed to the
+// callback (unless the callback returned an error or SkipDir for it)
+// and pass its content to fn, so that content processing, such as
+// hashing, is spread over the workers. Multiple stages may be added:
+// the file is opened once, and each stage reads it from the beginning.
+// Errors (including the ones returned by fn) are collected as usual.
+// Read-ahead hints are given for the opened files, see WithoutPrefetch().
+func WithContentStage(fn ContentFunc) Option {
+	return func(w *Walker) {
+		w.stages = append(w.stages, fn)
+	}
+}
+
+// WithoutPrefetch disables the read-ahead hints (posix_fadvise(2) with
+// POSIX_FADV_SEQUENTIAL and POSIX_FADV_WILLNEED on Linux) given for the
+// files opened by the content stages, which improve the throughput of
+// sequential reads on spinning disks, but may waste I/O if the stages
+// only read the beginning of the files
+func WithoutPrefetch() Option {
+	return func(w *Walker) {
+		w.noPrefetch = true
+	}
+}
+
+// WithNoAtime makes the content stages open files with O_NOATIME
+// on Linux, so that reading them doesn't update their access times
+// (which matters for forensic use, and avoids a write for every file
+// read on file systems mounted without noatime or relatime). The flag
+// is only permitted for the owner of the file or a privileged user;
+// other files are silently opened without it. Other platforms don't
+// have an equivalent open flag, so this option has no effect there.
+func WithNoAtime() Option {
+	return func(w *Walker) {
+		w.noAtime = true
+	}
+}
+
+// processContent passes the content of the file at relpath
+// to the content stages
+func (w *Walker) processContent(relpath string, entry *Entry) error {
+	if !w.acquire() {
+		return nil
+	}
+	defer w.release()
+	f, err := w.openContentFile(relpath, entry.Info)
+	if err != nil {
+		return err
+	}
+	defer w.closeFD()
+	defer f.Close()
+	if !w.noPrefetch {
+		prefetch(f, entry.Info.Size())
+	}
+	for i, fn := range w.stages {
+		if i > 0 {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		}
+		if err := fn(entry, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrFileReplaced is reported for the files which are no longer the
+// regular files that were listed when they are opened for the content
+// stages (see WithContentStage() and WithArchiveDescent())
+var ErrFileReplaced = errors.New("File replaced since it was listed")
+
+// openContent opens the file for the content stages, trying O_NOATIME
+// first if requested. The file is opened without following symlinks
+// (unless the walk follows them) and without blocking, and it must be
+// the regular file which info describes: otherwise, it could have been
+// swapped for a symlink, a FIFO or a device in the meantime.
+func (w *Walker) openContent(relpath string, info os.FileInfo) (*os.File, error) {
+	flags := oNonBlock
+	if !w.followSymlinks {
+		flags |= oNoFollow
+	}
+	f, err := w.openFlags(relpath, flags)
+	if err != nil {
+		return nil, err
+	}
+	opened, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !opened.Mode().IsRegular() || !sameFile(opened, info) {
+		f.Close()
+		return nil, &os.PathError{Op: "open", Path: relpath, Err: ErrFileReplaced}
+	}
+	return f, nil
+}
+
+// openFlags opens the file with the flags,
+// trying O_NOATIME first if requested
+func (w *Walker) openFlags(relpath string, flags int) (*os.File, error) {
+	return w.openNoAtime(func(flag int) (*os.File, error) {
+		return w.fs.Open(relpath, flags|flag)
+	})
+}
+
+// openNoAtime calls open with O_NOATIME if requested, and with
+// no additional flag if that isn't allowed (outside forensic mode)
+func (w *Walker) openNoAtime(open func(flag int) (*os.File, error)) (*os.File, error) {
+	if w.noAtime && oNoAtime != 0 {
+		f, err := open(oNoAtime)
+		if !errors.Is(err, syscall.EPERM) || w.forensic {
+			return f, err
+		}
+		// only allowed for the owner of the file or a privileged user
+	}
+	return open(0)
+}
+
+// sameFile reports whether a and b describe the same file
+func sameFile(a, b os.FileInfo) bool {
+	ka, okA := fileKey(a)
+	kb, okB := fileKey(b)
+	if okA && okB {
+		return ka.Dev == kb.Dev && ka.Ino == kb.Ino
+	}
+	if li, ok := b.(*lazyInfo); ok { // see WithLazyStat()
+		b = li.stat()
+	}
+	return b != nil && os.SameFile(a, b)
+}
+
+// withContentFilter makes the content stages only
+// open the files for which fn returns true
+func withContentFilter(fn func(entry *Entry) bool) Option {
+	return func(w *Walker) {
+		w.contentFilter = fn
+	}
+}
+
+// isContentFile reports whether the content stages apply to the file
+func (w *Walker) isContentFile(entry *Entry) bool {
+	info := entry.Info
+	if len(w.stages) == 0 || !info.Mode().IsRegular() || w.forensic && isPlaceholder(info) {
+		return false
+	}
+	return w.contentFilter == nil || w.contentFilter(entry)
+}
@@ -0,0 +1,823 @@
+package cwalk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNotDir indicates that the path, which is being passed
+// to a walker function, does not point to a directory
+// (only reported when the WithStrictRoot() option is used)
+var ErrNotDir = errors.New("Not a directory")
+
+// ErrStopped is returned by the walk when it was stopped with Stop()
+var ErrStopped = errors.New("Walk stopped")
+
+// ErrTooManyErrors indicates that the walk has reported more errors
+// than allowed by WithMaxErrors(); the WalkerErrorList
+// returned by Walk() satisfies errors.Is(err, ErrTooManyErrors)
+// in this case
+var ErrTooManyErrors = errors.New("Too many errors")
+
+// WalkerError struct stores individual errors reported from each worker routine
+type WalkerError struct {
+	error error
+	path  string
+}
+
+// WalkerErrorList struct store a list of errors reported from all worker routines
+type WalkerErrorList struct {
+	ErrorList []WalkerError
+	Truncated int  // number of errors not stored because of the error limit
+	Aborted   bool // the walk was aborted because of the error limit
+}
+
+// Implement the error interface for WalkerError
+func (we WalkerError) Error() string {
+	return we.error.Error()
+}
+
+// Path returns the path (relative to the walker root)
+// the error was reported for
+func (we WalkerError) Path() string {
+	return we.path
+}
+
+// Unwrap returns the underlying error
+func (we WalkerError) Unwrap() error {
+	return we.error
+}
+
+// Implement the error interface fo WalkerErrorList
+func (wel WalkerErrorList) Error() string {
+	if len(wel.ErrorList) > 0 {
+		out := make([]string, len(wel.ErrorList))
+		for i, err := range wel.ErrorList {
+			out[i] = err.Error()
+		}
+		if wel.Truncated > 0 {
+			out = append(out, fmt.Sprintf("(%d more errors truncated)", wel.Truncated))
+		}
+		return strings.Join(out, "\n")
+	}
+	return ""
+}
+
+// Is reports whether the error list matches the target error,
+// which is the case for ErrTooManyErrors if the error limit was hit
+func (wel WalkerErrorList) Is(target error) bool {
+	return target == ErrTooManyErrors && (wel.Truncated > 0 || wel.Aborted)
+}
+
+// OrderToken identifies the position of an entry in the tree,
+// so that entries processed concurrently can be put back
+// into a deterministic order once the walk is complete
+type OrderToken struct {
+	ParentID uint64 // ID of the directory the entry was read from (0 for the root)
+	Index    int    // position of the entry in its directory listing
+}
+
+// Entry describes a single file or directory visited by the walker
+type Entry struct {
+	Path  string      // path relative to the walker root
+	Info  os.FileInfo // nil if the path could not be stat'ed
+	ID    uint64      // unique ID of a directory entry (0 for files)
+	Order OrderToken
+	Meta  *Metadata // extended metadata, see WithEnrichedMetadata()
+
+	// AbsPath is the absolute path of the entry, for opening it; it is
+	// resolved against the working directory at the start of the walk,
+	// and isn't affected by WithSlashPaths() and WithPathMapper().
+	// For archive members (see WithArchiveDescent()), it is the path of
+	// the archive.
+	AbsPath string
+}
+
+// EntryFunc is the type of the function called for each
+// file or directory visited by WalkEntries(). The err argument
+// has the same meaning as in filepath.WalkFunc
+type EntryFunc func(entry *Entry, err error) error
+
+// job is a single directory queued for processing
+type job struct {
+	path   string
+	id     uint64
+	info   os.FileInfo // nil for seeded directories
+	seeded bool        // queued by WithSeedDirs() rather than found in the parent
+	listed bool        // part of the list passed to WalkList(), not to be descended into
+	part   *dirPart    // set for the parts of split directories
+}
+
+// Walker is constructed for each Walk() function invocation
+type Walker struct {
+	wg               sync.WaitGroup // waits for the workers to exit
+	queue            *jobQueue
+	root             string
+	fs               fileSystem
+	rootFile         *os.File // the open root directory for WalkDirFile()
+	confineToRoot    bool
+	pathMapper       func(string) string
+	report           *Report
+	lastPeakQueueLen int   // peak queue length observed during the previous walk
+	dirCount         int64 // number of directories read, updated atomically
+	entryCount       int64 // number of entries emitted, updated atomically
+	followSymlinks   bool
+	entryFunc        func(ws *workerState, entry *Entry, err error) error // ws is nil for the root
+	lastID           uint64                                               // last directory ID handed out
+	errMu            sync.Mutex                                           // guards errorList
+	errorList        WalkerErrorList                                      // this is where we store the errors as we go
+	strictRoot       bool
+	maxErrors        int // set by WithMaxErrors(), no limit if zero
+	maxErrorsAction  MaxErrorsAction
+	errLimit         int   // effective error limit for the current walk
+	aborted          int32 // set atomically once the walk is aborted
+	checkConsistency bool
+	visitedMu        sync.Mutex
+	visited          map[string]struct{}
+	seedDirs         []string
+	seeds            map[string]uint64 // IDs of the seeded directories
+	rootRel          string            // the path the current walk has started from
+	rootID           uint64
+	shardIndex       int
+	shardCount       int
+	snapshot         *Snapshot
+	index            *Index
+	filters          []FilterFunc
+	optionErr        error // the first error reported by an option
+	cache            MetadataCache
+	outcomeFunc      func(o Outcome)
+	outcomes         outcomeCounters
+	enrich           bool
+	windowsACL       bool
+	streams          bool
+	numWorkers       int             // set by WithWorkers(), GOMAXPROCS if not positive
+	filterNames      []string        // descriptions of the filters, for Explain()
+	ctx              context.Context // set by WithContext()
+	limiter          Limiter
+	walkErr          error // the error which aborted the walk, guarded by errMu
+	spawned          int64 // number of goroutines started by the walk, updated atomically
+	running          int64 // number of goroutines still running, updated atomically
+	checkGoroutines  bool
+	sorted           *SortedSink
+	failFast         bool
+	suppressed       []error // error classes set by WithSuppressedErrors()
+	suppressedCount  int64   // updated atomically
+	timing           *Timing
+	statSamples      Histogram // sampled stat latency, for Report.Hints
+	statCalls        int64     // updated atomically
+	firstOpens       Histogram // sampled latency of opening files, for Report.Hints
+	repeatOpens      Histogram // the latency of opening the same files again
+	openCalls        int64     // updated atomically
+	stages           []ContentFunc
+	contentFilter    func(entry *Entry) bool // the files the stages apply to, see Verify()
+	noPrefetch       bool
+	noAtime          bool
+	forensic         bool
+	archiveFormats   []string
+	archiveReaders   chan struct{} // limits the number of archives read at the same time
+	immutable        bool          // set by WithImmutableTree()
+	slashPaths       bool          // set by WithSlashPaths()
+	absRoot          string        // absolute path of the root, for Entry.AbsPath
+	pinRoot          bool          // set by WithPinnedRoot()
+	pathStream       *PathStream
+	list             []string // the paths passed to WalkList()
+	watches          *Watches
+	traceOut         *traceWriter // set by WithTraceRecording()
+	replay           *Trace       // set by WithTraceReplay()
+	readDirLatency   LatencyFunc  // set by WithInjectedLatency()
+	statLatency      LatencyFunc
+	fdThrottle       fdThrottle // see withFDRetry()
+	governor         *Governor  // set by WithQuota()
+	queueQuota       int
+	ioPriority       *IOPriority     // set by WithIOPriority()
+	abortCtx         context.Context // canceled once the walk is aborted
+	cancelAbort      context.CancelFunc
+	throttle         Throttle // set by WithThrottle()
+	throttleEvery    int
+	sampleRate       float64 // set by WithSampling()
+	sampleSeed       int64
+	nlinkDevs        sync.Map                             // device -> whether its link counts are reliable, see isLeafDir()
+	listingFunc      func(relpath string, names []string) // called with the names of every directory read, see CheckNames()
+	securityLabels   bool                                 // set by WithSecurityLabels()
+	labelErrors      bool                                 // report the errors reading security labels, see SecurityLabelCounts()
+	writesTree       bool                                 // the walk stores checksums in the tree, see Scrub()
+	entropy          *EntropySnapshot                     // set by WithEntropy()
+	lazyStat         bool                                 // set by WithLazyStat()
+	gitignore        bool                                 // set by WithGitignore()
+	pathBytes        bool                                 // paths are passed as byte slices, see WalkBytes()
+	queueCap         int                                  // set by WithQueueCapacity()
+	mu               sync.Mutex                           // guards queue, cancelAbort and workers for Dump() and Stop()
+	workers          []*workerState
+}
+
+// lstat is a wrapper for fileSystem.Lstat
+// which also follows symlinks
+func (w *Walker) lstat(relpath string) (os.FileInfo, error) {
+	info, err := w.fs.Lstat(relpath)
+	if err != nil {
+		return nil, err
+	}
+	// check if this is a symlink
+	if w.followSymlinks && info.Mode()&os.ModeSymlink > 0 {
+		return w.fs.Stat(relpath)
+	}
+	return info, nil
+}
+
+// emit assigns an ID to directory entries and passes
+// the entry to the user callback
+func (w *Walker) emit(ws *workerState, entry *Entry, err error) error {
+	if w.checkConsistency && w.checkVisited(entry.Path) {
+		w.addError(entry.Path, &ConsistencyError{
+			Path:   entry.Path,
+			Reason: "path visited more than once",
+		})
+		return errDuplicate
+	}
+	if entry.Info != nil && entry.Info.IsDir() {
+		if id, ok := w.seedID(entry.Path); ok {
+			entry.ID = id
+		} else {
+			entry.ID = atomic.AddUint64(&w.lastID, 1)
+		}
+	}
+	if w.enrich {
+		w.enrichEntry(entry)
+	}
+	if w.snapshot != nil && entry.Info != nil {
+		w.snapshot.add(entry.Path, entry.Info)
+	}
+	if w.index != nil {
+		w.index.add(entry.Path)
+	}
+	if entry.AbsPath == "" {
+		entry.AbsPath = filepath.Join(w.absRoot, entry.Path)
+	}
+	entry.Path = w.userPath(entry.Path)
+	if w.sorted != nil {
+		w.sorted.Add(entry.Path)
+	}
+	if w.pathStream != nil && entry.Path != "" {
+		if err := w.pathStream.add(entry.Path); err != nil {
+			w.setWalkError(err)
+			w.abort()
+		}
+	}
+	atomic.AddInt64(&w.entryCount, 1)
+	return w.entryFunc(ws, entry, err)
+}
+
+// addError stores the error reported for the given path
+// in the errorList, or just counts it once the list
+// has reached the maxErrors limit
+func (w *Walker) addError(path string, err error) {
+	w.outcome(path, Failed, 0, err)
+	if w.isSuppressed(err) {
+		atomic.AddInt64(&w.suppressedCount, 1)
+		return
+	}
+	if w.failFast {
+		w.setWalkError(WalkerError{error: err, path: w.userPath(path)})
+		w.abort()
+		return
+	}
+	w.errMu.Lock()
+	defer w.errMu.Unlock()
+	if w.errLimit > 0 && len(w.errorList.ErrorList) >= w.errLimit {
+		w.errorList.Truncated++
+		return
+	}
+	w.errorList.ErrorList = append(w.errorList.ErrorList, WalkerError{
+		error: err,
+		path:  w.userPath(path),
+	})
+	if w.maxErrorsAction == AbortWalk && len(w.errorList.ErrorList) == w.errLimit {
+		w.errorList.Aborted = true
+		w.abort()
+	}
+}
+
+// abort stops the walk: queued directories are dropped,
+// and workers stop processing their current directories
+func (w *Walker) abort() {
+	atomic.StoreInt32(&w.aborted, 1)
+	w.queue.abort()
+	w.cancelAbort()
+}
+
+// Stop aborts the walk in progress, if any: the directories queued
+// but not read yet are dropped (and reported with SkipAborted), and the
+// walk returns ErrStopped once the entries being processed are done.
+// No callback is called after the walk has returned. Stop may be called
+// from any goroutine, including from the callback.
+func (w *Walker) Stop() {
+	w.mu.Lock()
+	q, cancel := w.queue, w.cancelAbort
+	w.mu.Unlock()
+	if q == nil {
+		return
+	}
+	w.setWalkError(ErrStopped)
+	atomic.StoreInt32(&w.aborted, 1)
+	q.abort()
+	cancel()
+}
+
+// setWalkError records the error which aborts the walk
+// and is returned instead of the error list
+func (w *Walker) setWalkError(err error) {
+	w.errMu.Lock()
+	defer w.errMu.Unlock()
+	if w.walkErr == nil {
+		w.walkErr = err
+	}
+}
+
+// isAborted reports whether the walk has been aborted
+func (w *Walker) isAborted() bool {
+	return atomic.LoadInt32(&w.aborted) != 0
+}
+
+// processPath processes one directory and adds
+// its subdirectories to the queue for further processing.
+// If the directory could only be read partially, the entries
+// obtained so far are still processed, and the read error
+// is returned afterwards.
+func (w *Walker) processPath(ws *workerState, j job) error {
+	relpath := j.path
+	ws.start(relpath)
+	defer ws.stop()
+	var names []string
+	var infos []os.FileInfo
+	var readErr error
+	var skipped *int32      // shared by the parts of a split directory
+	var entries []Entry     // allocated per listing, see WithLazyStat()
+	var paths *listingPaths // joined per listing, see WithLazyStat() and WalkBytes()
+	offset := 0
+	var dt *DirTiming
+	if w.timing != nil {
+		dt = &DirTiming{Path: w.userPath(relpath)}
+		defer func() { w.timing.record(*dt) }()
+	}
+	if j.part != nil {
+		names, offset, skipped = j.part.names, j.part.offset, j.part.skipped
+	} else {
+		if !w.throttleOp(ws) || !w.acquire() {
+			return nil
+		}
+		var storeInCache func()
+		start := time.Now()
+		names, infos, storeInCache, readErr = w.readDir(j)
+		if dt != nil {
+			dt.ReadDir = time.Since(start)
+			w.timing.ReadDir.add(dt.ReadDir)
+		}
+		w.release()
+		atomic.AddInt64(&w.dirCount, 1)
+		if j.seeded && len(names) == 0 && isStaleSeed(readErr) {
+			return nil
+		}
+		defer storeInCache()
+		if w.listingFunc != nil && !j.listed && len(names) > 0 {
+			w.listingFunc(relpath, names)
+		}
+		if infos == nil {
+			names, skipped = w.splitDir(j, names)
+		}
+	}
+
+	for i, name := range names {
+		if w.isAborted() {
+			return nil
+		}
+		if skipped != nil && atomic.LoadInt32(skipped) != 0 {
+			return readErr
+		}
+		if !w.throttleOp(ws) {
+			return nil
+		}
+		var subpath, abspath string
+		if (w.lazyStat || w.pathBytes) && paths == nil {
+			paths = w.joinListing(relpath, names)
+		}
+		if paths != nil {
+			subpath, abspath = paths.path(i)
+		} else {
+			subpath = filepath.Join(relpath, name)
+		}
+		if j.id == w.rootID && !w.inShard(subpath) {
+			w.outcome(subpath, Skipped, SkipOtherShard, nil)
+			continue
+		}
+		sampled := w.sampled(subpath)
+		if !sampled && w.isLeafDir(relpath, j.info) {
+			w.outcome(subpath, Skipped, SkipSampled, nil)
+			continue
+		}
+		var info os.FileInfo
+		var err error
+		if infos != nil && infos[i] != nil {
+			info = infos[i]
+		} else {
+			info, err = w.timedLstat(subpath, dt)
+			if infos != nil {
+				infos[i] = info
+			}
+		}
+		ws.touch()
+		if !sampled && (info == nil || !info.IsDir()) {
+			w.outcome(subpath, Skipped, SkipSampled, nil)
+			continue
+		}
+
+		action := w.filter(subpath, info)
+		if action == FilterPrune {
+			w.outcome(subpath, Skipped, SkipPruned, nil)
+			continue
+		}
+		if action == FilterExclude {
+			w.outcome(subpath, Skipped, SkipExcluded, nil)
+			// descend into the directory, even though it is not emitted
+			if _, seeded := w.seedID(subpath); err == nil && info.IsDir() && !seeded && !j.listed {
+				w.pushDir(ws, job{path: subpath, id: atomic.AddUint64(&w.lastID, 1), info: info})
+			}
+			continue
+		}
+
+		var entry *Entry
+		if w.lazyStat || w.pathBytes {
+			if entries == nil {
+				entries = make([]Entry, len(names))
+			}
+			entry = &entries[i]
+		} else {
+			entry = new(Entry)
+		}
+		*entry = Entry{
+			Path:    subpath,
+			AbsPath: abspath,
+			Info:    info,
+			Order:   OrderToken{ParentID: j.id, Index: offset + i},
+		}
+		err = w.emit(ws, entry, err)
+
+		if err == errDuplicate {
+			continue
+		}
+
+		if err == nil || err == filepath.SkipDir {
+			w.outcome(subpath, Visited, 0, nil)
+		}
+
+		if err == filepath.SkipDir && (j.listed || info != nil && info.IsDir()) {
+			continue // just like filepath.Walk, don't descend into the directory
+		}
+		if err == filepath.SkipDir {
+			// returned for a file, this skips the rest of the directory
+			if skipped != nil {
+				atomic.StoreInt32(skipped, 1)
+			}
+			return readErr
+		}
+
+		if err != nil {
+			w.addError(subpath, err)
+			continue
+		}
+
+		if info == nil {
+			if j.listed {
+				continue // the callback has already seen the error
+			}
+			w.addError(subpath, fmt.Errorf("Broken symlink: %s", subpath))
+			continue
+		}
+
+		if w.streams && info.Mode().IsRegular() {
+			w.emitStreams(ws, subpath, info, entry.Order)
+		}
+
+		if format := w.archiveFormat(name); format != "" && info.Mode().IsRegular() {
+			if err := w.emitArchive(ws, subpath, info, format, entry.Order); err != nil {
+				w.addError(subpath, err)
+			}
+		}
+
+		if w.isContentFile(entry) {
+			if err := w.processContent(subpath, entry); err != nil {
+				w.addError(subpath, err)
+			}
+		}
+
+		if w.watches != nil && info.IsDir() {
+			w.watches.add(entry.Path, entry.AbsPath)
+		}
+		if _, seeded := w.seedID(subpath); info.IsDir() && !seeded && !j.listed {
+			w.pushDir(ws, job{path: subpath, id: entry.ID, info: info})
+		}
+	}
+	return readErr
+}
+
+// pushDir queues the subdirectory found while processing a directory,
+// or processes it right away if the queue is full (see Quota.QueuedDirs),
+// so that the walk goes on depth-first without queueing more directories
+func (w *Walker) pushDir(ws *workerState, j job) {
+	if w.queue.pushLimited(j) {
+		return
+	}
+	parent := ws.path
+	ws.stop()
+	if err := w.processPath(ws, j); err != nil {
+		w.addError(j.path, err)
+	}
+	ws.start(parent)
+}
+
+// worker processes the jobs until
+// there are no pending jobs left in the queue
+func (w *Walker) worker(ws *workerState) {
+	w.applyIOPriority()
+	for {
+		j, ok := w.queue.pop()
+		if !ok {
+			return
+		}
+		if w.acquireWorker() {
+			err := w.processPath(ws, j)
+			if err != nil {
+				w.addError(j.path, err)
+			}
+			w.releaseWorker()
+		} else if j.part == nil {
+			w.outcome(j.path, Skipped, SkipAborted, nil)
+		}
+		w.queue.done()
+	}
+}
+
+// Walk recursively descends into subdirectories,
+// calling walkFn for each file or directory
+// in the tree, including the root directory.
+// If the root is not a directory, walkFn is called
+// for the root only. Each path is passed to walkFn
+// exactly once per walk (see WithConsistencyChecks()).
+//
+// walkFn is called concurrently from multiple goroutines, so it must
+// synchronize access to shared data while the walk is running. However,
+// every call to walkFn (and to any other function passed in options,
+// such as the outcome function) happens before Walk returns, in the
+// sense of the Go memory model, on all paths, including errors, aborts
+// and cancellation: data written by the callbacks can be read with plain,
+// non-atomic reads once Walk has returned, without data races.
+func (w *Walker) Walk(relpath string, walkFn filepath.WalkFunc) error {
+	return w.WalkEntries(relpath, func(entry *Entry, err error) error {
+		return walkFn(entry.Path, entry.Info, err)
+	})
+}
+
+// WalkEntries is like Walk, but passes each file or directory
+// to fn as an Entry, which carries an OrderToken that allows
+// to reconstruct a deterministic order of the results.
+// The same concurrency and memory ordering guarantees apply.
+func (w *Walker) WalkEntries(relpath string, fn EntryFunc) error {
+	return w.walk(relpath, func(_ *workerState, entry *Entry, err error) error {
+		return fn(entry, err)
+	})
+}
+
+// begin sets up the state of the Walker for a walk (see walk() and
+// WalkPage()); the returned function releases its resources once
+// the walk is complete
+func (w *Walker) begin() (end func(), err error) {
+	if w.optionErr != nil {
+		return nil, w.optionErr
+	}
+	if err := w.checkForensic(); err != nil {
+		return nil, err
+	}
+	if err := w.context().Err(); err != nil {
+		return nil, err
+	}
+	w.absRoot = w.root
+	if abs, err := filepath.Abs(w.root); err == nil {
+		w.absRoot = abs
+	} else if w.pinRoot {
+		return nil, err
+	}
+	closeFS, err := w.initFS()
+	if err != nil {
+		return nil, err
+	}
+	w.injectLatency()
+	w.recordTrace()
+	w.errorList = WalkerErrorList{}
+	w.walkErr = nil
+	w.errLimit = w.maxErrors
+	atomic.StoreInt32(&w.aborted, 0)
+	if w.checkConsistency {
+		w.visited = make(map[string]struct{})
+	}
+	abortCtx, cancelAbort := context.WithCancel(w.context())
+	w.mu.Lock()
+	w.abortCtx, w.cancelAbort = abortCtx, cancelAbort
+	w.queue = newJobQueue(w.queueCapacity())
+	w.queue.limit = w.queueQuota
+	w.workers = nil
+	w.mu.Unlock()
+	return func() {
+		cancelAbort()
+		closeFS()
+	}, nil
+}
+
+// walk implements WalkEntries(), passing the state of the calling
+// worker to fn along with each entry (nil for the root)
+func (w *Walker) walk(relpath string, fn func(ws *workerState, entry *Entry, err error) error) error {
+	end, err := w.begin()
+	if err != nil {
+		return err
+	}
+	defer end()
+	w.entryFunc = fn
+	w.rootRel = relpath
+	if w.snapshot != nil {
+		w.snapshot.reset(w.root)
+	}
+	if w.entropy != nil {
+		w.entropy.reset()
+	}
+	if w.index != nil {
+		w.index.reset()
+		defer w.index.build()
+	}
+	if w.pathStream != nil {
+		// flushed below when the workers are done; this covers
+		// the walks which end early (e.g. with a file as the root)
+		defer w.pathStream.Flush()
+	}
+	w.outcomes = outcomeCounters{}
+	atomic.StoreInt64(&w.dirCount, 0)
+	atomic.StoreInt64(&w.entryCount, 0)
+	atomic.StoreInt64(&w.spawned, 0)
+	atomic.StoreInt64(&w.suppressedCount, 0)
+	atomic.StoreInt64(&w.statCalls, 0)
+	atomic.StoreInt64(&w.openCalls, 0)
+	w.fdThrottle.reset()
+	w.statSamples = Histogram{}
+	w.firstOpens = Histogram{}
+	w.repeatOpens = Histogram{}
+	if w.report != nil {
+		defer w.beginReport()()
+	}
+	if w.list != nil {
+		w.pushList(relpath)
+		return w.run()
+	}
+
+	info, lstatErr := w.lstat(relpath)
+	root := &Entry{
+		Path: relpath,
+		Info: info,
+	}
+
+	// the filters apply to the root just like to any other entry
+	switch w.filter(relpath, info) {
+	case FilterPrune:
+		w.outcome(relpath, Skipped, SkipPruned, nil)
+		return nil
+	case FilterExclude:
+		w.outcome(relpath, Skipped, SkipExcluded, nil)
+		if lstatErr != nil {
+			return nil
+		}
+		root.ID = atomic.AddUint64(&w.lastID, 1)
+	default:
+		err = w.emit(nil, root, lstatErr)
+		if err == filepath.SkipDir {
+			w.outcome(relpath, Visited, 0, nil)
+			return nil
+		}
+		if err != nil {
+			w.outcome(relpath, Failed, 0, err)
+			return err
+		}
+		w.outcome(relpath, Visited, 0, nil)
+
+		// just like filepath.Walk, if the root doesn't exist
+		// (or can't be stat'ed), walkFn gets the error and decides
+		// whether it is returned from the walk
+		if lstatErr != nil {
+			return nil
+		}
+		if w.watches != nil && info.IsDir() {
+			w.watches.add(root.Path, root.AbsPath)
+		}
+	}
+
+	if !info.IsDir() {
+		if w.strictRoot {
+			return ErrNotDir
+		}
+		return nil
+	}
+
+	// add this path as a first job
+	w.rootID = root.ID
+	w.queue.push(job{path: relpath, id: root.ID, info: info})
+	w.pushSeedDirs(relpath)
+	return w.run()
+}
+
+// run spawns the workers, waits till they have processed
+// all queued jobs and returns the result of the walk
+func (w *Walker) run() error {
+	// spawn workers
+	numWorkers := w.workerCount()
+	workers := make([]*workerState, numWorkers)
+	for n := range workers {
+		ws := &workerState{lastActivity: time.Now()}
+		workers[n] = ws
+		w.spawn(&w.wg, func() { w.worker(ws) })
+	}
+	w.mu.Lock()
+	w.workers = workers
+	w.mu.Unlock()
+
+	finished := make(chan struct{})
+	var watcher sync.WaitGroup
+	if w.ctx != nil {
+		w.spawn(&watcher, func() {
+			select {
+			case <-w.ctx.Done():
+				w.setWalkError(w.ctx.Err())
+				w.abort()
+			case <-finished:
+			}
+		})
+	}
+	if w.pathStream != nil {
+		// the paths are flushed on a ticker rather than when they
+		// are added, so that they reach the consumer even while
+		// the walk is stalled, e.g. on a slow directory
+		w.spawn(&watcher, func() {
+			ticker := time.NewTicker(pathStreamFlushInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := w.pathStream.Flush(); err != nil {
+						w.setWalkError(err)
+						w.abort()
+						return
+					}
+				case <-finished:
+					return
+				}
+			}
+		})
+	}
+
+	// wait till all paths are processed and workers exit; this is what
+	// makes all callback calls happen before the walk returns, so every
+	// goroutine calling them must be waited for here
+	w.wg.Wait()
+	close(finished)
+	watcher.Wait()
+	w.lastPeakQueueLen = w.queue.peakLen()
+	if w.pathStream != nil {
+		if err := w.pathStream.Flush(); err != nil {
+			w.setWalkError(err)
+		}
+	}
+	for _, j := range w.queue.droppedJobs() {
+		if j.part == nil {
+			w.outcome(j.path, Skipped, SkipAborted, nil)
+		}
+	}
+
+	if err := w.checkLeaks(); err != nil {
+		return err
+	}
+	if w.walkErr != nil {
+		return w.walkErr
+	}
+
+	if len(w.errorList.ErrorList) > 0 || w.errorList.Truncated > 0 {
+		return w.errorList
+	}
+	return nil
+}
+
+// walkTree walks root with a new Walker,
+// for the walks built into the package
+func walkTree(root string, walkFn filepath.WalkFunc, opts ...Option) error {
+	return NewWalker(root, opts...).Walk("", walkFn)
+}
@@ -0,0 +1,116 @@
+package cwalk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// makeTree creates dirs directories of files files each
+func makeTree(t testing.TB, dirs, files int) string {
+	root := t.TempDir()
+	for d := 0; d < dirs; d++ {
+		dir := filepath.Join(root, fmt.Sprint("d", d))
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for f := 0; f < files; f++ {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprint("f", f)), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return root
+}
+
+// TestCallbacksHappenBeforeReturn checks, when run with -race, that the
+// plain writes made by the callbacks can be read once Walk has returned,
+// however the walk ends. Every callback writes to its own slot without
+// synchronization, so the only happens-before edge between the writes
+// and the reads below is the return from Walk.
+func TestCallbacksHappenBeforeReturn(t *testing.T) {
+	root := makeTree(t, 20, 20)
+	var paths []string
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		rel, _ := filepath.Rel(root, path)
+		if rel == "." {
+			rel = ""
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	errFail := errors.New("fail")
+
+	for _, end := range []string{"complete", "stop", "cancel", "fail fast"} {
+		t.Run(end, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			opts := []Option{WithContext(ctx), WithWorkers(8)}
+			if end == "fail fast" {
+				opts = append(opts, WithFailFast())
+			}
+
+			// the slots are allocated before the walk, so the maps are
+			// only read concurrently
+			visited := map[string]*int{}
+			outcomes := map[string]*int{}
+			for _, path := range paths {
+				visited[path] = new(int)
+				for _, kind := range []OutcomeKind{Visited, Skipped, Failed} {
+					outcomes[fmt.Sprint(path, kind)] = new(int)
+				}
+			}
+			var calls, outcomeCalls int64
+			opts = append(opts, WithOutcomeFunc(func(o Outcome) {
+				if slot := outcomes[fmt.Sprint(o.Path, o.Kind)]; slot != nil {
+					*slot = 1
+					atomic.AddInt64(&outcomeCalls, 1)
+				}
+			}))
+
+			w := NewWalker(root, opts...)
+			err := w.Walk("", func(path string, info os.FileInfo, err error) error {
+				*visited[path] = 1
+				n := atomic.AddInt64(&calls, 1)
+				if n == 50 {
+					switch end {
+					case "stop":
+						w.Stop()
+					case "cancel":
+						cancel()
+					case "fail fast":
+						return errFail
+					}
+				}
+				return nil
+			})
+			// the workers may finish the walk before the cancellation
+			// is noticed, in which case the walk is complete
+			if end != "complete" && err == nil && (end != "cancel" || calls != int64(len(paths))) {
+				t.Fatal("the walk didn't end early")
+			}
+
+			// plain reads, without synchronization
+			seen, outcomesSeen := 0, 0
+			for _, slot := range visited {
+				seen += *slot
+			}
+			for _, slot := range outcomes {
+				outcomesSeen += *slot
+			}
+			if int64(seen) != atomic.LoadInt64(&calls) {
+				t.Errorf("%d callback writes seen after Walk, want %d", seen, calls)
+			}
+			if int64(outcomesSeen) != atomic.LoadInt64(&outcomeCalls) {
+				t.Errorf("%d outcome writes seen after Walk, want %d", outcomesSeen, outcomeCalls)
+			}
+			if end == "complete" && seen != len(paths) {
+				t.Errorf("%d paths visited, want %d", seen, len(paths))
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package cwalk
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// direntBufSize is the size of the buffer getdents(2) fills
+const direntBufSize = 32 << 10
+
+// ReadDirTypes reads the names and the types of the directory entries
+func (fsys osFS) ReadDirTypes(relpath string) ([]string, []os.FileMode, error) {
+	path := filepath.Join(fsys.root, relpath)
+	var fd int
+	var err error
+	for {
+		fd, err = syscall.Open(path, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		return nil, nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	defer syscall.Close(fd)
+	return readDirTypes(fd, path)
+}
+
+// ReadDirTypes reads the names and the types of the directory entries
+func (fsys fdFS) ReadDirTypes(relpath string) ([]string, []os.FileMode, error) {
+	fd, err := fsys.open(relpath, syscall.O_RDONLY|syscall.O_DIRECTORY)
+	if err != nil {
+		return nil, nil, &os.PathError{Op: "openat", Path: relpath, Err: err}
+	}
+	defer syscall.Close(fd)
+	return readDirTypes(fd, relpath)
+}
+
+// readDirTypes reads the directory entries of the open directory with
+// getdents(2). The names are sliced from a single string, so the listing
+// takes a few allocations in total rather than one per entry.
+func readDirTypes(fd int, path string) ([]string, []os.FileMode, error) {
+	buf := make([]byte, direntBufSize)
+	var data []byte // the names, one after another
+	var ends []int
+	var types []os.FileMode
+	var err error
+	for {
+		n, e := syscall.ReadDirent(fd, buf)
+		if e == syscall.EINTR {
+			continue
+		}
+		if e != nil {
+			err = &os.PathError{Op: "readdirent", Path: path, Err: e}
+			break
+		}
+		if n <= 0 {
+			break
+		}
+		for rec := buf[:n]; len(rec) > 0; {
+			// struct linux_dirent64: ino (8), off (8), reclen (2), type (1), name
+			const nameOffset = 19
+			if len(rec) < nameOffset {
+				break
+			}
+			reclen := int(*(*uint16)(unsafe.Pointer(&rec[16])))
+			if reclen < nameOffset || reclen > len(rec) {
+				break
+			}
+			name := rec[nameOffset:reclen]
+			for i, c := range name {
+				if c == 0 {
+					name = name[:i]
+					break
+				}
+			}
+			if !(len(name) == 1 && name[0] == '.' || len(name) == 2 && name[0] == '.' && name[1] == '.') {
+				data = append(data, name...)
+				ends = append(ends, len(data))
+				types = append(types, direntType(rec[18]))
+			}
+			rec = rec[reclen:]
+		}
+	}
+
+	all := string(data)
+	names := make([]string, len(ends))
+	start := 0
+	for i, end := range ends {
+		names[i] = all[start:end]
+		start = end
+	}
+	return names, types, err
+}
+
+// direntType converts the d_type of a directory entry to the type bits
+func direntType(t byte) os.FileMode {
+	switch t {
+	case syscall.DT_REG:
+		return 0
+	case syscall.DT_DIR:
+		return os.ModeDir
+	case syscall.DT_LNK:
+		return os.ModeSymlink
+	case syscall.DT_BLK:
+		return os.ModeDevice
+	case syscall.DT_CHR:
+		return os.ModeDevice | os.ModeCharDevice
+	case syscall.DT_FIFO:
+		return os.ModeNamedPipe
+	case syscall.DT_SOCK:
+		return os.ModeSocket
+	}
+	return typeUnknown
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9 || wasip1
+// +build windows plan9 wasip1
+
+package cwalk
+
+import "os"
+
+// diskUsage returns the size of the file, as the space
+// allocated for it is not known
+func diskUsage(info os.FileInfo) int64 {
+	return info.Size()
+}
@@ -0,0 +1,17 @@
+//go:build !windows && !plan9 && !wasip1
+// +build !windows,!plan9,!wasip1
+
+package cwalk
+
+import (
+	"os"
+	"syscall"
+)
+
+// diskUsage returns the space allocated for the file, in bytes
+func diskUsage(info os.FileInfo) int64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int64(st.Blocks) * 512
+	}
+	return info.Size()
+}
@@ -0,0 +1,183 @@
+package cwalk
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Usage is the disk usage of a file, or of a directory
+// including its contents, see DiskUsage()
+type Usage struct {
+	Path     string // path relative to the root ("" for the root itself)
+	IsDir    bool
+	Bytes    int64 // space allocated on disk
+	Apparent int64 // file sizes, as reported by du --apparent-size
+}
+
+// UsageConfig selects the entries listed by DiskUsage()
+type UsageConfig struct {
+	// MaxDepth is the number of directory levels below the root
+	// which are listed, like du -d does: 0 lists the root only (du -s),
+	// and a negative value lists the whole tree
+	MaxDepth int
+
+	// All lists the files within MaxDepth as well as the directories (du -a)
+	All bool
+
+	// Links records the files with multiple hard links which have
+	// been counted; share it between the calls for several roots to
+	// count such files once in total, like du does for its arguments.
+	// If nil, they are counted once per call.
+	Links *LinkSet
+}
+
+// LinkSet is the set of the files with multiple hard links counted
+// by DiskUsage(), see UsageConfig; the zero value is an empty set
+type LinkSet struct {
+	mu   sync.Mutex
+	seen map[FileKey]bool
+}
+
+// add adds the file to the set, reporting whether it was already there
+func (s *LinkSet) add(key FileKey) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen == nil {
+		s.seen = map[FileKey]bool{}
+	}
+	dup := s.seen[key]
+	s.seen[key] = true
+	return dup
+}
+
+// DiskUsage walks root and returns the disk usage of the root and of
+// the entries under it selected by cfg, listed like du does: the
+// entries of each directory (in lexical order, as the concurrent walk
+// has no directory order) followed by the directory itself, so the
+// root comes last. The usage of the entries which are not listed is
+// added up as the walk goes, so only the listed entries are kept in
+// memory. Like du, it counts the files with multiple hard links only
+// once (and lists only the first path found for them). Errors are
+// returned after the walk along with the usage of the entries that
+// could be read.
+func DiskUsage(root string, cfg UsageConfig, opts ...Option) ([]Usage, error) {
+	links := cfg.Links
+	if links == nil {
+		links = &LinkSet{}
+	}
+	var mu sync.Mutex
+	own := map[string]*Usage{} // the listed entries
+	err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && linkCount(info) > 1 {
+			if key, ok := fileKey(info); ok {
+				key.ModTime = 0
+				if links.add(key) {
+					return nil
+				}
+			}
+		}
+		// the usage goes to the entry itself if it is listed,
+		// otherwise to its closest listed parent directory
+		listed := info.IsDir() || cfg.All || path == ""
+		if cfg.MaxDepth >= 0 && path != "" && pathDepth(path) > cfg.MaxDepth {
+			path, listed = pathPrefix(path, cfg.MaxDepth), false
+		} else if !listed {
+			path = parentPath(path)
+		}
+		mu.Lock()
+		u := own[path]
+		if u == nil {
+			u = &Usage{Path: path, IsDir: !listed || info.IsDir()}
+			own[path] = u
+		}
+		u.Bytes += diskUsage(info)
+		u.Apparent += info.Size()
+		mu.Unlock()
+		return nil
+	}, opts...)
+
+	// add the usage of every entry to its parents, and order
+	// the entries of each directory before the directory
+	children := map[string][]string{}
+	for path := range own {
+		if path != "" {
+			parent := parentPath(path)
+			children[parent] = append(children[parent], path)
+		}
+	}
+	list := make([]Usage, 0, len(own))
+	var visit func(path string) *Usage
+	visit = func(path string) *Usage {
+		u := own[path]
+		names := children[path]
+		sort.Strings(names)
+		for _, child := range names {
+			c := visit(child)
+			u.Bytes += c.Bytes
+			u.Apparent += c.Apparent
+		}
+		list = append(list, *u)
+		return u
+	}
+	if _, ok := own[""]; ok {
+		visit("")
+	}
+	return list, err
+}
+
+// pathPrefix returns the path of the ancestor at the depth
+func pathPrefix(path string, depth int) string {
+	for i := 0; i < len(path); i++ {
+		if os.IsPathSeparator(path[i]) {
+			if depth--; depth == 0 {
+				return path[:i]
+			}
+		}
+	}
+	return ""
+}
+
+// parentPath returns the path of the parent
+// directory, "" for the entries of the root
+func parentPath(path string) string {
+	dir := filepath.Dir(path)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// FormatUsage formats the usage the way du does: the number of blocks
+// of blockSize bytes, rounded up, or if human is set, a value rounded up
+// to two significant digits with a binary unit suffix (du -h)
+func FormatUsage(bytes, blockSize int64, human bool) string {
+	if !human {
+		return fmt.Sprint((bytes + blockSize - 1) / blockSize)
+	}
+	const units = "KMGTPE"
+	if bytes < 1024 {
+		return fmt.Sprint(bytes)
+	}
+	v := float64(bytes)
+	for i := 0; i < len(units); i++ {
+		v /= 1024
+		if v < 10 {
+			v = math.Ceil(v*10) / 10
+			if v < 10 {
+				return fmt.Sprintf("%.1f%c", v, units[i])
+			}
+		}
+		v = math.Ceil(v)
+		if v < 1024 || i == len(units)-1 {
+			return fmt.Sprintf("%.0f%c", v, units[i])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package cwalk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiskUsage(t *testing.T) {
+	root := t.TempDir()
+	for name, size := range map[string]int{"a/b/c/f": 1, "a/b/g": 10, "a/h": 100, "i": 1000} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the usage of every entry, which the totals of
+	// the entries listed with a depth limit must match
+	full, err := DiskUsage(root, UsageConfig{MaxDepth: -1, All: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := map[string]Usage{}
+	for _, u := range full {
+		usage[filepath.ToSlash(u.Path)] = u
+	}
+	if own := usage[""].Apparent - usage["a"].Apparent - usage["i"].Apparent; own != mustLstat(t, root).Size() {
+		t.Errorf("the root has %d bytes of its own, want its size", own)
+	}
+	for _, c := range []struct {
+		cfg  UsageConfig
+		want string
+	}{
+		{UsageConfig{MaxDepth: -1}, "a/b/c,a/b,a,"},
+		{UsageConfig{MaxDepth: -1, All: true}, "a/b/c/f,a/b/c,a/b/g,a/b,a/h,a,i,"},
+		{UsageConfig{MaxDepth: 0}, ""},
+		{UsageConfig{MaxDepth: 0, All: true}, ""},
+		{UsageConfig{MaxDepth: 1}, "a,"},
+		{UsageConfig{MaxDepth: 2, All: true}, "a/b,a/h,a,i,"},
+	} {
+		list, err := DiskUsage(root, c.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, u := range list {
+			path := filepath.ToSlash(u.Path)
+			paths = append(paths, path)
+			if want := usage[path]; u != want {
+				t.Errorf("%+v: got %+v, want %+v", c.cfg, u, want)
+			}
+		}
+		if got := strings.Join(paths, ","); got != c.want {
+			t.Errorf("%+v: got %s, want %s", c.cfg, got, c.want)
+		}
+	}
+
+	// a file is listed even without All
+	list, err := DiskUsage(filepath.Join(root, "i"), UsageConfig{MaxDepth: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].IsDir || list[0].Apparent != 1000 {
+		t.Errorf("got %+v for a file", list)
+	}
+}
+
+func TestDiskUsageLinks(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"x", "y"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "x", "f"), make([]byte, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(root, "x", "f"), filepath.Join(root, "y", "f")); err != nil {
+		t.Skip(err)
+	}
+	if _, ok := fileKey(mustLstat(t, filepath.Join(root, "x", "f"))); !ok {
+		t.Skip("files can't be identified on this platform")
+	}
+
+	total := func(links *LinkSet) int64 {
+		var total int64
+		for _, dir := range []string{"x", "y"} {
+			list, err := DiskUsage(filepath.Join(root, dir), UsageConfig{MaxDepth: 0, Links: links})
+			if err != nil {
+				t.Fatal(err)
+			}
+			total += list[0].Apparent
+		}
+		return total
+	}
+	// the file is counted once for every call,
+	// or once in total with a shared set
+	separate, shared := total(nil), total(&LinkSet{})
+	if separate-shared != 10 {
+		t.Errorf("got %d bytes with a shared set, %d without, want 10 less", shared, separate)
+	}
+}
+
+func mustLstat(t *testing.T, name string) os.FileInfo {
+	info, err := os.Lstat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
@@ -0,0 +1,91 @@
+package cwalk
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// workerState tracks what a single worker is doing,
+// so that it can be reported by Dump()
+type workerState struct {
+	mu           sync.Mutex
+	path         string // directory being processed, empty if idle
+	started      time.Time
+	lastActivity time.Time
+	busy         time.Duration // total time spent processing directories
+	local        interface{}   // worker-local data, only accessed by the worker itself
+	ops          int           // file system operations, counted for WithThrottle()
+}
+
+// start marks the beginning of processing of a directory
+func (ws *workerState) start(path string) {
+	now := time.Now()
+	ws.mu.Lock()
+	ws.path = path
+	ws.started = now
+	ws.lastActivity = now
+	ws.mu.Unlock()
+}
+
+// touch records that the worker has made progress
+func (ws *workerState) touch() {
+	ws.mu.Lock()
+	ws.lastActivity = time.Now()
+	ws.mu.Unlock()
+}
+
+// stop marks the worker as idle
+func (ws *workerState) stop() {
+	now := time.Now()
+	ws.mu.Lock()
+	ws.path = ""
+	ws.busy += now.Sub(ws.started)
+	ws.lastActivity = now
+	ws.mu.Unlock()
+}
+
+// Dump writes a human-readable snapshot of the walker state
+// (workers, the paths they are processing, queue length and
+// the time of the last activity of each worker) to out.
+// It is safe to call Dump() while the walk is in progress,
+// which is useful to diagnose stalls e.g. on hung mounts.
+func (w *Walker) Dump(out io.Writer) error {
+	w.mu.Lock()
+	workers := w.workers
+	queued := 0
+	if w.queue != nil {
+		queued = w.queue.len()
+	}
+	w.mu.Unlock()
+
+	now := time.Now()
+	if _, err := fmt.Fprintf(out, "workers: %d, queued jobs: %d\n", len(workers), queued); err != nil {
+		return err
+	}
+	if limit, throttled := w.fdThrottle.state(); limit > 0 {
+		_, err := fmt.Fprintf(out, "out of file descriptors %d times, throttled to %d open files\n", throttled, limit)
+		if err != nil {
+			return err
+		}
+	}
+	for n, ws := range workers {
+		ws.mu.Lock()
+		path, started, lastActivity := ws.path, ws.started, ws.lastActivity
+		ws.mu.Unlock()
+
+		var err error
+		if path == "" {
+			_, err = fmt.Fprintf(out, "worker %d: idle, last activity %s ago\n",
+				n+1, now.Sub(lastActivity))
+		} else {
+			_, err = fmt.Fprintf(out, "worker %d: processing %q for %s, last activity %s ago\n",
+				n+1, path, now.Sub(started), now.Sub(lastActivity))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
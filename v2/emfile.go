@@ -0,0 +1,156 @@
+package cwalk
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// fdRetries is the number of times opening a file or directory
+	// is retried while the process is out of file descriptors
+	fdRetries = 8
+
+	// fdMinBackoff and fdMaxBackoff bound the delay between the retries
+	fdMinBackoff = 10 * time.Millisecond
+	fdMaxBackoff = time.Second
+
+	// fdRecoveryOpens is the number of successful opens after which
+	// the throttled limit of open files is raised by one
+	fdRecoveryOpens = 64
+)
+
+// fdThrottle limits the number of files and directories the walk
+// keeps open at the same time once it has run out of descriptors;
+// the limit is lifted gradually as opening files succeeds again
+type fdThrottle struct {
+	mu        sync.Mutex
+	initCond  sync.Once
+	cond      *sync.Cond // see wake(), so that the zero value is usable
+	limit     int        // 0 if not throttled
+	open      int
+	opened    int   // successful opens since the limit was last changed
+	throttled int64 // number of times the walk has backed off
+}
+
+// reset lifts the limit and clears the statistics
+func (t *fdThrottle) reset() {
+	t.mu.Lock()
+	t.limit, t.open, t.opened, t.throttled = 0, 0, 0, 0
+	t.mu.Unlock()
+}
+
+// wake returns the condition signaled when a file is closed
+func (t *fdThrottle) wake() *sync.Cond {
+	t.initCond.Do(func() {
+		t.cond = sync.NewCond(&t.mu)
+	})
+	return t.cond
+}
+
+// enter waits till one more file can be opened
+func (t *fdThrottle) enter() {
+	t.mu.Lock()
+	for t.limit > 0 && t.open >= t.limit {
+		t.wake().Wait()
+	}
+	t.open++
+	t.mu.Unlock()
+}
+
+// leave records that a file entered before was closed (or failed to
+// open); exhausted reports whether it failed for lack of descriptors,
+// in which case the limit is lowered to half the files still open
+func (t *fdThrottle) leave(maxOpen int, exhausted bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.open--
+	t.wake().Broadcast()
+	switch {
+	case exhausted:
+		t.throttled++
+		limit := t.open / 2
+		if limit < 1 {
+			limit = 1
+		}
+		if t.limit == 0 || limit < t.limit {
+			t.limit = limit
+		}
+		t.opened = 0
+	case t.limit > 0:
+		t.opened++
+		if t.opened >= fdRecoveryOpens {
+			t.opened = 0
+			t.limit++
+			if t.limit >= maxOpen {
+				t.limit = 0 // recovered: each worker keeps a single file open
+			}
+		}
+	}
+}
+
+// state returns the current limit and the number of times
+// the walk has backed off
+func (t *fdThrottle) state() (limit int, throttled int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.limit, t.throttled
+}
+
+// withFDRetry opens a file or directory with open, which returns
+// whether it has released the descriptor already; if the process has
+// run out of descriptors, the walk backs off, opening fewer files
+// at the same time, and open is retried, so that the descriptors
+// held by the rest of the process can be released in the meantime.
+// If open holds the descriptor, closeFD must be called once it is closed.
+func (w *Walker) withFDRetry(open func() (closed bool, err error)) error {
+	backoff := fdMinBackoff
+	for attempt := 0; ; attempt++ {
+		w.fdThrottle.enter()
+		closed, err := open()
+		exhausted := isFDExhausted(err)
+		if err != nil || closed {
+			w.fdThrottle.leave(w.workerCount(), exhausted)
+		}
+		if !exhausted || attempt == fdRetries || w.isAborted() {
+			return err
+		}
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > fdMaxBackoff {
+			backoff = fdMaxBackoff
+		}
+	}
+}
+
+// closeFD records that a descriptor opened
+// with withFDRetry() has been closed
+func (w *Walker) closeFD() {
+	w.fdThrottle.leave(w.workerCount(), false)
+}
+
+// readDirNames reads the directory listing, backing off
+// while the process is out of file descriptors
+func (w *Walker) readDirNames(relpath string) (names []string, err error) {
+	err = w.withFDRetry(func() (bool, error) {
+		names, err = w.fs.ReadDirNames(relpath)
+		return true, err
+	})
+	return names, err
+}
+
+// openContentFile opens the file for the content stages, backing off
+// while the process is out of file descriptors; closeFD must be
+// called once the file is closed
+func (w *Walker) openContentFile(relpath string, info os.FileInfo) (f *os.File, err error) {
+	err = w.withFDRetry(func() (bool, error) {
+		f, err = w.openContent(relpath, info)
+		return false, err
+	})
+	return f, err
+}
+
+// throttled returns the number of times the walk has backed off
+func (w *Walker) throttled() int64 {
+	_, n := w.fdThrottle.state()
+	return n
+}
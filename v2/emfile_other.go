@@ -0,0 +1,15 @@
+//go:build !plan9
+// +build !plan9
+
+package cwalk
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isFDExhausted reports whether the error indicates that the process
+// (EMFILE) or the system (ENFILE) has run out of file descriptors
+func isFDExhausted(err error) bool {
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE)
+}
@@ -0,0 +1,7 @@
+package cwalk
+
+// isFDExhausted reports that running out of file
+// descriptors is not detected on this platform
+func isFDExhausted(err error) bool {
+	return false
+}
@@ -0,0 +1,225 @@
+package cwalk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// ErrSequentialOption indicates that an option
+// is not supported by the Sequential() engine
+var ErrSequentialOption = errors.New("Option not supported by the sequential engine")
+
+// sequentialFields are the fields of the Walker which the options
+// supported by the Sequential() engine set
+var sequentialFields = map[string]bool{
+	"root":            true,
+	"filters":         true,
+	"filterNames":     true,
+	"gitignore":       true,
+	"ctx":             true,
+	"optionErr":       true,
+	"maxErrors":       true,
+	"maxErrorsAction": true,
+	"numWorkers":      true,
+	"queueCap":        true,
+}
+
+// TreeWalker is a walk engine, so that applications can choose the
+// engine at runtime, e.g. the sequential one for spinning disks, where
+// concurrent reads cause seeks, and the concurrent one otherwise
+type TreeWalker interface {
+	// Walk walks the tree at root like the Walk() function does
+	Walk(root string, walkFn filepath.WalkFunc) error
+
+	// WalkContext is like Walk, but aborts the walk
+	// once ctx is canceled (see WithContext())
+	WalkContext(ctx context.Context, root string, walkFn filepath.WalkFunc) error
+}
+
+// engine implements TreeWalker
+type engine struct {
+	opts       []Option
+	sequential bool
+}
+
+// Concurrent returns the concurrent TreeWalker,
+// which walks with the options like Walk() does
+func Concurrent(opts ...Option) TreeWalker {
+	return engine{opts: opts}
+}
+
+// Sequential returns a TreeWalker built on filepath.Walk, so that walkFn
+// is called from one goroutine at a time, in lexical order, and only
+// one directory is read at a time. It calls walkFn with the same paths,
+// relative to the root, as the concurrent engine, and follows the same
+// rules: the filters of the options apply (see WithFilter()), walkFn
+// may return SkipDir just like with filepath.Walk, and the other errors
+// it returns, as well as the directory read errors, are returned as a
+// WalkerErrorList once the walk is complete. Besides the filters,
+// it only supports WithContext() and WithMaxErrors(), as well as
+// WithWorkers() and WithQueueCapacity(), which have no effect on it;
+// the walk fails with ErrSequentialOption if any other option is set.
+// As it shares no code with the concurrent engine beyond the filters,
+// it is the reference for differential tests (see walktest.Differential()).
+func Sequential(opts ...Option) TreeWalker {
+	return engine{opts: opts, sequential: true}
+}
+
+// Walk implements TreeWalker
+func (e engine) Walk(root string, walkFn filepath.WalkFunc) error {
+	w := e.walker(root)
+	if e.sequential {
+		return w.walkSequential(walkFn)
+	}
+	return w.Walk("", walkFn)
+}
+
+// WalkContext implements TreeWalker
+func (e engine) WalkContext(ctx context.Context, root string, walkFn filepath.WalkFunc) error {
+	w := e.walker(root, WithContext(ctx))
+	if e.sequential {
+		return w.walkSequential(walkFn)
+	}
+	return w.Walk("", walkFn)
+}
+
+// walker creates the Walker for the engine
+func (e engine) walker(root string, opts ...Option) *Walker {
+	return NewWalker(root, append(append([]Option{}, e.opts...), opts...)...)
+}
+
+// errAborted stops the sequential engine once the error limit is reached
+var errAborted = errors.New("aborted")
+
+// walkSequential implements the sequential engine, using
+// the Walker for its filters and its context only
+func (w *Walker) walkSequential(walkFn filepath.WalkFunc) error {
+	if w.optionErr != nil {
+		return w.optionErr
+	}
+	// options are set on a zero Walker, so those which the engine
+	// doesn't support are the ones leaving other fields set
+	v := reflect.ValueOf(w).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if name := v.Type().Field(i).Name; !sequentialFields[name] && !v.Field(i).IsZero() {
+			return fmt.Errorf("%w (sets %s)", ErrSequentialOption, name)
+		}
+	}
+	ctx := w.context()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// for the filters reading files, such as WithGitignore()
+	closeFS, err := w.initFS()
+	if err != nil {
+		return err
+	}
+	defer closeFS()
+	var errs WalkerErrorList
+	addError := func(relpath string, err error) {
+		// just like the concurrent engine does, see Walker.addError()
+		if w.maxErrors > 0 && len(errs.ErrorList) >= w.maxErrors {
+			errs.Truncated++
+			return
+		}
+		errs.ErrorList = append(errs.ErrorList, WalkerError{error: err, path: relpath})
+		if w.maxErrorsAction == AbortWalk && len(errs.ErrorList) == w.maxErrors {
+			errs.Aborted = true
+		}
+	}
+	skipRest := "" // the directory which remaining entries are skipped, if any
+
+	err = filepath.Walk(w.root, func(path string, info os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if errs.Aborted {
+			return errAborted
+		}
+		relpath, relErr := filepath.Rel(w.root, path)
+		if relErr != nil {
+			return relErr
+		}
+		if relpath == "." {
+			relpath = ""
+		}
+		isDir := info != nil && info.IsDir()
+		var skip error // doesn't descend into a directory
+		if isDir {
+			skip = filepath.SkipDir
+		}
+		if relpath != "" && filepath.Dir(relpath) == skipRest {
+			return skip
+		}
+
+		// filepath.Walk calls walkFn for a directory once it has read
+		// it, with the read error, and doesn't descend into it then;
+		// the concurrent engine calls walkFn before reading the
+		// directory, and reports the read error separately
+		var readErr error
+		if isDir {
+			readErr, err = err, nil
+		}
+
+		switch w.filter(relpath, info) {
+		case FilterPrune:
+			if relpath == "" {
+				return filepath.SkipDir
+			}
+			return skip
+		case FilterExclude:
+			if readErr != nil {
+				addError(relpath, readErr)
+			}
+			if relpath == "" && err != nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		fnErr := walkFn(relpath, info, err)
+		if relpath == "" {
+			// just like filepath.Walk, the errors for the root
+			// (other than SkipDir) end the walk
+			switch {
+			case fnErr != nil:
+				return fnErr
+			case err != nil:
+				return filepath.SkipDir
+			}
+		} else {
+			switch {
+			case fnErr == filepath.SkipDir && !isDir:
+				// returned for a file, this skips the rest of the directory
+				skipRest = filepath.Dir(relpath)
+				return nil
+			case fnErr == filepath.SkipDir:
+				return filepath.SkipDir
+			case fnErr != nil:
+				addError(relpath, fnErr)
+				return skip
+			case info == nil:
+				addError(relpath, fmt.Errorf("Broken symlink: %s", relpath))
+				return nil
+			}
+		}
+		if readErr != nil {
+			addError(relpath, readErr)
+		}
+		return nil
+	})
+	if err == filepath.SkipDir || err == errAborted {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(errs.ErrorList) > 0 || errs.Truncated > 0 {
+		return errs
+	}
+	return nil
+}
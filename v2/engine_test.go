@@ -0,0 +1,135 @@
+package cwalk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSkipDirSubdirectory(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a", "b", "c"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"a/x", "b/y", "c/z", "d"} {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(file)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := ",a,b,b/y,c,c/z,d"
+
+	var stdlib []string
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		rel, _ := filepath.Rel(root, path)
+		if rel == "." {
+			rel = ""
+		}
+		stdlib = append(stdlib, filepath.ToSlash(rel))
+		if rel == "a" {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if got := strings.Join(stdlib, ","); got != want {
+		t.Fatalf("filepath.Walk visited %s, want %s", got, want)
+	}
+
+	for name, e := range map[string]TreeWalker{"concurrent": Concurrent(), "sequential": Sequential()} {
+		var mu sync.Mutex
+		var visited []string
+		err := e.Walk(root, func(path string, info os.FileInfo, err error) error {
+			mu.Lock()
+			visited = append(visited, filepath.ToSlash(path))
+			mu.Unlock()
+			if path == "a" {
+				return filepath.SkipDir
+			}
+			return err
+		})
+		if err != nil {
+			t.Errorf("%s engine: %v", name, err)
+		}
+		sort.Strings(visited)
+		if got := strings.Join(visited, ","); got != want {
+			t.Errorf("%s engine visited %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestSequentialOptions(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "real", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "real", "sub", "f"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("real", filepath.Join(root, "link")); err != nil {
+		t.Skip(err)
+	}
+	walk := func(opts ...Option) error {
+		return Sequential(opts...).Walk(root, func(path string, info os.FileInfo, err error) error {
+			return err
+		})
+	}
+	findExpr, err := ParseFindExpr([]string{"-type", "f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opt := range []Option{WithSkipHidden(), WithDevTreeDefaults(), WithFindExpr(findExpr),
+		WithWorkers(2), WithQueueCapacity(10), WithMaxErrors(5, AbortWalk)} {
+		if err := walk(opt); err != nil {
+			t.Errorf("supported option rejected: %v", err)
+		}
+	}
+	for _, opt := range []Option{WithFollowSymlinks(), WithFailFast(), WithLazyStat(), WithSampling(0.5, 1)} {
+		if err := walk(opt); !errors.Is(err, ErrSequentialOption) {
+			t.Errorf("got %v, want ErrSequentialOption", err)
+		}
+	}
+}
+
+func TestSequentialMaxErrors(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if err := os.WriteFile(filepath.Join(root, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []struct {
+		action    MaxErrorsAction
+		visited   string
+		truncated int
+	}{
+		{StopCollecting, "a,b,c,d", 2},
+		{AbortWalk, "a,b", 0},
+	} {
+		var visited []string
+		err := Sequential(WithMaxErrors(2, c.action)).Walk(root, func(path string, info os.FileInfo, err error) error {
+			if path == "" {
+				return nil
+			}
+			visited = append(visited, path)
+			return errors.New("failed")
+		})
+		var errs WalkerErrorList
+		if !errors.As(err, &errs) {
+			t.Fatalf("got %v, want a WalkerErrorList", err)
+		}
+		if len(errs.ErrorList) != 2 || errs.Truncated != c.truncated || errs.Aborted != (c.action == AbortWalk) {
+			t.Errorf("action %d: got %d errors, %d truncated, aborted %v", c.action, len(errs.ErrorList), errs.Truncated, errs.Aborted)
+		}
+		if got := strings.Join(visited, ","); got != c.visited {
+			t.Errorf("action %d: visited %s, want %s", c.action, got, c.visited)
+		}
+		if !errors.Is(err, ErrTooManyErrors) {
+			t.Errorf("action %d: the error doesn't match ErrTooManyErrors", c.action)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package cwalk
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultEntropyThreshold is the entropy, in bits per byte, above which
+// content is considered high-entropy: compressed or encrypted data is
+// close to 8, while text and most executables stay well below 7
+const DefaultEntropyThreshold = 7.5
+
+// FileEntropy is the entropy measured for a file, see WithEntropy()
+type FileEntropy struct {
+	Path    string    `json:"path"`
+	Entropy float64   `json:"entropy"` // Shannon entropy of the sample, in bits per byte (0 to 8)
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// EntropySnapshot holds the entropy of the files measured by a walk
+// with WithEntropy(), so that it can be compared with the one measured
+// by the next walk (see CompareEntropy()). It can be saved between
+// walks with Save() and LoadEntropySnapshot(). EntropySnapshot is safe
+// for concurrent use.
+type EntropySnapshot struct {
+	mu    sync.Mutex
+	files map[string]FileEntropy
+}
+
+// NewEntropySnapshot creates an empty EntropySnapshot
+func NewEntropySnapshot() *EntropySnapshot {
+	return &EntropySnapshot{files: map[string]FileEntropy{}}
+}
+
+// WithEntropy adds a content stage (see WithContentStage()) which
+// measures the Shannon entropy of a sample of every regular file
+// (see WithClassifier() for the sample and the throttling of the
+// reads) and records it in s, replacing what s has recorded before.
+// Encrypting files raises their entropy close to the maximum, so
+// that a sudden rise between walks is a sign of ransomware activity,
+// see CompareEntropy().
+func WithEntropy(s *EntropySnapshot, sample Sample, bytesPerSec int64) Option {
+	var throttle *byteRate
+	if bytesPerSec > 0 {
+		throttle = &byteRate{rate: bytesPerSec}
+	}
+	return func(w *Walker) {
+		w.entropy = s
+		w.stages = append(w.stages, func(entry *Entry, r io.Reader) error {
+			buf, err := readSample(entry, r, sample, throttle)
+			if err != nil {
+				return err
+			}
+			s.add(FileEntropy{
+				Path:    entry.Path,
+				Entropy: shannonEntropy(buf),
+				Size:    entry.Info.Size(),
+				ModTime: entry.Info.ModTime(),
+			})
+			return nil
+		})
+	}
+}
+
+// shannonEntropy returns the entropy of the data in bits per byte
+func shannonEntropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	h := 0.0
+	for _, n := range counts {
+		if n > 0 {
+			p := float64(n) / float64(len(data))
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// reset drops all recorded files
+func (s *EntropySnapshot) reset() {
+	s.mu.Lock()
+	s.files = map[string]FileEntropy{}
+	s.mu.Unlock()
+}
+
+// add records the entropy of a file
+func (s *EntropySnapshot) add(fe FileEntropy) {
+	s.mu.Lock()
+	s.files[fe.Path] = fe
+	s.mu.Unlock()
+}
+
+// Files returns the recorded files, in lexical order of their paths
+func (s *EntropySnapshot) Files() []FileEntropy {
+	s.mu.Lock()
+	files := make([]FileEntropy, 0, len(s.files))
+	for _, fe := range s.files {
+		files = append(files, fe)
+	}
+	s.mu.Unlock()
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// Save writes the recorded files as JSON lines
+func (s *EntropySnapshot) Save(out io.Writer) error {
+	bw := bufio.NewWriter(out)
+	enc := json.NewEncoder(bw)
+	for _, fe := range s.Files() {
+		if err := enc.Encode(fe); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// LoadEntropySnapshot reads the files written by EntropySnapshot.Save()
+func LoadEntropySnapshot(r io.Reader) (*EntropySnapshot, error) {
+	s := NewEntropySnapshot()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var fe FileEntropy
+		if err := json.Unmarshal(scanner.Bytes(), &fe); err != nil {
+			return nil, fmt.Errorf("Invalid entropy record on line %d: %w", line, err)
+		}
+		s.files[fe.Path] = fe
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// EntropyDiff is the change of the entropy of the files between
+// two snapshots, see CompareEntropy()
+type EntropyDiff struct {
+	Compared int      // number of files recorded in both snapshots
+	Modified int      // number of them modified in between
+	Rose     []string // modified files which entropy rose above the threshold, in lexical order
+
+	// share of the files recorded in each snapshot
+	// which entropy is above the threshold
+	HighBefore float64
+	HighAfter  float64
+}
+
+// CompareEntropy compares the entropy of the files recorded in the
+// snapshots of two walks, with the entropy threshold in bits per byte
+// (DefaultEntropyThreshold if zero)
+func CompareEntropy(prev, cur *EntropySnapshot, threshold float64) EntropyDiff {
+	if threshold == 0 {
+		threshold = DefaultEntropyThreshold
+	}
+	var d EntropyDiff
+	before, after := prev.Files(), cur.Files()
+	d.HighBefore = highEntropyShare(before, threshold)
+	d.HighAfter = highEntropyShare(after, threshold)
+
+	prev.mu.Lock()
+	defer prev.mu.Unlock()
+	for _, fe := range after {
+		old, ok := prev.files[fe.Path]
+		if !ok {
+			continue
+		}
+		d.Compared++
+		if old.Size == fe.Size && old.ModTime.Equal(fe.ModTime) {
+			continue
+		}
+		d.Modified++
+		if old.Entropy <= threshold && fe.Entropy > threshold {
+			d.Rose = append(d.Rose, fe.Path)
+		}
+	}
+	return d
+}
+
+// highEntropyShare returns the share of the files
+// which entropy is above the threshold
+func highEntropyShare(files []FileEntropy, threshold float64) float64 {
+	if len(files) == 0 {
+		return 0
+	}
+	high := 0
+	for _, fe := range files {
+		if fe.Entropy > threshold {
+			high++
+		}
+	}
+	return float64(high) / float64(len(files))
+}
+
+// Sudden reports whether the change looks like a sudden mass encryption:
+// at least minFiles files rose above the threshold, and they make up
+// at least half of the files modified since the previous snapshot
+func (d EntropyDiff) Sudden(minFiles int) bool {
+	return len(d.Rose) > 0 && len(d.Rose) >= minFiles && len(d.Rose)*2 >= d.Modified
+}
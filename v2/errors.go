@@ -0,0 +1,101 @@
+package cwalk
+
+import (
+	"errors"
+	"syscall"
+)
+
+// WithSuppressedErrors makes the walk drop the errors matching any
+// of the classes (as per errors.Is(), e.g. fs.ErrPermission or
+// fs.ErrNotExist), which are expected when scanning system directories
+// as an unprivileged user: they are counted in Report.Failed and
+// Report.Suppressed, and passed to the outcome function, but not stored
+// or returned (nor do they count towards the error limit or stop
+// a WithFailFast() walk). The callback still gets these errors.
+func WithSuppressedErrors(classes ...error) Option {
+	return func(w *Walker) {
+		w.suppressed = append(w.suppressed, classes...)
+	}
+}
+
+// isSuppressed reports whether the error belongs
+// to one of the suppressed classes
+func (w *Walker) isSuppressed(err error) bool {
+	for _, class := range w.suppressed {
+		if errors.Is(err, class) {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupByErrno groups the errors by the system error number they wrap;
+// the errors which don't wrap one are grouped under zero
+func (wel WalkerErrorList) GroupByErrno() map[syscall.Errno][]WalkerError {
+	groups := map[syscall.Errno][]WalkerError{}
+	for _, we := range wel.ErrorList {
+		var errno syscall.Errno
+		errors.As(we.error, &errno)
+		groups[errno] = append(groups[errno], we)
+	}
+	return groups
+}
+
+// Paths returns the paths the errors were reported for,
+// in the order the errors were reported
+func (wel WalkerErrorList) Paths() []string {
+	paths := make([]string, len(wel.ErrorList))
+	for i, we := range wel.ErrorList {
+		paths[i] = we.path
+	}
+	return paths
+}
+
+// Summary returns the number of errors per error class, which is the
+// description of the system error number (e.g. "permission denied")
+// for the errors wrapping one, and "other" for the rest; the errors
+// not stored because of the error limit are counted as "truncated"
+func (wel WalkerErrorList) Summary() map[string]int {
+	return convertError(wel).(*WalkError).Summary()
+}
+
+// GroupByErrno groups the errors by the system error number they wrap;
+// the errors which don't wrap one are grouped under zero
+func (e *WalkError) GroupByErrno() map[syscall.Errno][]*PathError {
+	groups := map[syscall.Errno][]*PathError{}
+	for _, pe := range e.Errors {
+		var errno syscall.Errno
+		errors.As(pe.Err, &errno)
+		groups[errno] = append(groups[errno], pe)
+	}
+	return groups
+}
+
+// Paths returns the paths the errors were reported for,
+// in the order the errors were reported
+func (e *WalkError) Paths() []string {
+	paths := make([]string, len(e.Errors))
+	for i, pe := range e.Errors {
+		paths[i] = pe.Path
+	}
+	return paths
+}
+
+// Summary returns the number of errors per error class, which is the
+// description of the system error number (e.g. "permission denied")
+// for the errors wrapping one, and "other" for the rest; the errors
+// not stored because of the error limit are counted as "truncated"
+func (e *WalkError) Summary() map[string]int {
+	counts := map[string]int{}
+	for errno, list := range e.GroupByErrno() {
+		class := "other"
+		if errno != 0 {
+			class = errno.Error()
+		}
+		counts[class] += len(list)
+	}
+	if e.Truncated > 0 {
+		counts["truncated"] = e.Truncated
+	}
+	return counts
+}
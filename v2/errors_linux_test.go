@@ -0,0 +1,64 @@
+package cwalk
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestErrorHelpers(t *testing.T) {
+	wel := WalkerErrorList{
+		ErrorList: []WalkerError{
+			{error: &os.PathError{Op: "open", Path: "a", Err: syscall.EACCES}, path: "a"},
+			{error: errors.New("bad"), path: "b"},
+			{error: &os.PathError{Op: "lstat", Path: "c", Err: syscall.EACCES}, path: "c"},
+			{error: &os.PathError{Op: "readdirent", Path: "d", Err: syscall.EIO}, path: "d"},
+		},
+		Truncated: 2,
+	}
+	we, ok := convertError(wel).(*WalkError)
+	if !ok {
+		t.Fatalf("got %T, want *WalkError", convertError(wel))
+	}
+
+	want := map[string]int{
+		syscall.EACCES.Error(): 2,
+		syscall.EIO.Error():    1,
+		"other":                1,
+		"truncated":            2,
+	}
+	if got := wel.Summary(); !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkerErrorList: got summary %v, want %v", got, want)
+	}
+	if got := we.Summary(); !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkError: got summary %v, want %v", got, want)
+	}
+
+	paths := []string{"a", "b", "c", "d"}
+	if got := wel.Paths(); !reflect.DeepEqual(got, paths) {
+		t.Errorf("WalkerErrorList: got paths %v, want %v", got, paths)
+	}
+	if got := we.Paths(); !reflect.DeepEqual(got, paths) {
+		t.Errorf("WalkError: got paths %v, want %v", got, paths)
+	}
+
+	groups := we.GroupByErrno()
+	for errno, want := range map[syscall.Errno][]string{
+		syscall.EACCES: {"a", "c"},
+		syscall.EIO:    {"d"},
+		0:              {"b"},
+	} {
+		var got []string
+		for _, pe := range groups[errno] {
+			got = append(got, pe.Path)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("errno %d: got %v, want %v", errno, got, want)
+		}
+		if n := len(wel.GroupByErrno()[errno]); n != len(want) {
+			t.Errorf("WalkerErrorList: errno %d has %d errors, want %d", errno, n, len(want))
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package cwalk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// estimateStatSample is the number of files of a directory
+// stat'ed by Estimate() to estimate their total size
+const estimateStatSample = 256
+
+// estimateReadBatch is the number of entries Estimate() reads
+// at once, checking the deadline in between
+const estimateReadBatch = 1024
+
+// Interval is an estimated quantity along with
+// the bounds of its 95% confidence interval
+type Interval struct {
+	Estimate  float64
+	Low, High float64
+}
+
+// EstimateResult holds the estimated size of a tree, see Estimate()
+type EstimateResult struct {
+	Files Interval // number of entries other than directories
+	Dirs  Interval // number of directories, including the root
+	Bytes Interval // total apparent size of the files
+
+	Exact  bool // the tree was walked completely, the intervals are exact
+	Probes int  // number of random descents the estimate is based on
+}
+
+// Estimate estimates the number of files and directories in the tree
+// at root, and the total size of the files, spending about the given
+// time, for capacity planning. If the tree can be walked completely in
+// a quarter of the budget, the result is exact. Otherwise, the rest of
+// the budget is spent on random descents from the root (Knuth's
+// estimator): each descent picks a random subdirectory at every level
+// and scales what it finds by the fan-out of the directories above,
+// which gives an unbiased estimate of the whole tree; the result is
+// the mean of the descents. The estimate converges slowly for very
+// unbalanced trees, which shows in wide confidence intervals.
+// Symlinks are not followed.
+//
+// The descents stop reading as soon as the budget is spent, even in
+// the middle of a huge directory; the descents which didn't complete
+// are not counted. If not even the root directory could be read within
+// the budget, Estimate() fails with context.DeadlineExceeded.
+//
+// Unlike WithSampling(), which reads every directory, the estimate
+// only reads the directories along the descents, so its cost is
+// bounded by the budget however large the tree is. The options apply
+// to the walk; of them, only the number of workers (see WithWorkers())
+// applies to the descents.
+func Estimate(root string, budget time.Duration, opts ...Option) (EstimateResult, error) {
+	res, err := estimateExact(root, budget/4, opts)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return res, err
+	}
+
+	deadline := time.Now().Add(budget - budget/4)
+	e := &estimator{root: root, dirs: map[string]*estimateDir{}}
+	if _, err := e.readDir("", deadline); err != nil {
+		return EstimateResult{}, err
+	}
+	var wg sync.WaitGroup
+	for n := 0; n < NewWalker(root, opts...).workerCount(); n++ {
+		rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(n)))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				e.probe(rnd, deadline)
+			}
+		}()
+	}
+	wg.Wait()
+
+	res = EstimateResult{Probes: len(e.files)}
+	res.Files = interval(e.files)
+	res.Dirs = interval(e.dirCounts)
+	res.Bytes = interval(e.bytes)
+	return res, nil
+}
+
+// estimateExact walks the tree with a time limit,
+// counting the files, directories and bytes
+func estimateExact(root string, limit time.Duration, opts []Option) (EstimateResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), limit)
+	defer cancel()
+	var files, dirs, bytes int64
+	err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info == nil {
+			return nil
+		}
+		if info.IsDir() {
+			atomic.AddInt64(&dirs, 1)
+		} else {
+			atomic.AddInt64(&files, 1)
+			atomic.AddInt64(&bytes, info.Size())
+		}
+		return nil
+	}, append(opts[:len(opts):len(opts)], WithContext(ctx))...)
+
+	exact := func(n int64) Interval {
+		return Interval{Estimate: float64(n), Low: float64(n), High: float64(n)}
+	}
+	res := EstimateResult{Files: exact(files), Dirs: exact(dirs), Bytes: exact(bytes), Exact: true}
+	return res, err
+}
+
+// interval returns the mean of the samples with its confidence interval
+func interval(samples []float64) Interval {
+	n := float64(len(samples))
+	if n == 0 {
+		return Interval{}
+	}
+	var sum, sumSq float64
+	for _, x := range samples {
+		sum += x
+	}
+	mean := sum / n
+	if n < 2 {
+		return Interval{Estimate: mean, Low: 0, High: math.Inf(1)}
+	}
+	for _, x := range samples {
+		sumSq += (x - mean) * (x - mean)
+	}
+	margin := 1.96 * math.Sqrt(sumSq/(n-1)/n)
+	return Interval{Estimate: mean, Low: math.Max(0, mean-margin), High: mean + margin}
+}
+
+// estimator holds the directories read by the random descents,
+// so that the levels close to the root are only read once
+type estimator struct {
+	root string
+
+	mu        sync.Mutex
+	dirs      map[string]*estimateDir
+	files     []float64 // the estimates of the descents
+	dirCounts []float64
+	bytes     []float64
+}
+
+// estimateDir is what a descent needs to know about a directory
+type estimateDir struct {
+	files   int
+	bytes   float64 // estimated from a sample for large directories
+	subdirs []string
+}
+
+// readDir returns the directory, reading it if needed; it fails with
+// context.DeadlineExceeded if the deadline expires before it is read
+func (e *estimator) readDir(relpath string, deadline time.Time) (*estimateDir, error) {
+	e.mu.Lock()
+	d, ok := e.dirs[relpath]
+	e.mu.Unlock()
+	if ok {
+		return d, nil
+	}
+
+	entries, err := readDirUntil(filepath.Join(e.root, relpath), deadline)
+	if errors.Is(err, context.DeadlineExceeded) || err != nil && len(entries) == 0 {
+		return nil, err
+	}
+	d = &estimateDir{}
+	var files []os.DirEntry
+	for _, entry := range entries {
+		if entry.IsDir() {
+			d.subdirs = append(d.subdirs, filepath.Join(relpath, entry.Name()))
+		} else {
+			files = append(files, entry)
+		}
+	}
+	d.files = len(files)
+	step := 1
+	if len(files) > estimateStatSample {
+		step = len(files) / estimateStatSample
+	}
+	sampled := 0
+	for i := 0; i < len(files); i += step {
+		if i%estimateReadBatch == 0 && !time.Now().Before(deadline) {
+			return nil, context.DeadlineExceeded
+		}
+		if info, err := files[i].Info(); err == nil {
+			d.bytes += float64(info.Size())
+			sampled++
+		}
+	}
+	if sampled > 0 {
+		d.bytes *= float64(len(files)) / float64(sampled)
+	}
+
+	e.mu.Lock()
+	e.dirs[relpath] = d
+	e.mu.Unlock()
+	return d, nil
+}
+
+// readDirUntil reads the directory in batches, failing with
+// context.DeadlineExceeded once the deadline has expired
+func readDirUntil(name string, deadline time.Time) ([]os.DirEntry, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []os.DirEntry
+	for {
+		if !time.Now().Before(deadline) {
+			return nil, context.DeadlineExceeded
+		}
+		batch, err := f.ReadDir(estimateReadBatch)
+		entries = append(entries, batch...)
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+	}
+}
+
+// probe makes a random descent from the root and records its
+// estimates, unless the deadline expires before it is complete
+func (e *estimator) probe(rnd *rand.Rand, deadline time.Time) {
+	weight, files, dirs, bytes := 1.0, 0.0, 1.0, 0.0
+	for relpath := ""; ; {
+		d, err := e.readDir(relpath, deadline)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return
+		}
+		if err != nil {
+			break // counted as an empty directory
+		}
+		files += weight * float64(d.files)
+		bytes += weight * d.bytes
+		dirs += weight * float64(len(d.subdirs))
+		if len(d.subdirs) == 0 {
+			break
+		}
+		weight *= float64(len(d.subdirs))
+		relpath = d.subdirs[rnd.Intn(len(d.subdirs))]
+	}
+	e.mu.Lock()
+	e.files = append(e.files, files)
+	e.dirCounts = append(e.dirCounts, dirs)
+	e.bytes = append(e.bytes, bytes)
+	e.mu.Unlock()
+}
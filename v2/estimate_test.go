@@ -0,0 +1,56 @@
+package cwalk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEstimateDeadline(t *testing.T) {
+	root := t.TempDir()
+	// more entries than a single batch
+	for i := 0; i < estimateReadBatch+10; i++ {
+		if err := os.WriteFile(filepath.Join(root, fmt.Sprint(i)), []byte("ab"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// the reading stops once the deadline has expired
+	e := &estimator{root: root, dirs: map[string]*estimateDir{}}
+	if _, err := e.readDir("", time.Now()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v past the deadline, want context.DeadlineExceeded", err)
+	}
+	e.probe(rand.New(rand.NewSource(1)), time.Now())
+	if len(e.files) != 0 || len(e.dirs) != 0 {
+		t.Errorf("got %d estimates and %d directories past the deadline", len(e.files), len(e.dirs))
+	}
+
+	d, err := e.readDir("", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.files != estimateReadBatch+10 || len(d.subdirs) != 1 || d.bytes != 2*float64(d.files) {
+		t.Errorf("got %+v", d)
+	}
+	e.probe(rand.New(rand.NewSource(1)), time.Now().Add(time.Minute))
+	if fmt.Sprint(e.files, e.dirCounts) != fmt.Sprint([]float64{estimateReadBatch + 10}, []float64{2}) {
+		t.Errorf("got the estimates %v and %v", e.files, e.dirCounts)
+	}
+
+	// the tree is walked completely within the budget
+	res, err := Estimate(root, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Exact || res.Files.Estimate != estimateReadBatch+10 || res.Dirs.Estimate != 2 {
+		t.Errorf("got %+v", res)
+	}
+}
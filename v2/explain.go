@@ -0,0 +1,232 @@
+package cwalk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExplainPlan returns a human-readable description of the effective
+// walk settings for the configuration, including the ones set
+// by its presets
+func ExplainPlan(cfg Config) (string, error) {
+	opts, err := cfg.Options()
+	if err != nil {
+		return "", err
+	}
+	return NewWalker("", opts...).ExplainPlan()
+}
+
+// ExplainPlan returns a human-readable description of the effective
+// settings of the walker: traversal order, concurrency, symlink policy,
+// error handling, filters (in the order they are applied) and the sinks
+// the entries are collected into besides the callback
+func (w *Walker) ExplainPlan() (string, error) {
+	if w.optionErr != nil {
+		return "", w.optionErr
+	}
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format+"\n", args...)
+	}
+
+	line("Order: unordered; entries are passed to the callback as the workers find them " +
+		"(use WalkEntries() order tokens to restore the directory order)")
+	source := "GOMAXPROCS"
+	if w.numWorkers > 0 {
+		source = "WithWorkers()"
+	}
+	if w.limiter != nil {
+		source += ", directory reads limited by WithLimiter()"
+	}
+	line("Concurrency: %d workers (%s)", w.workerCount(), source)
+
+	symlinks := "not followed"
+	if w.followSymlinks {
+		symlinks = "directory symlinks followed"
+	}
+	if w.confineToRoot {
+		symlinks += ", confined to the root"
+	}
+	if w.pinRoot {
+		symlinks += ", root pinned to its absolute path"
+	}
+	line("Symlinks: %s", symlinks)
+	if w.strictRoot {
+		line("Root: must be a directory")
+	}
+
+	limit, action := w.maxErrors, "stop collecting"
+	if w.maxErrorsAction == AbortWalk {
+		action = "abort the walk"
+	}
+	if w.failFast {
+		line("Errors: the walk stops at the first error")
+	} else if limit > 0 {
+		line("Errors: up to %d stored, then %s", limit, action)
+	} else {
+		line("Errors: all stored")
+	}
+
+	if w.shardCount > 1 {
+		line("Shard: %d of %d", w.shardIndex, w.shardCount)
+	}
+	if len(w.seedDirs) > 0 {
+		line("Seed directories: %s", strings.Join(w.seedDirs, ", "))
+	}
+
+	if len(w.filterNames) == 0 {
+		line("Filters: none")
+	} else {
+		line("Filters (the first one not including an entry decides):")
+		for i, name := range w.filterNames {
+			line("  %d. %s", i+1, name)
+		}
+	}
+
+	var sinks []string
+	if w.snapshot != nil {
+		sinks = append(sinks, "snapshot")
+	}
+	if w.index != nil {
+		sinks = append(sinks, "index")
+	}
+	if w.sorted != nil {
+		sinks = append(sinks, "sorted sink")
+	}
+	if w.pathStream != nil {
+		sinks = append(sinks, "path stream")
+	}
+	if w.watches != nil {
+		sinks = append(sinks, "directory watches")
+	}
+	if w.traceOut != nil {
+		sinks = append(sinks, "trace recording")
+	}
+	if w.cache != nil && w.immutable {
+		sinks = append(sinks, "metadata cache (immutable tree)")
+	} else if w.cache != nil {
+		sinks = append(sinks, "metadata cache")
+	}
+	if w.report != nil {
+		sinks = append(sinks, "report")
+	}
+	if w.outcomeFunc != nil {
+		sinks = append(sinks, "outcome stream")
+	}
+	if len(sinks) == 0 {
+		sinks = append(sinks, "none")
+	}
+	line("Sinks: %s", strings.Join(sinks, ", "))
+
+	var extras []string
+	if w.enrich {
+		extras = append(extras, "enriched metadata")
+	}
+	if w.slashPaths {
+		extras = append(extras, "forward slash paths")
+	}
+	if w.windowsACL {
+		extras = append(extras, "Windows ACLs")
+	}
+	if w.securityLabels {
+		extras = append(extras, "security labels")
+	}
+	if w.lazyStat {
+		extras = append(extras, "lazy stat")
+	}
+	if w.streams {
+		extras = append(extras, "alternate data streams")
+	}
+	if w.checkConsistency {
+		extras = append(extras, "consistency checks")
+	}
+	if len(w.archiveFormats) > 0 {
+		extras = append(extras, "archive descent ("+strings.Join(w.archiveFormats, ", ")+")")
+	}
+	if w.forensic {
+		extras = append(extras, "forensic mode")
+	}
+	if len(w.stages) > 0 {
+		stage := fmt.Sprintf("%d content stages", len(w.stages))
+		if !w.noPrefetch {
+			stage += " with read-ahead hints"
+		}
+		if w.noAtime {
+			stage += ", opening files with O_NOATIME"
+		}
+		extras = append(extras, stage)
+	}
+	if len(extras) > 0 {
+		line("Extras: %s", strings.Join(extras, ", "))
+	}
+	return b.String(), nil
+}
+
+// Explain tells whether the path (relative to the walker root) would be
+// passed to the callback, and if not, why: which filter excluded it or
+// pruned one of its parent directories, or which other setting skipped it.
+// The path and its parents are stat'ed, so the answer reflects the current
+// state of the file system.
+func (w *Walker) Explain(relpath string) (string, error) {
+	if w.optionErr != nil {
+		return "", w.optionErr
+	}
+	relpath = filepath.Clean(relpath)
+	fsys := osFS{root: w.root}
+	if relpath == "." {
+		info, _ := fsys.Lstat("")
+		for n, fn := range w.filters {
+			if action := fn("", info); action != FilterInclude {
+				return fmt.Sprintf("%s: skipped (the root, %sd by %s)", relpath, action, w.filterNames[n]), nil
+			}
+		}
+		return fmt.Sprintf("%s: included (the root)", relpath), nil
+	}
+	if strings.HasPrefix(relpath, ".."+string(filepath.Separator)) || relpath == ".." || filepath.IsAbs(relpath) {
+		return fmt.Sprintf("%s: not walked (outside of the root)", relpath), nil
+	}
+	if !w.inShard(relpath) {
+		return fmt.Sprintf("%s: skipped (belongs to another shard)", relpath), nil
+	}
+
+	parts := strings.Split(relpath, string(filepath.Separator))
+	for i := range parts {
+		subpath := filepath.Join(parts[:i+1]...)
+		last := i == len(parts)-1
+
+		info, err := fsys.Lstat(subpath)
+		if err == nil && w.followSymlinks && info.Mode()&os.ModeSymlink != 0 {
+			info, err = fsys.Stat(subpath)
+		}
+		if os.IsNotExist(err) {
+			return fmt.Sprintf("%s: not walked (%s doesn't exist)", relpath, subpath), nil
+		}
+		if err != nil && !last {
+			return fmt.Sprintf("%s: not walked (%s can't be stat'ed: %v)", relpath, subpath, err), nil
+		}
+
+		for n, fn := range w.filters {
+			action := fn(subpath, info)
+			if action == FilterInclude {
+				continue
+			}
+			if last {
+				return fmt.Sprintf("%s: skipped (%sd by %s)", relpath, action, w.filterNames[n]), nil
+			}
+			if action == FilterPrune {
+				return fmt.Sprintf("%s: skipped (parent %s pruned by %s)", relpath, subpath, w.filterNames[n]), nil
+			}
+			break // excluded directories are still descended into
+		}
+
+		if err != nil {
+			return fmt.Sprintf("%s: reported as an error (%v)", relpath, err), nil
+		}
+		if !last && !info.IsDir() {
+			return fmt.Sprintf("%s: not walked (%s is not a directory)", relpath, subpath), nil
+		}
+	}
+	return fmt.Sprintf("%s: included", relpath), nil
+}
@@ -0,0 +1,124 @@
+package cwalk
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FDBudget is a Limiter which caps the number of open file descriptors:
+// once the budget is used up, the walk queues the directory reads (and
+// the content reads) until descriptors are released, instead of failing
+// with EMFILE. The same budget can be passed to any number of walks,
+// and used by the application for its own files, e.g. by the content
+// stages opening other files than the one they are passed. The waiters
+// are served in FIFO order. FDBudget is safe for concurrent use.
+type FDBudget struct {
+	mu      sync.Mutex
+	size    int64
+	used    int64
+	peak    int64
+	waiters list.List // of *fdWaiter
+}
+
+// fdWaiter is a pending Acquire call
+type fdWaiter struct {
+	n     int64
+	ready chan struct{} // closed once the descriptors are acquired
+}
+
+// FDBudgetStats holds the current usage of an FDBudget
+type FDBudgetStats struct {
+	Size    int64 // the budget
+	Used    int64 // the descriptors currently acquired
+	Peak    int64 // the highest number of descriptors acquired at once
+	Waiting int   // the number of callers waiting for descriptors
+}
+
+// NewFDBudget creates an FDBudget of n descriptors (at least one)
+func NewFDBudget(n int) *FDBudget {
+	if n < 1 {
+		n = 1
+	}
+	return &FDBudget{size: int64(n)}
+}
+
+// WithFDBudget makes the walk acquire a descriptor from b for every
+// directory it reads and for every file read by the content stages,
+// as long as they are open; this is WithLimiter(b)
+func WithFDBudget(b *FDBudget) Option {
+	return WithLimiter(b)
+}
+
+// Acquire acquires n descriptors, waiting till they are available
+// or ctx is done, in which case it returns ctx.Err()
+func (b *FDBudget) Acquire(ctx context.Context, n int64) error {
+	b.mu.Lock()
+	if n > b.size {
+		b.mu.Unlock()
+		return fmt.Errorf("Can't acquire %d descriptors from a budget of %d", n, b.size)
+	}
+	if b.waiters.Len() == 0 && b.used+n <= b.size {
+		b.take(n)
+		b.mu.Unlock()
+		return nil
+	}
+	waiter := &fdWaiter{n: n, ready: make(chan struct{})}
+	e := b.waiters.PushBack(waiter)
+	b.mu.Unlock()
+
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		select {
+		case <-waiter.ready:
+			// acquired in the meantime: give the descriptors back
+			b.used -= n
+		default:
+			b.waiters.Remove(e)
+		}
+		b.wake()
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Release releases n descriptors acquired before
+func (b *FDBudget) Release(n int64) {
+	b.mu.Lock()
+	b.used -= n
+	b.wake()
+	b.mu.Unlock()
+}
+
+// Stats returns the current usage of the budget
+func (b *FDBudget) Stats() FDBudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return FDBudgetStats{Size: b.size, Used: b.used, Peak: b.peak, Waiting: b.waiters.Len()}
+}
+
+// take marks n descriptors as acquired; b.mu must be held
+func (b *FDBudget) take(n int64) {
+	b.used += n
+	if b.used > b.peak {
+		b.peak = b.used
+	}
+}
+
+// wake hands the available descriptors to the waiters
+// in FIFO order; b.mu must be held
+func (b *FDBudget) wake() {
+	for e := b.waiters.Front(); e != nil; e = b.waiters.Front() {
+		waiter := e.Value.(*fdWaiter)
+		if b.used+waiter.n > b.size {
+			return
+		}
+		b.take(waiter.n)
+		b.waiters.Remove(e)
+		close(waiter.ready)
+	}
+}
@@ -0,0 +1,61 @@
+//go:build darwin || freebsd || netbsd
+// +build darwin freebsd netbsd
+
+package cwalk
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// BSD file flags (see chflags(2)) missing from the syscall package
+const (
+	ufImmutable = 0x2
+	ufAppend    = 0x4
+	ufHidden    = 0x8000 // not used by NetBSD
+	sfImmutable = 0x20000
+	sfAppend    = 0x40000
+)
+
+// fillFileInfo fills the creation time and the file flags,
+// which are part of the stat structure on these platforms
+func fillFileInfo(meta *Metadata, fsys fileSystem, relpath string, info os.FileInfo) {
+	if isHidden(info) {
+		meta.Flags |= FlagHidden
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	if sec, nsec := st.Birthtimespec.Unix(); sec > 0 || nsec != 0 {
+		// zero if not recorded by the file system
+		meta.BirthTime = time.Unix(sec, nsec)
+		meta.HasBirthTime = true
+	}
+	if st.Flags&(ufImmutable|sfImmutable) != 0 {
+		meta.Flags |= FlagImmutable
+	}
+	if st.Flags&(ufAppend|sfAppend) != 0 {
+		meta.Flags |= FlagAppendOnly
+	}
+}
+
+// isHidden reports whether the file is hidden, either
+// by the dot convention or by the UF_HIDDEN flag
+func isHidden(info os.FileInfo) bool {
+	if isDotFile(info.Name()) {
+		return true
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && st.Flags&ufHidden != 0
+}
+
+// accessTime returns the last access time of the file
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(st.Atimespec.Unix()), true
+}
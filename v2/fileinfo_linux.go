@@ -0,0 +1,134 @@
+package cwalk
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// statx(2) definitions missing from the syscall package
+const (
+	atEmptyPath        = 0x1000 // operate on the file descriptor itself
+	statxBtime         = 0x800
+	statxAttrImmutable = 0x10
+	statxAttrAppend    = 0x20
+)
+
+// sysStatx is the statx(2) system call number for the architecture
+var sysStatx = map[string]uintptr{
+	"386":      383,
+	"amd64":    332,
+	"arm":      397,
+	"arm64":    291,
+	"loong64":  291,
+	"mips":     4366,
+	"mipsle":   4366,
+	"mips64":   5326,
+	"mips64le": 5326,
+	"ppc64":    383,
+	"ppc64le":  383,
+	"riscv64":  291,
+	"s390x":    379,
+}[runtime.GOARCH]
+
+// statxTimestamp is struct statx_timestamp
+type statxTimestamp struct {
+	sec      int64
+	nsec     uint32
+	reserved int32
+}
+
+// statxT is struct statx
+type statxT struct {
+	mask           uint32
+	blksize        uint32
+	attributes     uint64
+	nlink          uint32
+	uid            uint32
+	gid            uint32
+	mode           uint16
+	spare0         uint16
+	ino            uint64
+	size           uint64
+	blocks         uint64
+	attributesMask uint64
+	atime          statxTimestamp
+	btime          statxTimestamp
+	ctime          statxTimestamp
+	mtime          statxTimestamp
+	spare          [128]byte // device numbers and fields added by later kernels
+}
+
+// statx calls statx(2) for the open file, requesting the fields in mask
+func statx(f *os.File, mask uint32) (*statxT, error) {
+	if sysStatx == 0 {
+		return nil, syscall.ENOSYS
+	}
+	empty, err := syscall.BytePtrFromString("")
+	if err != nil {
+		return nil, err
+	}
+	var st statxT
+	_, _, errno := syscall.Syscall6(sysStatx, f.Fd(), uintptr(unsafe.Pointer(empty)),
+		atEmptyPath, uintptr(mask), uintptr(unsafe.Pointer(&st)), 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &st, nil
+}
+
+// openPath opens the file at relpath through fsys with O_PATH, which
+// needs no permissions on the file itself; symbolic links are only
+// followed if info describes the target
+func openPath(fsys fileSystem, relpath string, info os.FileInfo) (*os.File, error) {
+	flags := oPath
+	if info.Mode()&os.ModeSymlink != 0 {
+		flags |= syscall.O_NOFOLLOW
+	}
+	return fsys.Open(relpath, flags)
+}
+
+// fillFileInfo fills the creation time and the file flags, using
+// statx(2), which needs Linux 4.11 or later; the creation time is only
+// reported if the file system records it. Files are hidden by
+// the dot convention
+func fillFileInfo(meta *Metadata, fsys fileSystem, relpath string, info os.FileInfo) {
+	if isHidden(info) {
+		meta.Flags |= FlagHidden
+	}
+	f, err := openPath(fsys, relpath, info)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	st, err := statx(f, statxBtime)
+	if err != nil {
+		return
+	}
+	if st.mask&statxBtime != 0 {
+		meta.BirthTime = time.Unix(st.btime.sec, int64(st.btime.nsec))
+		meta.HasBirthTime = true
+	}
+	if st.attributes&statxAttrImmutable != 0 {
+		meta.Flags |= FlagImmutable
+	}
+	if st.attributes&statxAttrAppend != 0 {
+		meta.Flags |= FlagAppendOnly
+	}
+}
+
+// isHidden reports whether the file is hidden
+func isHidden(info os.FileInfo) bool {
+	return isDotFile(info.Name())
+}
+
+// accessTime returns the last access time of the file
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(st.Atim.Unix()), true
+}
@@ -0,0 +1,27 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !windows
+// +build !linux,!darwin,!freebsd,!netbsd,!windows
+
+package cwalk
+
+import (
+	"os"
+	"time"
+)
+
+// fillFileInfo fills the file flags; the creation time and
+// the chflags(2) flags are not available on this platform
+func fillFileInfo(meta *Metadata, fsys fileSystem, relpath string, info os.FileInfo) {
+	if isHidden(info) {
+		meta.Flags |= FlagHidden
+	}
+}
+
+// isHidden reports whether the file is hidden
+func isHidden(info os.FileInfo) bool {
+	return isDotFile(info.Name())
+}
+
+// accessTime reports that the access time is not available
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,51 @@
+package cwalk
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fillFileInfo fills the creation time and the file flags, which
+// are part of the file attribute data on Windows; the read-only
+// attribute is not reported as FlagImmutable, as it doesn't
+// prevent renaming or deleting the file
+func fillFileInfo(meta *Metadata, fsys fileSystem, relpath string, info os.FileInfo) {
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return
+	}
+	meta.BirthTime = time.Unix(0, attrs.CreationTime.Nanoseconds())
+	meta.HasBirthTime = true
+	if attrs.FileAttributes&syscall.FILE_ATTRIBUTE_HIDDEN != 0 {
+		meta.Flags |= FlagHidden
+	}
+	if attrs.FileAttributes&syscall.FILE_ATTRIBUTE_SYSTEM != 0 {
+		meta.Flags |= FlagSystem
+	}
+}
+
+// isHidden reports whether the file has the hidden or the system
+// attribute, which is what Explorer hides by default
+func isHidden(info os.FileInfo) bool {
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	return ok && attrs.FileAttributes&(syscall.FILE_ATTRIBUTE_HIDDEN|syscall.FILE_ATTRIBUTE_SYSTEM) != 0
+}
+
+// isPlaceholder reports whether the file is a cloud placeholder
+// which content would be downloaded (hydrated) when read
+func isPlaceholder(info os.FileInfo) bool {
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	return ok && attrs.FileAttributes&(fileAttributeRecallOnDataAccess|fileAttributeOffline) != 0
+}
+
+// accessTime returns the last access time of the file; note that
+// NTFS only updates it hourly, if at all (see fsutil behavior
+// disablelastaccess)
+func accessTime(info os.FileInfo) (time.Time, bool) {
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, attrs.LastAccessTime.Nanoseconds()), true
+}
@@ -0,0 +1,22 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package cwalk
+
+import "os"
+
+// fileKey reports that directories can't be identified
+// on this platform, so nothing is cached
+func fileKey(info os.FileInfo) (FileKey, bool) {
+	return FileKey{}, false
+}
+
+// linkCount reports that the number of links is not known
+func linkCount(info os.FileInfo) uint64 {
+	return 0
+}
+
+// fileOwner reports that files have no numeric owner on this platform
+func fileOwner(info os.FileInfo) (uid, gid uint32, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,42 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package cwalk
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileKey returns the key identifying the version of the directory
+func fileKey(info os.FileInfo) (FileKey, bool) {
+	if info == nil {
+		return FileKey{}, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileKey{}, false
+	}
+	return FileKey{
+		Dev:     uint64(st.Dev),
+		Ino:     uint64(st.Ino),
+		ModTime: info.ModTime().UnixNano(),
+	}, true
+}
+
+// linkCount returns the number of hard links to the file;
+// for directories, this is usually the number of subdirectories plus 2
+func linkCount(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Nlink)
+	}
+	return 0
+}
+
+// fileOwner returns the user and group IDs of the file
+func fileOwner(info os.FileInfo) (uid, gid uint32, ok bool) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint32(st.Uid), uint32(st.Gid), true
+	}
+	return 0, 0, false
+}
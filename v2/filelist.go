@@ -0,0 +1,87 @@
+package cwalk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// listBatchSize is the number of listed paths processed by one job
+const listBatchSize = 256
+
+// WalkList stats the paths read from list, which are relative to the
+// root, concurrently, and passes them to fn like WalkEntries() would,
+// in place of walking the tree: this refreshes the metadata of an
+// inventory taken before (e.g. written by a PathStream), or of a list
+// produced by an external tool, like the --files-from lists of rsync
+// and tar.
+//
+// The list is NUL-separated, as written by PathStream or find -print0,
+// or newline-separated if it contains no NUL byte; it is read entirely
+// before the walk starts. Listed directories are not descended into,
+// and the paths which can't be stat'ed (e.g. because they were removed)
+// are passed to fn with the error, which fn may ignore. The filters,
+// content stages and sinks apply as in a regular walk; the entries
+// carry no parent ID, and their order index is their position in the list.
+func (w *Walker) WalkList(list io.Reader, fn EntryFunc) error {
+	data, err := io.ReadAll(list)
+	if err != nil {
+		return err
+	}
+	sep := byte('\n')
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = 0
+	}
+	w.list = []string{}
+	for _, line := range bytes.Split(data, []byte{sep}) {
+		if sep == '\n' {
+			line = bytes.TrimSuffix(line, []byte{'\r'})
+		}
+		if len(line) > 0 {
+			w.list = append(w.list, string(line))
+		}
+	}
+	defer func() { w.list = nil }()
+	return w.WalkEntries("", fn)
+}
+
+// WalkList is a wrapper function for the Walker object
+// that works like Walker.WalkList()
+func WalkList(root string, list io.Reader, fn EntryFunc, opts ...Option) error {
+	return NewWalker(root, opts...).WalkList(list, fn)
+}
+
+// pushList queues the listed paths in batches; the paths
+// escaping relpath are reported as errors
+func (w *Walker) pushList(relpath string) {
+	w.seeds = nil
+	w.rootID = 0
+	var names []string
+	offset := 0
+	flush := func() {
+		if len(names) > 0 {
+			w.queue.push(job{path: relpath, listed: true, part: &dirPart{names: names, offset: offset}})
+		}
+		names = nil
+	}
+	for i, name := range w.list {
+		name = filepath.Clean(filepath.FromSlash(name))
+		if name == "." {
+			name = ""
+		}
+		if !isWithin(".", name) {
+			w.addError(name, fmt.Errorf("Listed path is outside of the root: %s", name))
+			flush() // keep the indexes of the following paths in line with the list
+			continue
+		}
+		if len(names) == 0 {
+			offset = i
+		}
+		names = append(names, name)
+		if len(names) == listBatchSize {
+			flush()
+		}
+	}
+	flush()
+}
@@ -0,0 +1,114 @@
+package cwalk
+
+import (
+	"fmt"
+	"os"
+)
+
+// FilterAction tells the walker what to do with an entry
+type FilterAction int
+
+const (
+	// FilterInclude passes the entry to the callback
+	FilterInclude FilterAction = iota
+
+	// FilterExclude doesn't pass the entry to the callback,
+	// but still descends into it if it is a directory
+	FilterExclude
+
+	// FilterPrune neither passes the entry to the callback,
+	// nor descends into it
+	FilterPrune
+)
+
+// String returns the name of the action
+func (a FilterAction) String() string {
+	switch a {
+	case FilterInclude:
+		return "include"
+	case FilterExclude:
+		return "exclude"
+	case FilterPrune:
+		return "prune"
+	}
+	return fmt.Sprintf("FilterAction(%d)", int(a))
+}
+
+// FilterFunc decides what to do with an entry before it is passed
+// to the callback; relpath is relative to the walker root, and info
+// is nil if the entry could not be stat'ed
+type FilterFunc func(relpath string, info os.FileInfo) FilterAction
+
+// WithFilter adds a filter to the walk. Filters are called in the order
+// they were added, and the first one that returns anything other than
+// FilterInclude decides what happens to the entry. Filters apply to the
+// root of the walk too (unlike the built-in filters, such as the one of
+// WithSkipHidden(), which never skip the root).
+// Filters must be safe for concurrent use.
+func WithFilter(fn FilterFunc) Option {
+	return func(w *Walker) {
+		w.addFilter("", fn)
+	}
+}
+
+// addFilter adds a filter along with its description
+// reported by Explain(); an empty name means a user filter
+func (w *Walker) addFilter(name string, fn FilterFunc) {
+	if name == "" {
+		name = fmt.Sprintf("filter #%d", len(w.filters)+1)
+	}
+	w.filters = append(w.filters, fn)
+	w.filterNames = append(w.filterNames, name)
+}
+
+// exceptRoot wraps a built-in filter so that it never skips the root
+// of the walk, which has been chosen explicitly
+func (w *Walker) exceptRoot(fn FilterFunc) FilterFunc {
+	return func(relpath string, info os.FileInfo) FilterAction {
+		if relpath == w.rootRel {
+			return FilterInclude
+		}
+		return fn(relpath, info)
+	}
+}
+
+// filter runs the entry through the filters
+func (w *Walker) filter(relpath string, info os.FileInfo) FilterAction {
+	for _, fn := range w.filters {
+		if action := fn(relpath, info); action != FilterInclude {
+			return action
+		}
+	}
+	return FilterInclude
+}
+
+// DevTreeIgnoredDirs lists the names of the directories
+// pruned by WithDevTreeDefaults(): VCS internals, dependency
+// directories and common build outputs
+var DevTreeIgnoredDirs = []string{
+	".git", ".hg", ".svn", ".bzr", "_darcs", "CVS",
+	"node_modules", "bower_components", "vendor",
+	"target", "dist", "__pycache__", ".tox", ".venv",
+	".gradle", ".next",
+}
+
+// WithDevTreeDefaults prunes the directories listed in DevTreeIgnoredDirs,
+// except for the ones named in keep, which is what most developer tools
+// want when walking source trees
+func WithDevTreeDefaults(keep ...string) Option {
+	ignored := make(map[string]bool, len(DevTreeIgnoredDirs))
+	for _, name := range DevTreeIgnoredDirs {
+		ignored[name] = true
+	}
+	for _, name := range keep {
+		delete(ignored, name)
+	}
+	return func(w *Walker) {
+		w.addFilter("dev tree defaults", w.exceptRoot(func(relpath string, info os.FileInfo) FilterAction {
+			if info != nil && info.IsDir() && ignored[info.Name()] {
+				return FilterPrune
+			}
+			return FilterInclude
+		}))
+	}
+}
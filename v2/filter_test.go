@@ -0,0 +1,100 @@
+package cwalk
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFiltersApplyToRoot(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a", ".hidden", "b/c"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"a/x", ".hidden/y", "b/c/d", "f"} {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(file)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	only := func(path string, action FilterAction) Option {
+		return WithFilter(func(relpath string, info os.FileInfo) FilterAction {
+			if filepath.ToSlash(relpath) == path {
+				return action
+			}
+			return FilterInclude
+		})
+	}
+	findExpr := func(args ...string) Option {
+		e, err := ParseFindExpr(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return WithFindExpr(e)
+	}
+
+	for _, c := range []struct {
+		name string
+		root string // relative to the tree
+		opt  Option
+		want string
+	}{
+		{"exclude root", "", only("", FilterExclude), "a,a/x,.hidden,.hidden/y,b,b/c,b/c/d,f"},
+		{"prune root", "", only("", FilterPrune), ""},
+		{"exclude child", "", only("a", FilterExclude), ".,a/x,.hidden,.hidden/y,b,b/c,b/c/d,f"},
+		{"prune child", "", only("b", FilterPrune), ".,a,a/x,.hidden,.hidden/y,f"},
+		{"skip hidden", "", WithSkipHidden(), ".,a,a/x,b,b/c,b/c/d,f"},
+		{"skip hidden root", ".hidden", WithSkipHidden(), ".,y"},
+		{"maxdepth 0", "", findExpr("-maxdepth", "0"), "."},
+		{"maxdepth 1", "", findExpr("-maxdepth", "1"), ".,a,.hidden,b,f"},
+		{"maxdepth 1 files", "", findExpr("-maxdepth", "1", "-type", "f"), "f"},
+		{"type f", "", findExpr("-type", "f"), "a/x,.hidden/y,b/c/d,f"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var visited []string
+			err := walkTree(filepath.Join(root, c.root), func(path string, info os.FileInfo, err error) error {
+				mu.Lock()
+				if path == "" {
+					path = "."
+				}
+				visited = append(visited, filepath.ToSlash(path))
+				mu.Unlock()
+				return err
+			}, c.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.Split(c.want, ",")
+			if c.want == "" {
+				want = nil
+			}
+			sort.Strings(visited)
+			sort.Strings(want)
+			if strings.Join(visited, ",") != strings.Join(want, ",") {
+				t.Errorf("visited %q, want %q", visited, want)
+			}
+		})
+	}
+}
+
+func TestExplainRoot(t *testing.T) {
+	root := t.TempDir()
+	w := NewWalker(root, WithSkipHidden(), WithFilter(func(relpath string, info os.FileInfo) FilterAction {
+		if relpath == "" {
+			return FilterExclude
+		}
+		return FilterInclude
+	}))
+	got, err := w.Explain("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ".: skipped (the root, excluded by filter #2)"; got != want {
+		t.Errorf("Explain = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,333 @@
+package cwalk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrFindSyntax is returned by ParseFindExpr() for invalid expressions
+var ErrFindSyntax = errors.New("Invalid find expression")
+
+// FindExpr is a find(1) expression, see ParseFindExpr()
+type FindExpr struct {
+	args     []string
+	root     findNode
+	maxDepth int // -1 if not set
+	now      time.Time
+}
+
+// findNode evaluates a part of the expression for an entry
+type findNode func(e *findEnv) bool
+
+// findEnv is the entry the expression is evaluated for
+type findEnv struct {
+	relpath string
+	info    os.FileInfo
+	pruned  bool // set by -prune
+}
+
+// ParseFindExpr parses a subset of the find(1) expression syntax:
+//
+//	-name pattern    the base name matches the shell pattern
+//	-type c          the file is of type c: f, d, l, p, s, c or b
+//	-size [+-]n[ckMGwb]  the size, rounded up to the units (512-byte blocks by default)
+//	-mtime [+-]n     the file was modified n*24 hours ago (rounded down)
+//	-newer file      the file was modified more recently than file
+//	-maxdepth n      descend at most n levels below the root
+//	-prune           true; don't descend into the directory
+//	-print           true (entries are passed to the callback if the expression is true)
+//	! expr, -not expr, expr -a expr, expr -and expr, expr expr,
+//	expr -o expr, expr -or expr, ( expr )
+//
+// The time of the call is used as the current time for -mtime,
+// and the file given to -newer is stat'ed by the call.
+func ParseFindExpr(args []string) (*FindExpr, error) {
+	p := &findParser{args: args, expr: &FindExpr{args: args, maxDepth: -1, now: time.Now()}}
+	if len(args) == 0 {
+		p.expr.root = func(*findEnv) bool { return true }
+		return p.expr, nil
+	}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(args) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrFindSyntax, args[p.pos])
+	}
+	p.expr.root = root
+	return p.expr, nil
+}
+
+// String returns the expression as it was given
+func (e *FindExpr) String() string {
+	return strings.Join(e.args, " ")
+}
+
+// Match evaluates the expression for the entry (relpath is relative
+// to the root, info is nil if the entry could not be stat'ed), and reports
+// whether it matches, and whether the walk should skip it along with
+// its contents (because of -prune, or because it is below -maxdepth)
+func (e *FindExpr) Match(relpath string, info os.FileInfo) (match, prune bool) {
+	depth := 0
+	if relpath != "" {
+		depth = strings.Count(relpath, string(filepath.Separator)) + 1
+	}
+	if e.maxDepth >= 0 && depth > e.maxDepth {
+		return false, true
+	}
+	env := &findEnv{relpath: relpath, info: info}
+	match = e.root(env)
+	return match, env.pruned
+}
+
+// WithFindExpr adds a filter which passes the entries matching
+// the expression to the callback, and doesn't descend into the
+// directories pruned by it. Unlike find(1), a directory for which -prune
+// is evaluated is not passed to the callback, even if the expression
+// is true for it. Entries which could not be stat'ed are passed to the
+// callback along with the error.
+func WithFindExpr(e *FindExpr) Option {
+	return func(w *Walker) {
+		w.addFilter("find expression: "+e.String(), func(relpath string, info os.FileInfo) FilterAction {
+			if info == nil {
+				return FilterInclude
+			}
+			match, prune := e.Match(relpath, info)
+			switch {
+			case prune:
+				return FilterPrune
+			case !match:
+				return FilterExclude
+			}
+			return FilterInclude
+		})
+	}
+}
+
+// findParser is a recursive descent parser of find expressions
+type findParser struct {
+	args []string
+	pos  int
+	expr *FindExpr
+}
+
+// peek returns the next argument, or "" at the end
+func (p *findParser) peek() string {
+	if p.pos < len(p.args) {
+		return p.args[p.pos]
+	}
+	return ""
+}
+
+// value returns the argument of the primary
+func (p *findParser) value(primary string) (string, error) {
+	if p.pos >= len(p.args) {
+		return "", fmt.Errorf("%w: missing argument to %s", ErrFindSyntax, primary)
+	}
+	p.pos++
+	return p.args[p.pos-1], nil
+}
+
+// or parses: and { (-o | -or) and }
+func (p *findParser) or() (findNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "-o" || p.peek() == "-or" {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e *findEnv) bool { return l(e) || right(e) }
+	}
+	return left, nil
+}
+
+// and parses: not { [-a | -and] not }
+func (p *findParser) and() (findNode, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case "", "-o", "-or", ")":
+			return left, nil
+		case "-a", "-and":
+			p.pos++
+		}
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e *findEnv) bool { return l(e) && right(e) }
+	}
+}
+
+// not parses: (! | -not) not | ( or ) | primary
+func (p *findParser) not() (findNode, error) {
+	switch p.peek() {
+	case "!", "-not":
+		p.pos++
+		n, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(e *findEnv) bool { return !n(e) }, nil
+	case "(":
+		p.pos++
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("%w: missing )", ErrFindSyntax)
+		}
+		p.pos++
+		return n, nil
+	case "":
+		return nil, fmt.Errorf("%w: expression expected", ErrFindSyntax)
+	}
+	return p.primary()
+}
+
+// primary parses a test or an action
+func (p *findParser) primary() (findNode, error) {
+	primary := p.args[p.pos]
+	p.pos++
+	switch primary {
+	case "-prune":
+		return func(e *findEnv) bool {
+			e.pruned = true
+			return true
+		}, nil
+	case "-print", "-true":
+		return func(*findEnv) bool { return true }, nil
+	case "-false":
+		return func(*findEnv) bool { return false }, nil
+	}
+
+	arg, err := p.value(primary)
+	if err != nil {
+		return nil, err
+	}
+	switch primary {
+	case "-name":
+		if _, err := filepath.Match(arg, ""); err != nil {
+			return nil, fmt.Errorf("%w: -name %s: %v", ErrFindSyntax, arg, err)
+		}
+		return func(e *findEnv) bool {
+			name := filepath.Base(e.relpath)
+			if e.info != nil {
+				name = e.info.Name()
+			}
+			ok, _ := filepath.Match(arg, name)
+			return ok
+		}, nil
+
+	case "-type":
+		mode, ok := findTypes[arg]
+		if !ok {
+			return nil, fmt.Errorf("%w: -type %s: unknown type", ErrFindSyntax, arg)
+		}
+		return func(e *findEnv) bool {
+			return e.info != nil && e.info.Mode()&os.ModeType == mode
+		}, nil
+
+	case "-size":
+		cmp, n, unit, err := parseFindNumber(primary, arg, "ckMGwb")
+		if err != nil {
+			return nil, err
+		}
+		size := int64(findSizeUnits[unit])
+		return func(e *findEnv) bool {
+			return e.info != nil && cmp((e.info.Size()+size-1)/size, n)
+		}, nil
+
+	case "-mtime":
+		cmp, n, _, err := parseFindNumber(primary, arg, "")
+		if err != nil {
+			return nil, err
+		}
+		now := p.expr.now
+		return func(e *findEnv) bool {
+			return e.info != nil && cmp(int64(now.Sub(e.info.ModTime())/(24*time.Hour)), n)
+		}, nil
+
+	case "-newer":
+		ref, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		t := ref.ModTime()
+		return func(e *findEnv) bool {
+			return e.info != nil && e.info.ModTime().After(t)
+		}, nil
+
+	case "-maxdepth":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: -maxdepth %s: not a non-negative number", ErrFindSyntax, arg)
+		}
+		p.expr.maxDepth = n
+		return func(*findEnv) bool { return true }, nil
+	}
+	return nil, fmt.Errorf("%w: unknown primary %s", ErrFindSyntax, primary)
+}
+
+// findTypes maps the -type letters to the file type bits
+var findTypes = map[string]os.FileMode{
+	"f": 0,
+	"d": os.ModeDir,
+	"l": os.ModeSymlink,
+	"p": os.ModeNamedPipe,
+	"s": os.ModeSocket,
+	"c": os.ModeDevice | os.ModeCharDevice,
+	"b": os.ModeDevice,
+}
+
+// findSizeUnits maps the -size units to their sizes in bytes
+var findSizeUnits = map[byte]int{
+	'c': 1,
+	'w': 2,
+	'b': 512,
+	'k': 1 << 10,
+	'M': 1 << 20,
+	'G': 1 << 30,
+}
+
+// parseFindNumber parses a numeric argument: [+-]n, followed by one
+// of the units, if any (512-byte blocks by default); the returned
+// function compares a value to n as requested by the sign
+func parseFindNumber(primary, arg, units string) (cmp func(v, n int64) bool, n int64, unit byte, err error) {
+	s := arg
+	cmp = func(v, n int64) bool { return v == n }
+	if strings.HasPrefix(s, "+") {
+		cmp = func(v, n int64) bool { return v > n }
+		s = s[1:]
+	} else if strings.HasPrefix(s, "-") {
+		cmp = func(v, n int64) bool { return v < n }
+		s = s[1:]
+	}
+	if units != "" {
+		unit = 'b'
+		if s != "" && strings.IndexByte(units, s[len(s)-1]) >= 0 {
+			unit = s[len(s)-1]
+			s = s[:len(s)-1]
+		}
+	}
+	n, err = strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return nil, 0, 0, fmt.Errorf("%w: %s %s: not a number", ErrFindSyntax, primary, arg)
+	}
+	return cmp, n, unit, nil
+}
@@ -0,0 +1,179 @@
+package cwalk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// findInfo is the file info the expressions are evaluated for
+type findInfo struct {
+	name  string
+	size  int64
+	mode  os.FileMode
+	mtime time.Time
+}
+
+func (fi findInfo) Name() string       { return fi.name }
+func (fi findInfo) Size() int64        { return fi.size }
+func (fi findInfo) Mode() os.FileMode  { return fi.mode }
+func (fi findInfo) ModTime() time.Time { return fi.mtime }
+func (fi findInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi findInfo) Sys() interface{}   { return nil }
+
+func TestFindExpr(t *testing.T) {
+	now := time.Now()
+	newer := filepath.Join(t.TempDir(), "newer")
+	if err := os.WriteFile(newer, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(newer, now, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	file := findInfo{name: "a.go", size: 1500, mtime: now.Add(-50 * time.Hour)}
+	dir := findInfo{name: "d", size: 4096, mode: os.ModeDir | 0755, mtime: now}
+	link := findInfo{name: "l", mode: os.ModeSymlink | 0777, mtime: now}
+	for _, c := range []struct {
+		expr              string
+		relpath           string
+		info              os.FileInfo
+		wantMatch, pruned bool
+	}{
+		// the predicates
+		{"", "a.go", file, true, false},
+		{"-name *.go", "x/a.go", file, true, false},
+		{"-name *.c", "a.go", file, false, false},
+		{"-name a?go", "a.go", file, true, false},
+		{"-type f", "a.go", file, true, false},
+		{"-type f", "d", dir, false, false},
+		{"-type d", "d", dir, true, false},
+		{"-type l", "l", link, true, false},
+		{"-type f", "l", link, false, false},
+		{"-size 3", "a.go", file, true, false}, // 512-byte blocks, rounded up
+		{"-size +2", "a.go", file, true, false},
+		{"-size -3", "a.go", file, false, false},
+		{"-size 1500c", "a.go", file, true, false},
+		{"-size 2k", "a.go", file, true, false},
+		{"-size -1M", "a.go", file, false, false},
+		{"-size 1M", "a.go", file, true, false},
+		{"-mtime 2", "a.go", file, true, false},
+		{"-mtime +1", "a.go", file, true, false},
+		{"-mtime -2", "a.go", file, false, false},
+		{"-mtime 0", "d", dir, true, false},
+		{"-newer " + newer, "a.go", file, false, false},
+		{"-newer " + newer, "d", dir, true, false},
+		{"-true", "a.go", file, true, false},
+		{"-false", "a.go", file, false, false},
+		{"-print", "a.go", file, true, false},
+		// the entries which couldn't be stat'ed only match by name
+		{"-name a.go", "a.go", nil, true, false},
+		{"-type f", "a.go", nil, false, false},
+
+		// the operators and their precedence
+		{"! -type d", "a.go", file, true, false},
+		{"-not -type f", "a.go", file, false, false},
+		{"-type f -name *.go", "a.go", file, true, false},
+		{"-type f -a -name *.c", "a.go", file, false, false},
+		{"-type f -and -name *.go", "a.go", file, true, false},
+		{"-type d -o -name *.go", "a.go", file, true, false},
+		{"-type d -or -name *.c", "a.go", file, false, false},
+		{"-true -o -false -a -false", "a.go", file, true, false},
+		{"-true -o -false -false", "a.go", file, true, false},
+		{"( -true -o -false ) -a -false", "a.go", file, false, false},
+		{"! -true -o -true", "a.go", file, true, false},
+		{"! ( -true -o -true )", "a.go", file, false, false},
+		{"! ! -true", "a.go", file, true, false},
+
+		// -prune is only evaluated where the operators get to it
+		{"-name d -prune -o -print", "d", dir, true, true},
+		{"-name d -prune -o -print", "a.go", file, true, false},
+		{"-type d -prune", "a.go", file, false, false},
+		{"-prune -false", "d", dir, false, true},
+		{"-true -o -prune", "d", dir, true, false},
+
+		// -maxdepth applies wherever it is given
+		{"-maxdepth 1", "a.go", file, true, false},
+		{"-maxdepth 1", "x/a.go", file, false, true},
+		{"-type f -maxdepth 2", "x/a.go", file, true, false},
+		{"-maxdepth 0", "", dir, true, false},
+		{"-maxdepth 0", "d", dir, false, true},
+	} {
+		var args []string
+		if c.expr != "" {
+			args = strings.Fields(c.expr)
+		}
+		e, err := ParseFindExpr(args)
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		e.now = now
+		relpath := filepath.FromSlash(c.relpath)
+		if match, pruned := e.Match(relpath, c.info); match != c.wantMatch || pruned != c.pruned {
+			t.Errorf("%s for %s: got match %v, prune %v; want %v, %v",
+				c.expr, c.relpath, match, pruned, c.wantMatch, c.pruned)
+		}
+	}
+}
+
+func TestFindExprErrors(t *testing.T) {
+	for _, expr := range []string{
+		"(",
+		"( -true",
+		"-true )",
+		")",
+		"!",
+		"-true -o",
+		"-true -a",
+		"-name",
+		"-name [",
+		"-type",
+		"-type x",
+		"-size",
+		"-size abc",
+		"-size 1x",
+		"-size +-1",
+		"-mtime 1k",
+		"-maxdepth -1",
+		"-maxdepth x",
+		"-bogus",
+		"pattern",
+	} {
+		if _, err := ParseFindExpr(strings.Fields(expr)); !errors.Is(err, ErrFindSyntax) {
+			t.Errorf("%s: got %v, want ErrFindSyntax", expr, err)
+		}
+	}
+	// the file given to -newer must exist
+	missing := filepath.Join(t.TempDir(), "missing")
+	if _, err := ParseFindExpr([]string{"-newer", missing}); !os.IsNotExist(err) {
+		t.Errorf("-newer %s: got %v, want a not exist error", missing, err)
+	}
+}
+
+func TestWithFindExpr(t *testing.T) {
+	root := pageTree(t, "a.go", "b.txt", "vendor/c.go", "src/d.go", "src/sub/e.go")
+	e, err := ParseFindExpr(strings.Fields("-name vendor -prune -o -name *.go -maxdepth 2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var paths []string
+	if err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+		mu.Lock()
+		paths = append(paths, filepath.ToSlash(path))
+		mu.Unlock()
+		return err
+	}, WithFindExpr(e)); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(paths)
+	if got := strings.Join(paths, ","); got != "a.go,src/d.go" {
+		t.Errorf("got %s, want a.go,src/d.go", got)
+	}
+}
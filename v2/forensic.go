@@ -0,0 +1,62 @@
+package cwalk
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrForensicConflict is returned by the walk when WithForensicMode()
+// is combined with a setting which could modify the file system
+var ErrForensicConflict = errors.New("Not allowed in forensic mode")
+
+// WithForensicMode guarantees that the walk doesn't modify the walked
+// tree in any way, for evidence handling:
+//
+//   - the content stages, archive descent and the ignore files (see
+//     WithGitignore()) open files with O_NOATIME (see WithNoAtime()),
+//     and report an error for the files that can't be opened this way
+//     instead of updating their access times; on platforms without
+//     O_NOATIME, these features are not allowed;
+//   - the content stages never open anything but the regular files
+//     which were listed: files replaced in the meantime (e.g. by a FIFO
+//     or a device, which opening could affect) are reported with
+//     ErrFileReplaced;
+//   - the content of cloud placeholder files (e.g. OneDrive Files
+//     On-Demand) is never read, as that would download it;
+//   - symlinks are never followed (so neither are magic links,
+//     such as /proc/[pid]/fd/*).
+//
+// The walker itself never writes to the walked tree. If another option
+// conflicts with these rules, the walk fails with ErrForensicConflict.
+func WithForensicMode() Option {
+	return func(w *Walker) {
+		w.forensic = true
+		w.noAtime = true
+	}
+}
+
+// checkForensic returns ErrForensicConflict if forensic
+// mode is combined with a conflicting setting
+func (w *Walker) checkForensic() error {
+	if !w.forensic {
+		return nil
+	}
+	if w.followSymlinks {
+		return fmt.Errorf("%w: following symlinks", ErrForensicConflict)
+	}
+	if w.writesTree {
+		return fmt.Errorf("%w: storing checksums", ErrForensicConflict)
+	}
+	if oNoAtime == 0 {
+		// access times can't be preserved when opening files
+		switch {
+		case len(w.stages) > 0:
+			return fmt.Errorf("%w: content stages (access times can't be preserved on this platform)", ErrForensicConflict)
+		case len(w.archiveFormats) > 0:
+			return fmt.Errorf("%w: archive descent (access times can't be preserved on this platform)", ErrForensicConflict)
+		case w.gitignore:
+			return fmt.Errorf("%w: reading ignore files (access times can't be preserved on this platform)", ErrForensicConflict)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package cwalk
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestForensicPreservesAtime(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "file")
+	if err := os.WriteFile(path, []byte("evidence"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	atime := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, atime, atime); err != nil {
+		t.Fatal(err)
+	}
+
+	read := false
+	err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+		return err
+	}, WithForensicMode(), WithContentStage(func(entry *Entry, r io.Reader) error {
+		data, err := ioutil.ReadAll(r)
+		read = string(data) == "evidence"
+		return err
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !read {
+		t.Fatal("the content stage didn't read the file")
+	}
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		t.Fatal(err)
+	}
+	if got := time.Unix(st.Atim.Unix()); !got.Equal(atime) {
+		t.Errorf("access time changed from %v to %v", atime, got)
+	}
+}
+
+func TestOpenContentReplaced(t *testing.T) {
+	root := t.TempDir()
+	w := NewWalker(root, WithForensicMode())
+	closeFS, err := w.initFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFS()
+
+	for _, c := range []struct {
+		name    string
+		replace func(path string) error
+		want    error
+	}{
+		{"symlink", func(path string) error {
+			return os.Symlink(filepath.Join(root, "other"), path)
+		}, syscall.ELOOP},
+		{"fifo", func(path string) error {
+			return syscall.Mkfifo(path, 0644)
+		}, ErrFileReplaced},
+		{"file", func(path string) error {
+			// a file which exists already, so its inode number differs
+			return os.Rename(filepath.Join(root, "other"), path)
+		}, ErrFileReplaced},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(root, c.name)
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(root, "other"), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			info, err := w.fs.Lstat(c.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Remove(path); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.replace(path); err != nil {
+				t.Fatal(err)
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				f, err := w.openContent(c.name, info)
+				if err == nil {
+					f.Close()
+				}
+				done <- err
+			}()
+			select {
+			case err := <-done:
+				if !errors.Is(err, c.want) {
+					t.Errorf("openContent returned %v, want %v", err, c.want)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("openContent blocked")
+			}
+		})
+	}
+}
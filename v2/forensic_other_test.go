@@ -0,0 +1,25 @@
+//go:build !linux
+// +build !linux
+
+package cwalk
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestForensicOpenConflicts(t *testing.T) {
+	root := t.TempDir()
+	for name, opt := range map[string]Option{
+		"content stage":   WithContentStage(func(*Entry, io.Reader) error { return nil }),
+		"archive descent": WithArchiveDescent("zip"),
+		"gitignore":       WithGitignore(),
+	} {
+		err := walkTree(root, func(string, os.FileInfo, error) error { return nil }, WithForensicMode(), opt)
+		if !errors.Is(err, ErrForensicConflict) {
+			t.Errorf("%s: got %v, want ErrForensicConflict", name, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package cwalk
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// fileSystem abstracts the file system operations used by the walker;
+// all paths are relative to the walker root
+type fileSystem interface {
+	// ReadDirNames returns the names of the directory entries;
+	// if reading fails midway, the names obtained so far
+	// are returned along with the error
+	ReadDirNames(relpath string) ([]string, error)
+
+	// Lstat returns the file info without following symlinks
+	Lstat(relpath string) (os.FileInfo, error)
+
+	// Stat returns the file info of the symlink target
+	Stat(relpath string) (os.FileInfo, error)
+
+	// Readlink returns the target of the symlink
+	Readlink(relpath string) (string, error)
+
+	// Open opens the file for reading, with additional open flags
+	Open(relpath string, flag int) (*os.File, error)
+}
+
+// initFS sets up the fileSystem for the walk; the returned
+// function releases its resources once the walk is complete
+func (w *Walker) initFS() (func(), error) {
+	noop := func() {}
+	if w.replay != nil {
+		w.fs = replayFS{trace: w.replay}
+		return noop, nil
+	}
+	root := w.root
+	if w.pinRoot {
+		root = w.absRoot
+	}
+	if !w.confineToRoot {
+		if w.rootFile == nil {
+			w.fs = osFS{root: root}
+			return noop, nil
+		}
+		fsys, err := newFileFS(w.rootFile)
+		if err != nil {
+			return noop, err
+		}
+		w.fs = fsys
+		return noop, nil
+	}
+
+	dir := w.rootFile
+	closeDir := noop
+	if dir == nil {
+		var err error
+		dir, err = os.Open(root)
+		if err != nil {
+			// no path may be resolved without the root
+			// directory, so the walk can't go on
+			return noop, err
+		}
+		closeDir = func() { dir.Close() }
+	}
+	fsys, err := newConfinedFS(dir)
+	if err != nil {
+		closeDir()
+		return noop, err
+	}
+	w.fs = fsys
+	return closeDir, nil
+}
+
+// osPath returns the path of the file at relpath for accessing
+// it directly, rather than through the fileSystem
+func (w *Walker) osPath(relpath string) string {
+	if w.pinRoot {
+		return filepath.Join(w.absRoot, relpath)
+	}
+	return filepath.Join(w.root, relpath)
+}
+
+// userPath converts the path relative to the walker root
+// to the form presented to the user (see WithSlashPaths()
+// and WithPathMapper())
+func (w *Walker) userPath(relpath string) string {
+	if w.slashPaths {
+		relpath = filepath.ToSlash(relpath)
+	}
+	if w.pathMapper == nil {
+		return relpath
+	}
+	return w.pathMapper(relpath)
+}
+
+// osFS is the default fileSystem, which accesses files
+// by their paths joined with the root directory path
+type osFS struct {
+	root string
+}
+
+// the ReadDirNames function below was taken from the original
+// implementation (see https://golang.org/src/path/filepath/path.go)
+// but has sorting removed (sorting doesn't make sense
+// in concurrent execution, anyway)
+
+// ReadDirNames reads the directory named by relpath and returns
+// a list of directory entries. If reading fails midway, the names
+// obtained so far are returned along with the error.
+func (fsys osFS) ReadDirNames(relpath string) ([]string, error) {
+	f, err := os.Open(filepath.Join(fsys.root, relpath))
+	if err != nil {
+		return nil, err
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	return names, err
+}
+
+// Lstat is a wrapper for os.Lstat
+func (fsys osFS) Lstat(relpath string) (os.FileInfo, error) {
+	return os.Lstat(filepath.Join(fsys.root, relpath))
+}
+
+// Readlink is a wrapper for os.Readlink
+func (fsys osFS) Readlink(relpath string) (string, error) {
+	return os.Readlink(filepath.Join(fsys.root, relpath))
+}
+
+// Open is a wrapper for os.OpenFile
+func (fsys osFS) Open(relpath string, flag int) (*os.File, error) {
+	return os.OpenFile(filepath.Join(fsys.root, relpath), os.O_RDONLY|flag, 0)
+}
+
+// Stat evaluates the symlinks in the path
+// and returns the file info of the target
+func (fsys osFS) Stat(relpath string) (os.FileInfo, error) {
+	path, err := filepath.EvalSymlinks(filepath.Join(fsys.root, relpath))
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(path)
+}
@@ -0,0 +1,180 @@
+package cwalk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// oPath is the O_PATH open flag, which is missing
+// from the syscall package on some architectures
+const oPath = 0x200000
+
+// oNoAtime is the open flag which prevents access time updates
+const oNoAtime = syscall.O_NOATIME
+
+// openat2(2) definitions, missing from the syscall package
+// (the syscall number is in the sysnum files)
+const (
+	resolveNoMagiclinks = 0x02
+	resolveBeneath      = 0x08
+)
+
+// openHow is the struct open_how argument of openat2(2)
+type openHow struct {
+	flags   uint64
+	mode    uint64
+	resolve uint64
+}
+
+// fdFS is a fileSystem which resolves all paths relative
+// to an open directory file descriptor using openat(2),
+// so that the root can't be swapped during the walk.
+// If resolve flags are set, openat2(2) is used instead
+type fdFS struct {
+	dir     *os.File
+	resolve uint64
+}
+
+// newFileFS creates a fileSystem rooted at the open directory f
+func newFileFS(f *os.File) (fileSystem, error) {
+	return fdFS{dir: f}, nil
+}
+
+// newConfinedFS creates a fileSystem rooted at the open directory f,
+// which uses openat2(2) to make sure that path resolution never
+// escapes the root directory (neither via "..", nor via symlinks)
+// and never follows magic links, such as /proc/[pid]/fd/*
+func newConfinedFS(f *os.File) (fileSystem, error) {
+	fsys := fdFS{
+		dir:     f,
+		resolve: resolveBeneath | resolveNoMagiclinks,
+	}
+	// make sure openat2(2) is supported by the kernel (5.6+)
+	fd, err := fsys.open("", oPath)
+	if err != nil {
+		return nil, &os.SyscallError{Syscall: "openat2", Err: err}
+	}
+	syscall.Close(fd)
+	return fsys, nil
+}
+
+// open opens the path relative to the root directory
+func (fsys fdFS) open(relpath string, flags int) (int, error) {
+	if relpath == "" {
+		relpath = "."
+	}
+	for {
+		var fd int
+		var err error
+		if fsys.resolve == 0 {
+			fd, err = syscall.Openat(int(fsys.dir.Fd()), relpath, flags|syscall.O_CLOEXEC, 0)
+		} else {
+			fd, err = fsys.openat2(relpath, flags|syscall.O_CLOEXEC)
+		}
+		if err != syscall.EINTR {
+			return fd, err
+		}
+	}
+}
+
+// openat2 is a wrapper for the openat2(2) system call
+func (fsys fdFS) openat2(relpath string, flags int) (int, error) {
+	path, err := syscall.BytePtrFromString(relpath)
+	if err != nil {
+		return -1, err
+	}
+	how := openHow{
+		flags:   uint64(flags),
+		resolve: fsys.resolve,
+	}
+	fd, _, errno := syscall.Syscall6(sysOpenat2, fsys.dir.Fd(),
+		uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&how)), unsafe.Sizeof(how), 0, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// ReadDirNames reads the names of the directory entries
+func (fsys fdFS) ReadDirNames(relpath string) ([]string, error) {
+	fd, err := fsys.open(relpath, syscall.O_RDONLY|syscall.O_DIRECTORY)
+	if err != nil {
+		return nil, &os.PathError{Op: "openat", Path: relpath, Err: err}
+	}
+	f := os.NewFile(uintptr(fd), relpath)
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	return names, err
+}
+
+// Open opens the file for reading, with additional open flags
+func (fsys fdFS) Open(relpath string, flag int) (*os.File, error) {
+	fd, err := fsys.open(relpath, syscall.O_RDONLY|flag)
+	if err != nil {
+		return nil, &os.PathError{Op: "openat", Path: relpath, Err: err}
+	}
+	return os.NewFile(uintptr(fd), relpath), nil
+}
+
+// stat opens the path with O_PATH and calls fstat(2) on it
+func (fsys fdFS) stat(op, relpath string, flags int) (os.FileInfo, error) {
+	fd, err := fsys.open(relpath, oPath|flags)
+	if err != nil {
+		return nil, &os.PathError{Op: op, Path: relpath, Err: err}
+	}
+	defer syscall.Close(fd)
+
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		return nil, &os.PathError{Op: op, Path: relpath, Err: err}
+	}
+	name := filepath.Base(relpath)
+	if relpath == "" {
+		name = filepath.Base(fsys.dir.Name())
+	}
+	if flags&syscall.O_NOFOLLOW == 0 {
+		// report the name of the symlink target, just like osFS does
+		if target, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", fd)); err == nil {
+			name = filepath.Base(target)
+		}
+	}
+	return newStatInfo(name, &st), nil
+}
+
+// Lstat returns the file info without following symlinks
+func (fsys fdFS) Lstat(relpath string) (os.FileInfo, error) {
+	return fsys.stat("lstat", relpath, syscall.O_NOFOLLOW)
+}
+
+// Stat returns the file info of the symlink target
+func (fsys fdFS) Stat(relpath string) (os.FileInfo, error) {
+	return fsys.stat("stat", relpath, 0)
+}
+
+// Readlink opens the symlink with O_PATH and calls readlinkat(2) on it
+func (fsys fdFS) Readlink(relpath string) (string, error) {
+	fd, err := fsys.open(relpath, oPath|syscall.O_NOFOLLOW)
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: relpath, Err: err}
+	}
+	defer syscall.Close(fd)
+
+	empty, err := syscall.BytePtrFromString("")
+	if err != nil {
+		return "", err
+	}
+	for size := 128; ; size *= 2 {
+		buf := make([]byte, size)
+		n, _, errno := syscall.Syscall6(syscall.SYS_READLINKAT, uintptr(fd),
+			uintptr(unsafe.Pointer(empty)), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), 0, 0)
+		if errno != 0 {
+			return "", &os.PathError{Op: "readlink", Path: relpath, Err: errno}
+		}
+		if int(n) < size {
+			return string(buf[:n]), nil
+		}
+	}
+}
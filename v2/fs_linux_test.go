@@ -0,0 +1,141 @@
+package cwalk
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+func TestConfineToRoot(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	walked := map[string]error{}
+	err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+		mu.Lock()
+		walked[path] = err
+		mu.Unlock()
+		return nil
+	}, WithConfineToRoot(), WithFollowSymlinks())
+	if errors.Is(err, syscall.ENOSYS) || errors.Is(err, syscall.EPERM) {
+		t.Skip("openat2 not available:", err)
+	}
+	if err == nil {
+		t.Error("Walk returned no error for the escaping symlink")
+	}
+	if err := walked["escape"]; !errors.Is(err, syscall.EXDEV) {
+		t.Errorf("walkFn got %v for the escaping symlink, want EXDEV", err)
+	}
+	if _, ok := walked[filepath.Join("escape", "secret")]; ok {
+		t.Errorf("the walk escaped the root: %v", walked)
+	}
+	if err, ok := walked["file"]; !ok || err != nil {
+		t.Errorf("file not walked: %v", walked)
+	}
+}
+
+func TestConfineToRootMissingRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "missing")
+	called := false
+	err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+		called = true
+		return nil
+	}, WithConfineToRoot())
+	if !os.IsNotExist(err) {
+		t.Errorf("Walk returned %v, want a not exist error", err)
+	}
+	if called {
+		t.Error("walkFn called for a root which couldn't be opened")
+	}
+}
+
+// linkMeta returns the metadata of the entries of the walk of root
+func linkMeta(t *testing.T, root string, opts ...Option) map[string]*Metadata {
+	var mu sync.Mutex
+	meta := map[string]*Metadata{}
+	w := NewWalker(root, append(opts, WithEnrichedMetadata())...)
+	err := w.WalkEntries("", func(entry *Entry, err error) error {
+		mu.Lock()
+		meta[entry.Path] = entry.Meta
+		mu.Unlock()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return meta
+}
+
+func TestConfineToRootMetadata(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("dir", filepath.Join(root, "inside")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newConfinedFS(mustOpen(t, root)); err != nil {
+		t.Skip("openat2 not available:", err)
+	}
+
+	meta := linkMeta(t, root)
+	if m := meta["escape"]; m.LinkTarget != outside || m.LinkBroken || m.LinkType != os.ModeDir {
+		t.Errorf("unconfined: got %+v for the escaping symlink", m)
+	}
+	// the target outside the root can't be resolved by a confined walk
+	meta = linkMeta(t, root, WithConfineToRoot())
+	if m := meta["escape"]; m.LinkTarget != outside || !m.LinkBroken || m.LinkType != 0 {
+		t.Errorf("confined: got %+v for the escaping symlink", m)
+	}
+	if m := meta["inside"]; m.LinkTarget != "dir" || m.LinkBroken || m.LinkType != os.ModeDir {
+		t.Errorf("confined: got %+v for the symlink inside the root", m)
+	}
+}
+
+func TestTraceReplayMetadata(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Symlink("target", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	var trace bytes.Buffer
+	linkMeta(t, root, WithTraceRecording(&trace))
+	tr, err := LoadTrace(&trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the symlink target is served from the trace,
+	// even once the symlink is gone
+	if err := os.Remove(filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if m := linkMeta(t, root, WithTraceReplay(tr))["link"]; m.LinkTarget != "target" || !m.LinkBroken {
+		t.Errorf("got %+v for the replayed symlink", m)
+	}
+}
+
+func mustOpen(t *testing.T, name string) *os.File {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
@@ -0,0 +1,35 @@
+//go:build !linux
+// +build !linux
+
+package cwalk
+
+import "os"
+
+// oNoAtime is zero, as there is no open flag which
+// prevents access time updates on this platform
+const oNoAtime = 0
+
+// newFileFS creates a fileSystem rooted at the open directory f.
+// On this platform, paths are still resolved relative to the name of f,
+// so the best we can do is to check that the name refers to f
+// before the walk starts
+func newFileFS(f *os.File) (fileSystem, error) {
+	opened, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	current, err := os.Stat(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	if !os.SameFile(opened, current) {
+		return nil, ErrRootMoved
+	}
+	return osFS{root: f.Name()}, nil
+}
+
+// newConfinedFS reports that confining the walk
+// to the root is not supported on this platform
+func newConfinedFS(f *os.File) (fileSystem, error) {
+	return nil, ErrConfineUnsupported
+}
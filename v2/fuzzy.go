@@ -0,0 +1,87 @@
+package cwalk
+
+import (
+	"fmt"
+	"io"
+)
+
+// FuzzyHasher is a similarity (fuzzy) hashing algorithm, such as
+// ssdeep or TLSH, plugged into the walk with WithFuzzyHashing();
+// implementations typically wrap a third-party package
+type FuzzyHasher interface {
+	// Name identifies the algorithm in the digests, e.g. "tlsh"
+	Name() string
+
+	// New returns a new hash, to which the content of a file of the
+	// given size is written; it is called by the workers, so it must
+	// be safe for concurrent use
+	New(size int64) FuzzyHash
+}
+
+// FuzzyHash computes the similarity digest of the content written to it
+type FuzzyHash interface {
+	io.Writer
+
+	// Digest returns the digest of the content written so far, or
+	// an error if none can be computed (e.g. the content is too short
+	// or not varied enough for the algorithm)
+	Digest() (string, error)
+}
+
+// FuzzyDigest is the similarity digest of a file computed by a hasher
+type FuzzyDigest struct {
+	Hasher string // FuzzyHasher.Name()
+	Digest string
+	Err    error // set if the hasher couldn't compute a digest
+}
+
+// FuzzyFunc receives the similarity digests of a file, in the order
+// of the hashers passed to WithFuzzyHashing()
+type FuzzyFunc func(entry *Entry, digests []FuzzyDigest) error
+
+// FuzzyLimits are the sizes of the files WithFuzzyHashing() hashes:
+// files smaller than MinSize carry too little content to be compared,
+// and files larger than MaxSize (if not zero) would take too long
+type FuzzyLimits struct {
+	MinSize int64
+	MaxSize int64
+}
+
+// WithFuzzyHashing adds a content stage (see WithContentStage()) which
+// computes the similarity digests of the regular files within limits
+// with every hasher, reading each file once, and passes them to fn, e.g.
+// for malware triage on file servers. The files are hashed by the
+// workers, so fn must be safe for concurrent use; it isn't called for
+// the files out of limits. A hasher failing to compute a digest for
+// a file doesn't fail the file, see FuzzyDigest.Err.
+func WithFuzzyHashing(limits FuzzyLimits, fn FuzzyFunc, hashers ...FuzzyHasher) Option {
+	return func(w *Walker) {
+		if len(hashers) == 0 || limits.MinSize < 0 || limits.MaxSize < 0 ||
+			limits.MaxSize > 0 && limits.MaxSize < limits.MinSize {
+			w.setOptionError(fmt.Errorf("Invalid fuzzy hashing settings: %d hashers, %+v", len(hashers), limits))
+			return
+		}
+		w.stages = append(w.stages, func(entry *Entry, r io.Reader) error {
+			size := entry.Info.Size()
+			if size < limits.MinSize || limits.MaxSize > 0 && size > limits.MaxSize {
+				return nil
+			}
+			hashes := make([]FuzzyHash, len(hashers))
+			writers := make([]io.Writer, len(hashers))
+			for i, h := range hashers {
+				hashes[i] = h.New(size)
+				writers[i] = hashes[i]
+			}
+			r = io.LimitReader(r, size) // the file may grow meanwhile
+			if _, err := io.Copy(io.MultiWriter(writers...), r); err != nil {
+				return err
+			}
+			digests := make([]FuzzyDigest, len(hashers))
+			for i, h := range hashers {
+				digests[i].Hasher = h.Name()
+				digests[i].Digest, digests[i].Err = hashes[i].Digest()
+			}
+			return fn(entry, digests)
+		})
+	}
+}
@@ -0,0 +1,207 @@
+//go:build ignore
+// +build ignore
+
+// gen_normtable generates normtable.go, the Unicode tables used by
+// decomposeName(), from UnicodeData.txt. Run it with go generate; the
+// data file is downloaded from unicode.org unless -data names a local
+// copy.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	version = flag.String("version", "14.0.0", "Unicode version")
+	data    = flag.String("data", "", "path of UnicodeData.txt (downloaded if empty)")
+	output  = flag.String("output", "normtable.go", "output file")
+)
+
+// hangulFirst and hangulLast delimit the Hangul syllables,
+// which are decomposed algorithmically
+const (
+	hangulFirst = 0xAC00
+	hangulLast  = 0xD7A3
+)
+
+func main() {
+	flag.Parse()
+	r, err := open()
+	if err != nil {
+		log.Fatal(err)
+	}
+	decomps, classes, err := parse(r)
+	r.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated from UnicodeData.txt (Unicode %s); DO NOT EDIT.\n\n", *version)
+	b.WriteString("package cwalk\n\n")
+	b.WriteString("// canonicalDecompositions maps the characters which have a canonical\n")
+	b.WriteString("// decomposition to their full decomposition, except for the Hangul\n")
+	b.WriteString("// syllables, which are decomposed algorithmically\n")
+	b.WriteString("var canonicalDecompositions = map[rune]string{\n")
+	var runes []rune
+	for r := range decomps {
+		if r < hangulFirst || r > hangulLast {
+			runes = append(runes, r)
+		}
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	var items []string
+	for _, r := range runes {
+		items = append(items, fmt.Sprintf("0x%04X: %s,", r, literal(full(decomps, r))))
+	}
+	writeRows(&b, items)
+	b.WriteString("}\n\n")
+
+	b.WriteString("// combiningClassRanges are the ranges of characters with a non-zero\n")
+	b.WriteString("// canonical combining class, which are reordered by decomposeName()\n")
+	b.WriteString("var combiningClassRanges = []struct {\n\tlo, hi rune\n\tclass  uint8\n}{\n")
+	items = nil
+	for _, rg := range ranges(classes) {
+		items = append(items, fmt.Sprintf("{0x%04X, 0x%04X, %d},", rg.lo, rg.hi, rg.class))
+	}
+	writeRows(&b, items)
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// open opens the data file, downloading it if no path is given
+func open() (io.ReadCloser, error) {
+	if *data != "" {
+		return os.Open(*data)
+	}
+	url := fmt.Sprintf("https://www.unicode.org/Public/%s/ucd/UnicodeData.txt", *version)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// parse reads the canonical decompositions (a single level, as listed)
+// and the non-zero canonical combining classes from the data file
+func parse(r io.Reader) (map[rune][]rune, map[rune]uint8, error) {
+	decomps := map[rune][]rune{}
+	classes := map[rune]uint8{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Split(s.Text(), ";")
+		if len(fields) < 6 {
+			continue
+		}
+		code, err := strconv.ParseUint(fields[0], 16, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid code point %q", fields[0])
+		}
+		class, err := strconv.ParseUint(fields[3], 10, 8)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid combining class %q", fields[3])
+		}
+		if class != 0 {
+			classes[rune(code)] = uint8(class)
+		}
+		// compatibility decompositions start with a <tag>
+		if fields[5] == "" || strings.HasPrefix(fields[5], "<") {
+			continue
+		}
+		var d []rune
+		for _, h := range strings.Fields(fields[5]) {
+			c, err := strconv.ParseUint(h, 16, 32)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid decomposition %q", fields[5])
+			}
+			d = append(d, rune(c))
+		}
+		decomps[rune(code)] = d
+	}
+	return decomps, classes, s.Err()
+}
+
+// full returns the full canonical decomposition of r
+func full(decomps map[rune][]rune, r rune) []rune {
+	d, ok := decomps[r]
+	if !ok {
+		return []rune{r}
+	}
+	var out []rune
+	for _, c := range d {
+		out = append(out, full(decomps, c)...)
+	}
+	return out
+}
+
+// classRange is a range of characters with the same combining class
+type classRange struct {
+	lo, hi rune
+	class  uint8
+}
+
+// ranges merges the consecutive characters with the same class
+func ranges(classes map[rune]uint8) []classRange {
+	var runes []rune
+	for r := range classes {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	var out []classRange
+	for _, r := range runes {
+		if n := len(out); n > 0 && out[n-1].hi == r-1 && out[n-1].class == classes[r] {
+			out[n-1].hi = r
+			continue
+		}
+		out = append(out, classRange{lo: r, hi: r, class: classes[r]})
+	}
+	return out
+}
+
+// literal formats the runes as a string literal of escapes
+func literal(runes []rune) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range runes {
+		if r < 0x10000 {
+			fmt.Fprintf(&b, `\u%04X`, r)
+		} else {
+			fmt.Fprintf(&b, `\U%08X`, r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// writeRows writes the items four per line
+func writeRows(b *bytes.Buffer, items []string) {
+	for i := 0; i < len(items); i += 4 {
+		end := i + 4
+		if end > len(items) {
+			end = len(items)
+		}
+		b.WriteString("\t" + strings.Join(items[i:end], " ") + "\n")
+	}
+}
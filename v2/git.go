@@ -0,0 +1,334 @@
+package cwalk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// GitMode selects the files passed to the callback by WithGitFilter()
+type GitMode int
+
+const (
+	// GitTracked selects the files tracked in the git index
+	GitTracked GitMode = iota + 1
+
+	// GitUntracked selects the files not tracked in the git index
+	// (including the ignored ones)
+	GitUntracked
+
+	// GitModified selects the tracked files which differ from the HEAD
+	// commit (like git diff HEAD): the files which changes are staged,
+	// and the ones which size or modification time differs from the
+	// ones recorded in the git index. The contents are not compared,
+	// so files which are modified within the timestamp granularity of
+	// the index write (whose contents git would compare) are not
+	// selected, unless their size changed.
+	GitModified
+)
+
+// String returns the name of the mode
+func (m GitMode) String() string {
+	switch m {
+	case GitTracked:
+		return "tracked"
+	case GitUntracked:
+		return "untracked"
+	case GitModified:
+		return "modified"
+	}
+	return fmt.Sprintf("GitMode(%d)", int(m))
+}
+
+// ErrNoGitRepo indicates that WithGitFilter() could not find
+// a git repository containing the walker root
+var ErrNoGitRepo = errors.New("Not inside a git repository")
+
+// gitIndexEntry holds the stat data and the object
+// recorded in the git index
+type gitIndexEntry struct {
+	mtimeSec  uint32
+	mtimeNsec uint32
+	size      uint32
+	mode      uint32
+	id        string // the raw object ID
+}
+
+// WithGitFilter makes the walk pass to the callback only the files
+// selected by mode, based on the index of the git repository containing
+// the walker root (the index file, and for GitModified the objects of
+// the HEAD commit, are parsed directly, so git doesn't need to be
+// installed). Directories are always passed to the callback with
+// GitUntracked, and only if they contain tracked files otherwise;
+// the .git directory itself is pruned. If the repository can't
+// be read, the walk fails with the corresponding error.
+func WithGitFilter(mode GitMode) Option {
+	return func(w *Walker) {
+		prefix, gitDir, index, err := loadGitIndex(w.root)
+		if err != nil {
+			w.setOptionError(err)
+			return
+		}
+
+		// the tracked files which changes are staged
+		staged := map[string]bool{}
+		if mode == GitModified {
+			head, err := readGitHead(gitDir)
+			if err != nil {
+				w.setOptionError(err)
+				return
+			}
+			for p, entry := range index {
+				if h, ok := head[p]; !ok || h.mode != entry.mode || h.id != entry.id {
+					staged[p] = true
+				}
+			}
+		}
+
+		// directories containing tracked files
+		dirs := map[string]bool{}
+		for p := range index {
+			for d := path.Dir(p); d != "." && !dirs[d]; d = path.Dir(d) {
+				dirs[d] = true
+			}
+		}
+
+		w.addFilter(fmt.Sprintf("git filter (%s)", mode), w.exceptRoot(func(relpath string, info os.FileInfo) FilterAction {
+			p := path.Join(prefix, filepath.ToSlash(relpath))
+			if info == nil {
+				return FilterInclude
+			}
+			if info.IsDir() {
+				if info.Name() == ".git" {
+					return FilterPrune
+				}
+				if mode == GitUntracked || dirs[p] {
+					return FilterInclude
+				}
+				return FilterPrune
+			}
+
+			entry, tracked := index[p]
+			switch mode {
+			case GitTracked:
+				if tracked {
+					return FilterInclude
+				}
+			case GitUntracked:
+				if !tracked {
+					return FilterInclude
+				}
+			case GitModified:
+				if tracked && (staged[p] || entry.modified(info)) {
+					return FilterInclude
+				}
+			}
+			return FilterExclude
+		}))
+	}
+}
+
+// modified reports whether the file info differs
+// from the stat data recorded in the index
+func (e gitIndexEntry) modified(info os.FileInfo) bool {
+	mtime := info.ModTime()
+	if uint32(info.Size()) != e.size || uint32(mtime.Unix()) != e.mtimeSec {
+		return true
+	}
+	return e.mtimeNsec != 0 && uint32(mtime.Nanosecond()) != e.mtimeNsec
+}
+
+// loadGitIndex finds the git repository containing root and reads its index;
+// prefix is the slash-separated path of root relative to the work tree
+func loadGitIndex(root string) (prefix, gitDir string, index map[string]gitIndexEntry, err error) {
+	_, prefix, gitDir, err = findWorkTree(root)
+	if err != nil {
+		return "", "", nil, err
+	}
+	index, err = readGitIndex(gitDir)
+	return prefix, gitDir, index, err
+}
+
+// findWorkTree finds the top of the git work tree containing root,
+// and its git directory; prefix is the slash-separated path of root
+// relative to the top
+func findWorkTree(root string) (top, prefix, gitDir string, err error) {
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		return "", "", "", err
+	}
+	var rel []string
+	for {
+		gitDir, err := findGitDir(dir)
+		if err != nil {
+			return "", "", "", err
+		}
+		if gitDir != "" {
+			for i, j := 0, len(rel)-1; i < j; i, j = i+1, j-1 {
+				rel[i], rel[j] = rel[j], rel[i]
+			}
+			return dir, path.Join(rel...), gitDir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", "", ErrNoGitRepo
+		}
+		rel = append(rel, filepath.Base(dir))
+		dir = parent
+	}
+}
+
+// findGitDir returns the git directory of the work tree at dir,
+// or an empty string if dir is not the top of a work tree
+func findGitDir(dir string) (string, error) {
+	gitPath := filepath.Join(dir, ".git")
+	info, err := os.Stat(gitPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return gitPath, nil
+	}
+
+	// a .git file pointing to the git directory (worktrees, submodules)
+	data, err := ioutil.ReadFile(gitPath)
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", fmt.Errorf("Invalid .git file: %s", gitPath)
+	}
+	gitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	return gitDir, nil
+}
+
+// gitHashSize returns the size of object IDs used by the repository
+func gitHashSize(gitDir string) int {
+	f, err := os.Open(filepath.Join(gitDir, "config"))
+	if err != nil {
+		return 20
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.ToLower(strings.Join(strings.Fields(s.Text()), ""))
+		if line == "objectformat=sha256" {
+			return 32
+		}
+	}
+	return 20
+}
+
+// readGitIndex parses the index file (versions 2 to 4)
+// of the git directory, see Documentation/gitformat-index.txt
+func readGitIndex(gitDir string) (map[string]gitIndexEntry, error) {
+	data, err := ioutil.ReadFile(filepath.Join(gitDir, "index"))
+	if os.IsNotExist(err) {
+		// a fresh repository without an index yet
+		return map[string]gitIndexEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	invalid := fmt.Errorf("Invalid git index: %s", filepath.Join(gitDir, "index"))
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, invalid
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("Unsupported git index version %d", version)
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+	hashSize := gitHashSize(gitDir)
+
+	const statSize = 40 // ctime, mtime, dev, ino, mode, uid, gid, size
+	index := make(map[string]gitIndexEntry, count)
+	pos := 12
+	prev := ""
+	for i := uint32(0); i < count; i++ {
+		start := pos
+		if pos+statSize+hashSize+2 > len(data) {
+			return nil, invalid
+		}
+		entry := gitIndexEntry{
+			mtimeSec:  binary.BigEndian.Uint32(data[pos+8:]),
+			mtimeNsec: binary.BigEndian.Uint32(data[pos+12:]),
+			mode:      binary.BigEndian.Uint32(data[pos+24:]),
+			size:      binary.BigEndian.Uint32(data[pos+36:]),
+			id:        string(data[pos+statSize : pos+statSize+hashSize]),
+		}
+		pos += statSize + hashSize
+		flags := binary.BigEndian.Uint16(data[pos:])
+		pos += 2
+		if version >= 3 && flags&0x4000 != 0 {
+			pos += 2 // extended flags
+		}
+
+		var name string
+		if version == 4 {
+			// the name is prefix-compressed against the previous one
+			strip, n := gitVarint(data[pos:])
+			if n <= 0 || strip > uint64(len(prev)) {
+				return nil, invalid
+			}
+			pos += n
+			end := bytes.IndexByte(data[pos:], 0)
+			if end < 0 {
+				return nil, invalid
+			}
+			name = prev[:len(prev)-int(strip)] + string(data[pos:pos+end])
+			pos += end + 1
+		} else {
+			end := bytes.IndexByte(data[pos:], 0)
+			if end < 0 {
+				return nil, invalid
+			}
+			name = string(data[pos : pos+end])
+			// entries are NUL-padded to a multiple of 8 bytes
+			pos = start + (pos+end-start+8)&^7
+		}
+		prev = name
+
+		// skip the entries of the merge stages other than 0
+		if flags>>12&3 == 0 {
+			index[name] = entry
+		}
+	}
+	return index, nil
+}
+
+// gitVarint decodes the offset varint of git (see decode_varint()
+// in varint.c), which unlike the one of encoding/binary is big-endian
+// and adds one to each continued group, so there is only one encoding
+// of each value; n is the number of bytes read, or 0 if b is too short
+// or the value overflows
+func gitVarint(b []byte) (val uint64, n int) {
+	for i, c := range b {
+		if i > 0 {
+			if val+1 > 1<<57-1 {
+				return 0, 0
+			}
+			val = (val+1)<<7 | uint64(c&127)
+		} else {
+			val = uint64(c & 127)
+		}
+		if c&128 == 0 {
+			return val, i + 1
+		}
+	}
+	return 0, 0
+}
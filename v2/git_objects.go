@@ -0,0 +1,438 @@
+package cwalk
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the types of git objects, as numbered in the pack files
+const (
+	gitCommit   = 1
+	gitTree     = 2
+	gitBlob     = 3
+	gitTag      = 4
+	gitOfsDelta = 6
+	gitRefDelta = 7
+)
+
+// gitTreeEntry is a file of the tree of a commit
+type gitTreeEntry struct {
+	mode uint32
+	id   string // the raw object ID
+}
+
+// gitObjects reads the objects of a repository, from the loose object
+// files and from the pack files (see Documentation/gitformat-pack.txt)
+type gitObjects struct {
+	dirs     []string // the object directories, including the alternates
+	packs    []*gitPack
+	hashSize int
+}
+
+// gitPack is a pack file along with its index (version 2)
+type gitPack struct {
+	f       *os.File
+	fanout  []byte // 256 big-endian counts
+	ids     []byte // the sorted object IDs
+	offsets []byte // 4 bytes per object
+	large   []byte // 8 bytes per offset beyond 2GiB
+}
+
+// openGitObjects opens the object directory and its alternates;
+// the objects must be closed once read
+func openGitObjects(dir string, hashSize int) (*gitObjects, error) {
+	o := &gitObjects{dirs: []string{dir}, hashSize: hashSize}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "info", "alternates")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line == "" || line[0] == '#' {
+				continue
+			}
+			if !filepath.IsAbs(line) {
+				line = filepath.Join(dir, line)
+			}
+			o.dirs = append(o.dirs, line)
+		}
+	}
+	for _, dir := range o.dirs {
+		idxs, err := filepath.Glob(filepath.Join(dir, "pack", "*.idx"))
+		if err != nil {
+			o.close()
+			return nil, err
+		}
+		for _, idx := range idxs {
+			p, err := openGitPack(idx, hashSize)
+			if os.IsNotExist(err) {
+				continue // removed by a concurrent repack
+			}
+			if err != nil {
+				o.close()
+				return nil, err
+			}
+			o.packs = append(o.packs, p)
+		}
+	}
+	return o, nil
+}
+
+// openGitPack reads the pack index, and opens its pack file
+func openGitPack(idx string, hashSize int) (*gitPack, error) {
+	data, err := ioutil.ReadFile(idx)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8+256*4 || string(data[:4]) != "\xfftOc" || binary.BigEndian.Uint32(data[4:]) != 2 {
+		return nil, fmt.Errorf("Unsupported git pack index: %s", idx)
+	}
+	p := &gitPack{fanout: data[8 : 8+256*4]}
+	n := int(binary.BigEndian.Uint32(p.fanout[255*4:]))
+	pos := 8 + 256*4
+	if len(data) < pos+n*(hashSize+8) {
+		return nil, fmt.Errorf("Invalid git pack index: %s", idx)
+	}
+	p.ids = data[pos : pos+n*hashSize]
+	pos += n*hashSize + n*4 // skipping the CRCs
+	p.offsets = data[pos : pos+n*4]
+	p.large = data[pos+n*4:]
+	if p.f, err = os.Open(strings.TrimSuffix(idx, ".idx") + ".pack"); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// close closes the pack files
+func (o *gitObjects) close() {
+	for _, p := range o.packs {
+		p.f.Close()
+	}
+}
+
+// read returns the type and the content of the object
+func (o *gitObjects) read(id []byte) (int, []byte, error) {
+	for _, p := range o.packs {
+		if offset, ok := p.find(id, o.hashSize); ok {
+			return o.readPacked(p, offset)
+		}
+	}
+	for _, dir := range o.dirs {
+		name := hex.EncodeToString(id)
+		typ, data, err := readLooseObject(filepath.Join(dir, name[:2], name[2:]))
+		if !os.IsNotExist(err) {
+			return typ, data, err
+		}
+	}
+	return 0, nil, fmt.Errorf("Missing git object %x", id)
+}
+
+// find returns the offset of the object in the pack file
+func (p *gitPack) find(id []byte, hashSize int) (int64, bool) {
+	lo := 0
+	if id[0] > 0 {
+		lo = int(binary.BigEndian.Uint32(p.fanout[(int(id[0])-1)*4:]))
+	}
+	hi := int(binary.BigEndian.Uint32(p.fanout[int(id[0])*4:]))
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(p.ids[(lo+i)*hashSize:(lo+i+1)*hashSize], id) >= 0
+	})
+	if i == hi || !bytes.Equal(p.ids[i*hashSize:(i+1)*hashSize], id) {
+		return 0, false
+	}
+	offset := binary.BigEndian.Uint32(p.offsets[i*4:])
+	if offset&0x80000000 == 0 {
+		return int64(offset), true
+	}
+	i = int(offset & 0x7fffffff)
+	if len(p.large) < (i+1)*8 {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(p.large[i*8:])), true
+}
+
+// readPacked reads the object at the offset of the pack file,
+// applying the deltas it is stored as
+func (o *gitObjects) readPacked(p *gitPack, offset int64) (int, []byte, error) {
+	invalid := fmt.Errorf("Invalid git pack: %s", p.f.Name())
+	r := bufio.NewReader(io.NewSectionReader(p.f, offset, 1<<62))
+	c, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	typ, size := int(c>>4&7), uint64(c&15)
+	for shift := 4; c&0x80 != 0; shift += 7 {
+		if c, err = r.ReadByte(); err != nil || shift > 57 {
+			return 0, nil, invalid
+		}
+		size |= uint64(c&0x7f) << shift
+	}
+
+	var baseType int
+	var base []byte
+	switch typ {
+	case gitCommit, gitTree, gitBlob, gitTag:
+	case gitOfsDelta:
+		// the same encoding as the offsets of the index, see gitVarint()
+		var rel uint64
+		for i := 0; ; i++ {
+			if c, err = r.ReadByte(); err != nil || i > 8 {
+				return 0, nil, invalid
+			}
+			if i > 0 {
+				rel++
+			}
+			rel = rel<<7 | uint64(c&0x7f)
+			if c&0x80 == 0 {
+				break
+			}
+		}
+		if rel == 0 || rel > uint64(offset) {
+			return 0, nil, invalid
+		}
+		baseType, base, err = o.readPacked(p, offset-int64(rel))
+	case gitRefDelta:
+		id := make([]byte, o.hashSize)
+		if _, err := io.ReadFull(r, id); err != nil {
+			return 0, nil, invalid
+		}
+		baseType, base, err = o.read(id)
+	default:
+		return 0, nil, invalid
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return 0, nil, invalid
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(zr, data); err != nil {
+		return 0, nil, invalid
+	}
+	if base == nil {
+		return typ, data, nil
+	}
+	if data, err = applyGitDelta(base, data); err != nil {
+		return 0, nil, invalid
+	}
+	return baseType, data, nil
+}
+
+// applyGitDelta rebuilds an object from its base and a delta, which is
+// a sequence of instructions copying parts of the base or inserting data
+func applyGitDelta(base, delta []byte) ([]byte, error) {
+	invalid := fmt.Errorf("Invalid git delta")
+	srcSize, n := binary.Uvarint(delta)
+	if n <= 0 || srcSize != uint64(len(base)) {
+		return nil, invalid
+	}
+	delta = delta[n:]
+	dstSize, n := binary.Uvarint(delta)
+	if n <= 0 {
+		return nil, invalid
+	}
+	delta = delta[n:]
+
+	out := make([]byte, 0, dstSize)
+	for len(delta) > 0 {
+		c := delta[0]
+		delta = delta[1:]
+		if c == 0 {
+			return nil, invalid
+		}
+		if c&0x80 == 0 {
+			if int(c) > len(delta) {
+				return nil, invalid
+			}
+			out = append(out, delta[:c]...)
+			delta = delta[c:]
+			continue
+		}
+		// the bits tell which bytes of the offset and size follow
+		var offset, size int
+		for i := uint(0); i < 7; i++ {
+			if c&(1<<i) == 0 {
+				continue
+			}
+			if len(delta) == 0 {
+				return nil, invalid
+			}
+			if i < 4 {
+				offset |= int(delta[0]) << (8 * i)
+			} else {
+				size |= int(delta[0]) << (8 * (i - 4))
+			}
+			delta = delta[1:]
+		}
+		if size == 0 {
+			size = 0x10000
+		}
+		if offset+size > len(base) {
+			return nil, invalid
+		}
+		out = append(out, base[offset:offset+size]...)
+	}
+	if uint64(len(out)) != dstSize {
+		return nil, invalid
+	}
+	return out, nil
+}
+
+// readLooseObject reads the object file
+func readLooseObject(name string) (int, []byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Invalid git object: %s", name)
+	}
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Invalid git object: %s", name)
+	}
+	// the content is preceded by "<type> <size>\x00"
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return 0, nil, fmt.Errorf("Invalid git object: %s", name)
+	}
+	header := strings.Fields(string(data[:end]))
+	types := map[string]int{"commit": gitCommit, "tree": gitTree, "blob": gitBlob, "tag": gitTag}
+	if len(header) != 2 || types[header[0]] == 0 || header[1] != strconv.Itoa(len(data)-end-1) {
+		return 0, nil, fmt.Errorf("Invalid git object: %s", name)
+	}
+	return types[header[0]], data[end+1:], nil
+}
+
+// readGitHead returns the files of the tree of the HEAD commit,
+// by their slash-separated paths relative to the top of the
+// work tree; the tree is empty if there is no commit yet
+func readGitHead(gitDir string) (map[string]gitTreeEntry, error) {
+	// the refs and objects of linked work trees are in the common directory
+	commonDir := gitDir
+	if data, err := ioutil.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		if commonDir = strings.TrimSpace(string(data)); !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+	}
+	hashSize := gitHashSize(commonDir)
+	commit, err := resolveGitRef(gitDir, commonDir, "HEAD")
+	if err != nil || commit == nil {
+		return map[string]gitTreeEntry{}, err
+	}
+
+	o, err := openGitObjects(filepath.Join(commonDir, "objects"), hashSize)
+	if err != nil {
+		return nil, err
+	}
+	defer o.close()
+	typ, data, err := o.read(commit)
+	if err != nil {
+		return nil, err
+	}
+	if typ != gitCommit || !bytes.HasPrefix(data, []byte("tree ")) {
+		return nil, fmt.Errorf("Invalid git commit %x", commit)
+	}
+	tree, err := hex.DecodeString(string(bytes.SplitN(data[5:], []byte("\n"), 2)[0]))
+	if err != nil || len(tree) != hashSize {
+		return nil, fmt.Errorf("Invalid git commit %x", commit)
+	}
+	files := map[string]gitTreeEntry{}
+	return files, o.readTree(tree, "", files)
+}
+
+// resolveGitRef returns the commit the ref points to, following the
+// symbolic refs, or nil if it doesn't exist yet (an unborn branch)
+func resolveGitRef(gitDir, commonDir, ref string) ([]byte, error) {
+	for depth := 0; depth < 5; depth++ {
+		// HEAD and the other pseudo-refs are specific to the work tree
+		dir := commonDir
+		if !strings.Contains(ref, "/") {
+			dir = gitDir
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(ref)))
+		if os.IsNotExist(err) {
+			return findPackedRef(commonDir, ref)
+		}
+		if err != nil {
+			return nil, err
+		}
+		line := strings.TrimSpace(string(data))
+		if strings.HasPrefix(line, "ref:") {
+			ref = strings.TrimSpace(strings.TrimPrefix(line, "ref:"))
+			continue
+		}
+		id, err := hex.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid git ref %s: %s", ref, line)
+		}
+		return id, nil
+	}
+	return nil, fmt.Errorf("Too many levels of symbolic git refs: %s", ref)
+}
+
+// findPackedRef looks the ref up in the packed-refs file
+func findPackedRef(commonDir, ref string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(commonDir, "packed-refs"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == ref {
+			return hex.DecodeString(fields[0])
+		}
+	}
+	return nil, nil
+}
+
+// readTree adds the files of the tree, and of its subtrees,
+// to files, prefixing their paths with dir
+func (o *gitObjects) readTree(id []byte, dir string, files map[string]gitTreeEntry) error {
+	typ, data, err := o.read(id)
+	if err != nil {
+		return err
+	}
+	if typ != gitTree {
+		return fmt.Errorf("Invalid git tree %x", id)
+	}
+	// the entries are "<octal mode> <name>\x00<raw object ID>"
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		end := bytes.IndexByte(data, 0)
+		if sp < 0 || end < sp || len(data) < end+1+o.hashSize {
+			return fmt.Errorf("Invalid git tree %x", id)
+		}
+		mode, err := strconv.ParseUint(string(data[:sp]), 8, 32)
+		if err != nil {
+			return fmt.Errorf("Invalid git tree %x", id)
+		}
+		name := path.Join(dir, string(data[sp+1:end]))
+		entry := data[end+1 : end+1+o.hashSize]
+		data = data[end+1+o.hashSize:]
+		if mode == 040000 {
+			if err := o.readTree(entry, name, files); err != nil {
+				return err
+			}
+			continue
+		}
+		files[name] = gitTreeEntry{mode: uint32(mode), id: string(entry)}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package cwalk
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestGitVarint(t *testing.T) {
+	// encodings produced by encode_varint() in git's varint.c
+	for _, c := range []struct {
+		b   []byte
+		val uint64
+	}{
+		{[]byte{0x00}, 0},
+		{[]byte{0x7f}, 127},
+		{[]byte{0x80, 0x00}, 128},
+		{[]byte{0x80, 0x17}, 151},
+		{[]byte{0xff, 0x7f}, 16511},
+		{[]byte{0x80, 0x80, 0x00}, 16512},
+	} {
+		val, n := gitVarint(c.b)
+		if val != c.val || n != len(c.b) {
+			t.Errorf("gitVarint(%x) = %d, %d, want %d, %d", c.b, val, n, c.val, len(c.b))
+		}
+	}
+	if _, n := gitVarint([]byte{0x80}); n != 0 {
+		t.Errorf("gitVarint of a truncated value read %d bytes", n)
+	}
+}
+
+func TestGitIndexV4(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	// long names sharing long prefixes, so that the stripped lengths
+	// of the prefix-compressed names need more than one varint byte
+	long := strings.Repeat("d", 200)
+	var tracked []string
+	for _, name := range []string{
+		long + "/" + strings.Repeat("a", 150),
+		long + "/b",
+		long + "/" + strings.Repeat("c", 200) + "/x",
+		long + "/" + strings.Repeat("c", 200) + "/y",
+		"z",
+	} {
+		tracked = append(tracked, filepath.FromSlash(name))
+	}
+	if err := os.MkdirAll(filepath.Join(root, long, strings.Repeat("c", 200)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range append([]string{"untracked"}, tracked...) {
+		if err := os.WriteFile(filepath.Join(root, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "-q")
+	git(append([]string{"add", "--"}, tracked...)...)
+	git("update-index", "--index-version", "4")
+
+	index, err := readGitIndex(filepath.Join(root, ".git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name := range index {
+		names = append(names, filepath.FromSlash(name))
+	}
+	sort.Strings(names)
+	sort.Strings(tracked)
+	if strings.Join(names, ",") != strings.Join(tracked, ",") {
+		t.Fatalf("index = %v, want %v", names, tracked)
+	}
+
+	var mu sync.Mutex
+	var walked []string
+	err = walkTree(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			mu.Lock()
+			walked = append(walked, path)
+			mu.Unlock()
+		}
+		return err
+	}, WithGitFilter(GitTracked))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(walked)
+	if strings.Join(walked, ",") != strings.Join(tracked, ",") {
+		t.Errorf("walked %v, want %v", walked, tracked)
+	}
+}
+
+func TestGitModified(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	for _, format := range []string{"sha1", "sha256"} {
+		root := t.TempDir()
+		git := func(args ...string) string {
+			t.Helper()
+			cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+			cmd.Dir = root
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+			}
+			return string(out)
+		}
+		write := func(name, content string) {
+			t.Helper()
+			p := filepath.Join(root, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		modified := func() string {
+			t.Helper()
+			var mu sync.Mutex
+			var walked []string
+			err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() {
+					mu.Lock()
+					walked = append(walked, filepath.ToSlash(path))
+					mu.Unlock()
+				}
+				return err
+			}, WithGitFilter(GitModified))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(walked)
+			return strings.Join(walked, ",")
+		}
+
+		if err := exec.Command("git", "init", "-q", "--object-format="+format, root).Run(); err != nil {
+			t.Skipf("git init --object-format=%s: %v", format, err)
+		}
+		// the files staged on an unborn branch
+		write("a", "a")
+		git("add", "a")
+		if got := modified(); got != "a" {
+			t.Errorf("%s: got %s before the first commit, want a", format, got)
+		}
+
+		// packed objects (blobs and trees) stored as deltas
+		// of the previous commit, followed by loose objects
+		long := strings.Repeat("line\n", 1000)
+		for _, name := range []string{"b", "d/c", "d/e/f", "g", "h"} {
+			write(name, long+name)
+		}
+		for i := 0; i < 50; i++ {
+			write(fmt.Sprintf("d/%02d", i), "")
+		}
+		git("add", "-A")
+		git("commit", "-q", "-m", "1")
+		write("b", long+"b2")
+		write("d/c", long+"c2")
+		git("commit", "-q", "-a", "-m", "2")
+		git("gc", "-q")
+		write("g", long+"g2")
+		git("commit", "-q", "-a", "-m", "3")
+
+		o, err := openGitObjects(filepath.Join(root, ".git", "objects"), gitHashSize(filepath.Join(root, ".git")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(git("cat-file", "--batch-all-objects", "--batch-check")), "\n") {
+			fields := strings.Fields(line) // <id> <type> <size>
+			id, _ := hex.DecodeString(fields[0])
+			typ, data, err := o.read(id)
+			if err != nil {
+				t.Errorf("%s: %v", line, err)
+				continue
+			}
+			if want := git("cat-file", fields[1], fields[0]); fields[1] != "tag" && string(data) != want {
+				t.Errorf("%s: got type %d, %q", line, typ, data)
+			}
+		}
+		o.close()
+
+		write("b", "unstaged")
+		write("d/c", "staged")
+		write("d/e/new", "new")
+		git("add", "d/c", "d/e/new")
+		write("untracked", "")
+		want := strings.Join(strings.Fields(git("diff", "HEAD", "--name-only")), ",")
+		if want != "b,d/c,d/e/new" {
+			t.Fatalf("%s: git diff HEAD: %s", format, want)
+		}
+		if got := modified(); got != want {
+			t.Errorf("%s: got %s, want %s", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package cwalk
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ignoreFiles lists the names of the files read by WithGitignore()
+var ignoreFiles = []string{".gitignore", ".ignore"}
+
+// WithGitignore prunes the entries ignored by the .gitignore files
+// (and the .ignore files used by ripgrep and fd, which take precedence),
+// both in the walked tree and in its parent directories up to the top
+// of the git work tree containing the root, if any. The files are read
+// as the walk reaches their directories, through the same file system
+// access as the walk (see WithConfineToRoot() and WithTraceReplay()),
+// and only if they are regular files; the ones in the parent
+// directories are not read by confined walks and replays. Global
+// excludes and .git/info/exclude are not taken into account.
+func WithGitignore() Option {
+	return func(w *Walker) {
+		top, prefix, err := ignoreTop(w.root)
+		if err != nil {
+			w.setOptionError(err)
+			return
+		}
+		w.gitignore = true
+		m := &ignoreMatcher{w: w, top: top, prefix: prefix, files: map[string][]ignoreRule{}}
+		w.addFilter("gitignore", w.exceptRoot(func(relpath string, info os.FileInfo) FilterAction {
+			if info == nil {
+				return FilterInclude
+			}
+			p := path.Join(prefix, filepath.ToSlash(relpath))
+			if m.ignored(p, info.IsDir()) {
+				return FilterPrune
+			}
+			return FilterInclude
+		}))
+	}
+}
+
+// ignoreTop returns the top of the git work tree containing root
+// (or root itself, if it is not in a work tree) and the slash-separated
+// path of root relative to it
+func ignoreTop(root string) (top, prefix string, err error) {
+	top, prefix, _, err = findWorkTree(root)
+	if err == ErrNoGitRepo {
+		top, err = filepath.Abs(root)
+		return top, "", err
+	}
+	return top, prefix, err
+}
+
+// ignoreRule is a single pattern of an ignore file
+type ignoreRule struct {
+	re      *regexp.Regexp // matches paths relative to the directory of the file
+	negate  bool           // the pattern starts with "!"
+	dirOnly bool           // the pattern ends with "/"
+}
+
+// ignoreMatcher evaluates the ignore files of a tree,
+// reading them as they are needed
+type ignoreMatcher struct {
+	w      *Walker
+	top    string
+	prefix string // path of the root relative to the top
+	mu     sync.Mutex
+	files  map[string][]ignoreRule // rules of each directory read so far
+}
+
+// ignored reports whether the path (slash-separated,
+// relative to the top) is ignored
+func (m *ignoreMatcher) ignored(p string, isDir bool) bool {
+	ignored := false
+	dir := ""
+	for {
+		rel := strings.TrimPrefix(p, dir)
+		rel = strings.TrimPrefix(rel, "/")
+		for _, r := range m.rules(dir) {
+			if (!r.dirOnly || isDir) && r.re.MatchString(rel) {
+				ignored = !r.negate
+			}
+		}
+		i := strings.IndexByte(rel, '/')
+		if i < 0 {
+			return ignored
+		}
+		dir = path.Join(dir, rel[:i])
+	}
+}
+
+// rules returns the rules of the ignore files in the directory
+func (m *ignoreMatcher) rules(dir string) []ignoreRule {
+	m.mu.Lock()
+	rules, ok := m.files[dir]
+	m.mu.Unlock()
+	if ok {
+		return rules
+	}
+	for _, name := range ignoreFiles {
+		rules = append(rules, m.readFile(path.Join(dir, name))...)
+	}
+	m.mu.Lock()
+	m.files[dir] = rules
+	m.mu.Unlock()
+	return rules
+}
+
+// readFile parses the ignore file at p (relative to the top);
+// a missing or unreadable file has no rules
+func (m *ignoreMatcher) readFile(p string) []ignoreRule {
+	f, err := m.open(p)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	var rules []ignoreRule
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if r, ok := parseIgnoreRule(s.Text()); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// open opens the ignore file at p (relative to the top) without
+// following symlinks or blocking; the files in the tree are opened
+// through the file system of the walk
+func (m *ignoreMatcher) open(p string) (*os.File, error) {
+	w := m.w
+	if m.prefix == "" || strings.HasPrefix(p, m.prefix+"/") {
+		relpath := filepath.FromSlash(strings.TrimPrefix(p[len(m.prefix):], "/"))
+		fsys := w.fs
+		if fsys == nil { // outside of a walk, see Explain()
+			fsys = osFS{root: w.root}
+		}
+		return w.openNoAtime(func(flag int) (*os.File, error) {
+			return fsys.Open(relpath, oNonBlock|oNoFollow|flag)
+		})
+	}
+	if w.confineToRoot || w.replay != nil {
+		return nil, os.ErrNotExist
+	}
+	name := filepath.Join(m.top, filepath.FromSlash(p))
+	return w.openNoAtime(func(flag int) (*os.File, error) {
+		return os.OpenFile(name, os.O_RDONLY|oNonBlock|oNoFollow|flag, 0)
+	})
+}
+
+// parseIgnoreRule parses a line of an ignore file
+// (see gitignore(5)); ok is false for blank lines and comments
+func parseIgnoreRule(line string) (r ignoreRule, ok bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || line[0] == '#' {
+		return r, false
+	}
+	if line[0] == '!' {
+		r.negate = true
+		line = line[1:]
+	} else if line[0] == '\\' {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return r, false
+	}
+
+	// patterns without a slash (other than a trailing one)
+	// match the names at any level
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "**") && i+2 == len(line):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			j := strings.IndexByte(line[i+1:], ']')
+			if j < 0 {
+				b.WriteString(`\[`)
+				break
+			}
+			class := line[i+1 : i+1+j]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += j + 1
+		case c == '\\' && i+1 < len(line):
+			i++
+			b.WriteString(regexp.QuoteMeta(line[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return r, false
+	}
+	r.re = re
+	return r, true
+}
@@ -0,0 +1,63 @@
+package cwalk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestGitignoreSpecialFiles(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "rules")
+	ignoreTree(t, root, map[string]string{"a/x": "", "b/x": ""})
+	if err := os.WriteFile(outside, []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(root, "a", ".gitignore"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "b", ".gitignore")); err != nil {
+		t.Fatal(err)
+	}
+
+	// neither the FIFO (which would block) nor the symlink is read
+	done := make(chan string)
+	go func() {
+		got, err := ignorePaths(root)
+		if err != nil {
+			got = err.Error()
+		}
+		done <- got
+	}()
+	select {
+	case got := <-done:
+		if got != "a,a/x,b,b/x" {
+			t.Errorf("got %s, want a,a/x,b,b/x", got)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the walk is blocked")
+	}
+}
+
+func TestGitignoreConfined(t *testing.T) {
+	top := t.TempDir()
+	ignoreTree(t, top, map[string]string{
+		".git/":          "",
+		".gitignore":     "*.tmp\n",
+		"sub/.gitignore": "*.bak\n",
+		"sub/a.tmp":      "",
+		"sub/b.bak":      "",
+		"sub/c":          "",
+	})
+	root := filepath.Join(top, "sub")
+	if _, err := ignorePaths(root, WithConfineToRoot()); errors.Is(err, ErrConfineUnsupported) {
+		t.Skip(err)
+	}
+	// the ignore files above the root are outside of the confinement
+	if got := ignoreWalk(t, root, WithConfineToRoot()); got != "a.tmp,c" {
+		t.Errorf("got %s, want a.tmp,c", got)
+	}
+}
@@ -0,0 +1,144 @@
+package cwalk
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ignoreTree creates the files (and their parent directories)
+// under dir; the names ending with "/" are empty directories
+func ignoreTree(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(p, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// ignoreWalk returns the sorted slash-separated paths visited
+// by a walk of root with WithGitignore(), ignore files left out
+func ignoreWalk(t *testing.T, root string, opts ...Option) string {
+	visited, err := ignorePaths(root, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return visited
+}
+
+// ignorePaths is like ignoreWalk, but returns the error of the walk
+func ignorePaths(root string, opts ...Option) (string, error) {
+	var mu sync.Mutex
+	var visited []string
+	err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if name := filepath.Base(path); path != "" && name != ".gitignore" && name != ".ignore" {
+			mu.Lock()
+			visited = append(visited, filepath.ToSlash(path))
+			mu.Unlock()
+		}
+		return nil
+	}, append([]Option{WithGitignore()}, opts...)...)
+	sort.Strings(visited)
+	return strings.Join(visited, ","), err
+}
+
+func TestGitignore(t *testing.T) {
+	root := t.TempDir()
+	ignoreTree(t, root, map[string]string{
+		".gitignore":     "*.log\n!keep.log\nbuild/\n/top\n# comment\n\n",
+		"a.log":          "",
+		"keep.log":       "",
+		"top":            "",
+		"build/out":      "",
+		"src/top":        "",
+		"src/build":      "", // a file, not matched by "build/"
+		"src/x.go":       "",
+		"src/.gitignore": "*.go\n",
+		"src/.ignore":    "!x.go\n",
+		"src/deep/b.log": "",
+		"src/deep/y.go":  "",
+	})
+	const want = "keep.log,src,src/build,src/deep,src/top,src/x.go"
+	if got := ignoreWalk(t, root); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	// the same through an open root directory
+	if got := ignoreWalk(t, root, WithPinnedRoot()); got != want {
+		t.Errorf("pinned root: got  %s\nwant %s", got, want)
+	}
+}
+
+func TestGitignoreParents(t *testing.T) {
+	top := t.TempDir()
+	ignoreTree(t, top, map[string]string{
+		".git/":           "",
+		".gitignore":      "*.tmp\n",
+		"sub/.gitignore":  "/ignored\n",
+		"sub/dir/a.tmp":   "",
+		"sub/dir/b":       "",
+		"sub/dir/ignored": "",
+		"sub/ignored":     "",
+	})
+	if got := ignoreWalk(t, filepath.Join(top, "sub", "dir")); got != "b,ignored" {
+		t.Errorf("got %s, want b,ignored", got)
+	}
+	if got := ignoreWalk(t, filepath.Join(top, "sub")); got != "dir,dir/b,dir/ignored" {
+		t.Errorf("got %s, want dir,dir/b,dir/ignored", got)
+	}
+}
+
+func TestParseIgnoreRule(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"*.o", "a.o", true},
+		{"*.o", "dir/a.o", true},
+		{"*.o", "a.oo", false},
+		{"/a", "a", true},
+		{"/a", "dir/a", false},
+		{"dir/*.c", "dir/x.c", true},
+		{"dir/*.c", "dir/sub/x.c", false},
+		{"**/foo", "a/b/foo", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**", "a/x/y", true},
+		{"?.txt", "a.txt", true},
+		{"?.txt", "ab.txt", false},
+		{"[ab].txt", "b.txt", true},
+		{"[!ab].txt", "b.txt", false},
+		{`\#x`, "#x", true},
+		{`\!x`, "!x", true},
+	} {
+		r, ok := parseIgnoreRule(c.pattern)
+		if !ok {
+			t.Errorf("%q: not parsed", c.pattern)
+			continue
+		}
+		if got := r.re.MatchString(c.path); got != c.match {
+			t.Errorf("%q on %q: got %v, want %v", c.pattern, c.path, got, c.match)
+		}
+	}
+	for _, line := range []string{"", "   ", "# comment", "!", "/"} {
+		if _, ok := parseIgnoreRule(line); ok {
+			t.Errorf("%q: parsed as a rule", line)
+		}
+	}
+}
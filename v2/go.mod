@@ -0,0 +1,3 @@
+module github.com/iafan/cwalk/v2
+
+go 1.18
@@ -0,0 +1,49 @@
+package cwalk
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrGoroutineLeak is returned by the walk when WithGoroutineCheck()
+// is used and some of the goroutines started by the walk are still
+// running after it has completed, which indicates a bug in the walker
+var ErrGoroutineLeak = errors.New("Goroutines still running after the walk")
+
+// WithGoroutineCheck is a debug mode which verifies that every goroutine
+// started by the walk has exited by the time it returns, on all paths
+// (including errors, aborts and cancellation); if that is not the case,
+// the walk returns ErrGoroutineLeak. The number of goroutines started
+// is available as Report.Goroutines.
+func WithGoroutineCheck() Option {
+	return func(w *Walker) {
+		w.checkGoroutines = true
+	}
+}
+
+// spawn runs fn in a new goroutine tracked by wg
+// and by the goroutine counters
+func (w *Walker) spawn(wg *sync.WaitGroup, fn func()) {
+	wg.Add(1)
+	atomic.AddInt64(&w.spawned, 1)
+	atomic.AddInt64(&w.running, 1)
+	go func() {
+		defer wg.Done()
+		defer atomic.AddInt64(&w.running, -1)
+		fn()
+	}()
+}
+
+// checkLeaks returns ErrGoroutineLeak if WithGoroutineCheck() is used
+// and some of the goroutines started by the walk are still running
+func (w *Walker) checkLeaks() error {
+	if !w.checkGoroutines {
+		return nil
+	}
+	if n := atomic.LoadInt64(&w.running); n != 0 {
+		return fmt.Errorf("%w: %d of %d", ErrGoroutineLeak, n, atomic.LoadInt64(&w.spawned))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package cwalk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestGoroutineLeaks(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 50; i++ {
+		if err := os.MkdirAll(filepath.Join(root, fmt.Sprint(i%5), fmt.Sprint(i)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	errStop := errors.New("stop")
+
+	for _, c := range []struct {
+		name    string
+		fn      func(ctx context.Context, cancel func()) filepath.WalkFunc
+		opts    []Option
+		wantErr bool
+	}{
+		{"complete", func(context.Context, func()) filepath.WalkFunc {
+			return func(path string, info os.FileInfo, err error) error { return err }
+		}, nil, false},
+		{"path stream", func(context.Context, func()) filepath.WalkFunc {
+			return func(path string, info os.FileInfo, err error) error { return err }
+		}, []Option{WithPathStream(NewPathStream(io.Discard))}, false},
+		{"fail fast", func(context.Context, func()) filepath.WalkFunc {
+			return func(path string, info os.FileInfo, err error) error {
+				if path == filepath.Join("1", "1") {
+					return errStop
+				}
+				return err
+			}
+		}, []Option{WithFailFast()}, true},
+		{"cancelled", func(ctx context.Context, cancel func()) filepath.WalkFunc {
+			return func(path string, info os.FileInfo, err error) error {
+				if path == "2" {
+					cancel()
+					<-ctx.Done()
+				}
+				return err
+			}
+		}, nil, true},
+	} {
+		// the baseline is taken before the walk starts any goroutine
+		baseline := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		var report Report
+		opts := append([]Option{WithWorkers(4), WithContext(ctx), WithReport(&report), WithGoroutineCheck()}, c.opts...)
+		err := walkTree(root, c.fn(ctx, cancel), opts...)
+		cancel()
+		if errors.Is(err, ErrGoroutineLeak) || (err != nil) != c.wantErr {
+			t.Errorf("%s: got %v", c.name, err)
+		}
+		if report.Goroutines == 0 {
+			t.Errorf("%s: no goroutines reported", c.name)
+		}
+
+		// the goroutines which have returned may take
+		// a moment to be accounted for by the runtime
+		deadline := time.Now().Add(time.Second)
+		n := runtime.NumGoroutine()
+		for ; n > baseline && time.Now().Before(deadline); n = runtime.NumGoroutine() {
+			time.Sleep(time.Millisecond)
+		}
+		if n > baseline {
+			buf := make([]byte, 1<<16)
+			t.Errorf("%s: %d goroutines running after the walk, %d before\n%s",
+				c.name, n, baseline, buf[:runtime.Stack(buf, true)])
+		}
+	}
+}
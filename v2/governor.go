@@ -0,0 +1,127 @@
+package cwalk
+
+import (
+	"context"
+	"fmt"
+)
+
+// Quota limits the resources used by a single walk, see WithQuota();
+// zero fields mean no limit other than the Governor's own
+type Quota struct {
+	Workers int // number of workers, overriding WithWorkers()
+	FDs     int // number of open descriptors, see FDBudget
+
+	// QueuedDirs is the number of directories waiting in the queue,
+	// which bounds the memory used by the queue: once it is reached,
+	// the workers process the subdirectories they find right away
+	// (depth-first, one after another) instead of queueing them, until
+	// the other workers have taken enough directories from the queue
+	QueuedDirs int
+}
+
+// Governor shares the workers and the file descriptors of a process
+// between many walks running at the same time, e.g. scanning the
+// mounts of different customers, so that one huge tree can't starve
+// the other walks: each walk gets its own Quota, and the walks take
+// turns (in FIFO order) for the workers and descriptors of the process.
+// Governor is safe for concurrent use.
+type Governor struct {
+	workers *FDBudget // not descriptors: worker slots, with the same semantics
+	fds     *FDBudget
+}
+
+// GovernorStats holds the current usage of a Governor
+type GovernorStats struct {
+	Workers FDBudgetStats // the workers processing directories
+	FDs     FDBudgetStats
+}
+
+// NewGovernor creates a Governor letting at most the given number
+// of workers process directories, and at most fds descriptors be open,
+// at the same time in all governed walks
+func NewGovernor(workers, fds int) *Governor {
+	return &Governor{
+		workers: NewFDBudget(workers),
+		fds:     NewFDBudget(fds),
+	}
+}
+
+// Stats returns the current usage of the governor
+func (g *Governor) Stats() GovernorStats {
+	return GovernorStats{Workers: g.workers.Stats(), FDs: g.fds.Stats()}
+}
+
+// WithQuota makes the walk run under the governor g with the quota q.
+// The descriptors are acquired from g as with WithFDBudget(), which
+// replaces the Limiter set by WithLimiter(), if any.
+func WithQuota(g *Governor, q Quota) Option {
+	return func(w *Walker) {
+		if q.Workers < 0 || q.FDs < 0 || q.QueuedDirs < 0 {
+			w.setOptionError(fmt.Errorf("Invalid quota: %+v", q))
+			return
+		}
+		w.governor = g
+		w.queueQuota = q.QueuedDirs
+		if q.Workers > 0 {
+			w.numWorkers = q.Workers
+		}
+		var own *FDBudget
+		if q.FDs > 0 {
+			own = NewFDBudget(q.FDs)
+		}
+		w.limiter = quotaLimiter{own: own, shared: g.fds}
+	}
+}
+
+// quotaLimiter acquires the descriptors from the walk's own
+// budget (if any) first, and then from the shared one
+type quotaLimiter struct {
+	own, shared *FDBudget
+}
+
+// Acquire implements Limiter
+func (l quotaLimiter) Acquire(ctx context.Context, n int64) error {
+	if l.own != nil {
+		if err := l.own.Acquire(ctx, n); err != nil {
+			return err
+		}
+	}
+	if err := l.shared.Acquire(ctx, n); err != nil {
+		if l.own != nil {
+			l.own.Release(n)
+		}
+		return err
+	}
+	return nil
+}
+
+// Release implements Limiter
+func (l quotaLimiter) Release(n int64) {
+	l.shared.Release(n)
+	if l.own != nil {
+		l.own.Release(n)
+	}
+}
+
+// acquireWorker waits for a worker slot of the governor,
+// aborting the walk on failure
+func (w *Walker) acquireWorker() bool {
+	if w.governor == nil {
+		return true
+	}
+	if err := w.governor.workers.Acquire(w.abortCtx, 1); err != nil {
+		if !w.isAborted() {
+			w.setWalkError(err)
+			w.abort()
+		}
+		return false
+	}
+	return true
+}
+
+// releaseWorker releases the slot acquired by acquireWorker()
+func (w *Walker) releaseWorker() {
+	if w.governor != nil {
+		w.governor.workers.Release(1)
+	}
+}
@@ -0,0 +1,50 @@
+package cwalk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestQuotaQueuedDirs(t *testing.T) {
+	root := t.TempDir()
+	want := map[string]bool{"": true}
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 5; j++ {
+			dir := filepath.Join(fmt.Sprint(i), fmt.Sprint(j))
+			if err := os.MkdirAll(filepath.Join(root, dir, "sub"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			want[fmt.Sprint(i)], want[dir], want[filepath.Join(dir, "sub")] = true, true, true
+		}
+	}
+
+	for _, workers := range []int{1, 4} {
+		var mu sync.Mutex
+		seen := map[string]int{}
+		var report Report
+		err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+			mu.Lock()
+			seen[path]++
+			mu.Unlock()
+			return err
+		}, WithQuota(NewGovernor(workers, 100), Quota{QueuedDirs: 2}), WithReport(&report))
+		if err != nil {
+			t.Fatalf("%d workers: %v", workers, err)
+		}
+		// the directories beyond the quota are walked rather than queued
+		if report.PeakQueueLen > 2 {
+			t.Errorf("%d workers: %d directories queued, want at most 2", workers, report.PeakQueueLen)
+		}
+		if len(seen) != len(want) {
+			t.Errorf("%d workers: %d paths walked, want %d", workers, len(seen), len(want))
+		}
+		for path, n := range seen {
+			if !want[path] || n != 1 {
+				t.Errorf("%d workers: %s walked %d times", workers, path, n)
+			}
+		}
+	}
+}
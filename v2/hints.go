@@ -0,0 +1,65 @@
+package cwalk
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// statSampleRate is the rate at which stat calls are timed
+	// to detect slow file systems when only the report is requested
+	statSampleRate = 16
+
+	// minStatSamples is the number of timed stat calls
+	// needed to give the slow stat hint
+	minStatSamples = 64
+
+	// slowStatLatency is the median stat latency above which
+	// the slow stat hint is given; local file systems stat
+	// cached entries in microseconds
+	slowStatLatency = 2 * time.Millisecond
+
+	// openSampleRate is the rate at which the regular files
+	// sampled for the stat latency are opened (see sampleOpen())
+	openSampleRate = 4
+
+	// minOpenSamples is the number of files opened twice
+	// needed to give the scanner hint (see sampleOpen())
+	minOpenSamples = 16
+
+	// slowOpenLatency is the median latency of opening files for the
+	// first time above which the scanner hint is given, if opening
+	// them again is at least openSlowdown times faster
+	slowOpenLatency = 2 * time.Millisecond
+	openSlowdown    = 8
+)
+
+// hints returns the hints on the environment for Report.Hints
+func (w *Walker) hints() []string {
+	var hints []string
+	if w.statSamples.Count() >= minStatSamples {
+		if m := w.statSamples.median(); m >= slowStatLatency {
+			hints = append(hints, fmt.Sprintf("stat calls consistently take %s or more; %s", m, slowStatHint))
+		}
+	}
+	if w.firstOpens.Count() >= minOpenSamples {
+		first, repeat := w.firstOpens.median(), w.repeatOpens.median()
+		if first >= slowOpenLatency && first >= openSlowdown*repeat {
+			hints = append(hints, fmt.Sprintf("opening a file for the first time consistently takes %s or more, "+
+				"and opening it again %s; %s", first, repeat, scannerHint))
+		}
+	}
+	if w.ioPriority != nil && w.ioPriority.Class != IOClassNone {
+		if info, err := os.Stat(w.osPath(w.rootRel)); err == nil {
+			if hint := ioSchedulerHint(info); hint != "" {
+				hints = append(hints, hint)
+			}
+		}
+	}
+	if _, throttled := w.fdThrottle.state(); throttled > 0 {
+		hints = append(hints, fmt.Sprintf("the process ran out of file descriptors %d times, "+
+			"so the walk backed off; raise the limit of open files, or see WithFDBudget()", throttled))
+	}
+	return hints
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package cwalk
+
+import "os"
+
+// slowStatHint explains the likely causes of slow stat calls
+const slowStatHint = "the file system may be remote, overloaded, " +
+	"or inspected by an on-access scanner"
+
+// scannerHint is not given on this platform, see sampleOpen()
+const scannerHint = ""
+
+// sampleOpen doesn't open the files, as on-access scanners
+// are not commonly used on this platform
+func (w *Walker) sampleOpen(relpath string, info os.FileInfo) {}
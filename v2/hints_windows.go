@@ -0,0 +1,40 @@
+package cwalk
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// slowStatHint explains the likely causes of slow stat calls; on-access
+// scanners inspect the files when they are opened rather than stat'ed
+// (see sampleOpen())
+const slowStatHint = "the file system may be remote or overloaded, " +
+	"or an indexer may be inspecting the files"
+
+// scannerHint explains the likely cause of slow first opens
+const scannerHint = "this is typical of an on-access antivirus scanner " +
+	"(e.g. Microsoft Defender real-time protection) inspecting the files; " +
+	"consider excluding the tree or the process from scanning"
+
+// sampleOpen times opening some of the regular files sampled for the
+// stat latency, and opening them again right after, for the scanner
+// hint: on-access scanners inspect a file when it is opened, and cache
+// the verdict until it changes, so the first open is much slower than
+// the next ones.
+// Cloud placeholders are left alone, as opening them may download them.
+func (w *Walker) sampleOpen(relpath string, info os.FileInfo) {
+	if !info.Mode().IsRegular() || isPlaceholder(info) ||
+		atomic.AddInt64(&w.openCalls, 1)%openSampleRate != 0 {
+		return
+	}
+	for _, h := range []*Histogram{&w.firstOpens, &w.repeatOpens} {
+		start := time.Now()
+		f, err := w.fs.Open(relpath, 0)
+		if err != nil {
+			return
+		}
+		f.Close()
+		h.add(time.Since(start))
+	}
+}
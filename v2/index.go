@@ -0,0 +1,127 @@
+package cwalk
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Index is a sorted list of the paths recorded during a walk
+// (see WithIndex()), which answers prefix, extension and glob
+// queries without touching the file system, e.g. for interactive
+// file pickers over large trees. Paths are slash-separated and
+// relative to the walker root. Index is safe for concurrent use.
+type Index struct {
+	mu    sync.RWMutex
+	paths []string         // sorted once the walk is complete
+	exts  map[string][]int // lower-case extension -> indexes into paths
+}
+
+// NewIndex creates an empty Index
+func NewIndex() *Index {
+	return &Index{}
+}
+
+// WithIndex makes the walk record every visited path in ix,
+// replacing what ix has recorded before
+func WithIndex(ix *Index) Option {
+	return func(w *Walker) {
+		w.index = ix
+	}
+}
+
+// reset drops all recorded paths
+func (ix *Index) reset() {
+	ix.mu.Lock()
+	ix.paths = ix.paths[:0]
+	ix.exts = nil
+	ix.mu.Unlock()
+}
+
+// add records the path
+func (ix *Index) add(relpath string) {
+	if relpath == "" {
+		return
+	}
+	ix.mu.Lock()
+	ix.paths = append(ix.paths, filepath.ToSlash(relpath))
+	ix.mu.Unlock()
+}
+
+// build sorts the recorded paths and groups them by extension
+func (ix *Index) build() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	sort.Strings(ix.paths)
+	ix.exts = map[string][]int{}
+	for i, p := range ix.paths {
+		if ext := strings.ToLower(path.Ext(p)); ext != "" {
+			ix.exts[ext] = append(ix.exts[ext], i)
+		}
+	}
+}
+
+// Len returns the number of recorded paths
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.paths)
+}
+
+// prefixRange returns the range of paths starting with the prefix;
+// ix.mu must be held
+func (ix *Index) prefixRange(prefix string) (int, int) {
+	from := sort.SearchStrings(ix.paths, prefix)
+	to := from + sort.Search(len(ix.paths)-from, func(i int) bool {
+		return !strings.HasPrefix(ix.paths[from+i], prefix)
+	})
+	return from, to
+}
+
+// QueryPrefix returns the sorted paths starting with the prefix
+// (e.g. "src/" for everything inside the src directory)
+func (ix *Index) QueryPrefix(prefix string) []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	from, to := ix.prefixRange(prefix)
+	return append([]string(nil), ix.paths[from:to]...)
+}
+
+// QueryExt returns the sorted paths with the given extension
+// (e.g. ".go"), matched case-insensitively
+func (ix *Index) QueryExt(ext string) []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	idx := ix.exts[strings.ToLower(ext)]
+	out := make([]string, len(idx))
+	for i, n := range idx {
+		out[i] = ix.paths[n]
+	}
+	return out
+}
+
+// QueryGlob returns the sorted paths matching the pattern, using
+// the syntax of path.Match (so "*" doesn't match "/"). Only the paths
+// starting with the literal prefix of the pattern are checked.
+func (ix *Index) QueryGlob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	prefix := pattern
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		prefix = pattern[:i]
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	var out []string
+	from, to := ix.prefixRange(prefix)
+	for _, p := range ix.paths[from:to] {
+		if ok, _ := path.Match(pattern, p); ok {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,71 @@
+package cwalk
+
+import "fmt"
+
+// IOClass is an I/O scheduling class, see IOPriority
+type IOClass int
+
+// I/O scheduling classes, as in ionice(1)
+const (
+	IOClassNone       IOClass = iota // keep the class of the process
+	IOClassRealtime                  // served first; requires privileges
+	IOClassBestEffort                // the default class, with levels
+	IOClassIdle                      // only served when the disk is otherwise idle
+)
+
+// IOPriority is the I/O and CPU scheduling priority of the workers,
+// see WithIOPriority()
+type IOPriority struct {
+	Class IOClass
+	Level int // 0 (highest) to 7 (lowest), for the realtime and best-effort classes
+	Nice  int // CPU scheduling niceness, from -20 to 19; 0 keeps that of the process
+}
+
+// WithIOPriority makes the workers run with the I/O scheduling class
+// (like ionice(1)) and the niceness of p, so that background scans on
+// production hosts stay out of the way of serving traffic. Only the
+// workers are affected, not the rest of the process: each of them
+// runs on its own thread for the duration of the walk, which is
+// discarded afterwards. The walk fails if the priority can't be set.
+//
+// Note that the I/O classes are only honored by some I/O schedulers
+// (BFQ on Linux; Report.Hints tells if the root's device uses another
+// one): on devices shared with other workloads, limiting the walk with
+// the io.max file of a cgroup v2 is more effective, see CgroupIOMax().
+// On Windows, the idle class puts the workers into the background
+// mode, which lowers their I/O and memory priority, so that e.g.
+// desktop indexing doesn't make the machine stutter, and Nice maps
+// to the closest thread priority level; the other classes have no
+// equivalent there. The option has no effect on other platforms.
+func WithIOPriority(p IOPriority) Option {
+	return func(w *Walker) {
+		if p.Class < IOClassNone || p.Class > IOClassIdle || p.Level < 0 || p.Level > 7 || p.Nice < -20 || p.Nice > 19 {
+			w.setOptionError(fmt.Errorf("Invalid I/O priority: %+v", p))
+			return
+		}
+		w.ioPriority = &p
+	}
+}
+
+// IOMax is a limit from the io.max file of a cgroup v2, for the
+// device identified by its major and minor numbers; negative
+// values mean no limit
+type IOMax struct {
+	Major, Minor uint32
+	ReadBPS      int64 // bytes per second
+	WriteBPS     int64
+	ReadIOPS     int64 // operations per second
+	WriteIOPS    int64
+}
+
+// applyIOPriority applies the priority requested
+// by WithIOPriority() to the calling worker
+func (w *Walker) applyIOPriority() {
+	if w.ioPriority == nil {
+		return
+	}
+	if err := setThreadPriority(*w.ioPriority); err != nil {
+		w.setWalkError(err)
+		w.abort()
+	}
+}
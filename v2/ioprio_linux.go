@@ -0,0 +1,132 @@
+package cwalk
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ioprio_set(2) definitions, missing from the syscall package
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+// setThreadPriority locks the calling goroutine to its thread, which
+// is never unlocked, so that the thread exits along with the goroutine,
+// and sets the priority of the thread
+func setThreadPriority(p IOPriority) error {
+	runtime.LockOSThread()
+	tid := syscall.Gettid()
+	if p.Class != IOClassNone {
+		prio := uintptr(p.Class)<<ioprioClassShift | uintptr(p.Level)
+		_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), prio)
+		if errno != 0 {
+			return os.NewSyscallError("ioprio_set", errno)
+		}
+	}
+	if p.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, p.Nice); err != nil {
+			return os.NewSyscallError("setpriority", err)
+		}
+	}
+	return nil
+}
+
+// ioSchedulerHint tells whether the I/O classes are ignored
+// by the scheduler of the device holding the file
+func ioSchedulerHint(info os.FileInfo) string {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	dev := uint64(st.Dev)
+	major := (dev&0xfff00)>>8 | (dev&0xfffff00000000000)>>32
+	minor := dev&0xff | (dev&0xffffff00000)>>12
+	data, err := os.ReadFile(fmt.Sprintf("/sys/dev/block/%d:%d/queue/scheduler", major, minor))
+	if err != nil {
+		// partitions have the scheduler of their disk
+		data, err = os.ReadFile(fmt.Sprintf("/sys/dev/block/%d:%d/../queue/scheduler", major, minor))
+		if err != nil {
+			return ""
+		}
+	}
+	// the active scheduler is in brackets, e.g. "[mq-deadline] bfq none"
+	s := string(data)
+	i, j := strings.IndexByte(s, '['), strings.IndexByte(s, ']')
+	if i < 0 || j < i || s[i+1:j] == "bfq" {
+		return ""
+	}
+	return fmt.Sprintf("the I/O priority is ignored by the %s scheduler of the device; "+
+		"switch it to bfq, or limit the walk with the io.max file of a cgroup v2", s[i+1:j])
+}
+
+// CgroupIOMax returns the directory of the cgroup v2 of the process
+// and the limits set in its io.max file, so that background scans can
+// check (or log) whether they are throttled. To throttle a scan,
+// move the process to a cgroup and write its limits to io.max, e.g.
+// "8:0 rbps=10485760 riops=500" (see the cgroup v2 documentation).
+func CgroupIOMax() (string, []IOMax, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", nil, err
+	}
+	var dir string
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest := strings.TrimPrefix(line, "0::"); rest != line {
+			dir = filepath.Join("/sys/fs/cgroup", rest)
+		}
+	}
+	if dir == "" {
+		return "", nil, errors.New("The process is not in a cgroup v2")
+	}
+	f, err := os.Open(filepath.Join(dir, "io.max"))
+	if os.IsNotExist(err) {
+		return dir, nil, nil // the root cgroup, or the io controller is not enabled
+	}
+	if err != nil {
+		return dir, nil, err
+	}
+	defer f.Close()
+
+	var limits []IOMax
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		l := IOMax{ReadBPS: -1, WriteBPS: -1, ReadIOPS: -1, WriteIOPS: -1}
+		if _, err := fmt.Sscanf(fields[0], "%d:%d", &l.Major, &l.Minor); err != nil {
+			return dir, nil, fmt.Errorf("Invalid io.max line %q", scanner.Text())
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 || kv[1] == "max" {
+				continue
+			}
+			n, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return dir, nil, fmt.Errorf("Invalid io.max line %q", scanner.Text())
+			}
+			switch kv[0] {
+			case "rbps":
+				l.ReadBPS = n
+			case "wbps":
+				l.WriteBPS = n
+			case "riops":
+				l.ReadIOPS = n
+			case "wiops":
+				l.WriteIOPS = n
+			}
+		}
+		limits = append(limits, l)
+	}
+	return dir, limits, scanner.Err()
+}
@@ -0,0 +1,24 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package cwalk
+
+import (
+	"errors"
+	"os"
+)
+
+// setThreadPriority does nothing on this platform
+func setThreadPriority(p IOPriority) error {
+	return nil
+}
+
+// ioSchedulerHint gives no hint on this platform
+func ioSchedulerHint(info os.FileInfo) string {
+	return ""
+}
+
+// CgroupIOMax fails on this platform, which has no cgroups
+func CgroupIOMax() (string, []IOMax, error) {
+	return "", nil, errors.New("Cgroups are not supported on this platform")
+}
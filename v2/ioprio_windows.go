@@ -0,0 +1,76 @@
+package cwalk
+
+import (
+	"errors"
+	"os"
+	"runtime"
+)
+
+// SetThreadPriority definitions missing from the syscall package
+const (
+	threadModeBackgroundBegin = 0x00010000
+	threadPriorityIdle        = -15
+	threadPriorityLowest      = -2
+	threadPriorityBelowNormal = -1
+	threadPriorityAboveNormal = 1
+	threadPriorityHighest     = 2
+)
+
+var (
+	procGetCurrentThread  = modKernel32.NewProc("GetCurrentThread")
+	procSetThreadPriority = modKernel32.NewProc("SetThreadPriority")
+)
+
+// setThreadPriority locks the calling goroutine to its thread, which
+// is never unlocked, so that the thread exits along with the goroutine,
+// and sets the priority of the thread: the idle class enables the
+// background mode, which lowers its I/O and memory priority, and the
+// niceness maps to the thread priority levels
+func setThreadPriority(p IOPriority) error {
+	runtime.LockOSThread()
+	thread, _, _ := procGetCurrentThread.Call()
+	if p.Nice != 0 {
+		if err := setPriority(thread, niceToThreadPriority(p.Nice)); err != nil {
+			return err
+		}
+	}
+	if p.Class == IOClassIdle {
+		return setPriority(thread, threadModeBackgroundBegin)
+	}
+	return nil
+}
+
+// setPriority calls SetThreadPriority
+func setPriority(thread uintptr, priority int) error {
+	ret, _, errno := procSetThreadPriority.Call(thread, uintptr(priority))
+	if ret == 0 {
+		return os.NewSyscallError("SetThreadPriority", errno)
+	}
+	return nil
+}
+
+// niceToThreadPriority maps the niceness
+// to the closest thread priority level
+func niceToThreadPriority(nice int) int {
+	switch {
+	case nice >= 19:
+		return threadPriorityIdle
+	case nice >= 10:
+		return threadPriorityLowest
+	case nice > 0:
+		return threadPriorityBelowNormal
+	case nice <= -10:
+		return threadPriorityHighest
+	}
+	return threadPriorityAboveNormal
+}
+
+// ioSchedulerHint gives no hint on this platform
+func ioSchedulerHint(info os.FileInfo) string {
+	return ""
+}
+
+// CgroupIOMax fails on this platform, which has no cgroups
+func CgroupIOMax() (string, []IOMax, error) {
+	return "", nil, errors.New("Cgroups are not supported on this platform")
+}
@@ -0,0 +1,63 @@
+package cwalk
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// securityLabelAttrs are the extended attributes holding the security
+// label of a file, for the Linux security modules which label files
+var securityLabelAttrs = []string{"security.selinux", "security.SMACK64"}
+
+// readSecurityLabel returns the security label of the file, without
+// following symbolic links; files without a label have an empty one
+func readSecurityLabel(fsys fileSystem, relpath string) (string, error) {
+	f, err := fsys.Open(relpath, oPath|syscall.O_NOFOLLOW)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	// fgetxattr(2) doesn't accept O_PATH descriptors, but the file
+	// they refer to (even a symbolic link) can be reached through
+	// their /proc/self/fd entry
+	path := fmt.Sprintf("/proc/self/fd/%d", f.Fd())
+	for _, attr := range securityLabelAttrs {
+		value, err := getxattr(path, attr)
+		switch err {
+		case nil:
+			return strings.TrimRight(string(value), "\x00"), nil
+		case syscall.ENODATA, syscall.ENOTSUP:
+			continue
+		default:
+			return "", &os.PathError{Op: "getxattr", Path: relpath, Err: err}
+		}
+	}
+	return "", nil
+}
+
+// getxattr returns the value of the extended attribute of the file
+func getxattr(path, attr string) ([]byte, error) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	a, err := syscall.BytePtrFromString(attr)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 256)
+	for {
+		n, _, errno := syscall.Syscall6(syscall.SYS_GETXATTR, uintptr(unsafe.Pointer(p)),
+			uintptr(unsafe.Pointer(a)), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), 0, 0)
+		if errno != syscall.ERANGE {
+			if errno != 0 {
+				return nil, errno
+			}
+			return buf[:n], nil
+		}
+		buf = make([]byte, 2*len(buf)) // the value has grown
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package cwalk
+
+// readSecurityLabel returns no label, as
+// files are not labeled on this platform
+func readSecurityLabel(fsys fileSystem, relpath string) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,122 @@
+package cwalk
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyFunc returns the latency to inject into
+// a single file system operation, see WithInjectedLatency()
+type LatencyFunc func() time.Duration
+
+// FixedLatency returns a LatencyFunc which always returns d
+func FixedLatency(d time.Duration) LatencyFunc {
+	return func() time.Duration { return d }
+}
+
+// UniformLatency returns a LatencyFunc with latencies
+// distributed uniformly between min and max
+func UniformLatency(min, max time.Duration) LatencyFunc {
+	if max <= min {
+		return FixedLatency(min)
+	}
+	return func() time.Duration {
+		return min + time.Duration(rand.Int63n(int64(max-min)))
+	}
+}
+
+// LogNormalLatency returns a LatencyFunc with latencies following
+// a log-normal distribution with the given median, which models the
+// long tail of network storage; sigma is the standard deviation of
+// the logarithm of the latency (e.g. 0.5 for a moderate tail, 1.5
+// for occasional stalls two orders of magnitude above the median)
+func LogNormalLatency(median time.Duration, sigma float64) LatencyFunc {
+	return func() time.Duration {
+		return time.Duration(float64(median) * math.Exp(sigma*rand.NormFloat64()))
+	}
+}
+
+// Latency returns a LatencyFunc with latencies distributed as counted
+// by the histogram, e.g. recorded with WithTiming() on the target
+// storage; latencies are uniformly distributed within each bucket.
+// An empty histogram yields no latency.
+func (h *Histogram) Latency() LatencyFunc {
+	var buckets [len(h.Buckets)]int64
+	var total int64
+	for i := range h.Buckets {
+		total += atomic.LoadInt64(&h.Buckets[i])
+		buckets[i] = total
+	}
+	return func() time.Duration {
+		if total == 0 {
+			return 0
+		}
+		n := rand.Int63n(total)
+		i := 0
+		for buckets[i] <= n {
+			i++
+		}
+		low := time.Duration(1<<uint(i)) * time.Microsecond
+		return low + time.Duration(rand.Int63n(int64(low)))
+	}
+}
+
+// WithInjectedLatency delays every directory listing by the duration
+// returned by readDir, and every stat call by the one returned by stat
+// (either may be nil), which is meant for modeling how the walk, e.g.
+// with a given number of workers, would behave on slower storage.
+// The delays block the workers just like the real latencies would.
+func WithInjectedLatency(readDir, stat LatencyFunc) Option {
+	return func(w *Walker) {
+		w.readDirLatency = readDir
+		w.statLatency = stat
+	}
+}
+
+// latencyFS is a fileSystem which delays
+// the calls of the underlying one
+type latencyFS struct {
+	fileSystem
+	readDir, stat LatencyFunc
+}
+
+// injectLatency makes the walk inject the latency, if requested
+func (w *Walker) injectLatency() {
+	if w.readDirLatency != nil || w.statLatency != nil {
+		w.fs = latencyFS{fileSystem: w.fs, readDir: w.readDirLatency, stat: w.statLatency}
+	}
+}
+
+// delay sleeps for the duration returned by fn, if any
+func delay(fn LatencyFunc) {
+	if fn != nil {
+		time.Sleep(fn())
+	}
+}
+
+// ReadDirNames delays the directory listing
+func (fsys latencyFS) ReadDirNames(relpath string) ([]string, error) {
+	delay(fsys.readDir)
+	return fsys.fileSystem.ReadDirNames(relpath)
+}
+
+// Lstat delays the stat call
+func (fsys latencyFS) Lstat(relpath string) (os.FileInfo, error) {
+	delay(fsys.stat)
+	return fsys.fileSystem.Lstat(relpath)
+}
+
+// Stat delays the stat call
+func (fsys latencyFS) Stat(relpath string) (os.FileInfo, error) {
+	delay(fsys.stat)
+	return fsys.fileSystem.Stat(relpath)
+}
+
+// Readlink delays the readlink call
+func (fsys latencyFS) Readlink(relpath string) (string, error) {
+	delay(fsys.stat)
+	return fsys.fileSystem.Readlink(relpath)
+}
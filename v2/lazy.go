@@ -0,0 +1,190 @@
+package cwalk
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// typeUnknown is the type of the directory entries which
+// the file system doesn't report in its listing (DT_UNKNOWN)
+const typeUnknown = os.ModeIrregular
+
+// typeLister is implemented by the fileSystems which list the types
+// of the directory entries along with their names (from d_type), so
+// that the entries need not be stat'ed to tell files from directories
+type typeLister interface {
+	// ReadDirTypes returns the names of the directory entries and
+	// their types (type bits only, or typeUnknown); if reading fails
+	// midway, the entries obtained so far are returned along with the error
+	ReadDirTypes(relpath string) ([]string, []os.FileMode, error)
+}
+
+// WithLazyStat skips stat'ing the directory entries which type is
+// reported by the directory listing (d_type): their Entry.Info only
+// knows the name and the type, and lstat is called the first time any
+// other file info is asked for, such as the size, the permissions or
+// the modification time. The entries of a directory, their paths and
+// their file info are allocated along with the listing, so walks that
+// mostly look at the names, such as WalkMap() with a callback checking
+// d.Name() and d.Type(), take a few allocations per directory rather than
+// several per entry (see also WalkBytes()). The FileInfo also
+// implements fs.DirEntry, which Info() returns the stat'ed file info or
+// error.
+//
+// Directories are still stat'ed during the walk, and so are symlinks
+// when they are followed (see WithFollowSymlinks()), and all entries
+// whose type is unknown. If a file can't be stat'ed later (e.g. it was
+// removed in the meantime), no error is reported by the walk: its
+// Size() and ModTime() are zero, and Mode() reports the type only. The
+// file info should not be used once the walk has returned, as the root
+// may no longer be accessible (see WithConfineToRoot()). The types are
+// listed on Linux only, with the directory cache disabled (see
+// WithCache()); elsewhere, the option has no effect.
+func WithLazyStat() Option {
+	return func(w *Walker) {
+		w.lazyStat = true
+	}
+}
+
+// readDirLazy reads the directory listing along with the types of the
+// entries, and fills in the file info of the entries that need not be
+// stat'ed; ok is false if the file system doesn't list the types
+func (w *Walker) readDirLazy(relpath string) (names []string, infos []os.FileInfo, ok bool, err error) {
+	lister, ok := w.fs.(typeLister)
+	if !ok {
+		return nil, nil, false, nil
+	}
+	var types []os.FileMode
+	err = w.withFDRetry(func() (bool, error) {
+		names, types, err = lister.ReadDirTypes(relpath)
+		return true, err
+	})
+	// the file info of the whole listing is allocated at once
+	lazy := make([]lazyInfo, len(names))
+	infos = make([]os.FileInfo, len(names))
+	for i, typ := range types {
+		if typ == typeUnknown || typ.IsDir() || w.followSymlinks && typ&os.ModeSymlink != 0 {
+			continue // stat'ed during the walk
+		}
+		lazy[i] = lazyInfo{fs: w.fs, dir: relpath, name: names[i], typ: typ}
+		infos[i] = &lazy[i]
+	}
+	return names, infos, true, err
+}
+
+// lazyInfo implements os.FileInfo and fs.DirEntry for a directory
+// entry which type is known from the listing: it is stat'ed once,
+// when more than its name and type is asked for
+type lazyInfo struct {
+	fs   fileSystem
+	dir  string
+	name string
+	typ  os.FileMode
+	once sync.Once
+	info os.FileInfo
+	err  error
+}
+
+// stat returns the file info, stat'ing the file the first time
+func (li *lazyInfo) stat() os.FileInfo {
+	li.once.Do(func() {
+		li.info, li.err = li.fs.Lstat(filepath.Join(li.dir, li.name))
+	})
+	return li.info
+}
+
+func (li *lazyInfo) Name() string      { return li.name }
+func (li *lazyInfo) IsDir() bool       { return li.typ.IsDir() }
+func (li *lazyInfo) Type() fs.FileMode { return li.typ }
+
+// Info returns the stat'ed file info
+func (li *lazyInfo) Info() (fs.FileInfo, error) {
+	if info := li.stat(); info != nil {
+		return info, nil
+	}
+	return nil, li.err
+}
+
+func (li *lazyInfo) Size() int64 {
+	if info := li.stat(); info != nil {
+		return info.Size()
+	}
+	return 0
+}
+
+func (li *lazyInfo) Mode() os.FileMode {
+	if info := li.stat(); info != nil {
+		return info.Mode()
+	}
+	return li.typ
+}
+
+func (li *lazyInfo) ModTime() time.Time {
+	if info := li.stat(); info != nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+func (li *lazyInfo) Sys() interface{} {
+	if info := li.stat(); info != nil {
+		return info.Sys()
+	}
+	return nil
+}
+
+// listingPaths holds the relative and the absolute paths of the entries
+// of a directory, all sliced from a single string
+type listingPaths struct {
+	all  string
+	ends []int // the end of the absolute path of every entry
+	skip int   // the length of the root and the separator following it
+}
+
+// joinListing returns the paths of the directory entries, sharing
+// a single allocation; relpath and the names are clean, and so is the
+// root once made absolute, so they are simply concatenated. It returns
+// nil if the root couldn't be made absolute.
+func (w *Walker) joinListing(relpath string, names []string) *listingPaths {
+	root := w.absRoot
+	if !filepath.IsAbs(root) {
+		return nil
+	}
+	const sep = string(filepath.Separator)
+	rootSep := sep
+	if os.IsPathSeparator(root[len(root)-1]) {
+		rootSep = "" // e.g. "/"
+	}
+	dir := root + rootSep
+	if relpath != "" {
+		dir += relpath + sep
+	}
+	size := 0
+	for _, name := range names {
+		size += len(dir) + len(name)
+	}
+	var b strings.Builder
+	b.Grow(size)
+	paths := &listingPaths{ends: make([]int, len(names)), skip: len(root) + len(rootSep)}
+	for i, name := range names {
+		b.WriteString(dir)
+		b.WriteString(name)
+		paths.ends[i] = b.Len()
+	}
+	paths.all = b.String()
+	return paths
+}
+
+// path returns the relative and the absolute path of the i-th entry
+func (p *listingPaths) path(i int) (subpath, abspath string) {
+	start := 0
+	if i > 0 {
+		start = p.ends[i-1]
+	}
+	abspath = p.all[start:p.ends[i]]
+	return abspath[p.skip:], abspath
+}
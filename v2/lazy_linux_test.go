@@ -0,0 +1,217 @@
+package cwalk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+func TestLazyStat(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "f"), []byte("abc"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "gone"), []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("f", filepath.Join(root, "l")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, follow := range []bool{false, true} {
+		opts := []Option{WithLazyStat()}
+		if follow {
+			opts = append(opts, WithFollowSymlinks())
+		}
+		var mu sync.Mutex
+		infos := map[string]os.FileInfo{}
+		err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+			if path == "gone" {
+				// the file is only stat'ed when asked for more than its type
+				if err := os.Remove(filepath.Join(root, path)); err != nil {
+					return err
+				}
+				if info.Size() != 0 || info.Mode() != 0 {
+					t.Errorf("got size %d, mode %v for the removed file", info.Size(), info.Mode())
+				}
+			}
+			mu.Lock()
+			infos[path] = info
+			mu.Unlock()
+			return err
+		}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, "gone"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, c := range []struct {
+			path string
+			lazy bool
+			mode os.FileMode
+		}{
+			{"f", true, 0640},
+			{"d", false, os.ModeDir | 0755},
+			{"l", !follow, os.ModeSymlink | 0777},
+		} {
+			info := infos[c.path]
+			if _, lazy := info.(*lazyInfo); lazy != c.lazy {
+				t.Errorf("follow %v: %s is lazy: %v, want %v", follow, c.path, lazy, c.lazy)
+			}
+			mode := c.mode
+			if follow && c.path == "l" {
+				mode = 0640 // the file info of the target
+			}
+			if info.Mode() != mode {
+				t.Errorf("follow %v: %s has mode %v, want %v", follow, c.path, info.Mode(), mode)
+			}
+		}
+		if size := infos["f"].Size(); size != 3 {
+			t.Errorf("follow %v: f has size %d, want 3", follow, size)
+		}
+	}
+}
+
+// typesFS is a fileSystem listing the given entries and types
+// rather than the ones of the directory, followed by err
+type typesFS struct {
+	osFS
+	names []string
+	types []os.FileMode
+	err   error
+}
+
+func (fsys typesFS) ReadDirTypes(relpath string) ([]string, []os.FileMode, error) {
+	return fsys.names, fsys.types, fsys.err
+}
+
+func TestReadDirLazy(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "f"), []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// a listing which failed midway, with
+	// an entry the file system doesn't type
+	fsys := typesFS{
+		osFS:  osFS{root: root},
+		names: []string{"f", "unknown", "d", "l"},
+		types: []os.FileMode{0, typeUnknown, os.ModeDir, os.ModeSymlink},
+		err:   io.ErrUnexpectedEOF,
+	}
+	for _, follow := range []bool{false, true} {
+		w := NewWalker(root)
+		w.fs = fsys
+		w.followSymlinks = follow
+		names, infos, ok, err := w.readDirLazy("")
+		if !ok {
+			t.Fatal("the types are not listed")
+		}
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("got error %v, want the listing error", err)
+		}
+		if fmt.Sprint(names) != "[f unknown d l]" {
+			t.Errorf("got names %v, want the ones listed before the error", names)
+		}
+		// the entries of unknown type, the directories and the
+		// followed symlinks are left to be stat'ed during the walk
+		want := []bool{true, false, false, !follow}
+		for i, info := range infos {
+			if (info != nil) != want[i] {
+				t.Errorf("follow %v: %s has file info %v", follow, names[i], info)
+			}
+		}
+		if size := infos[0].Size(); size != 3 {
+			t.Errorf("f has size %d, want 3", size)
+		}
+	}
+
+	// the file system doesn't list the types
+	w := NewWalker(root)
+	w.fs = latencyFS{fileSystem: osFS{root: root}}
+	if _, _, ok, _ := w.readDirLazy(""); ok {
+		t.Error("got a listing from a file system without types")
+	}
+}
+
+func TestReadDirTypes(t *testing.T) {
+	root := t.TempDir()
+	// more entries than the buffer of a single getdents(2) call holds
+	for i := 0; i < 2*direntBufSize/40; i++ {
+		name := filepath.Join(root, fmt.Sprintf("file-with-a-rather-long-name-%04d", i))
+		if err := os.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("dir", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(root, "fifo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, types, err := osFS{root: root}.ReadDirTypes("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != len(entries) || len(types) != len(entries) {
+		t.Fatalf("got %d names and %d types, want %d", len(names), len(types), len(entries))
+	}
+	listed := map[string]os.FileMode{}
+	for i, name := range names {
+		listed[name] = types[i]
+	}
+	for _, e := range entries {
+		typ, ok := listed[e.Name()]
+		if !ok {
+			t.Errorf("%s is not listed", e.Name())
+		} else if typ != typeUnknown && typ != e.Type() {
+			t.Errorf("%s has type %v, want %v", e.Name(), typ, e.Type())
+		}
+	}
+
+	// reading a file which is not a directory fails
+	file, err := os.Open(filepath.Join(root, "file-with-a-rather-long-name-0000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	names, _, err = readDirTypes(int(file.Fd()), file.Name())
+	if !errors.Is(err, syscall.ENOTDIR) || len(names) != 0 {
+		t.Errorf("got %v and %d names for a file, want ENOTDIR", err, len(names))
+	}
+}
+
+func TestDirentType(t *testing.T) {
+	for typ, want := range map[byte]os.FileMode{
+		syscall.DT_REG:     0,
+		syscall.DT_DIR:     os.ModeDir,
+		syscall.DT_LNK:     os.ModeSymlink,
+		syscall.DT_BLK:     os.ModeDevice,
+		syscall.DT_CHR:     os.ModeDevice | os.ModeCharDevice,
+		syscall.DT_FIFO:    os.ModeNamedPipe,
+		syscall.DT_SOCK:    os.ModeSocket,
+		syscall.DT_UNKNOWN: typeUnknown,
+		syscall.DT_WHT:     typeUnknown,
+	} {
+		if got := direntType(typ); got != want {
+			t.Errorf("d_type %d: got %v, want %v", typ, got, want)
+		}
+	}
+}
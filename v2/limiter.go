@@ -0,0 +1,46 @@
+package cwalk
+
+import "context"
+
+// Limiter is an external concurrency limit shared with the rest of the
+// application, such as a global file descriptor budget; its method set
+// matches *semaphore.Weighted from golang.org/x/sync/semaphore
+type Limiter interface {
+	Acquire(ctx context.Context, n int64) error
+	Release(n int64)
+}
+
+// WithLimiter makes every worker acquire a unit of l for the time
+// a directory, or a file read by the content stages, is open, so that
+// the walk composes with application-wide I/O budgets instead of
+// competing with them (see FDBudget). Acquire is called with a context
+// derived from the one set by WithContext() (if any), which is also
+// canceled by Stop(); if it fails, the walk is aborted and returns
+// the error.
+func WithLimiter(l Limiter) Option {
+	return func(w *Walker) {
+		w.limiter = l
+	}
+}
+
+// acquire acquires a unit of the limiter, aborting the walk on failure
+func (w *Walker) acquire() bool {
+	if w.limiter == nil {
+		return true
+	}
+	if err := w.limiter.Acquire(w.abortCtx, 1); err != nil {
+		if !w.isAborted() {
+			w.setWalkError(err)
+			w.abort()
+		}
+		return false
+	}
+	return true
+}
+
+// release releases the unit acquired by acquire()
+func (w *Walker) release() {
+	if w.limiter != nil {
+		w.limiter.Release(1)
+	}
+}
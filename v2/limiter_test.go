@@ -0,0 +1,58 @@
+package cwalk
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// heldLimiter is a Limiter with a single unit, held by the application
+type heldLimiter chan struct{}
+
+func (l heldLimiter) Acquire(ctx context.Context, n int64) error {
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l heldLimiter) Release(n int64) {
+	<-l
+}
+
+func TestStopUnblocksLimiter(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	l := make(heldLimiter, 1)
+	l <- struct{}{} // held by the application for the whole test
+
+	w := NewWalker(root, WithLimiter(l))
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Walk("", func(path string, info os.FileInfo, err error) error {
+			return err
+		})
+	}()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		w.Stop() // a no-op until the walk has started
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrStopped) {
+				t.Fatalf("Walk returned %v, want ErrStopped", err)
+			}
+			return
+		case <-timeout:
+			t.Fatal("Walk is still blocked on the limiter after Stop()")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
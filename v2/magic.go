@@ -0,0 +1,207 @@
+package cwalk
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// magicHeadSize is the number of bytes read from the beginning
+// of every file to detect its type
+const magicHeadSize = 512
+
+// magicPart is a sequence of bytes expected at an offset
+type magicPart struct {
+	offset int
+	magic  string
+}
+
+// magicType is a file type, recognized by all the parts
+// of any of its signatures, and its usual extensions
+type magicType struct {
+	name       string
+	signatures [][]magicPart
+	exts       []string
+}
+
+// sig returns single-part signatures for each of the magic strings
+func sig(magics ...string) [][]magicPart {
+	sigs := make([][]magicPart, len(magics))
+	for i, m := range magics {
+		sigs[i] = []magicPart{{0, m}}
+	}
+	return sigs
+}
+
+// magicTypes are the file types detected by DetectType(), the more
+// specific signatures first (e.g. WebP before other RIFF files)
+var magicTypes = []magicType{
+	{"pdf", sig("%PDF-"), []string{".pdf", ".ai"}},
+	{"png", sig("\x89PNG\r\n\x1a\n"), []string{".png"}},
+	{"jpeg", sig("\xff\xd8\xff"), []string{".jpg", ".jpeg", ".jpe", ".jfif"}},
+	{"gif", sig("GIF87a", "GIF89a"), []string{".gif"}},
+	{"webp", [][]magicPart{{{0, "RIFF"}, {8, "WEBP"}}}, []string{".webp"}},
+	{"wav", [][]magicPart{{{0, "RIFF"}, {8, "WAVE"}}}, []string{".wav"}},
+	{"avi", [][]magicPart{{{0, "RIFF"}, {8, "AVI "}}}, []string{".avi"}},
+	{"tiff", sig("II*\x00", "MM\x00*"), []string{".tif", ".tiff", ".dng", ".cr2", ".nef", ".arw"}},
+	{"bmp", [][]magicPart{{{0, "BM"}, {6, "\x00\x00\x00\x00"}}}, []string{".bmp", ".dib"}},
+	{"psd", sig("8BPS"), []string{".psd", ".psb"}},
+	{"mp4", [][]magicPart{{{4, "ftyp"}}}, []string{".mp4", ".m4a", ".m4v", ".mov", ".heic", ".heif", ".avif", ".3gp"}},
+	{"matroska", sig("\x1a\x45\xdf\xa3"), []string{".mkv", ".mka", ".webm"}},
+	{"mp3", sig("ID3"), []string{".mp3"}},
+	{"ogg", sig("OggS"), []string{".ogg", ".oga", ".ogv", ".opus"}},
+	{"flac", sig("fLaC"), []string{".flac"}},
+	{"zip", sig("PK\x03\x04", "PK\x05\x06"), []string{".zip", ".jar", ".war", ".apk", ".ipa", ".docx", ".xlsx",
+		".pptx", ".odt", ".ods", ".odp", ".epub", ".xpi", ".whl", ".nupkg", ".vsix", ".kmz"}},
+	{"gzip", sig("\x1f\x8b\x08"), []string{".gz", ".tgz"}},
+	{"bzip2", sig("BZh"), []string{".bz2", ".tbz", ".tbz2"}},
+	{"xz", sig("\xfd7zXZ\x00"), []string{".xz", ".txz"}},
+	{"zstd", sig("\x28\xb5\x2f\xfd"), []string{".zst", ".tzst"}},
+	{"7z", sig("7z\xbc\xaf\x27\x1c"), []string{".7z"}},
+	{"rar", sig("Rar!\x1a\x07"), []string{".rar"}},
+	{"tar", [][]magicPart{{{257, "ustar"}}}, []string{".tar"}},
+	{"ole2", sig("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"), []string{".doc", ".xls", ".ppt", ".msi", ".msg", ".vsd"}},
+	{"rtf", sig("{\\rtf"), []string{".rtf"}},
+	{"sqlite", sig("SQLite format 3\x00"), []string{".sqlite", ".sqlite3", ".db"}},
+	{"elf", sig("\x7fELF"), []string{"", ".so", ".o", ".ko", ".elf"}},
+	{"pe", sig("MZ"), []string{".exe", ".dll", ".sys", ".scr", ".ocx", ".cpl", ".efi", ".com"}},
+	{"macho", sig("\xfe\xed\xfa\xce", "\xfe\xed\xfa\xcf", "\xce\xfa\xed\xfe", "\xcf\xfa\xed\xfe"), []string{"", ".dylib", ".bundle"}},
+	{"java-class", sig("\xca\xfe\xba\xbe"), []string{".class"}},
+	{"wasm", sig("\x00asm"), []string{".wasm"}},
+}
+
+// textExtensions are the extensions of text formats,
+// which files are not expected to have magic bytes
+var textExtensions = map[string]bool{
+	".txt": true, ".log": true, ".md": true, ".csv": true, ".tsv": true, ".json": true, ".xml": true,
+	".html": true, ".htm": true, ".css": true, ".js": true, ".ts": true, ".svg": true, ".ini": true,
+	".cfg": true, ".conf": true, ".yaml": true, ".yml": true, ".toml": true, ".sh": true, ".bat": true,
+	".ps1": true, ".py": true, ".rb": true, ".pl": true, ".go": true, ".c": true, ".h": true, ".cpp": true,
+	".java": true, ".cs": true, ".php": true, ".sql": true,
+}
+
+// DetectType returns the type of the file which content starts with
+// head (of which 512 bytes are enough), detected by its magic bytes,
+// such as "pdf", "zip" or "elf"; "" if unknown
+func DetectType(head []byte) string {
+	for _, t := range magicTypes {
+		for _, parts := range t.signatures {
+			if matchMagic(head, parts) {
+				return t.name
+			}
+		}
+	}
+	return ""
+}
+
+// matchMagic reports whether all the parts of the signature match
+func matchMagic(head []byte, parts []magicPart) bool {
+	for _, p := range parts {
+		end := p.offset + len(p.magic)
+		if end > len(head) || !bytes.Equal(head[p.offset:end], []byte(p.magic)) {
+			return false
+		}
+	}
+	return true
+}
+
+// expectedTypes maps the extensions known to the
+// census to the types their files are expected to have
+var expectedTypes = func() map[string][]string {
+	m := map[string][]string{}
+	for _, t := range magicTypes {
+		for _, ext := range t.exts {
+			if ext != "" {
+				m[ext] = append(m[ext], t.name)
+			}
+		}
+	}
+	return m
+}()
+
+// typeMismatch reports whether the detected type contradicts the
+// extension: files with the extension of a type having magic bytes
+// are expected to have them, and files with the extension of a text
+// format are not expected to have any
+func typeMismatch(ext, typ string) bool {
+	if textExtensions[ext] {
+		return typ != ""
+	}
+	expected, known := expectedTypes[ext]
+	if !known {
+		return false
+	}
+	for _, t := range expected {
+		if t == typ {
+			return false
+		}
+	}
+	return true
+}
+
+// TypeMismatch is a file which type contradicts its extension,
+// see TypeCensus()
+type TypeMismatch struct {
+	Path      string
+	Extension string // in lower case, with the dot
+	Type      string // detected type, "" if unknown
+}
+
+// TypeReport is the result of TypeCensus()
+type TypeReport struct {
+	Types      map[string]int64            // number of files of each detected type ("" for unknown, "empty" for empty files)
+	Extensions map[string]map[string]int64 // number of files of each type, by extension ("" for none)
+	Mismatches []TypeMismatch              // in lexical order of their paths
+}
+
+// TypeCensus walks root and counts the regular files by their true type
+// detected from their magic bytes (see DetectType()) and by extension,
+// and lists the files which type contradicts their extension, such as
+// executables renamed to .jpg, or documents which no longer start with
+// the bytes of their format (e.g. once encrypted). Only the first bytes
+// of every file are read, by the content stages, so the reads are bounded
+// like those of other stages (see WithLimiter() and WithFDBudget());
+// read-ahead is disabled (see WithoutPrefetch()). Errors are returned
+// after the walk along with the report of the files that could be read.
+func TypeCensus(root string, opts ...Option) (TypeReport, error) {
+	report := TypeReport{Types: map[string]int64{}, Extensions: map[string]map[string]int64{}}
+	var mu sync.Mutex
+	stage := func(entry *Entry, r io.Reader) error {
+		head := make([]byte, magicHeadSize)
+		n, err := io.ReadFull(r, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		typ := DetectType(head[:n])
+		if n == 0 {
+			typ = "empty"
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Path))
+		mu.Lock()
+		defer mu.Unlock()
+		report.Types[typ]++
+		byType := report.Extensions[ext]
+		if byType == nil {
+			byType = map[string]int64{}
+			report.Extensions[ext] = byType
+		}
+		byType[typ]++
+		if n > 0 && typeMismatch(ext, typ) {
+			report.Mismatches = append(report.Mismatches, TypeMismatch{Path: entry.Path, Extension: ext, Type: typ})
+		}
+		return nil
+	}
+
+	opts = append(opts[:len(opts):len(opts)], WithoutPrefetch(), WithContentStage(stage))
+	err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+		return err
+	}, opts...)
+	sort.Slice(report.Mismatches, func(i, j int) bool {
+		return report.Mismatches[i].Path < report.Mismatches[j].Path
+	})
+	return report, err
+}
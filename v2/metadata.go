@@ -0,0 +1,282 @@
+package cwalk
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Metadata holds the extended file metadata collected
+// when the WithEnrichedMetadata() option is used; the fields
+// not supported on the platform are left empty
+type Metadata struct {
+	UID   uint32
+	GID   uint32
+	User  string // user name, or the numeric UID if it can't be resolved
+	Group string // group name, or the numeric GID if it can't be resolved
+
+	// BirthTime is the creation time of the file; HasBirthTime is false
+	// if the platform or the file system doesn't record it (on Linux,
+	// it requires statx(2), available since Linux 4.11)
+	BirthTime    time.Time
+	HasBirthTime bool
+
+	Flags FileFlags
+
+	// symbolic links only: the target as stored in the link, whether
+	// it is an absolute path, and the type bits of the file it resolves
+	// to (zero for regular files); LinkBroken is set if the target
+	// can't be resolved
+	LinkTarget   string
+	LinkAbsolute bool
+	LinkType     os.FileMode
+	LinkBroken   bool
+
+	// Windows only: file attributes (FILE_ATTRIBUTE_*), the reparse
+	// tag (IO_REPARSE_TAG_*) of reparse points, and whether the file
+	// is a cloud placeholder (e.g. OneDrive Files On-Demand) which
+	// content is not available locally
+	Attributes       uint32
+	ReparseTag       uint32
+	CloudPlaceholder bool
+
+	// Windows only, see WithWindowsACL()
+	OwnerSID string
+	DACL     []ACE
+	ACLError error // set if the security information could not be read
+
+	// Linux only, see WithSecurityLabels()
+	SecurityLabel string
+	LabelError    error // set if the label could not be read
+}
+
+// FileFlags are the file flags reported in Metadata.Flags
+type FileFlags uint32
+
+const (
+	// FlagImmutable is set for files which can't be modified, renamed
+	// or deleted (chattr +i on Linux, the BSD uchg and schg flags)
+	FlagImmutable FileFlags = 1 << iota
+
+	// FlagAppendOnly is set for files which can only be appended to
+	// (chattr +a on Linux, the BSD uappnd and sappnd flags)
+	FlagAppendOnly
+
+	// FlagHidden is set for hidden files: the ones with names starting
+	// with a dot on Unix, or with the UF_HIDDEN flag on macOS and FreeBSD,
+	// and the ones with the hidden attribute on Windows
+	FlagHidden
+
+	// FlagSystem is set for the files with the system attribute (Windows only)
+	FlagSystem
+)
+
+// ACE is a summary of a single access control entry of a DACL
+type ACE struct {
+	Type      string // "allow", "deny" or "other"
+	SID       string
+	Account   string // DOMAIN\name, or the SID if it can't be resolved
+	Mask      uint32 // access mask
+	Inherited bool
+}
+
+// WithEnrichedMetadata makes the walk collect extended metadata
+// for every entry, available as Entry.Meta
+func WithEnrichedMetadata() Option {
+	return func(w *Walker) {
+		w.enrich = true
+	}
+}
+
+// WithWindowsACL makes the walk collect the owner SID and a summary
+// of the DACL of every entry into Entry.Meta on Windows (where the owner
+// account name is also reported as Metadata.User); it implies
+// WithEnrichedMetadata(). The security information is read
+// by the workers, so this scales with the number of workers.
+// This option has no effect on other platforms.
+func WithWindowsACL() Option {
+	return func(w *Walker) {
+		w.enrich = true
+		w.windowsACL = true
+	}
+}
+
+// WithSecurityLabels makes the walk collect the security label of
+// every entry into Metadata.SecurityLabel on Linux: the SELinux context
+// (the security.selinux extended attribute), or the Smack label on
+// systems using Smack instead; it implies WithEnrichedMetadata().
+// AppArmor confines programs by path rather than by labeling files, so
+// there is nothing to collect for it. The labels are read by the
+// workers without opening the files, so this scales with their number;
+// see SecurityLabelCounts() for policy audits of large trees.
+// This option has no effect on other platforms.
+func WithSecurityLabels() Option {
+	return func(w *Walker) {
+		w.enrich = true
+		w.securityLabels = true
+	}
+}
+
+// SecurityLabelCounts walks root and returns the number of entries
+// having each security label (see WithSecurityLabels()), counting the
+// entries without a label under "". The counts are accumulated by each
+// worker and merged once the walk is complete. The entries which label
+// could not be read are reported as errors, which are returned after
+// the walk along with the counts.
+func SecurityLabelCounts(root string, opts ...Option) (map[string]int64, error) {
+	w := NewWalker(root, append(opts[:len(opts):len(opts)], WithSecurityLabels())...)
+	w.labelErrors = true
+	counts := map[string]int64{}
+	var mu sync.Mutex
+	err := w.walk("", func(ws *workerState, entry *Entry, err error) error {
+		if err != nil || entry.Meta == nil {
+			return err
+		}
+		if entry.Meta.LabelError != nil {
+			return nil // reported as an error of the walk
+		}
+		if ws == nil { // the root
+			mu.Lock()
+			counts[entry.Meta.SecurityLabel]++
+			mu.Unlock()
+			return nil
+		}
+		if ws.local == nil {
+			ws.local = map[string]int64{}
+		}
+		ws.local.(map[string]int64)[entry.Meta.SecurityLabel]++
+		return nil
+	})
+
+	// the workers have exited, so their counts can be read here
+	w.mu.Lock()
+	for _, ws := range w.workers {
+		if local, ok := ws.local.(map[string]int64); ok {
+			for label, n := range local {
+				counts[label] += n
+			}
+		}
+	}
+	w.mu.Unlock()
+	return counts, err
+}
+
+// enrichEntry collects the extended metadata for the entry
+func (w *Walker) enrichEntry(entry *Entry) {
+	if entry.Info == nil {
+		return
+	}
+	meta := &Metadata{}
+	fillFileInfo(meta, w.fs, entry.Path, entry.Info)
+	if entry.Info.Mode()&os.ModeSymlink != 0 {
+		fillLinkTarget(meta, w.fs, entry.Path)
+	}
+	w.fillMetadata(meta, w.osPath(entry.Path), entry.Info)
+	if w.securityLabels {
+		meta.SecurityLabel, meta.LabelError = readSecurityLabel(w.fs, entry.Path)
+		if meta.LabelError != nil && w.labelErrors {
+			w.addError(entry.Path, meta.LabelError)
+		}
+	}
+	entry.Meta = meta
+}
+
+// fillLinkTarget fills the symbolic link fields; the target is
+// resolved through the fileSystem, so that it is subject to the same
+// confinement (see WithConfineToRoot()) as the walk itself
+func fillLinkTarget(meta *Metadata, fsys fileSystem, relpath string) {
+	target, err := fsys.Readlink(relpath)
+	if err != nil {
+		meta.LinkBroken = true
+		return
+	}
+	meta.LinkTarget = target
+	meta.LinkAbsolute = filepath.IsAbs(target)
+	info, err := fsys.Stat(relpath)
+	if err != nil {
+		meta.LinkBroken = true
+		return
+	}
+	meta.LinkType = info.Mode().Type()
+}
+
+// WithSkipHidden prunes hidden files and directories, using the same
+// rules as FlagHidden, except that on Windows the files with the system
+// attribute are skipped as well. The root itself is never skipped.
+func WithSkipHidden() Option {
+	return func(w *Walker) {
+		w.addFilter("skip hidden", w.exceptRoot(func(relpath string, info os.FileInfo) FilterAction {
+			if info != nil && isHidden(info) {
+				return FilterPrune
+			}
+			return FilterInclude
+		}))
+	}
+}
+
+// isDotFile reports whether the name follows the Unix convention
+// for hidden files
+func isDotFile(name string) bool {
+	return len(name) > 1 && name[0] == '.' && name != ".."
+}
+
+// nameCache resolves user and group IDs to names, caching the results
+// (including the failed lookups), so that there is only one lookup
+// per ID no matter how many files are owned by it
+type nameCache struct {
+	mu     sync.Mutex
+	users  map[uint32]string
+	groups map[uint32]string
+}
+
+// owners is shared by all walks, as the names rarely change
+// during the lifetime of a process
+var owners = &nameCache{
+	users:  map[uint32]string{},
+	groups: map[uint32]string{},
+}
+
+// user returns the name of the user with the given ID
+func (c *nameCache) user(uid uint32) string {
+	return c.resolve(c.users, uid, func(id string) (string, error) {
+		u, err := user.LookupId(id)
+		if err != nil {
+			return "", err
+		}
+		return u.Username, nil
+	})
+}
+
+// group returns the name of the group with the given ID
+func (c *nameCache) group(gid uint32) string {
+	return c.resolve(c.groups, gid, func(id string) (string, error) {
+		g, err := user.LookupGroupId(id)
+		if err != nil {
+			return "", err
+		}
+		return g.Name, nil
+	})
+}
+
+// resolve looks the ID up in the cache, calling lookup on a miss
+func (c *nameCache) resolve(cache map[uint32]string, id uint32, lookup func(string) (string, error)) string {
+	c.mu.Lock()
+	name, ok := cache[id]
+	c.mu.Unlock()
+	if ok {
+		return name
+	}
+
+	numeric := strconv.FormatUint(uint64(id), 10)
+	name, err := lookup(numeric)
+	if err != nil {
+		name = numeric
+	}
+	c.mu.Lock()
+	cache[id] = name
+	c.mu.Unlock()
+	return name
+}
@@ -0,0 +1,10 @@
+//go:build plan9
+// +build plan9
+
+package cwalk
+
+import "os"
+
+// fillMetadata fills the platform-specific metadata fields
+func (w *Walker) fillMetadata(meta *Metadata, path string, info os.FileInfo) {
+}
@@ -0,0 +1,21 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package cwalk
+
+import (
+	"os"
+	"syscall"
+)
+
+// fillMetadata fills the platform-specific metadata fields
+func (w *Walker) fillMetadata(meta *Metadata, path string, info os.FileInfo) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	meta.UID = uint32(st.Uid)
+	meta.GID = uint32(st.Gid)
+	meta.User = owners.user(meta.UID)
+	meta.Group = owners.group(meta.GID)
+}
@@ -0,0 +1,165 @@
+package cwalk
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// Windows security API definitions missing from the syscall package
+const (
+	seFileObject             = 1
+	ownerSecurityInformation = 0x1
+	daclSecurityInformation  = 0x4
+	accessAllowedAceType     = 0
+	accessDeniedAceType      = 1
+	inheritedAce             = 0x10
+	errorSuccess             = 0
+)
+
+// Windows file attributes and reparse tags missing from the syscall package
+const (
+	fileAttributeOffline            = 0x1000
+	fileAttributeRecallOnOpen       = 0x40000
+	fileAttributeRecallOnDataAccess = 0x400000
+	ioReparseTagCloud               = 0x9000001a
+	ioReparseTagCloudMask           = 0x0000f000 // cloud tags differ in these bits
+)
+
+var (
+	modAdvapi32               = syscall.NewLazyDLL("advapi32.dll")
+	procGetNamedSecurityInfoW = modAdvapi32.NewProc("GetNamedSecurityInfoW")
+	procGetAce                = modAdvapi32.NewProc("GetAce")
+	procLocalFree             = modKernel32.NewProc("LocalFree")
+)
+
+// acl is the ACL structure header
+type acl struct {
+	revision byte
+	sbz1     byte
+	size     uint16
+	aceCount uint16
+	sbz2     uint16
+}
+
+// accessAce is the common layout of ACCESS_ALLOWED_ACE
+// and ACCESS_DENIED_ACE; the SID starts at sidStart
+type accessAce struct {
+	aceType  byte
+	aceFlags byte
+	aceSize  uint16
+	mask     uint32
+	sidStart uint32
+}
+
+// accounts caches the account names of SIDs
+var accounts sync.Map // SID string -> account name
+
+// fillMetadata fills the platform-specific metadata fields
+func (w *Walker) fillMetadata(meta *Metadata, path string, info os.FileInfo) {
+	if attrs, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		meta.Attributes = attrs.FileAttributes
+	}
+	if meta.Attributes&syscall.FILE_ATTRIBUTE_REPARSE_POINT != 0 {
+		meta.ReparseTag = reparseTag(path)
+	}
+	meta.CloudPlaceholder = meta.Attributes&(fileAttributeRecallOnOpen|
+		fileAttributeRecallOnDataAccess|fileAttributeOffline) != 0 ||
+		meta.ReparseTag&^ioReparseTagCloudMask == ioReparseTagCloud
+	if w.windowsACL {
+		meta.ACLError = readSecurity(meta, path)
+	}
+}
+
+// reparseTag returns the reparse tag of the file, which
+// FindFirstFile reports in WIN32_FIND_DATA.dwReserved0
+func reparseTag(path string) uint32 {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0
+	}
+	var data syscall.Win32finddata
+	h, err := syscall.FindFirstFile(p, &data)
+	if err != nil {
+		return 0
+	}
+	syscall.FindClose(h)
+	return data.Reserved0
+}
+
+// readSecurity reads the owner and the DACL of the file
+func readSecurity(meta *Metadata, path string) error {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	var owner *syscall.SID
+	var dacl *acl
+	var sd uintptr
+	ret, _, _ := procGetNamedSecurityInfoW.Call(
+		uintptr(unsafe.Pointer(p)), seFileObject,
+		ownerSecurityInformation|daclSecurityInformation,
+		uintptr(unsafe.Pointer(&owner)), 0,
+		uintptr(unsafe.Pointer(&dacl)), 0,
+		uintptr(unsafe.Pointer(&sd)))
+	if ret != errorSuccess {
+		return &os.PathError{Op: "GetNamedSecurityInfo", Path: path, Err: syscall.Errno(ret)}
+	}
+	defer procLocalFree.Call(sd)
+
+	if owner != nil {
+		meta.OwnerSID, meta.User = describeSID(owner)
+	}
+	if dacl == nil {
+		// a NULL DACL grants full access to everyone
+		return nil
+	}
+	meta.DACL = make([]ACE, 0, dacl.aceCount)
+	for i := uint16(0); i < dacl.aceCount; i++ {
+		var ace *accessAce
+		ret, _, _ := procGetAce.Call(uintptr(unsafe.Pointer(dacl)), uintptr(i), uintptr(unsafe.Pointer(&ace)))
+		if ret == 0 || ace == nil {
+			continue
+		}
+		entry := ACE{
+			Inherited: ace.aceFlags&inheritedAce != 0,
+		}
+		switch ace.aceType {
+		case accessAllowedAceType:
+			entry.Type = "allow"
+		case accessDeniedAceType:
+			entry.Type = "deny"
+		default:
+			// object and callback ACEs have different layouts
+			entry.Type = "other"
+			meta.DACL = append(meta.DACL, entry)
+			continue
+		}
+		entry.Mask = ace.mask
+		entry.SID, entry.Account = describeSID((*syscall.SID)(unsafe.Pointer(&ace.sidStart)))
+		meta.DACL = append(meta.DACL, entry)
+	}
+	return nil
+}
+
+// describeSID returns the string form of the SID
+// and the account name it resolves to (cached)
+func describeSID(sid *syscall.SID) (string, string) {
+	s, err := sid.String()
+	if err != nil {
+		return "", ""
+	}
+	if name, ok := accounts.Load(s); ok {
+		return s, name.(string)
+	}
+	name := s
+	if account, domain, _, err := sid.LookupAccount(""); err == nil {
+		name = account
+		if domain != "" {
+			name = domain + `\` + account
+		}
+	}
+	accounts.Store(s, name)
+	return s, name
+}
@@ -0,0 +1,368 @@
+package cwalk
+
+//go:generate go run gen_normtable.go
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NamePolicy tells which names CheckNames() reports as invalid
+// on the system a tree is to be migrated to
+type NamePolicy struct {
+	Reserved       bool // names reserved by Windows: CON, PRN, AUX, NUL, COM0-9 and LPT0-9, with any extension
+	InvalidChars   bool // characters invalid in Windows names: < > : " / \ | ? * and control characters
+	Trailing       bool // names ending with a space or a period, which Windows strips
+	InvalidUTF8    bool // names which are not valid UTF-8
+	CaseCollisions bool // names of the same directory differing only by case
+
+	// NormalizationCollisions reports the names of the same directory
+	// differing only by Unicode normalization form (e.g. "é" precomposed
+	// or as "e" followed by a combining acute accent), which macOS treats
+	// as the same name
+	NormalizationCollisions bool
+
+	// MaxNameLength and MaxPathLength are the maximum lengths of the names
+	// and of the paths, in UTF-16 code units like Windows counts them
+	// (0 for no limit); the length of a path is that of its path relative
+	// to the root plus BaseLength, the length of the destination directory
+	// the tree is to be moved to, including the trailing separator
+	MaxNameLength int
+	MaxPathLength int
+	BaseLength    int
+}
+
+// WindowsNamePolicy reports the names which can't be created
+// on NTFS by Windows applications not opted into long paths
+var WindowsNamePolicy = NamePolicy{
+	Reserved:       true,
+	InvalidChars:   true,
+	Trailing:       true,
+	InvalidUTF8:    true,
+	CaseCollisions: true,
+	MaxNameLength:  255,
+	MaxPathLength:  259, // MAX_PATH, less the terminating NUL
+}
+
+// SharePointNamePolicy reports the names which can't be uploaded to
+// SharePoint Online and OneDrive, which allow decoded paths of up to
+// 400 characters, the length of the site and library URL included
+// (set BaseLength accordingly)
+var SharePointNamePolicy = NamePolicy{
+	Reserved:       true,
+	InvalidChars:   true,
+	Trailing:       true,
+	InvalidUTF8:    true,
+	CaseCollisions: true,
+	MaxNameLength:  255,
+	MaxPathLength:  400,
+}
+
+// MacOSNamePolicy reports the names which collide on the default,
+// case-insensitive file systems of macOS, such as when checking out
+// a repository created on Linux
+var MacOSNamePolicy = NamePolicy{
+	InvalidUTF8:             true,
+	CaseCollisions:          true,
+	NormalizationCollisions: true,
+}
+
+// NameProblem tells why a name violates a NamePolicy
+type NameProblem int
+
+const (
+	// NameReserved means the name is reserved by Windows
+	NameReserved NameProblem = iota + 1
+
+	// NameInvalidChars means the name contains invalid characters
+	NameInvalidChars
+
+	// NameTrailing means the name ends with a space or a period
+	NameTrailing
+
+	// NameInvalidUTF8 means the name is not valid UTF-8
+	NameInvalidUTF8
+
+	// NameCaseCollision means other names of the same
+	// directory only differ from the name by case
+	NameCaseCollision
+
+	// NameNormalizationCollision means other names of the same directory
+	// only differ from the name by Unicode normalization form
+	NameNormalizationCollision
+
+	// NameDuplicate means the directory lists the name more than once,
+	// which only happens on corrupted or inconsistent file systems
+	NameDuplicate
+
+	// NameTooLong means the name is longer than MaxNameLength
+	NameTooLong
+
+	// PathTooLong means the path is longer than MaxPathLength
+	PathTooLong
+)
+
+// String returns a human-readable name problem
+func (p NameProblem) String() string {
+	switch p {
+	case NameReserved:
+		return "reserved name"
+	case NameInvalidChars:
+		return "invalid characters"
+	case NameTrailing:
+		return "trailing space or period"
+	case NameInvalidUTF8:
+		return "invalid UTF-8"
+	case NameCaseCollision:
+		return "case collision"
+	case NameNormalizationCollision:
+		return "normalization collision"
+	case NameDuplicate:
+		return "duplicate name"
+	case NameTooLong:
+		return "name too long"
+	case PathTooLong:
+		return "path too long"
+	}
+	return "unknown"
+}
+
+// NameViolation is a name found by CheckNames()
+type NameViolation struct {
+	Path    string // path relative to the root
+	Problem NameProblem
+	Others  []string // for collisions and duplicates: the colliding names of the same directory
+}
+
+// CheckNames walks root and returns the names which violate the policy,
+// ordered by path and problem, e.g. to find what has to be renamed before
+// migrating a share to NTFS or SharePoint (see WindowsNamePolicy and
+// SharePointNamePolicy). A name may have several problems, which are
+// reported separately. Collisions are found among all the names of every
+// directory, including those excluded by filters, along with the names
+// listed twice (if either kind of collision is checked); the other
+// problems are only reported for the entries passed to the callback.
+// Errors are returned after the walk along with the violations found.
+func CheckNames(root string, p NamePolicy, opts ...Option) ([]NameViolation, error) {
+	var mu sync.Mutex
+	var violations []NameViolation
+	report := func(v NameViolation) {
+		mu.Lock()
+		violations = append(violations, v)
+		mu.Unlock()
+	}
+
+	w := NewWalker(root, opts...)
+	if p.CaseCollisions || p.NormalizationCollisions {
+		w.listingFunc = func(relpath string, names []string) {
+			for _, v := range nameCollisions(relpath, names, &p) {
+				v.Path = w.userPath(v.Path)
+				report(v)
+			}
+		}
+	}
+	err := w.walk("", func(ws *workerState, entry *Entry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Path == "" {
+			return nil
+		}
+		name := filepath.Base(entry.Path)
+		for _, problem := range p.check(name) {
+			report(NameViolation{Path: entry.Path, Problem: problem})
+		}
+		if p.MaxPathLength > 0 && p.BaseLength+utf16Len(entry.Path) > p.MaxPathLength {
+			report(NameViolation{Path: entry.Path, Problem: PathTooLong})
+		}
+		return nil
+	})
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Path != violations[j].Path {
+			return violations[i].Path < violations[j].Path
+		}
+		return violations[i].Problem < violations[j].Problem
+	})
+	return violations, err
+}
+
+// check returns the problems of the name, except collisions
+func (p *NamePolicy) check(name string) []NameProblem {
+	var problems []NameProblem
+	if p.Reserved && isReservedName(name) {
+		problems = append(problems, NameReserved)
+	}
+	if p.InvalidChars && strings.IndexFunc(name, func(r rune) bool {
+		return r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r)
+	}) >= 0 {
+		problems = append(problems, NameInvalidChars)
+	}
+	if p.Trailing && (strings.HasSuffix(name, " ") || strings.HasSuffix(name, ".")) {
+		problems = append(problems, NameTrailing)
+	}
+	if p.InvalidUTF8 && !utf8.ValidString(name) {
+		problems = append(problems, NameInvalidUTF8)
+	}
+	if p.MaxNameLength > 0 && utf16Len(name) > p.MaxNameLength {
+		problems = append(problems, NameTooLong)
+	}
+	return problems
+}
+
+// isReservedName reports whether the name, without its extension
+// and trailing spaces, is one of the device names of Windows
+func isReservedName(name string) bool {
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.ToUpper(strings.TrimRight(name, " "))
+	switch name {
+	case "CON", "PRN", "AUX", "NUL":
+		return true
+	}
+	return len(name) == 4 && (strings.HasPrefix(name, "COM") || strings.HasPrefix(name, "LPT")) &&
+		name[3] >= '0' && name[3] <= '9'
+}
+
+// utf16Len returns the length of s in UTF-16 code units
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// nameCollisions returns the names of the directory which are listed
+// more than once, or only differ from other names by Unicode
+// normalization form or by case
+func nameCollisions(relpath string, names []string, p *NamePolicy) []NameViolation {
+	groups := map[string][]string{}
+	decomposed := map[string]string{}
+	for _, name := range names {
+		d := decomposeName(name)
+		decomposed[name] = d
+		key := d
+		if p.CaseCollisions {
+			key = foldName(d)
+		}
+		groups[key] = append(groups[key], name)
+	}
+	var violations []NameViolation
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Strings(group)
+		for i, name := range group {
+			others := make([]string, 0, len(group)-1)
+			others = append(others, group[:i]...)
+			others = append(others, group[i+1:]...)
+			problem := NameDuplicate
+			for _, other := range others {
+				if decomposed[other] != decomposed[name] {
+					problem = NameCaseCollision
+					break
+				} else if other != name && problem == NameDuplicate {
+					problem = NameNormalizationCollision
+				}
+			}
+			if problem == NameNormalizationCollision && !p.NormalizationCollisions {
+				continue // only differs from the others by normalization form
+			}
+			violations = append(violations, NameViolation{
+				Path:    filepath.Join(relpath, name),
+				Problem: problem,
+				Others:  others,
+			})
+		}
+	}
+	return violations
+}
+
+// Hangul syllables are decomposed algorithmically,
+// see section 3.12 of the Unicode standard
+const (
+	hangulBase  = 0xAC00
+	hangulCount = 11172
+	hangulLBase = 0x1100
+	hangulVBase = 0x1161
+	hangulTBase = 0x11A7
+	hangulTN    = 28
+	hangulNN    = 588 // number of vowels times hangulTN
+)
+
+// decomposeName returns the canonical decomposition of the name (NFD),
+// so that names which only differ by normalization form are equal;
+// invalid UTF-8 is left as is. The tables are in normtable.go, which
+// is generated by gen_normtable.go.
+func decomposeName(name string) string {
+	if !utf8.ValidString(name) {
+		return name
+	}
+	var runes []rune
+	for _, r := range name {
+		switch d, ok := canonicalDecompositions[r]; {
+		case ok:
+			runes = append(runes, []rune(d)...)
+		case r >= hangulBase && r < hangulBase+hangulCount:
+			s := r - hangulBase
+			runes = append(runes, hangulLBase+s/hangulNN, hangulVBase+(s%hangulNN)/hangulTN)
+			if t := s % hangulTN; t != 0 {
+				runes = append(runes, hangulTBase+t)
+			}
+		default:
+			runes = append(runes, r)
+		}
+	}
+	// put the combining characters following each base
+	// character in the canonical order
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && combiningClass(runes[j]) != 0 {
+			j++
+		}
+		if j-i > 1 {
+			marks := runes[i:j]
+			sort.SliceStable(marks, func(a, b int) bool {
+				return combiningClass(marks[a]) < combiningClass(marks[b])
+			})
+		}
+		i = j + 1
+	}
+	return string(runes)
+}
+
+// combiningClass returns the canonical combining class of the character
+func combiningClass(r rune) uint8 {
+	i := sort.Search(len(combiningClassRanges), func(i int) bool {
+		return combiningClassRanges[i].hi >= r
+	})
+	if i < len(combiningClassRanges) && combiningClassRanges[i].lo <= r {
+		return combiningClassRanges[i].class
+	}
+	return 0
+}
+
+// foldName returns the name with every letter replaced by
+// the smallest letter it is equal to under simple case folding
+func foldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+		return min
+	}, name)
+}
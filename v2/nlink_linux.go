@@ -0,0 +1,37 @@
+package cwalk
+
+import (
+	"os"
+	"syscall"
+)
+
+// reliableNlinkFS holds the magic numbers (see statfs(2)) of the file
+// systems known to count the subdirectories in the link count of
+// a directory; btrfs, overlayfs and most network file systems don't
+var reliableNlinkFS = map[uint32]bool{
+	0xef53:     true, // ext2, ext3 and ext4
+	0x58465342: true, // xfs
+	0x01021994: true, // tmpfs
+	0xf2f52010: true, // f2fs
+}
+
+// reliableNlink reports whether the link count of the directory
+// counts its subdirectories, based on the type of its file system,
+// which is looked up once per device
+func (w *Walker) reliableNlink(relpath string, info os.FileInfo) bool {
+	key, ok := fileKey(info)
+	if !ok {
+		return false
+	}
+	if reliable, ok := w.nlinkDevs.Load(key.Dev); ok {
+		return reliable.(bool)
+	}
+	reliable := false
+	if f, err := openPath(w.fs, relpath, info); err == nil {
+		var st syscall.Statfs_t
+		reliable = syscall.Fstatfs(int(f.Fd()), &st) == nil && reliableNlinkFS[uint32(st.Type)]
+		f.Close()
+	}
+	w.nlinkDevs.Store(key.Dev, reliable)
+	return reliable
+}
@@ -0,0 +1,36 @@
+package cwalk
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestReliableNlink(t *testing.T) {
+	root := t.TempDir()
+	info := mustLstat(t, root) // no subdirectories
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(root, &st); err != nil {
+		t.Fatal(err)
+	}
+	walker := func() *Walker {
+		w := NewWalker(root)
+		w.fs = osFS{root: root}
+		return w
+	}
+
+	// the directory is only assumed to be a leaf
+	// on the file systems known to count subdirectories
+	defer func(fs map[uint32]bool) { reliableNlinkFS = fs }(reliableNlinkFS)
+	for _, known := range []bool{false, true} {
+		reliableNlinkFS = map[uint32]bool{uint32(st.Type): known}
+		w := walker()
+		if got := w.isLeafDir("", info); got != known {
+			t.Errorf("known file system %v: got a leaf %v", known, got)
+		}
+		// the file system type is looked up once per device
+		reliableNlinkFS = map[uint32]bool{uint32(st.Type): !known}
+		if got := w.isLeafDir("", info); got != known {
+			t.Errorf("known file system %v: the type is looked up again", known)
+		}
+	}
+}
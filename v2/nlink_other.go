@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package cwalk
+
+import "os"
+
+// reliableNlink reports whether the link count of the directory counts
+// its subdirectories, which is not assumed on this platform
+func (w *Walker) reliableNlink(relpath string, info os.FileInfo) bool {
+	return false
+}
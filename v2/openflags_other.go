@@ -0,0 +1,12 @@
+//go:build windows || plan9 || js || wasip1
+// +build windows plan9 js wasip1
+
+package cwalk
+
+// oNoFollow and oNonBlock are zero, as these open flags are not
+// available on this platform; the files opened for the content
+// stages are still checked once they are open (see openContent())
+const (
+	oNoFollow = 0
+	oNonBlock = 0
+)
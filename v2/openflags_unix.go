@@ -0,0 +1,14 @@
+//go:build !windows && !plan9 && !js && !wasip1
+// +build !windows,!plan9,!js,!wasip1
+
+package cwalk
+
+import "syscall"
+
+// open flags making sure that opening the files read by the content
+// stages neither follows a symlink nor blocks (on a FIFO, for example),
+// should a file be replaced after it was listed
+const (
+	oNoFollow = syscall.O_NOFOLLOW
+	oNonBlock = syscall.O_NONBLOCK
+)
@@ -0,0 +1,184 @@
+package cwalk
+
+import (
+	"context"
+	"runtime"
+)
+
+// Option configures a Walker
+type Option func(*Walker)
+
+// MaxErrorsAction defines what happens once
+// the limit set by WithMaxErrors() is reached
+type MaxErrorsAction int
+
+const (
+	// StopCollecting continues the walk, but further errors
+	// are only counted in WalkerErrorList.Truncated
+	StopCollecting MaxErrorsAction = iota
+
+	// AbortWalk stops the walk as soon as possible
+	AbortWalk
+)
+
+// NewWalker creates a Walker for the given root directory
+func NewWalker(root string, opts ...Option) *Walker {
+	w := &Walker{
+		root: root,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// setOptionError records an error detected while applying an option;
+// the first such error is returned when the walk starts
+func (w *Walker) setOptionError(err error) {
+	if w.optionErr == nil {
+		w.optionErr = err
+	}
+}
+
+// WithWorkers sets the number of workers for the walk; by default,
+// or if n is not positive, it is runtime.GOMAXPROCS(0)
+func WithWorkers(n int) Option {
+	return func(w *Walker) {
+		w.numWorkers = n
+	}
+}
+
+// workerCount returns the number of workers to run
+func (w *Walker) workerCount() int {
+	n := w.numWorkers
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// WithQueueCapacity sets the initial capacity of the job queue. The
+// queue grows and shrinks as needed, and a Walker reused for another
+// walk starts with the queue capacity observed during the previous
+// one, so this is only worth setting for the first walk of a Walker
+// over a tree known to be very wide.
+func WithQueueCapacity(n int) Option {
+	return func(w *Walker) {
+		w.queueCap = n
+	}
+}
+
+// WithFollowSymlinks makes the walk follow directory symlinks
+func WithFollowSymlinks() Option {
+	return func(w *Walker) {
+		w.followSymlinks = true
+	}
+}
+
+// WithContext makes the walk abort as soon as possible once ctx
+// is canceled, in which case the walk returns ctx.Err(). To run
+// a walk as part of an errgroup, pass the group's context here.
+func WithContext(ctx context.Context) Option {
+	return func(w *Walker) {
+		w.ctx = ctx
+	}
+}
+
+// context returns the context of the walk
+func (w *Walker) context() context.Context {
+	if w.ctx == nil {
+		return context.Background()
+	}
+	return w.ctx
+}
+
+// WithMaxErrors limits the number of errors stored during the walk
+// to n (there is no limit by default); if n is zero, the limit set
+// before is kept, and only the action is changed. Once the limit
+// is reached, the walk is either aborted or continues without storing
+// further errors, depending on action. In both cases, the returned
+// error satisfies errors.Is(err, ErrTooManyErrors).
+func WithMaxErrors(n int, action MaxErrorsAction) Option {
+	return func(w *Walker) {
+		if n != 0 {
+			w.maxErrors = n
+		}
+		w.maxErrorsAction = action
+	}
+}
+
+// WithFailFast makes the walk stop at the first error, like
+// filepath.Walk does, and return that error as a WalkerError rather
+// than a WalkerErrorList, so that errors.Is(err, fs.ErrPermission),
+// errors.As() and the like work on it directly; the path the error
+// was reported for is available with errors.As() and WalkerError.Path().
+// Other errors which occur while the walk is stopping are dropped.
+func WithFailFast() Option {
+	return func(w *Walker) {
+		w.failFast = true
+	}
+}
+
+// WithStrictRoot makes the walk fail with ErrNotDir if the root
+// is not a directory (by default, walkFn is called for the root
+// file, and the walk returns nil, just like filepath.Walk does)
+func WithStrictRoot() Option {
+	return func(w *Walker) {
+		w.strictRoot = true
+	}
+}
+
+// WithConfineToRoot makes sure that no path opened during the walk
+// resolves outside of the root directory, even if the tree contains
+// symlinks pointing outside of it or is modified during the walk,
+// and that magic links (such as /proc/[pid]/fd/*) are never followed.
+// Such paths are reported as errors, and the walk fails if the root
+// can't be opened as a directory. This is intended for services
+// walking untrusted user-supplied trees. It requires openat2(2),
+// i.e. Linux 5.6+; on other systems, the walk fails
+// with ErrConfineUnsupported.
+func WithConfineToRoot() Option {
+	return func(w *Walker) {
+		w.confineToRoot = true
+	}
+}
+
+// WithPinnedRoot makes the walk resolve a relative root against
+// the working directory once, at the start of the walk, and access all
+// paths by their absolute names, so that a concurrent os.Chdir() elsewhere
+// in the process can't make the walk continue in another tree. The walk
+// fails if the working directory can't be determined. Walks of open
+// directories (see WalkDirFile()) and confined walks (see
+// WithConfineToRoot()) on Linux are not affected by the working
+// directory in the first place.
+func WithPinnedRoot() Option {
+	return func(w *Walker) {
+		w.pinRoot = true
+	}
+}
+
+// WithSlashPaths makes the walk present paths with forward slashes
+// as separators on all platforms, wherever it presents them (to the
+// callback, in errors, outcomes and timing), so that inventories
+// produced on Windows can be consumed elsewhere; the path mapper
+// (see WithPathMapper()) gets the converted paths. This has no effect
+// on platforms which use forward slashes already.
+func WithSlashPaths() Option {
+	return func(w *Walker) {
+		w.slashPaths = true
+	}
+}
+
+// WithPathMapper sets a function which rewrites the paths passed to
+// the callback and reported in errors (e.g. to strip a staging prefix
+// or to map container paths to host paths), so that every callback
+// doesn't have to do that itself. The walker keeps using the original
+// paths internally.
+func WithPathMapper(fn func(path string) string) Option {
+	return func(w *Walker) {
+		w.pathMapper = fn
+	}
+}
@@ -0,0 +1,134 @@
+package cwalk
+
+import "sync/atomic"
+
+// OutcomeKind tells what happened to a path during the walk
+type OutcomeKind int
+
+const (
+	// Visited means the path was passed to the callback,
+	// which didn't return an error
+	Visited OutcomeKind = iota
+
+	// Skipped means the path was deliberately left out
+	// of the walk, see SkipReason
+	Skipped
+
+	// Failed means an error was reported for the path
+	Failed
+)
+
+// String returns the name of the outcome kind
+func (k OutcomeKind) String() string {
+	switch k {
+	case Visited:
+		return "visited"
+	case Skipped:
+		return "skipped"
+	case Failed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// SkipReason tells why a path was skipped
+type SkipReason int
+
+const (
+	// SkipExcluded means a filter has excluded the entry
+	// (its subdirectories are still visited)
+	SkipExcluded SkipReason = iota + 1
+
+	// SkipPruned means a filter has pruned the entry
+	// along with everything beneath it
+	SkipPruned
+
+	// SkipOtherShard means the entry belongs to another shard,
+	// see WithShard()
+	SkipOtherShard
+
+	// SkipAborted means the directory was queued, but not read
+	// because the walk was aborted, canceled or stopped
+	SkipAborted
+
+	// SkipSampled means the file was left out of
+	// the sample, see WithSampling()
+	SkipSampled
+
+	numSkipReasons
+)
+
+// String returns a human-readable skip reason
+func (r SkipReason) String() string {
+	switch r {
+	case SkipExcluded:
+		return "excluded"
+	case SkipPruned:
+		return "pruned"
+	case SkipOtherShard:
+		return "other shard"
+	case SkipAborted:
+		return "aborted"
+	case SkipSampled:
+		return "not sampled"
+	}
+	return "unknown"
+}
+
+// Outcome describes what happened to a single path
+type Outcome struct {
+	Path   string
+	Kind   OutcomeKind
+	Reason SkipReason // set for Skipped
+	Err    error      // set for Failed
+}
+
+// WithOutcomeFunc sets a function which is called with the outcome
+// of every path, so that audit reports can tell the skipped paths from
+// the failed ones. Note that a directory may have two outcomes: it may
+// be visited, and then fail to be read. The function must be safe
+// for concurrent use.
+func WithOutcomeFunc(fn func(o Outcome)) Option {
+	return func(w *Walker) {
+		w.outcomeFunc = fn
+	}
+}
+
+// outcomeCounters counts the outcomes of a walk
+type outcomeCounters struct {
+	visited int64
+	failed  int64
+	skipped [numSkipReasons]int64
+}
+
+// outcome counts the outcome for the path (relative
+// to the walker root) and passes it to the outcome function
+func (w *Walker) outcome(relpath string, kind OutcomeKind, reason SkipReason, err error) {
+	switch kind {
+	case Visited:
+		atomic.AddInt64(&w.outcomes.visited, 1)
+	case Failed:
+		atomic.AddInt64(&w.outcomes.failed, 1)
+	case Skipped:
+		atomic.AddInt64(&w.outcomes.skipped[reason], 1)
+	}
+	if w.outcomeFunc != nil {
+		w.outcomeFunc(Outcome{
+			Path:   w.userPath(relpath),
+			Kind:   kind,
+			Reason: reason,
+			Err:    err,
+		})
+	}
+}
+
+// skippedCounts returns the number of skipped paths by reason
+func (w *Walker) skippedCounts() map[SkipReason]int64 {
+	counts := map[SkipReason]int64{}
+	for reason := SkipReason(1); reason < numSkipReasons; reason++ {
+		if n := atomic.LoadInt64(&w.outcomes.skipped[reason]); n > 0 {
+			counts[reason] = n
+		}
+	}
+	return counts
+}
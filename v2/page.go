@@ -0,0 +1,192 @@
+package cwalk
+
+import (
+	"errors"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// cursorPrefix marks the cursors produced by WalkPage()
+const cursorPrefix = "1:"
+
+// ErrInvalidCursor indicates that the cursor passed
+// to WalkPage() was not produced by it
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
+// pageFrame is a directory being listed by WalkPage()
+type pageFrame struct {
+	path  string
+	names []string // sorted entry names
+	next  int      // index of the next name to visit
+}
+
+// WalkPage returns up to limit entries of the tree under root,
+// starting after the position identified by cursor (use an empty cursor
+// to start from the root), and the cursor to get the next page with,
+// which is empty once the whole tree has been listed. The options
+// configure the Walker reading the tree, e.g. its filters.
+//
+// Unlike Walk(), WalkPage() lists the tree sequentially in a stable
+// depth-first order with directory entries sorted by name, so that
+// repeated calls produce consistent pages without keeping any state
+// between them: the cursor is the last path visited, and the listing
+// resumes right after it even if that path has been removed since.
+// Directory IDs are derived from the paths, so they (and the
+// OrderToken values) are the same across pages.
+//
+// Errors (e.g. for unreadable directories) don't stop the listing,
+// and are returned as a WalkerErrorList along with the entries, unless
+// the walk is aborted (see WithFailFast() and WithMaxErrors()).
+func WalkPage(root, cursor string, limit int, opts ...Option) (entries []Entry, nextCursor string, err error) {
+	w := NewWalker(root, opts...)
+	end, err := w.begin()
+	if err != nil {
+		return nil, "", err
+	}
+	defer end()
+	if limit <= 0 {
+		limit = 1
+	}
+
+	var stack []*pageFrame
+	last := "" // the last path visited, "" for the root
+	if cursor == "" {
+		info, err := w.lstat("")
+		if err != nil {
+			return nil, "", err
+		}
+		action := w.filter("", info)
+		if action == FilterPrune {
+			return nil, "", nil
+		}
+		entry := Entry{Info: info}
+		if info.IsDir() {
+			entry.ID = pageID("")
+			stack = append(stack, w.readPageFrame(""))
+		}
+		if action == FilterInclude {
+			entries = append(entries, entry)
+		}
+	} else {
+		if !strings.HasPrefix(cursor, cursorPrefix) {
+			return nil, "", ErrInvalidCursor
+		}
+		var parts []string
+		if last = cursor[len(cursorPrefix):]; last != "" {
+			parts = strings.Split(last, "/")
+		}
+		for _, part := range parts {
+			if !validPageName(part) {
+				return nil, "", ErrInvalidCursor
+			}
+		}
+		last = filepath.FromSlash(last)
+		stack = w.resumePage(parts)
+	}
+
+	for len(entries) < limit && len(stack) > 0 && atomic.LoadInt32(&w.aborted) == 0 {
+		top := stack[len(stack)-1]
+		if top.next >= len(top.names) {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		i := top.next
+		top.next++
+
+		path := filepath.Join(top.path, top.names[i])
+		last = path
+		info, lstatErr := w.lstat(path)
+		if os.IsNotExist(lstatErr) {
+			continue // removed since its directory was read
+		}
+		action := w.filter(path, info)
+		if action == FilterPrune {
+			continue
+		}
+		entry := Entry{
+			Path:  path,
+			Info:  info,
+			Order: OrderToken{ParentID: pageID(filepath.ToSlash(top.path)), Index: i},
+		}
+		if lstatErr != nil {
+			w.addError(path, lstatErr)
+		} else if info.IsDir() {
+			entry.ID = pageID(filepath.ToSlash(path))
+			stack = append(stack, w.readPageFrame(path))
+		}
+		if action == FilterInclude {
+			entries = append(entries, entry)
+		}
+	}
+
+	for _, f := range stack {
+		if f.next < len(f.names) {
+			nextCursor = cursorPrefix + filepath.ToSlash(last)
+			break
+		}
+	}
+	if w.walkErr != nil {
+		return entries, nextCursor, w.walkErr
+	}
+	if len(w.errorList.ErrorList) > 0 || w.errorList.Truncated > 0 {
+		return entries, nextCursor, w.errorList
+	}
+	return entries, nextCursor, nil
+}
+
+// pageID returns the ID of the directory at the slash-separated path,
+// which is the same for every page
+func pageID(path string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(path))
+	if id := h.Sum64(); id != 0 {
+		return id
+	}
+	return 1 // 0 is the ParentID of the root
+}
+
+// validPageName reports whether name can be the name of a directory entry
+func validPageName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/"+string(filepath.Separator))
+}
+
+// readPageFrame reads and sorts the names of the directory entries
+func (w *Walker) readPageFrame(path string) *pageFrame {
+	names, err := w.fs.ReadDirNames(path)
+	if err != nil {
+		w.addError(path, err)
+	}
+	sort.Strings(names)
+	return &pageFrame{path: path, names: names}
+}
+
+// resumePage rebuilds the directory stack so that the listing continues
+// right after the path given by its parts in the depth-first order,
+// reading the directories along the path again
+func (w *Walker) resumePage(parts []string) []*pageFrame {
+	stack := []*pageFrame{w.readPageFrame("")}
+	for _, part := range parts {
+		top := stack[len(stack)-1]
+		i := sort.SearchStrings(top.names, part)
+		if i == len(top.names) || top.names[i] != part {
+			// the path no longer exists, so continue with the next name
+			top.next = i
+			break
+		}
+		top.next = i + 1
+		path := filepath.Join(top.path, part)
+		info, err := w.lstat(path)
+		if err != nil || !info.IsDir() {
+			break
+		}
+		// the entries of this directory come next, either from
+		// the beginning (if the cursor points at the directory itself)
+		// or after the next part of the cursor path
+		stack = append(stack, w.readPageFrame(path))
+	}
+	return stack
+}
@@ -0,0 +1,214 @@
+package cwalk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pageTree creates the files (and their parent directories) under a
+// temporary root; the names ending with "/" are empty directories
+func pageTree(t *testing.T, names ...string) string {
+	root := t.TempDir()
+	for _, name := range names {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(p, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// allPages lists the tree with WalkPage(), limit entries at a time
+func allPages(t *testing.T, root string, limit int, opts ...Option) []Entry {
+	var all []Entry
+	cursor := ""
+	for {
+		entries, next, err := WalkPage(root, cursor, limit, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, entries...)
+		if next == "" {
+			return all
+		}
+		cursor = next
+	}
+}
+
+func pagePaths(entries []Entry) string {
+	var paths []string
+	for _, e := range entries {
+		paths = append(paths, filepath.ToSlash(e.Path))
+	}
+	return strings.Join(paths, ",")
+}
+
+func TestWalkPage(t *testing.T) {
+	root := pageTree(t, "a/x", "a/y", "b/c/d", "b/e/", "f")
+	const want = ",a,a/x,a/y,b,b/c,b/c/d,b/e,f"
+	for limit := 1; limit <= 10; limit++ {
+		entries := allPages(t, root, limit)
+		if got := pagePaths(entries); got != want {
+			t.Fatalf("limit %d: got %s, want %s", limit, got, want)
+		}
+		// the IDs are the same on every page, so the entries can be
+		// related to their parent directory across pages
+		ids := map[string]uint64{}
+		for _, e := range entries {
+			if e.ID != 0 {
+				ids[filepath.ToSlash(e.Path)] = e.ID
+			}
+		}
+		for _, e := range entries[1:] {
+			parent := filepath.ToSlash(filepath.Dir(e.Path))
+			if parent == "." {
+				parent = ""
+			}
+			if e.Order.ParentID != ids[parent] {
+				t.Errorf("limit %d: %s has ParentID %d, want %d", limit, e.Path, e.Order.ParentID, ids[parent])
+			}
+		}
+	}
+}
+
+func TestWalkPageChanges(t *testing.T) {
+	root := pageTree(t, "a/x", "a/y", "b/z", "c")
+	entries, cursor, err := WalkPage(root, "", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pagePaths(entries); got != ",a,a/x" {
+		t.Fatalf("got %s", got)
+	}
+	// the listings are read again for every page, so the entries
+	// added after the cursor are listed, and the ones removed aren't
+	for _, name := range []string{"a/x", "a/y", "c"} {
+		if err := os.Remove(filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a/w", "a/z", "d"} {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(name)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, cursor, err = WalkPage(root, cursor, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pagePaths(entries); got != "a/z,b,b/z,d" || cursor != "" {
+		t.Errorf("got %s (cursor %q), want a/z,b,b/z,d", got, cursor)
+	}
+}
+
+func TestWalkPageOptions(t *testing.T) {
+	root := pageTree(t, "a/x", ".hidden/y", "b")
+	if got := pagePaths(allPages(t, root, 1, WithSkipHidden())); got != ",a,a/x,b" {
+		t.Errorf("got %s", got)
+	}
+	_, _, err := WalkPage(root, "", 1, WithSampling(2, 0))
+	if err == nil {
+		t.Error("the option error is not returned")
+	}
+}
+
+func TestWalkPageAbort(t *testing.T) {
+	root := pageTree(t, "a/x", "c", "z")
+	if err := os.Symlink("loop", filepath.Join(root, "loop")); err != nil {
+		t.Skip(err)
+	}
+	for _, c := range []struct {
+		name string
+		opt  Option
+		want string
+	}{
+		{"fail fast", WithFailFast(), ",a,a/x,c,loop"},
+		{"max errors", WithMaxErrors(1, AbortWalk), ",a,a/x,c,loop"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			entries, cursor, err := WalkPage(root, "", 10, WithFollowSymlinks(), c.opt)
+			if err == nil {
+				t.Fatal("the error is not returned")
+			}
+			if got := pagePaths(entries); got != c.want {
+				t.Errorf("got %s, want %s", got, c.want)
+			}
+			if cursor != cursorPrefix+"loop" {
+				t.Errorf("got cursor %q, want %q", cursor, cursorPrefix+"loop")
+			}
+		})
+	}
+}
+
+func TestWalkPagePinnedRoot(t *testing.T) {
+	root := pageTree(t, "tree/a")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if got := pagePaths(allPages(t, "tree", 1, WithPinnedRoot())); got != ",a" {
+		t.Errorf("got %s, want ,a", got)
+	}
+}
+
+func TestWalkPageConfineToRoot(t *testing.T) {
+	root := pageTree(t, "a/x", "b")
+	if _, _, err := WalkPage(root, "", 1, WithConfineToRoot()); err == ErrConfineUnsupported {
+		t.Skip(err)
+	}
+	fds := func() int {
+		names, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Skip(err)
+		}
+		return len(names)
+	}
+	before := fds()
+	for i := 0; i < 10; i++ {
+		if got := pagePaths(allPages(t, root, 1, WithConfineToRoot())); got != ",a,a/x,b" {
+			t.Fatalf("got %s", got)
+		}
+	}
+	if after := fds(); after != before {
+		t.Errorf("%d open files before, %d after", before, after)
+	}
+}
+
+func TestWalkPageCursors(t *testing.T) {
+	root := pageTree(t, "a/x", "a/y", "b")
+	entries, _, err := WalkPage(root, cursorPrefix+"a/x", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pagePaths(entries); got != "a/y,b" {
+		t.Errorf("got %s, want a/y,b", got)
+	}
+	// the cursor of a removed path
+	entries, _, err = WalkPage(root, cursorPrefix+"a/w/v", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pagePaths(entries); got != "a/x,a/y,b" {
+		t.Errorf("got %s, want a/x,a/y,b", got)
+	}
+	for _, cursor := range []string{"a/x", "2:a/x", "1:../x", "1:a/../..", "1:/etc", "1:a//x", "1:a/"} {
+		if _, _, err := WalkPage(root, cursor, 10); err != ErrInvalidCursor {
+			t.Errorf("%s: got %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
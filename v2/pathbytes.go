@@ -0,0 +1,40 @@
+package cwalk
+
+import "io/fs"
+
+// WalkBytes walks the tree like WalkMap(), passing every entry that was
+// read without errors to fn, with its path as a byte slice: the slice is
+// only valid during the call, and is overwritten by the next entry the
+// worker finds, so fn must copy whatever it keeps (e.g. appending it to
+// an index buffer). In exchange, the paths are joined once per directory
+// listing rather than once per entry, so that throughput-critical
+// consumers, such as indexers, don't pay a string allocation per entry;
+// combined with WithLazyStat(), walks that only look at the names and
+// the types of the entries make a few allocations per directory.
+//
+// fn is called concurrently from multiple goroutines, just like the
+// callback of Walk(). fn may return filepath.SkipDir to skip a directory;
+// other errors are collected as usual and returned after the walk.
+func WalkBytes(root string, fn func(path []byte, d fs.DirEntry) error, opts ...Option) error {
+	w := NewWalker(root, opts...)
+	w.pathBytes = true
+	var rootBuf []byte
+	return w.walk("", func(ws *workerState, entry *Entry, err error) error {
+		if err != nil || entry.Info == nil {
+			return err
+		}
+		buf := &rootBuf
+		if ws != nil {
+			if ws.local == nil {
+				ws.local = new([]byte)
+			}
+			buf = ws.local.(*[]byte)
+		}
+		*buf = append((*buf)[:0], entry.Path...)
+		d, ok := entry.Info.(fs.DirEntry) // see WithLazyStat()
+		if !ok {
+			d = fs.FileInfoToDirEntry(entry.Info)
+		}
+		return fn(*buf, d)
+	})
+}
@@ -0,0 +1,97 @@
+package cwalk
+
+import (
+	"os"
+	"sort"
+	"sync"
+)
+
+// PathLimits are the thresholds of PathBudget()
+type PathLimits struct {
+	// MaxLength is the maximum length of the paths, in UTF-16 code units
+	// like Windows counts them, or in bytes if Bytes is set (0 for no
+	// limit); the length of a path is that of its path relative to the
+	// root plus BaseLength, the length of the destination directory
+	// the tree is to be moved to, including the trailing separator
+	MaxLength  int
+	BaseLength int
+	Bytes      bool
+
+	// MaxDepth is the maximum number of directory levels below
+	// the root (0 for no limit); the entries of the root are at depth 1
+	MaxDepth int
+}
+
+// PathReport is the result of PathBudget()
+type PathReport struct {
+	MaxLength   int    // including the BaseLength
+	LongestPath string // path relative to the root
+	MaxDepth    int
+	DeepestPath string
+
+	// the paths exceeding the limits, in lexical order; a directory
+	// and all of its contents are listed when the directory exceeds
+	// a limit, as they all have to be moved
+	TooLong []string
+	TooDeep []string
+}
+
+// PathBudget walks root and returns the maximum length and depth of
+// its paths, along with all the paths exceeding the limits, e.g. the
+// 260 characters of MAX_PATH on Windows, in a single pass. The longest
+// and the deepest paths are the first ones in lexical order in case
+// of a tie, so that reports of the same tree can be compared. Errors
+// are returned after the walk along with the report of the entries
+// that could be read.
+func PathBudget(root string, limits PathLimits, opts ...Option) (PathReport, error) {
+	var mu sync.Mutex
+	var report PathReport
+	err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			return nil
+		}
+		length, depth := limits.pathLength(path), pathDepth(path)
+		mu.Lock()
+		defer mu.Unlock()
+		if length > report.MaxLength || length == report.MaxLength && path < report.LongestPath {
+			report.MaxLength, report.LongestPath = length, path
+		}
+		if depth > report.MaxDepth || depth == report.MaxDepth && path < report.DeepestPath {
+			report.MaxDepth, report.DeepestPath = depth, path
+		}
+		if limits.MaxLength > 0 && length > limits.MaxLength {
+			report.TooLong = append(report.TooLong, path)
+		}
+		if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+			report.TooDeep = append(report.TooDeep, path)
+		}
+		return nil
+	}, opts...)
+
+	sort.Strings(report.TooLong)
+	sort.Strings(report.TooDeep)
+	return report, err
+}
+
+// pathLength returns the length of the path under the limits
+func (l *PathLimits) pathLength(path string) int {
+	if l.Bytes {
+		return l.BaseLength + len(path)
+	}
+	return l.BaseLength + utf16Len(path)
+}
+
+// pathDepth returns the number of levels of the (non-root)
+// path below the root
+func pathDepth(path string) int {
+	depth := 1
+	for i := 0; i < len(path); i++ {
+		if os.IsPathSeparator(path[i]) {
+			depth++
+		}
+	}
+	return depth
+}
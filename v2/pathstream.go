@@ -0,0 +1,94 @@
+package cwalk
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// pathStreamFlushInterval is how often the buffered
+// paths are flushed while the walk is running
+const pathStreamFlushInterval = 100 * time.Millisecond
+
+// PathStream writes the paths of a walk (see WithPathStream()) to
+// a writer as they are found, separated with NUL bytes, so that tools
+// such as tar --null --files-from or rsync --from0 --files-from can
+// consume them live. The paths are buffered, and flushed at least
+// every 100ms and at the end of the walk. If the writer blocks (e.g.
+// the consumer of a pipe is slower than the walk), the workers block
+// as well, so the walk proceeds at the pace of the consumer.
+type PathStream struct {
+	mu     sync.Mutex
+	bw     *bufio.Writer
+	closer io.Closer // set for streams opened by OpenPathStream()
+	err    error     // the first write error
+}
+
+// NewPathStream creates a PathStream writing to out
+func NewPathStream(out io.Writer) *PathStream {
+	return &PathStream{bw: bufio.NewWriter(out)}
+}
+
+// OpenPathStream opens the file (usually a named pipe, created
+// with mkfifo(1)) for writing and creates a PathStream writing
+// to it; opening a named pipe blocks until a reader opens it.
+// Close() closes the file.
+func OpenPathStream(name string) (*PathStream, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	s := NewPathStream(f)
+	s.closer = f
+	return s, nil
+}
+
+// WithPathStream makes the walk write the path of every entry passed
+// to the callback (as seen by the callback, so relative to the root)
+// to s, except for the root itself, which path is empty. As directories
+// are listed along with their contents, use tar --no-recursion
+// (or rsync --files-from, which doesn't recurse by default).
+// If writing fails (e.g. the consumer has exited), the walk
+// is aborted with the error.
+func WithPathStream(s *PathStream) Option {
+	return func(w *Walker) {
+		w.pathStream = s
+	}
+}
+
+// add writes the path
+func (s *PathStream) add(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.bw.WriteString(path)
+	s.err = s.bw.WriteByte(0)
+	return s.err
+}
+
+// Flush writes the buffered paths; the walk calls it
+// every pathStreamFlushInterval while it runs
+func (s *PathStream) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = s.bw.Flush()
+	}
+	return s.err
+}
+
+// Close flushes the buffered paths, and closes the file
+// if the stream was opened by OpenPathStream()
+func (s *PathStream) Close() error {
+	err := s.Flush()
+	if s.closer != nil {
+		if cerr := s.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package cwalk
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPathStreamFlush(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, pw := io.Pipe()
+	defer r.Close()
+	paths := make(chan string, 1)
+	go func() {
+		path, err := bufio.NewReader(r).ReadString(0)
+		if err == nil {
+			paths <- path
+		}
+	}()
+
+	// the path is added before the callback is called, and
+	// must reach the reader while the callback is still running
+	err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+		if path != "a" {
+			return err
+		}
+		select {
+		case got := <-paths:
+			if got != "a\x00" {
+				t.Errorf("got %q, want a", got)
+			}
+		case <-time.After(10 * pathStreamFlushInterval):
+			t.Error("the path is not flushed while the walk is running")
+		}
+		return err
+	}, WithPathStream(NewPathStream(pw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
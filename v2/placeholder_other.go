@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package cwalk
+
+import "os"
+
+// isPlaceholder reports that there are no cloud placeholder
+// files on this platform
+func isPlaceholder(info os.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,26 @@
+//go:build linux && (amd64 || arm64 || loong64 || mips64 || mips64le || ppc64 || ppc64le || riscv64 || s390x)
+// +build linux
+// +build amd64 arm64 loong64 mips64 mips64le ppc64 ppc64le riscv64 s390x
+
+package cwalk
+
+import (
+	"os"
+	"syscall"
+)
+
+// posix_fadvise(2) advice values missing from the syscall package
+const (
+	fadvSequential = 2
+	fadvWillNeed   = 3
+)
+
+// prefetch tells the kernel that the file is going to be read
+// sequentially, so that it reads ahead aggressively, and starts
+// reading it in the background; errors are ignored, as these
+// are only hints
+func prefetch(f *os.File, size int64) {
+	fd := f.Fd()
+	syscall.Syscall6(syscall.SYS_FADVISE64, fd, 0, 0, fadvSequential, 0, 0)
+	syscall.Syscall6(syscall.SYS_FADVISE64, fd, 0, uintptr(size), fadvWillNeed, 0, 0)
+}
@@ -0,0 +1,10 @@
+//go:build !linux || !(amd64 || arm64 || loong64 || mips64 || mips64le || ppc64 || ppc64le || riscv64 || s390x)
+// +build !linux !amd64,!arm64,!loong64,!mips64,!mips64le,!ppc64,!ppc64le,!riscv64,!s390x
+
+package cwalk
+
+import "os"
+
+// prefetch does nothing, as read-ahead hints are not
+// supported on this platform
+func prefetch(f *os.File, size int64) {}
@@ -0,0 +1,72 @@
+package cwalk
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownPreset is returned by the walk when WithPreset()
+// names a preset which hasn't been registered
+var ErrUnknownPreset = errors.New("Unknown preset")
+
+// presets holds the registered presets
+var presets = struct {
+	sync.RWMutex
+	m map[string][]Option
+}{m: map[string][]Option{
+	"dev-tree":    {WithDevTreeDefaults()},
+	"skip-hidden": {WithSkipHidden()},
+}}
+
+// RegisterPreset registers a named set of options (typically filters),
+// which can then be selected with WithPreset(), in Config.Presets,
+// or with the -preset flag of the command line tools, so that standard
+// scan profiles can be shared across tools. Registering a preset
+// with an existing name replaces it. The "dev-tree" and "skip-hidden"
+// presets are registered by default.
+func RegisterPreset(name string, opts ...Option) {
+	presets.Lock()
+	defer presets.Unlock()
+	presets.m[name] = append([]Option(nil), opts...)
+}
+
+// Presets returns the sorted names of the registered presets
+func Presets() []string {
+	presets.RLock()
+	defer presets.RUnlock()
+	names := make([]string, 0, len(presets.m))
+	for name := range presets.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupPreset returns the options of the named preset
+func lookupPreset(name string) ([]Option, error) {
+	presets.RLock()
+	defer presets.RUnlock()
+	opts, ok := presets.m[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPreset, name)
+	}
+	return opts, nil
+}
+
+// WithPreset applies the options of the named preset registered
+// with RegisterPreset(); the walk fails with ErrUnknownPreset
+// if there is no such preset
+func WithPreset(name string) Option {
+	return func(w *Walker) {
+		opts, err := lookupPreset(name)
+		if err != nil {
+			w.setOptionError(err)
+			return
+		}
+		for _, opt := range opts {
+			opt(w)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package cwalk
+
+import (
+	"os"
+	"sync"
+)
+
+// jobQueue is an unbounded queue of directories waiting to be processed.
+// It keeps count of pending jobs (both queued and being processed),
+// and is closed as soon as this count drops to zero, so that pushing
+// a job never blocks, and only has to be processed synchronously
+// if the queue is limited (see pushLimited()).
+//
+// Jobs are taken from the queue in LIFO order, i.e. the queue works
+// as an explicit stack, and the tree is traversed depth-first.
+// Directory processing never recurses, so the depth of the tree
+// doesn't affect the goroutine stack size, and the number of queued
+// jobs is proportional to depth times fan-out rather than to the
+// width of the widest level of the tree.
+//
+// Directories which look large (see largeDir()) are put into a separate
+// stack, which is served first: this way huge directories are started
+// early instead of possibly being the last ones processed by a single
+// worker while the others are idle.
+type jobQueue struct {
+	mu      sync.Mutex
+	cond    sync.Cond
+	jobs    []job
+	large   []job // large directories, served before jobs
+	pending int
+	peak    int // maximum number of queued jobs
+	closed  bool
+	dropped []job // jobs not processed because the queue was aborted
+
+	// limit is the number of queued jobs above which
+	// pushLimited() doesn't queue more (if positive)
+	limit int
+}
+
+// minQueueCapacity is the capacity below which
+// the queue is never shrunk
+const minQueueCapacity = 64
+
+// queueCapacity returns the initial capacity of the job queue:
+// the one set with WithQueueCapacity(), or the peak queue length
+// observed during the previous walk, but not less than the number
+// of workers
+func (w *Walker) queueCapacity() int {
+	if w.queueCap > 0 {
+		return w.queueCap
+	}
+	if n := w.workerCount(); w.lastPeakQueueLen < n {
+		return n
+	}
+	return w.lastPeakQueueLen
+}
+
+// newJobQueue creates an empty queue with the given initial capacity
+func newJobQueue(capacity int) *jobQueue {
+	q := &jobQueue{
+		jobs: make([]job, 0, capacity),
+	}
+	q.cond.L = &q.mu
+	return q
+}
+
+// push adds the job to the queue and increments the pending job counter
+func (q *jobQueue) push(j job) {
+	q.add(j, false)
+}
+
+// pushLimited is like push(), but only adds the job if the queue holds
+// fewer jobs than its limit, see Quota.QueuedDirs; it reports whether
+// the job has been taken care of, or should be processed by the caller
+func (q *jobQueue) pushLimited(j job) bool {
+	return q.add(j, true)
+}
+
+// add adds the job to the queue, unless limited and the queue is full
+func (q *jobQueue) add(j job, limited bool) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		if q.pending > 0 {
+			// the queue has been aborted
+			q.dropped = append(q.dropped, j)
+		}
+		return true
+	}
+	n := len(q.jobs) + len(q.large)
+	if limited && q.limit > 0 && n >= q.limit {
+		return false
+	}
+	if largeDir(j.info) {
+		q.large = append(q.large, j)
+	} else {
+		q.jobs = append(q.jobs, j)
+	}
+	if n+1 > q.peak {
+		q.peak = n + 1
+	}
+	q.pending++
+	q.cond.Signal()
+	return true
+}
+
+// pop waits for the next job; it returns false
+// once the queue has been closed
+func (q *jobQueue) pop() (job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.jobs) == 0 && len(q.large) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return job{}, false
+	}
+	if n := len(q.large) - 1; n >= 0 {
+		j := q.large[n]
+		q.large[n] = job{}
+		q.large = q.large[:n]
+		return j, true
+	}
+	n := len(q.jobs) - 1
+	j := q.jobs[n]
+	q.jobs[n] = job{}
+	q.jobs = q.jobs[:n]
+	// release the memory once the queue has drained after a burst
+	if c := cap(q.jobs); c > minQueueCapacity && n < c/4 {
+		jobs := make([]job, n, c/2)
+		copy(jobs, q.jobs)
+		q.jobs = jobs
+	}
+	return j, true
+}
+
+// done marks a job previously returned by pop() as processed,
+// closing the queue if there are no more pending jobs
+func (q *jobQueue) done() {
+	q.mu.Lock()
+	q.pending--
+	if q.pending == 0 {
+		q.closed = true
+		q.cond.Broadcast()
+	}
+	q.mu.Unlock()
+}
+
+// len returns the number of queued jobs
+func (q *jobQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs) + len(q.large)
+}
+
+// peakLen returns the maximum number of queued jobs so far
+func (q *jobQueue) peakLen() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.peak
+}
+
+// abort drops all queued jobs and closes the queue,
+// so that workers exit once they finish their current jobs
+func (q *jobQueue) abort() {
+	q.mu.Lock()
+	q.dropped = append(q.dropped, q.large...)
+	q.dropped = append(q.dropped, q.jobs...)
+	q.jobs = nil
+	q.large = nil
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+}
+
+// droppedJobs returns the jobs dropped because the queue was aborted,
+// including the ones pushed after that; it must only be called
+// once the workers have exited
+func (q *jobQueue) droppedJobs() []job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// largeDirEntries is the estimated number of entries
+// above which a directory is considered large
+// (a variable so that the tests can lower it)
+var largeDirEntries = 10000
+
+// largeDir tells whether the directory is likely to have many entries,
+// based on its number of links (i.e. subdirectories) and on its size,
+// which most Unix file systems report as growing with the number of
+// entries (assuming about 16 bytes per entry); on file systems
+// which report neither, no directory is considered large
+func largeDir(info os.FileInfo) bool {
+	if info == nil {
+		return false
+	}
+	return linkCount(info) > uint64(largeDirEntries) || info.Size()/16 > int64(largeDirEntries)
+}
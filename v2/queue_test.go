@@ -0,0 +1,89 @@
+package cwalk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestJobQueueLarge(t *testing.T) {
+	small := findInfo{name: "small", mode: os.ModeDir}
+	large := findInfo{name: "large", mode: os.ModeDir, size: 16 * int64(largeDirEntries+1)}
+	q := newJobQueue(0)
+	for _, j := range []job{
+		{path: "a", info: small},
+		{path: "big1", info: large},
+		{path: "b", info: small},
+		{path: "big2", info: large},
+		{path: "c"}, // not stat'ed
+	} {
+		q.push(j)
+	}
+	// the large directories come first, and each
+	// stack is served in LIFO order
+	var paths []string
+	for i := 0; i < 5; i++ {
+		j, ok := q.pop()
+		if !ok {
+			t.Fatal("the queue is closed")
+		}
+		paths = append(paths, j.path)
+		q.done()
+	}
+	if fmt.Sprint(paths) != "[big2 big1 c b a]" {
+		t.Errorf("got %v, want [big2 big1 c b a]", paths)
+	}
+	if _, ok := q.pop(); ok {
+		t.Error("the queue is not closed once all jobs are done")
+	}
+}
+
+func TestLargeDirWalk(t *testing.T) {
+	// every directory which reports a size is large
+	defer func(n int) { largeDirEntries = n }(largeDirEntries)
+	largeDirEntries = 0
+	root := t.TempDir()
+	want := map[string]bool{"": true}
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			file := filepath.Join(fmt.Sprint(i), fmt.Sprint(j), "f")
+			if err := os.MkdirAll(filepath.Join(root, filepath.Dir(file)), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(root, file), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			want[fmt.Sprint(i)], want[filepath.Dir(file)], want[file] = true, true, true
+		}
+	}
+	for _, workers := range []int{1, 4} {
+		checkVisitedOnce(t, root, want, WithWorkers(workers))
+	}
+}
+
+// checkVisitedOnce walks root and checks that the
+// wanted paths, and only them, are visited once
+func checkVisitedOnce(t *testing.T, root string, want map[string]bool, opts ...Option) {
+	t.Helper()
+	var mu sync.Mutex
+	seen := map[string]int{}
+	err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+		mu.Lock()
+		seen[path]++
+		mu.Unlock()
+		return err
+	}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != len(want) {
+		t.Errorf("%d paths walked, want %d", len(seen), len(want))
+	}
+	for path, n := range seen {
+		if !want[path] || n != 1 {
+			t.Errorf("%s walked %d times", path, n)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package cwalk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// kinds of read-only snapshots found by FindSnapshots()
+const (
+	SnapshotZFS     = "zfs"     // <dataset>/.zfs/snapshot/<name>
+	SnapshotSnapper = "snapper" // <subvolume>/.snapshots/<number>/snapshot (Btrfs)
+)
+
+// ReadOnlySnapshot is a read-only file system snapshot
+type ReadOnlySnapshot struct {
+	Kind string // SnapshotZFS or SnapshotSnapper
+	Name string // snapshot name (or number, for snapper)
+	Path string // path of the snapshot root directory
+}
+
+// FindSnapshots returns the read-only snapshots of the file system
+// mounted at dir: ZFS snapshots (available in the .zfs directory,
+// even if it is hidden) and Btrfs snapshots managed by snapper,
+// ordered by kind and name
+func FindSnapshots(dir string) ([]ReadOnlySnapshot, error) {
+	var list []ReadOnlySnapshot
+
+	zfs := filepath.Join(dir, ".zfs", "snapshot")
+	names, err := readDirNames(zfs)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		list = append(list, ReadOnlySnapshot{Kind: SnapshotZFS, Name: name, Path: filepath.Join(zfs, name)})
+	}
+
+	snapper := filepath.Join(dir, ".snapshots")
+	names, err = readDirNames(snapper)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		path := filepath.Join(snapper, name, "snapshot")
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			list = append(list, ReadOnlySnapshot{Kind: SnapshotSnapper, Name: name, Path: path})
+		}
+	}
+	return list, nil
+}
+
+// readDirNames returns the sorted names of the directory
+// entries, or none if the directory doesn't exist
+func readDirNames(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	sort.Strings(names)
+	return names, err
+}
+
+// WithImmutableTree declares that the walked tree doesn't change (such
+// as a read-only snapshot), so that the metadata cache (see
+// WithMetadataCache()) can be trusted entirely: cached subdirectories
+// are not stat'ed again, and a walk of a fully cached tree makes no
+// file system calls besides stat'ing the root. Without a cache,
+// this option has no effect.
+func WithImmutableTree() Option {
+	return func(w *Walker) {
+		w.immutable = true
+	}
+}
+
+// WalkSnapshot walks the snapshot with WithImmutableTree()
+// and the given options
+func WalkSnapshot(s ReadOnlySnapshot, walkFn filepath.WalkFunc, opts ...Option) error {
+	return walkTree(s.Path, walkFn, append([]Option{WithImmutableTree()}, opts...)...)
+}
@@ -0,0 +1,58 @@
+package cwalk
+
+// reduceState is the worker-local accumulator of WalkReduce()
+type reduceState[T any] struct {
+	acc T
+	ok  bool // acc holds a value
+}
+
+// add merges the value into the accumulator
+func (s *reduceState[T]) add(v T, reduceFn func(a, b T) T) {
+	if s.ok {
+		s.acc = reduceFn(s.acc, v)
+	} else {
+		s.acc, s.ok = v, true
+	}
+}
+
+// WalkReduce walks the tree like WalkEntries(), passing every entry
+// that was read without errors to mapFn, and combines the results with
+// reduceFn. The results are accumulated by each worker separately and
+// merged once the walk is complete, so neither function needs any
+// synchronization. reduceFn must be both commutative and associative
+// (like a sum, a maximum or a set union), as the entries are spread
+// over the workers in any order, and the partial results of the
+// workers are merged in any order as well: otherwise, the result
+// may differ from one walk to the next. If no entries were mapped,
+// the zero value of T is returned. Errors are collected as usual and
+// returned along with the result.
+func WalkReduce[T any](root string, mapFn func(Entry) T, reduceFn func(a, b T) T, opts ...Option) (T, error) {
+	w := NewWalker(root, opts...)
+	var rootState reduceState[T]
+	err := w.walk("", func(ws *workerState, entry *Entry, err error) error {
+		if err != nil {
+			return err
+		}
+		state := &rootState
+		if ws != nil {
+			if ws.local == nil {
+				ws.local = &reduceState[T]{}
+			}
+			state = ws.local.(*reduceState[T])
+		}
+		state.add(mapFn(*entry), reduceFn)
+		return nil
+	})
+
+	// the workers have exited, so their states can be read here
+	w.mu.Lock()
+	workers := w.workers
+	w.mu.Unlock()
+	result := rootState
+	for _, ws := range workers {
+		if state, ok := ws.local.(*reduceState[T]); ok {
+			result.add(state.acc, reduceFn)
+		}
+	}
+	return result.acc, err
+}
@@ -0,0 +1,119 @@
+package cwalk
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Report describes how the walk went and what resources it used,
+// to help tune the number of workers for the environment.
+// The walker itself never creates temporary files (except for
+// the runs spilled by a SortedSink), so the memory figures
+// cover everything the walk needs (except that they are
+// process-wide, and thus include allocations made by the callback
+// and by any other goroutines running at the same time)
+type Report struct {
+	Duration       time.Duration
+	Directories    int64 // number of directories read
+	Entries        int64 // number of entries passed to the callback
+	Visited        int64 // number of paths visited without errors
+	Failed         int64 // number of errors reported
+	Suppressed     int64 // number of errors dropped, see WithSuppressedErrors()
+	Skipped        map[SkipReason]int64
+	NotVisited     int64   // number of directories queued but not read because the walk was aborted
+	Throttled      int64   // number of times the walk backed off because the process ran out of file descriptors
+	Workers        int     // number of workers used
+	Goroutines     int64   // number of goroutines started by the walk (all exited before it returned)
+	Utilization    float64 // share of the walk time workers were busy, from 0 to 1
+	PeakQueueLen   int     // maximum number of directories waiting in the queue
+	Allocations    uint64  // number of heap allocations made during the walk
+	AllocatedBytes uint64  // number of heap bytes allocated during the walk
+
+	// Hints point out environment issues detected during the walk,
+	// such as consistently slow stat calls caused by a remote file
+	// system (a sample of stat calls is timed), or an antivirus scanner
+	// on Windows (a sample of files is opened twice, and the first open
+	// is compared with the second)
+	Hints []string
+}
+
+// WithReport makes the walk fill r once it is complete.
+// Note that collecting memory statistics briefly stops the world
+// at the beginning and at the end of the walk
+func WithReport(r *Report) Option {
+	return func(w *Walker) {
+		w.report = r
+	}
+}
+
+// beginReport starts collecting the data for the Report;
+// the returned function fills the Report once the walk is complete
+func (w *Walker) beginReport() func() {
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	return func() {
+		var after runtime.MemStats
+		runtime.ReadMemStats(&after)
+		r := w.report
+		*r = Report{
+			Duration:       time.Since(start),
+			Directories:    atomic.LoadInt64(&w.dirCount),
+			Entries:        atomic.LoadInt64(&w.entryCount),
+			Visited:        atomic.LoadInt64(&w.outcomes.visited),
+			Failed:         atomic.LoadInt64(&w.outcomes.failed),
+			Suppressed:     atomic.LoadInt64(&w.suppressedCount),
+			Skipped:        w.skippedCounts(),
+			NotVisited:     atomic.LoadInt64(&w.outcomes.skipped[SkipAborted]),
+			Throttled:      w.throttled(),
+			Goroutines:     atomic.LoadInt64(&w.spawned),
+			Hints:          w.hints(),
+			Allocations:    after.Mallocs - before.Mallocs,
+			AllocatedBytes: after.TotalAlloc - before.TotalAlloc,
+		}
+
+		w.mu.Lock()
+		workers := w.workers
+		if w.queue != nil {
+			r.PeakQueueLen = w.queue.peakLen()
+		}
+		w.mu.Unlock()
+
+		var busy time.Duration
+		for _, ws := range workers {
+			ws.mu.Lock()
+			busy += ws.busy
+			ws.mu.Unlock()
+		}
+		r.Workers = len(workers)
+		if r.Workers > 0 && r.Duration > 0 {
+			r.Utilization = float64(busy) / float64(r.Duration*time.Duration(r.Workers))
+		}
+	}
+}
+
+// Summary describes the outcome of a walk, see WalkWithSummary()
+type Summary struct {
+	Report
+	Errors WalkerErrorList // the errors collected during the walk
+}
+
+// WalkWithSummary works like Walk(), but also returns the Summary
+// of the walk, so that the statistics and the collected errors are
+// available without type-asserting the returned error. The error
+// is the same one Walk() would return. WithReport() should not be
+// passed, as the Report is filled in the Summary instead.
+func WalkWithSummary(root string, walkFn filepath.WalkFunc, opts ...Option) (Summary, error) {
+	var s Summary
+	w := NewWalker(root, append(opts, WithReport(&s.Report))...)
+	err := w.Walk("", walkFn)
+	var list WalkerErrorList
+	if errors.As(err, &list) {
+		s.Errors = list
+	}
+	return s, err
+}
@@ -0,0 +1,55 @@
+package cwalk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// WithSampling makes the walk pass only a sample of the files to the
+// callback, about rate of them (0 < rate <= 1), for quick estimates of
+// the composition of huge trees; all directories are still traversed.
+// Whether a file is sampled only depends on its path relative to the
+// root and on seed, so walks with the same seed sample the same files.
+// The files left out are reported with SkipSampled.
+//
+// Just like find(1) does, the walk assumes that directories with
+// a link count of 2 have no subdirectories, so that the entries of
+// such directories which are not sampled are not even stat'ed
+// (unless symlinks are followed). This is only done on the file
+// systems known to count subdirectories in the link counts (ext4,
+// xfs, tmpfs and f2fs on Linux); others, such as btrfs or overlayfs,
+// may report a link count of 2 for any directory.
+func WithSampling(rate float64, seed int64) Option {
+	return func(w *Walker) {
+		if !(rate > 0 && rate <= 1) {
+			w.setOptionError(fmt.Errorf("Invalid sampling rate: %g", rate))
+			return
+		}
+		w.sampleRate = rate
+		w.sampleSeed = seed
+	}
+}
+
+// sampled reports whether the file at relpath belongs to the sample
+func (w *Walker) sampled(relpath string) bool {
+	if w.sampleRate == 0 || w.sampleRate == 1 {
+		return true
+	}
+	h := fnv.New64a()
+	var seed [8]byte
+	binary.LittleEndian.PutUint64(seed[:], uint64(w.sampleSeed))
+	h.Write(seed[:])
+	h.Write([]byte(filepath.ToSlash(relpath)))
+	return float64(h.Sum64()) < w.sampleRate*math.MaxUint64
+}
+
+// isLeafDir reports whether the directory can be assumed to have
+// no subdirectories, so that entries which are not sampled don't need
+// to be stat'ed to tell whether they have to be traversed
+func (w *Walker) isLeafDir(relpath string, info os.FileInfo) bool {
+	return info != nil && !w.followSymlinks && linkCount(info) == 2 && w.reliableNlink(relpath, info)
+}
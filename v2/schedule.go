@@ -0,0 +1,304 @@
+package cwalk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrScheduleSyntax indicates an invalid schedule of a ScheduledWalk
+var ErrScheduleSyntax = errors.New("Invalid schedule")
+
+// ScheduledWalk is a walk run periodically by a Scheduler
+type ScheduledWalk struct {
+	Name string
+	Root string
+
+	// Schedule is a cron expression with five fields (minute, hour,
+	// day of month, month, day of week; with *, lists, ranges and steps),
+	// "@every <duration>" (e.g. "@every 90m"), or one of "@hourly",
+	// "@daily", "@weekly" and "@monthly"; cron times are local: the
+	// times skipped when clocks are set forward don't run on that day,
+	// and the ones repeated when clocks are set back run once
+	Schedule string
+
+	// Jitter is the maximum random delay added to the scheduled
+	// time of each run, so that agents sharing a schedule
+	// don't hit the same storage all at once
+	Jitter time.Duration
+
+	Config  Config
+	Options []Option // applied after the ones from Config
+
+	// WalkFn is called for each entry, if set
+	WalkFn filepath.WalkFunc
+
+	// Sink is called with the result of each run, if set
+	Sink func(r ScheduledResult)
+}
+
+// ScheduledResult is the result of a single run of a ScheduledWalk
+type ScheduledResult struct {
+	Name    string
+	Started time.Time
+	Summary Summary
+	Err     error // as returned by the walk
+
+	// Missed is the number of scheduled runs skipped since the previous
+	// run, because it was still running (runs never overlap)
+	Missed int
+}
+
+// Scheduler runs walks on their schedules, which turns cwalk into
+// the core of an inventory agent. Each walk runs in its own goroutine,
+// and never overlaps with itself.
+type Scheduler struct {
+	mu    sync.Mutex
+	walks []*scheduledWalk
+}
+
+// scheduledWalk is a ScheduledWalk ready to run
+type scheduledWalk struct {
+	ScheduledWalk
+	next func(after time.Time) time.Time
+	opts []Option
+}
+
+// NewScheduler creates a Scheduler without walks
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add adds the walk to the scheduler; walks added
+// while the scheduler is running start with the next Run()
+func (s *Scheduler) Add(sw ScheduledWalk) error {
+	next, err := parseSchedule(sw.Schedule)
+	if err != nil {
+		return err
+	}
+	opts, err := sw.Config.Options()
+	if err != nil {
+		return fmt.Errorf("Walk %q: %w", sw.Name, err)
+	}
+	s.mu.Lock()
+	s.walks = append(s.walks, &scheduledWalk{
+		ScheduledWalk: sw,
+		next:          next,
+		opts:          append(opts, sw.Options...),
+	})
+	s.mu.Unlock()
+	return nil
+}
+
+// Run runs the walks on their schedules until ctx is done, which
+// aborts the walks in progress (see WithContext()), and returns
+// ctx.Err() once they have returned
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	walks := s.walks
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, sw := range walks {
+		sw := sw
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sw.run(ctx)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// run runs the walk on its schedule until ctx is done
+func (sw *scheduledWalk) run(ctx context.Context) {
+	next := sw.next(time.Now())
+	missed := 0
+	for {
+		at := next
+		if sw.Jitter > 0 {
+			at = at.Add(time.Duration(rand.Int63n(int64(sw.Jitter))))
+		}
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		r := ScheduledResult{Name: sw.Name, Started: time.Now(), Missed: missed}
+		walkFn := sw.WalkFn
+		if walkFn == nil {
+			walkFn = func(string, os.FileInfo, error) error { return nil }
+		}
+		opts := append(append([]Option{}, sw.opts...), WithContext(ctx))
+		r.Summary, r.Err = WalkWithSummary(sw.Root, walkFn, opts...)
+		if ctx.Err() != nil {
+			return
+		}
+		if sw.Sink != nil {
+			sw.Sink(r)
+		}
+
+		// skip the runs which were due while the walk was running
+		missed = 0
+		now := time.Now()
+		for next = sw.next(next); !next.After(now); next = sw.next(next) {
+			missed++
+		}
+	}
+}
+
+// parseSchedule parses the schedule, see ScheduledWalk.Schedule,
+// and returns the function computing the next run time
+func parseSchedule(schedule string) (func(after time.Time) time.Time, error) {
+	switch schedule {
+	case "@hourly":
+		schedule = "0 * * * *"
+	case "@daily", "@midnight":
+		schedule = "0 0 * * *"
+	case "@weekly":
+		schedule = "0 0 * * 0"
+	case "@monthly":
+		schedule = "0 0 1 * *"
+	}
+	if s := strings.TrimPrefix(schedule, "@every "); s != schedule {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w %q: bad interval", ErrScheduleSyntax, schedule)
+		}
+		return func(after time.Time) time.Time { return after.Add(d) }, nil
+	}
+	c, err := parseCron(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrScheduleSyntax, schedule, err)
+	}
+	if c.next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("%w %q: never matches", ErrScheduleSyntax, schedule)
+	}
+	return c.next, nil
+}
+
+// cronSchedule holds the values allowed by each field
+// of a cron expression as bit sets
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	anyDom, anyDow                bool // the day fields are "*"
+}
+
+// parseCron parses a five-field cron expression
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("want 5 fields, got %d", len(fields))
+	}
+	var c cronSchedule
+	var err error
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	sets := [5]*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow}
+	for i, field := range fields {
+		if *sets[i], err = parseCronField(field, bounds[i][0], bounds[i][1]); err != nil {
+			return nil, err
+		}
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1 // both 0 and 7 mean Sunday
+	}
+	c.anyDom, c.anyDow = fields[2] == "*", fields[4] == "*"
+	return &c, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges
+// (a-b) and steps (*/n, a-b/n) within the bounds
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			rng = part[:i]
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("bad value in %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("bad value in %q", part)
+				}
+			} else if step > 1 {
+				hi = max // "a/n" means from a to the end
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// next returns the first matching minute after the time,
+// or the zero time if there is none
+func (c *cronSchedule) next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// give up after five years, which covers e.g. February 29
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			// time.Date() can't be used here, as the next hour
+			// may not exist when clocks are set forward
+			t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+		case c.minute&(1<<uint(t.Minute())) == 0 || repeatedTime(t):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{} // never matches, e.g. on February 31
+}
+
+// repeatedTime reports whether the wall clock showed the time of t
+// already, before the clocks were set back
+func repeatedTime(t time.Time) bool {
+	_, offset := t.Zone()
+	_, before := t.Add(-time.Hour).Zone()
+	if before <= offset {
+		return false
+	}
+	_, earlier := t.Add(-time.Duration(before-offset) * time.Second).Zone()
+	return earlier == before
+}
+
+// dayMatches reports whether the day matches the day fields, which
+// (as in cron) match either day if both are restricted
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if !c.anyDom && !c.anyDow {
+		return dom || dow
+	}
+	return dom && dow
+}
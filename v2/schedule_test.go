@@ -0,0 +1,46 @@
+package cwalk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNextDST(t *testing.T) {
+	for _, c := range []struct {
+		zone  string
+		expr  string
+		after string
+		want  string
+	}{
+		// clocks set forward at 02:00 on 2026-03-08
+		{"America/New_York", "30 2 * * *", "2026-03-07 12:00", "2026-03-09 02:30 EDT"},
+		{"America/New_York", "0 3 * * *", "2026-03-08 00:30", "2026-03-08 03:00 EDT"},
+		{"America/New_York", "*/20 * * * *", "2026-03-08 01:50", "2026-03-08 03:00 EDT"},
+		{"America/New_York", "@daily", "2026-03-07 12:00", "2026-03-08 00:00 EST"},
+		// clocks set back at 02:00 on 2026-11-01, 01:00-01:59 is repeated
+		{"America/New_York", "30 1 * * *", "2026-10-31 12:00", "2026-11-01 01:30 EDT"},
+		{"America/New_York", "30 1 * * *", "2026-11-01 01:30", "2026-11-02 01:30 EST"},
+		{"America/New_York", "0 * * * *", "2026-11-01 01:30", "2026-11-01 02:00 EST"},
+		{"America/New_York", "0 3 * * *", "2026-11-01 00:30", "2026-11-01 03:00 EST"},
+		// clocks set forward at midnight on 2026-09-06
+		{"America/Santiago", "@daily", "2026-09-05 12:00", "2026-09-07 00:00 -03"},
+		// a zone with a half-hour offset
+		{"Asia/Kolkata", "0 * * * *", "2026-03-08 00:30", "2026-03-08 01:00 IST"},
+	} {
+		loc, err := time.LoadLocation(c.zone)
+		if err != nil {
+			t.Skip(err)
+		}
+		after, err := time.ParseInLocation("2006-01-02 15:04", c.after, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next, err := parseSchedule(c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := next(after).Format("2006-01-02 15:04 MST"); got != c.want {
+			t.Errorf("%s %q after %s: got %s, want %s", c.zone, c.expr, c.after, got, c.want)
+		}
+	}
+}
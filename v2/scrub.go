@@ -0,0 +1,185 @@
+package cwalk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// scrubAttr is the extended attribute storing the checksum of a file
+const scrubAttr = "user.cwalk.checksum"
+
+// ErrScrubUnsupported is returned by Scrub() on platforms
+// where checksums can't be stored in extended attributes
+var ErrScrubUnsupported = errors.New("Checksum attributes are not supported on this platform")
+
+// ScrubConfig configures Scrub()
+type ScrubConfig struct {
+	// Rate limits the reads to this many bytes per second,
+	// shared by all workers (0 for no limit)
+	Rate int64
+
+	// ReadOnly only verifies the checksums already stored,
+	// without storing new ones
+	ReadOnly bool
+}
+
+// ScrubReport is the result of Scrub()
+type ScrubReport struct {
+	Verified  int      // number of files matching their stored checksum
+	Stored    int      // number of files which checksum was stored for the first time
+	Updated   int      // number of files modified since their checksum was stored, which was updated
+	Changed   int      // number of files modified while they were read, left for the next scrub
+	Corrupted []string // files which content no longer matches their checksum, in lexical order
+	Bytes     int64    // number of bytes read
+}
+
+// scrubSum is the checksum stored for a file, along with the size and
+// the modification time of the file when the checksum was computed
+type scrubSum struct {
+	size    int64
+	modTime int64 // in nanoseconds since the epoch
+	sum     []byte
+}
+
+// String formats the checksum as stored in the attribute
+func (s scrubSum) String() string {
+	return fmt.Sprintf("sha256 %d %d %s", s.size, s.modTime, hex.EncodeToString(s.sum))
+}
+
+// parseScrubSum parses the checksum stored in the attribute
+func parseScrubSum(value string) (scrubSum, bool) {
+	fields := strings.Fields(value)
+	if len(fields) != 4 || fields[0] != "sha256" {
+		return scrubSum{}, false
+	}
+	size, err1 := strconv.ParseInt(fields[1], 10, 64)
+	modTime, err2 := strconv.ParseInt(fields[2], 10, 64)
+	sum, err3 := hex.DecodeString(fields[3])
+	if err1 != nil || err2 != nil || err3 != nil || len(sum) != sha256.Size {
+		return scrubSum{}, false
+	}
+	return scrubSum{size: size, modTime: modTime, sum: sum}, true
+}
+
+// Scrub walks root and detects silent data corruption (bitrot): the
+// first time a regular file is scrubbed, its SHA-256 checksum is stored
+// in its user.cwalk.checksum extended attribute along with its size and
+// modification time; later scrubs report the files which content no
+// longer matches the checksum although their size and modification
+// time haven't changed. The checksums of the files modified in the
+// meantime are updated. The files are read by the content stages (see
+// WithContentStage()), so their access times are preserved with
+// WithNoAtime(); storing the attributes changes the inode change time
+// (ctime) of the files, and is not allowed in forensic mode.
+//
+// Scrubs are meant to run continuously in the background: the reads
+// can be rate limited with cfg.Rate, and combined with WithIOPriority()
+// and WithThrottle(), or run periodically by a Scheduler. Checksums are
+// stored on Linux only, on file systems supporting user extended
+// attributes; elsewhere, Scrub() fails with ErrScrubUnsupported. Errors
+// (such as files which attributes can't be written) are returned after
+// the walk along with the report.
+func Scrub(root string, cfg ScrubConfig, opts ...Option) (ScrubReport, error) {
+	if !scrubSupported {
+		return ScrubReport{}, ErrScrubUnsupported
+	}
+	var rate *byteRate
+	if cfg.Rate > 0 {
+		rate = &byteRate{rate: cfg.Rate}
+	}
+	var report ScrubReport
+	var mu sync.Mutex
+	count := func(fn func()) {
+		mu.Lock()
+		fn()
+		mu.Unlock()
+	}
+
+	stage := func(entry *Entry, r io.Reader) error {
+		// the content stages are passed the open file: the attribute
+		// and the stat data are read from it rather than from the path,
+		// so they are those of the file which is hashed, even if the
+		// path is replaced in the meantime
+		f, ok := r.(*os.File)
+		if !ok {
+			return fmt.Errorf("Unexpected content reader: %T", r)
+		}
+		stored, hasSum := scrubSum{}, false
+		value, err := getScrubAttr(f)
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			stored, hasSum = parseScrubSum(string(value))
+		}
+		info := entry.Info
+		current := scrubSum{size: info.Size(), modTime: info.ModTime().UnixNano()}
+		unchanged := hasSum && stored.size == current.size && stored.modTime == current.modTime
+		if cfg.ReadOnly && !unchanged {
+			return nil // nothing to verify
+		}
+
+		h := sha256.New()
+		n, err := io.Copy(h, &rateReader{r: r, rate: rate})
+		count(func() { report.Bytes += n })
+		if err != nil {
+			return err
+		}
+		current.sum = h.Sum(nil)
+		if after, err := f.Stat(); err != nil || after.Size() != current.size ||
+			after.ModTime().UnixNano() != current.modTime {
+			count(func() { report.Changed++ })
+			return nil
+		}
+
+		switch {
+		case unchanged && string(stored.sum) == string(current.sum):
+			count(func() { report.Verified++ })
+			return nil
+		case unchanged:
+			count(func() { report.Corrupted = append(report.Corrupted, entry.Path) })
+			return nil // keep the checksum of the intact content
+		}
+		if err := setScrubAttr(f, []byte(current.String())); err != nil {
+			return err
+		}
+		if hasSum {
+			count(func() { report.Updated++ })
+		} else {
+			count(func() { report.Stored++ })
+		}
+		return nil
+	}
+
+	w := NewWalker(root, append(opts[:len(opts):len(opts)], WithContentStage(stage))...)
+	w.writesTree = !cfg.ReadOnly
+	err := w.Walk("", func(path string, info os.FileInfo, err error) error {
+		return err
+	})
+	sort.Strings(report.Corrupted)
+	return report, err
+}
+
+// rateReader throttles the reads from r to the rate
+type rateReader struct {
+	r    io.Reader
+	rate *byteRate
+}
+
+// Read implements io.Reader
+func (rr *rateReader) Read(p []byte) (int, error) {
+	if rr.rate != nil && len(p) > 64<<10 {
+		p = p[:64<<10] // throttle in small steps
+	}
+	n, err := rr.r.Read(p)
+	rr.rate.wait(int64(n))
+	return n, err
+}
@@ -0,0 +1,43 @@
+package cwalk
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// scrubSupported tells whether Scrub() can store checksums on this platform
+const scrubSupported = true
+
+// getScrubAttr returns the stored checksum attribute
+// of the open file, nil if it has none
+func getScrubAttr(f *os.File) ([]byte, error) {
+	a, err := syscall.BytePtrFromString(scrubAttr)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 256)
+	n, _, errno := syscall.Syscall6(syscall.SYS_FGETXATTR, f.Fd(), uintptr(unsafe.Pointer(a)),
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), 0, 0)
+	switch errno {
+	case 0:
+		return buf[:n], nil
+	case syscall.ENODATA, syscall.ENOTSUP, syscall.ERANGE:
+		return nil, nil // not set, or not set by Scrub()
+	}
+	return nil, errno
+}
+
+// setScrubAttr stores the checksum attribute of the open file
+func setScrubAttr(f *os.File, value []byte) error {
+	a, err := syscall.BytePtrFromString(scrubAttr)
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall6(syscall.SYS_FSETXATTR, f.Fd(), uintptr(unsafe.Pointer(a)),
+		uintptr(unsafe.Pointer(&value[0])), uintptr(len(value)), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
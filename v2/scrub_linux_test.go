@@ -0,0 +1,46 @@
+package cwalk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestScrub(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "file")
+	if err := os.WriteFile(path, []byte("intact"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Scrub(root, ScrubConfig{})
+	if errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.EPERM) {
+		t.Skip("user extended attributes not supported:", err)
+	}
+	if err != nil || report.Stored != 1 {
+		t.Fatalf("first scrub: %+v, %v, want 1 stored checksum", report, err)
+	}
+	report, err = Scrub(root, ScrubConfig{ReadOnly: true})
+	if err != nil || report.Verified != 1 {
+		t.Fatalf("second scrub: %+v, %v, want 1 verified checksum", report, err)
+	}
+
+	// corrupt the content, keeping the size and the modification time
+	if err := os.WriteFile(path, []byte("broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	report, err = Scrub(root, ScrubConfig{})
+	if err != nil || len(report.Corrupted) != 1 || report.Corrupted[0] != "file" {
+		t.Fatalf("third scrub: %+v, %v, want file corrupted", report, err)
+	}
+}
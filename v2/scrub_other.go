@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package cwalk
+
+import "os"
+
+// scrubSupported tells whether Scrub() can store checksums on this platform
+const scrubSupported = false
+
+// getScrubAttr is never called on this platform
+func getScrubAttr(f *os.File) ([]byte, error) {
+	return nil, ErrScrubUnsupported
+}
+
+// setScrubAttr is never called on this platform
+func setScrubAttr(f *os.File, value []byte) error {
+	return ErrScrubUnsupported
+}
@@ -0,0 +1,76 @@
+package cwalk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+// WithSeedDirs pre-seeds the job queue with the given directories
+// (relative to the root, e.g. taken from a snapshot of a previous walk),
+// so that all their subtrees can be processed immediately instead of
+// being discovered one level at a time, which reduces the ramp-up time
+// on high-latency storage. Seeded directories are not queued again
+// when they are found in their parent directories, and the ones
+// that no longer exist are silently ignored.
+//
+// Note that the entries of a seeded directory may be passed to the
+// callback before the directory itself, and even if the callback
+// skips one of its parent directories.
+func WithSeedDirs(dirs ...string) Option {
+	return func(w *Walker) {
+		w.seedDirs = dirs
+	}
+}
+
+// pushSeedDirs queues the seeded directories located under relpath,
+// assigning them their directory IDs in advance
+func (w *Walker) pushSeedDirs(relpath string) {
+	w.seeds = nil
+	if len(w.seedDirs) == 0 {
+		return
+	}
+	w.seeds = make(map[string]uint64, len(w.seedDirs))
+	for _, dir := range w.seedDirs {
+		dir = filepath.Clean(dir)
+		if !isWithin(relpath, dir) || dir == filepath.Clean(relpath) || !w.inShard(dir) {
+			continue
+		}
+		if _, ok := w.seeds[dir]; ok {
+			continue
+		}
+		id := atomic.AddUint64(&w.lastID, 1)
+		w.seeds[dir] = id
+		w.queue.push(job{path: dir, id: id, seeded: true})
+	}
+}
+
+// seedID returns the ID assigned to a seeded directory
+func (w *Walker) seedID(relpath string) (uint64, bool) {
+	id, ok := w.seeds[relpath]
+	return id, ok
+}
+
+// isStaleSeed reports whether the error indicates that a seeded
+// directory no longer exists (or is not a directory anymore)
+func isStaleSeed(err error) bool {
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
+	}
+	return os.IsNotExist(err) || err == syscall.ENOTDIR
+}
+
+// isWithin reports whether the relative path is located
+// inside (or is equal to) the relative directory path dir
+func isWithin(dir, path string) bool {
+	dir = filepath.Clean(dir)
+	if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return false
+	}
+	if dir == "." {
+		return true
+	}
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
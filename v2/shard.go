@@ -0,0 +1,45 @@
+package cwalk
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strings"
+)
+
+// WithShard makes the walk visit only the part of the tree
+// assigned to shard i of n (0 <= i < n), so that multiple machines
+// can each walk a disjoint part of the same tree without coordination.
+// Entries located directly in the root directory (along with everything
+// beneath them) are assigned to shards by the hash of their names;
+// the root itself is visited by every shard. The walk fails if i
+// is out of range.
+func WithShard(i, n int) Option {
+	return func(w *Walker) {
+		if n <= 0 || i < 0 || i >= n {
+			w.setOptionError(fmt.Errorf("Invalid shard %d of %d", i, n))
+			return
+		}
+		w.shardIndex = i
+		w.shardCount = n
+	}
+}
+
+// inShard reports whether the path, relative to the walker root,
+// belongs to the shard set with WithShard()
+func (w *Walker) inShard(relpath string) bool {
+	if w.shardCount <= 1 {
+		return true
+	}
+	rel, err := filepath.Rel(filepath.Clean(w.rootRel), relpath)
+	if err != nil || rel == "." {
+		return true
+	}
+	top := rel
+	if i := strings.IndexRune(rel, filepath.Separator); i >= 0 {
+		top = rel[:i]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(top))
+	return int(h.Sum32()%uint32(w.shardCount)) == w.shardIndex
+}
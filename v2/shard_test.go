@@ -0,0 +1,45 @@
+package cwalk
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestShard(t *testing.T) {
+	root := pageTree(t, "a", "b/c", "d/e/f", "g", "h/")
+	const n = 3
+	var mu sync.Mutex
+	seen := map[string]int{}
+	for i := 0; i < n; i++ {
+		err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+			mu.Lock()
+			seen[path]++
+			mu.Unlock()
+			return err
+		}, WithShard(i, n))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the root is visited by every shard, everything else by one
+	if len(seen) != 9 || seen[""] != n {
+		t.Errorf("got %v", seen)
+	}
+	for path, count := range seen {
+		if path != "" && count != 1 {
+			t.Errorf("%s visited by %d shards", path, count)
+		}
+	}
+
+	for _, c := range [][2]int{{0, 0}, {0, -1}, {-1, 2}, {2, 2}, {3, 2}} {
+		err := walkTree(root, func(path string, info os.FileInfo, err error) error {
+			t.Errorf("shard %d of %d: visited %s", c[0], c[1], path)
+			return err
+		}, WithShard(c[0], c[1]))
+		if want := fmt.Sprintf("Invalid shard %d of %d", c[0], c[1]); err == nil || err.Error() != want {
+			t.Errorf("got %v, want %s", err, want)
+		}
+	}
+}
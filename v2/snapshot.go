@@ -0,0 +1,247 @@
+package cwalk
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Snapshot is an in-memory copy of the tree metadata recorded during
+// a walk (see WithSnapshot()). It implements fs.FS, fs.ReadDirFS and
+// fs.StatFS, so that subsequent lookups, globs (fs.Glob) and re-walks
+// (fs.WalkDir) are served from memory; only reading the contents of
+// files goes to the underlying file system. Snapshot is safe
+// for concurrent use.
+type Snapshot struct {
+	mu    sync.RWMutex
+	root  string
+	nodes map[string]*snapshotNode // keyed by fs.FS-style paths ("." for the root)
+	hooks []func(name string)
+}
+
+// snapshotNode is a single file or directory in the Snapshot
+type snapshotNode struct {
+	info     os.FileInfo
+	children map[string]struct{} // names of the directory entries
+}
+
+// NewSnapshot creates an empty Snapshot
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		nodes: map[string]*snapshotNode{},
+	}
+}
+
+// WithSnapshot makes the walk record the metadata of every visited
+// entry in s, replacing what s has recorded before
+func WithSnapshot(s *Snapshot) Option {
+	return func(w *Walker) {
+		w.snapshot = s
+	}
+}
+
+// snapshotName converts a path relative to the walker root to
+// the form used by fs.FS
+func snapshotName(relpath string) string {
+	if relpath == "" {
+		return "."
+	}
+	return filepath.ToSlash(filepath.Clean(relpath))
+}
+
+// reset drops all recorded entries and sets the root directory
+// used to read the contents of files
+func (s *Snapshot) reset(root string) {
+	s.mu.Lock()
+	s.root = root
+	s.nodes = map[string]*snapshotNode{}
+	s.mu.Unlock()
+}
+
+// node returns the node for the name, creating it if necessary;
+// s.mu must be held for writing
+func (s *Snapshot) node(name string) *snapshotNode {
+	n, ok := s.nodes[name]
+	if !ok {
+		n = &snapshotNode{}
+		s.nodes[name] = n
+	}
+	return n
+}
+
+// add records the entry and links it to its parent directory
+func (s *Snapshot) add(relpath string, info os.FileInfo) {
+	name := snapshotName(relpath)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.node(name).info = info
+	if name == "." {
+		return
+	}
+	parent := s.node(path.Dir(name))
+	if parent.children == nil {
+		parent.children = map[string]struct{}{}
+	}
+	parent.children[path.Base(name)] = struct{}{}
+}
+
+// lookup returns the node for the name
+func (s *Snapshot) lookup(op, name string) (*snapshotNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	s.mu.RLock()
+	n, ok := s.nodes[name]
+	s.mu.RUnlock()
+	if !ok || n.info == nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return n, nil
+}
+
+// Stat returns the recorded file info
+func (s *Snapshot) Stat(name string) (fs.FileInfo, error) {
+	n, err := s.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return n.info, nil
+}
+
+// ReadDir returns the recorded directory entries sorted by name
+func (s *Snapshot) ReadDir(name string) ([]fs.DirEntry, error) {
+	n, err := s.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !n.info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: ErrNotDir}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := make([]fs.DirEntry, 0, len(n.children))
+	for child := range n.children {
+		if c, ok := s.nodes[path.Join(name, child)]; ok && c.info != nil {
+			entries = append(entries, dirEntry{c.info})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// Open opens the named file: directories are read from memory,
+// while the contents of files are read from the underlying file system
+func (s *Snapshot) Open(name string) (fs.File, error) {
+	n, err := s.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if n.info.IsDir() {
+		entries, err := s.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &snapshotDir{info: n.info, entries: entries}, nil
+	}
+
+	s.mu.RLock()
+	root := s.root
+	s.mu.RUnlock()
+	f, err := os.Open(filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, err
+	}
+	return &snapshotFile{File: f, info: n.info}, nil
+}
+
+// OnInvalidate registers a function called with the name
+// of every path passed to Invalidate()
+func (s *Snapshot) OnInvalidate(fn func(name string)) {
+	s.mu.Lock()
+	s.hooks = append(s.hooks, fn)
+	s.mu.Unlock()
+}
+
+// Invalidate removes the named path and everything beneath it from
+// the snapshot (e.g. after a change notification), and then calls
+// the functions registered with OnInvalidate()
+func (s *Snapshot) Invalidate(name string) {
+	s.mu.Lock()
+	if name == "." {
+		s.nodes = map[string]*snapshotNode{}
+	} else {
+		prefix := name + "/"
+		for n := range s.nodes {
+			if n == name || strings.HasPrefix(n, prefix) {
+				delete(s.nodes, n)
+			}
+		}
+		if parent, ok := s.nodes[path.Dir(name)]; ok {
+			delete(parent.children, path.Base(name))
+		}
+	}
+	hooks := s.hooks
+	s.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn(name)
+	}
+}
+
+// dirEntry implements fs.DirEntry on top of the recorded file info
+type dirEntry struct {
+	info os.FileInfo
+}
+
+func (de dirEntry) Name() string               { return de.info.Name() }
+func (de dirEntry) IsDir() bool                { return de.info.IsDir() }
+func (de dirEntry) Type() fs.FileMode          { return de.info.Mode().Type() }
+func (de dirEntry) Info() (fs.FileInfo, error) { return de.info, nil }
+
+// snapshotDir is an open directory of the Snapshot
+type snapshotDir struct {
+	info    os.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *snapshotDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *snapshotDir) Close() error               { return nil }
+
+func (d *snapshotDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+// ReadDir implements fs.ReadDirFile
+func (d *snapshotDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(rest) {
+		count = len(rest)
+	}
+	d.offset += count
+	return rest[:count], nil
+}
+
+// snapshotFile is an open file of the Snapshot,
+// which reports the recorded file info
+type snapshotFile struct {
+	*os.File
+	info os.FileInfo
+}
+
+func (f *snapshotFile) Stat() (fs.FileInfo, error) { return f.info, nil }
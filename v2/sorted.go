@@ -0,0 +1,321 @@
+package cwalk
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+)
+
+// SortedSink collects the paths of a walk (see WithSortedSink())
+// or arbitrary records added with Add(), and produces them in lexical
+// order with bounded memory: once the records held in memory exceed
+// the limit, they are sorted and spilled to a temporary file, and the
+// sorted runs are merged when the results are read. As new records are
+// added while a batch is spilled, up to one batch per goroutine adding
+// records may be held in memory. SortedSink is safe for concurrent use;
+// Close() removes the temporary files.
+type SortedSink struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int
+	records  []string
+	size     int      // total size of records
+	runs     []string // temporary files with sorted runs
+	err      error    // the first error while spilling
+}
+
+// NewSortedSink creates a SortedSink which keeps up to maxBytes
+// of records in memory, spilling the rest to temporary files in dir
+// (the default directory for temporary files if dir is empty)
+func NewSortedSink(dir string, maxBytes int) *SortedSink {
+	return &SortedSink{dir: dir, maxBytes: maxBytes}
+}
+
+// WithSortedSink makes the walk add the path of every entry passed
+// to the callback (as seen by the callback) to s
+func WithSortedSink(s *SortedSink) Option {
+	return func(w *Walker) {
+		w.sorted = s
+	}
+}
+
+// Add adds a record. Once the records held in memory exceed the
+// limit, the goroutine adding the last one spills them, without
+// holding the lock, so that other goroutines keep adding records
+// meanwhile (each of them may be spilling its own batch).
+func (s *SortedSink) Add(record string) {
+	s.mu.Lock()
+	s.records = append(s.records, record)
+	s.size += len(record)
+	if s.size < s.maxBytes || s.err != nil {
+		// after an error, the records are kept in memory,
+		// and the error is reported by Each()
+		s.mu.Unlock()
+		return
+	}
+	records := s.records
+	s.records, s.size = nil, 0
+	s.mu.Unlock()
+	s.spill(records)
+}
+
+// maxRuns is the number of spilled runs which are merged into one,
+// so that merging never needs more than maxRuns open files
+const maxRuns = 64
+
+// spill writes the records as a sorted run, and merges the runs once
+// there are maxRuns of them; it is called without s.mu held
+func (s *SortedSink) spill(records []string) {
+	sort.Strings(records)
+	name, err := s.writeRun(func(fn func(record string) error) error {
+		for _, r := range records {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	s.mu.Lock()
+	if err != nil {
+		s.fail(err)
+		for _, r := range records {
+			s.records = append(s.records, r)
+			s.size += len(r)
+		}
+		s.mu.Unlock()
+		return
+	}
+	s.runs = append(s.runs, name)
+	if len(s.runs) < maxRuns {
+		s.mu.Unlock()
+		return
+	}
+	merging := s.runs
+	s.runs = nil
+	s.mu.Unlock()
+
+	name, err = s.mergeFiles(merging)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.fail(err)
+		s.runs = append(s.runs, merging...)
+		return
+	}
+	for _, old := range merging {
+		os.Remove(old)
+	}
+	s.runs = append(s.runs, name)
+}
+
+// fail records the first error while spilling;
+// it is called with s.mu held
+func (s *SortedSink) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// mergeFiles merges the runs spilled to the files into a new one
+func (s *SortedSink) mergeFiles(names []string) (string, error) {
+	runs, closeRuns, err := openRuns(names)
+	if err != nil {
+		return "", err
+	}
+	defer closeRuns()
+	return s.writeRun(func(fn func(record string) error) error {
+		return mergeRuns(runs, fn)
+	})
+}
+
+// writeRun writes the records produced by each (in order)
+// to a new temporary file, and returns its name
+func (s *SortedSink) writeRun(each func(fn func(record string) error) error) (string, error) {
+	f, err := ioutil.TempFile(s.dir, "cwalk-run-")
+	if err != nil {
+		return "", err
+	}
+	bw := bufio.NewWriter(f)
+	var n [binary.MaxVarintLen64]byte
+	err = each(func(record string) error {
+		bw.Write(n[:binary.PutUvarint(n[:], uint64(len(record)))])
+		_, err := bw.WriteString(record)
+		return err
+	})
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// Each calls fn for every record in lexical order, merging the
+// runs spilled to disk with the records held in memory, and stops
+// at the first error. The records must not be added meanwhile
+// (every call to Add() must have returned).
+func (s *SortedSink) Each(fn func(record string) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	sort.Strings(s.records)
+	runs, closeRuns, err := openRuns(s.runs)
+	if err != nil {
+		return err
+	}
+	defer closeRuns()
+	return mergeRuns(append(runs, &sliceRun{records: s.records}), fn)
+}
+
+// openRuns opens the files with sorted runs
+func openRuns(names []string) (runs []sortedRun, closeRuns func(), err error) {
+	var files []*os.File
+	closeRuns = func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			closeRuns()
+			return nil, nil, err
+		}
+		files = append(files, f)
+		runs = append(runs, &fileRun{r: bufio.NewReader(f)})
+	}
+	return runs, closeRuns, nil
+}
+
+// mergeRuns calls fn for the records of the sorted runs in lexical order
+func mergeRuns(runs []sortedRun, fn func(record string) error) error {
+	var h mergeHeap
+	for _, run := range runs {
+		if run.next() {
+			h = append(h, run)
+		} else if err := run.failure(); err != nil {
+			return err
+		}
+	}
+	heap.Init(&h)
+	for len(h) > 0 {
+		run := h[0]
+		if err := fn(run.current()); err != nil {
+			return err
+		}
+		if run.next() {
+			heap.Fix(&h, 0)
+			continue
+		}
+		if err := run.failure(); err != nil {
+			return err
+		}
+		heap.Pop(&h)
+	}
+	return nil
+}
+
+// WriteTo writes the records in lexical order, one per line
+func (s *SortedSink) WriteTo(out io.Writer) (int64, error) {
+	bw := bufio.NewWriter(out)
+	var total int64
+	err := s.Each(func(record string) error {
+		n, err := bw.WriteString(record + "\n")
+		total += int64(n)
+		return err
+	})
+	if err != nil {
+		return total, err
+	}
+	return total, bw.Flush()
+}
+
+// Close removes the temporary files and drops all records
+func (s *SortedSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	for _, name := range s.runs {
+		if rerr := os.Remove(name); err == nil {
+			err = rerr
+		}
+	}
+	s.runs = nil
+	s.records = nil
+	s.size = 0
+	return err
+}
+
+// sortedRun is a source of sorted records being merged
+type sortedRun interface {
+	next() bool // advances to the next record, false at the end
+	current() string
+	failure() error // the error which ended the run, if any
+}
+
+// sliceRun is a sorted run held in memory
+type sliceRun struct {
+	records []string
+	pos     int // index of the current record plus one
+}
+
+func (r *sliceRun) next() bool {
+	r.pos++
+	return r.pos <= len(r.records)
+}
+
+func (r *sliceRun) current() string { return r.records[r.pos-1] }
+func (r *sliceRun) failure() error  { return nil }
+
+// fileRun is a sorted run spilled to a file
+type fileRun struct {
+	r   *bufio.Reader
+	cur string
+	err error
+}
+
+func (r *fileRun) next() bool {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		if err != io.EOF {
+			r.err = err
+		}
+		return false
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		r.err = err
+		return false
+	}
+	r.cur = string(buf)
+	return true
+}
+
+func (r *fileRun) current() string { return r.cur }
+func (r *fileRun) failure() error  { return r.err }
+
+// mergeHeap orders the runs by their current records
+type mergeHeap []sortedRun
+
+func (h mergeHeap) Len() int            { return len(h) }
+func (h mergeHeap) Less(i, j int) bool  { return h[i].current() < h[j].current() }
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(sortedRun)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
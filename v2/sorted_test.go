@@ -0,0 +1,54 @@
+package cwalk
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestSortedSinkConcurrentSpills(t *testing.T) {
+	s := NewSortedSink(t.TempDir(), 64)
+	defer s.Close()
+
+	// enough records for more than maxRuns runs, so that they are merged
+	const adders, perAdder = 8, 400
+	var want []string
+	var wg sync.WaitGroup
+	for a := 0; a < adders; a++ {
+		for i := 0; i < perAdder; i++ {
+			want = append(want, fmt.Sprintf("%03d/%04d", a, i))
+		}
+		wg.Add(1)
+		go func(a int) {
+			defer wg.Done()
+			for i := perAdder - 1; i >= 0; i-- {
+				s.Add(fmt.Sprintf("%03d/%04d", a, i))
+			}
+		}(a)
+	}
+	wg.Wait()
+	sort.Strings(want)
+
+	if len(s.runs) == 0 {
+		t.Fatal("no run was spilled")
+	}
+	if len(s.runs) >= maxRuns {
+		t.Errorf("%d runs left, they should have been merged", len(s.runs))
+	}
+	var got []string
+	if err := s.Each(func(record string) error {
+		got = append(got, record)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("record %d is %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package cwalk
+
+// splitDirEntries is the number of entries above which a directory
+// is split into parts of this size, processed by separate jobs
+// (a variable so that the tests can lower it)
+var splitDirEntries = 4096
+
+// dirPart is a part of the listing of a split directory
+type dirPart struct {
+	names   []string
+	offset  int    // index of the first name in the listing
+	skipped *int32 // set once the callback has returned SkipDir for a file in any part
+}
+
+// splitDir splits the listing of a large directory, so that its entries
+// can be stat'ed and passed to the callback by multiple workers in
+// parallel: all parts but the first one are queued as separate jobs,
+// and the first one is returned along with the flag shared by the parts.
+// Directories are only split if there is more than one worker, and if
+// the metadata cache is not used (as it needs the complete listing).
+func (w *Walker) splitDir(j job, names []string) ([]string, *int32) {
+	if len(names) <= splitDirEntries || w.workerCount() < 2 {
+		return names, nil
+	}
+	skipped := new(int32)
+	for lo := splitDirEntries; lo < len(names); lo += splitDirEntries {
+		hi := lo + splitDirEntries
+		if hi > len(names) {
+			hi = len(names)
+		}
+		w.queue.push(job{
+			path: j.path,
+			id:   j.id,
+			info: j.info,
+			part: &dirPart{names: names[lo:hi], offset: lo, skipped: skipped},
+		})
+	}
+	return names[:splitDirEntries], skipped
+}
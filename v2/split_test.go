@@ -0,0 +1,64 @@
+package cwalk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitDir(t *testing.T) {
+	defer func(n int) { splitDirEntries = n }(splitDirEntries)
+	splitDirEntries = 3
+
+	names := []string{"a", "b", "c", "d", "e", "f", "g"}
+	w := NewWalker(t.TempDir(), WithWorkers(2))
+	w.queue = newJobQueue(0)
+	first, skipped := w.splitDir(job{path: "d"}, names)
+	if fmt.Sprint(first) != "[a b c]" || skipped == nil {
+		t.Errorf("got the first part %v, want [a b c] and a flag", first)
+	}
+	var parts []string
+	for q := w.queue; q.len() > 0; {
+		j, _ := q.pop()
+		if j.path != "d" || j.part == nil || j.part.skipped != skipped {
+			t.Fatalf("got job %+v", j)
+		}
+		parts = append(parts, fmt.Sprintf("%d%v", j.part.offset, j.part.names))
+		q.done()
+	}
+	if fmt.Sprint(parts) != "[6[g] 3[d e f]]" {
+		t.Errorf("got the parts %v, want [6[g] 3[d e f]]", parts)
+	}
+
+	// a single worker processes the directory as a whole
+	w = NewWalker(t.TempDir(), WithWorkers(1))
+	if first, skipped := w.splitDir(job{path: "d"}, names); len(first) != len(names) || skipped != nil {
+		t.Errorf("got %v with a single worker, want the whole listing", first)
+	}
+}
+
+func TestSplitDirWalk(t *testing.T) {
+	defer func(n int) { splitDirEntries = n }(splitDirEntries)
+	splitDirEntries = 3
+
+	root := t.TempDir()
+	want := map[string]bool{"": true}
+	for i := 0; i < 20; i++ {
+		dir := fmt.Sprint("d", i)
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		want[dir] = true
+		for j := 0; j < i; j++ {
+			file := filepath.Join(dir, fmt.Sprint(j))
+			if err := os.WriteFile(filepath.Join(root, file), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			want[file] = true
+		}
+	}
+	for _, workers := range []int{1, 4} {
+		checkVisitedOnce(t, root, want, WithWorkers(workers))
+	}
+}
@@ -0,0 +1,56 @@
+package cwalk
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// statInfo implements os.FileInfo for the results
+// of stat calls made directly via syscall
+type statInfo struct {
+	name string
+	mode os.FileMode
+	sys  syscall.Stat_t
+}
+
+// newStatInfo converts syscall.Stat_t to os.FileInfo
+// the same way os.Lstat does
+func newStatInfo(name string, st *syscall.Stat_t) *statInfo {
+	si := &statInfo{
+		name: name,
+		mode: os.FileMode(st.Mode & 0777),
+		sys:  *st,
+	}
+	switch st.Mode & syscall.S_IFMT {
+	case syscall.S_IFBLK:
+		si.mode |= os.ModeDevice
+	case syscall.S_IFCHR:
+		si.mode |= os.ModeDevice | os.ModeCharDevice
+	case syscall.S_IFDIR:
+		si.mode |= os.ModeDir
+	case syscall.S_IFIFO:
+		si.mode |= os.ModeNamedPipe
+	case syscall.S_IFLNK:
+		si.mode |= os.ModeSymlink
+	case syscall.S_IFSOCK:
+		si.mode |= os.ModeSocket
+	}
+	if st.Mode&syscall.S_ISGID != 0 {
+		si.mode |= os.ModeSetgid
+	}
+	if st.Mode&syscall.S_ISUID != 0 {
+		si.mode |= os.ModeSetuid
+	}
+	if st.Mode&syscall.S_ISVTX != 0 {
+		si.mode |= os.ModeSticky
+	}
+	return si
+}
+
+func (si *statInfo) Name() string       { return si.name }
+func (si *statInfo) Size() int64        { return si.sys.Size }
+func (si *statInfo) Mode() os.FileMode  { return si.mode }
+func (si *statInfo) ModTime() time.Time { return time.Unix(si.sys.Mtim.Unix()) }
+func (si *statInfo) IsDir() bool        { return si.mode.IsDir() }
+func (si *statInfo) Sys() interface{}   { return &si.sys }
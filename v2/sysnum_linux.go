@@ -0,0 +1,8 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+// +build linux,!mips,!mipsle,!mips64,!mips64le
+
+package cwalk
+
+// sysOpenat2 is the number of openat2(2), which is
+// the same on all architectures but the MIPS ones
+const sysOpenat2 = 437
@@ -0,0 +1,8 @@
+//go:build linux && (mips64 || mips64le)
+// +build linux
+// +build mips64 mips64le
+
+package cwalk
+
+// sysOpenat2 is the number of openat2(2) in the n64 ABI
+const sysOpenat2 = 5437
@@ -0,0 +1,8 @@
+//go:build linux && (mips || mipsle)
+// +build linux
+// +build mips mipsle
+
+package cwalk
+
+// sysOpenat2 is the number of openat2(2) in the o32 ABI
+const sysOpenat2 = 4437
@@ -0,0 +1,121 @@
+package cwalk
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Throttle slows down or pauses a walk, see WithThrottle()
+type Throttle interface {
+	// Wait blocks for as long as the worker should wait before going
+	// on, e.g. while the machine is on battery power or under thermal
+	// pressure; it must return ctx.Err() once ctx is done. Any error
+	// aborts the walk and is returned from it.
+	Wait(ctx context.Context) error
+}
+
+// WithThrottle makes every worker consult t once every n file system
+// operations (directory reads and stat calls; 1 if n is not positive),
+// so that applications can slow down or pause the walk without
+// tearing it down and starting over (see Pacer). The context passed
+// to t is derived from the one set by WithContext(), and is canceled
+// once the walk is stopped or aborted.
+func WithThrottle(t Throttle, n int) Option {
+	return func(w *Walker) {
+		if n < 1 {
+			n = 1
+		}
+		w.throttle = t
+		w.throttleEvery = n
+	}
+}
+
+// throttleOp counts a file system operation of the worker, and
+// consults the throttle if due; it returns false if the walk is aborted
+func (w *Walker) throttleOp(ws *workerState) bool {
+	if w.throttle == nil {
+		return true
+	}
+	ws.ops++
+	if ws.ops%w.throttleEvery != 0 {
+		return true
+	}
+	if err := w.throttle.Wait(w.abortCtx); err != nil {
+		if !w.isAborted() {
+			w.setWalkError(err)
+			w.abort()
+		}
+		return false
+	}
+	return !w.isAborted()
+}
+
+// Pacer is a Throttle controlled by the application: it delays every
+// operation it is consulted for by a given duration, or pauses the
+// walks until resumed. Pacer is safe for concurrent use, and can be
+// shared by any number of walks.
+type Pacer struct {
+	mu      sync.Mutex
+	delay   time.Duration
+	resumed chan struct{} // closed while not paused
+}
+
+// NewPacer creates a Pacer which doesn't slow the walks down
+func NewPacer() *Pacer {
+	resumed := make(chan struct{})
+	close(resumed)
+	return &Pacer{resumed: resumed}
+}
+
+// SetDelay sets the delay of every operation the pacer is consulted for
+func (p *Pacer) SetDelay(d time.Duration) {
+	p.mu.Lock()
+	p.delay = d
+	p.mu.Unlock()
+}
+
+// Pause pauses the walks until Resume() is called
+func (p *Pacer) Pause() {
+	p.mu.Lock()
+	select {
+	case <-p.resumed:
+		p.resumed = make(chan struct{})
+	default: // paused already
+	}
+	p.mu.Unlock()
+}
+
+// Resume resumes the walks paused by Pause()
+func (p *Pacer) Resume() {
+	p.mu.Lock()
+	select {
+	case <-p.resumed:
+	default:
+		close(p.resumed)
+	}
+	p.mu.Unlock()
+}
+
+// Wait implements Throttle
+func (p *Pacer) Wait(ctx context.Context) error {
+	p.mu.Lock()
+	delay, resumed := p.delay, p.resumed
+	p.mu.Unlock()
+	select {
+	case <-resumed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if delay <= 0 {
+		return nil
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
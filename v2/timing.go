@@ -0,0 +1,156 @@
+package cwalk
+
+import (
+	"container/heap"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Histogram counts operation latencies: Buckets[i] holds the number
+// of operations which took from 2^i to 2^(i+1) microseconds (the first
+// bucket also holds the faster ones, and the last one the slower ones)
+type Histogram struct {
+	Buckets [24]int64
+}
+
+// add counts the latency
+func (h *Histogram) add(d time.Duration) {
+	i := 0
+	for us := d.Microseconds(); us > 1 && i < len(h.Buckets)-1; us >>= 1 {
+		i++
+	}
+	atomic.AddInt64(&h.Buckets[i], 1)
+}
+
+// median returns the lower bound of the bucket
+// holding the median latency
+func (h *Histogram) median() time.Duration {
+	half := (h.Count() + 1) / 2
+	var n int64
+	for i := range h.Buckets {
+		n += atomic.LoadInt64(&h.Buckets[i])
+		if n >= half {
+			return time.Duration(1<<uint(i)) * time.Microsecond
+		}
+	}
+	return 0
+}
+
+// Count returns the total number of operations
+func (h *Histogram) Count() int64 {
+	var n int64
+	for i := range h.Buckets {
+		n += atomic.LoadInt64(&h.Buckets[i])
+	}
+	return n
+}
+
+// DirTiming holds the time spent on a directory: reading its listing,
+// and stat'ing its entries (which includes e.g. network round trips
+// on NFS, or antivirus filters inspecting the files)
+type DirTiming struct {
+	Path    string
+	ReadDir time.Duration
+	Stat    time.Duration // total for all entries
+	Entries int           // number of entries stat'ed
+}
+
+// Total returns the total time spent on the directory
+func (d DirTiming) Total() time.Duration {
+	return d.ReadDir + d.Stat
+}
+
+// Timing records the latency of the file system operations of a walk,
+// see WithTiming(). Large directories processed in parts (by multiple
+// workers) may be reported as several DirTimings with the same path.
+type Timing struct {
+	ReadDir Histogram // latency of reading directory listings
+	Stat    Histogram // latency of stat'ing entries
+
+	mu      sync.Mutex
+	n       int
+	slowest timingHeap // the fastest of the slowest directories on top
+}
+
+// NewTiming creates a Timing which keeps the n slowest directories
+func NewTiming(n int) *Timing {
+	return &Timing{n: n}
+}
+
+// WithTiming makes the walk record the latency of every readdir
+// and stat call in t, along with the slowest directories, to help
+// find the parts of the tree (such as a slow network export) that make
+// the walk slow. The timing itself adds two clock reads per call.
+func WithTiming(t *Timing) Option {
+	return func(w *Walker) {
+		w.timing = t
+	}
+}
+
+// timedLstat is lstat which adds its latency to dt, if set
+func (w *Walker) timedLstat(relpath string, dt *DirTiming) (os.FileInfo, error) {
+	if dt == nil {
+		if w.report != nil && atomic.AddInt64(&w.statCalls, 1)%statSampleRate == 0 {
+			start := time.Now()
+			info, err := w.lstat(relpath)
+			w.statSamples.add(time.Since(start))
+			if err == nil {
+				w.sampleOpen(relpath, info)
+			}
+			return info, err
+		}
+		return w.lstat(relpath)
+	}
+	start := time.Now()
+	info, err := w.lstat(relpath)
+	d := time.Since(start)
+	w.timing.Stat.add(d)
+	w.statSamples.add(d)
+	dt.Stat += d
+	dt.Entries++
+	return info, err
+}
+
+// record adds the timing of a directory
+func (t *Timing) record(d DirTiming) {
+	if t.n <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.slowest) < t.n {
+		heap.Push(&t.slowest, d)
+	} else if d.Total() > t.slowest[0].Total() {
+		t.slowest[0] = d
+		heap.Fix(&t.slowest, 0)
+	}
+}
+
+// Slowest returns the slowest directories recorded so far,
+// the slowest first
+func (t *Timing) Slowest() []DirTiming {
+	t.mu.Lock()
+	list := append([]DirTiming(nil), t.slowest...)
+	t.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Total() > list[j].Total()
+	})
+	return list
+}
+
+// timingHeap is a min-heap of directories by total time
+type timingHeap []DirTiming
+
+func (h timingHeap) Len() int            { return len(h) }
+func (h timingHeap) Less(i, j int) bool  { return h[i].Total() < h[j].Total() }
+func (h timingHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *timingHeap) Push(x interface{}) { *h = append(*h, x.(DirTiming)) }
+func (h *timingHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
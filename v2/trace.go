@@ -0,0 +1,248 @@
+package cwalk
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrNotInTrace indicates that the result of a file system call
+// requested during a replayed walk was not recorded in the trace
+var ErrNotInTrace = errors.New("Not recorded in the trace")
+
+// traceRecord is a single line of a trace: the result
+// of one directory listing or stat call
+type traceRecord struct {
+	Op     string     `json:"op"` // "readdir", "lstat", "stat" or "readlink"
+	Path   string     `json:"path"`
+	Names  []string   `json:"names,omitempty"`
+	Info   *traceInfo `json:"info,omitempty"`
+	Target string     `json:"target,omitempty"` // the symlink target
+	Err    string     `json:"err,omitempty"`
+	Errno  int        `json:"errno,omitempty"` // set if the error is a system error
+}
+
+// traceInfo is the recorded os.FileInfo; it
+// implements os.FileInfo when replayed
+type traceInfo struct {
+	FileName string      `json:"name"`
+	FileSize int64       `json:"size"`
+	FileMode os.FileMode `json:"mode"`
+	MTime    int64       `json:"mtime"` // Unix time in nanoseconds
+}
+
+func (fi *traceInfo) Name() string       { return fi.FileName }
+func (fi *traceInfo) Size() int64        { return fi.FileSize }
+func (fi *traceInfo) Mode() os.FileMode  { return fi.FileMode }
+func (fi *traceInfo) ModTime() time.Time { return time.Unix(0, fi.MTime) }
+func (fi *traceInfo) IsDir() bool        { return fi.FileMode.IsDir() }
+func (fi *traceInfo) Sys() interface{}   { return nil }
+
+// WithTraceRecording makes the walk write the results of the
+// directory listings, stat and readlink calls to out, one JSON object
+// per line, so that the walk can be replayed later (see LoadTrace()).
+// Each line is written with a single Write call; use a bufio.Writer to
+// reduce the number of system calls, and flush it once the walk is done.
+// If writing fails, the walk is aborted with the error.
+func WithTraceRecording(out io.Writer) Option {
+	return func(w *Walker) {
+		w.traceOut = &traceWriter{out: out}
+	}
+}
+
+// traceWriter serializes the records written by the workers
+type traceWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// write writes the record as a single line
+func (t *traceWriter) write(rec *traceRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err = t.out.Write(append(line, '\n'))
+	return err
+}
+
+// recordingFS is a fileSystem which records
+// the results of the calls of the underlying one
+type recordingFS struct {
+	fileSystem
+	w *Walker
+}
+
+// recordTrace makes the walk record the trace, if requested
+func (w *Walker) recordTrace() {
+	if w.traceOut != nil {
+		w.fs = recordingFS{fileSystem: w.fs, w: w}
+	}
+}
+
+// record writes the record, completed with the error,
+// and aborts the walk if that fails
+func (fsys recordingFS) record(rec *traceRecord, err error) {
+	rec.Path = filepath.ToSlash(rec.Path)
+	if err != nil {
+		rec.Err = err.Error()
+		var errno syscall.Errno
+		if errors.As(err, &errno) {
+			rec.Errno = int(errno)
+		}
+	}
+	if err := fsys.w.traceOut.write(rec); err != nil {
+		fsys.w.setWalkError(err)
+		fsys.w.abort()
+	}
+}
+
+// ReadDirNames records the directory listing
+func (fsys recordingFS) ReadDirNames(relpath string) ([]string, error) {
+	names, err := fsys.fileSystem.ReadDirNames(relpath)
+	fsys.record(&traceRecord{Op: "readdir", Path: relpath, Names: names}, err)
+	return names, err
+}
+
+// Lstat records the file info
+func (fsys recordingFS) Lstat(relpath string) (os.FileInfo, error) {
+	info, err := fsys.fileSystem.Lstat(relpath)
+	fsys.record(&traceRecord{Op: "lstat", Path: relpath, Info: newTraceInfo(info)}, err)
+	return info, err
+}
+
+// Stat records the file info of the symlink target
+func (fsys recordingFS) Stat(relpath string) (os.FileInfo, error) {
+	info, err := fsys.fileSystem.Stat(relpath)
+	fsys.record(&traceRecord{Op: "stat", Path: relpath, Info: newTraceInfo(info)}, err)
+	return info, err
+}
+
+// Readlink records the symlink target
+func (fsys recordingFS) Readlink(relpath string) (string, error) {
+	target, err := fsys.fileSystem.Readlink(relpath)
+	fsys.record(&traceRecord{Op: "readlink", Path: relpath, Target: target}, err)
+	return target, err
+}
+
+// newTraceInfo converts the file info for recording
+func newTraceInfo(info os.FileInfo) *traceInfo {
+	if info == nil {
+		return nil
+	}
+	return &traceInfo{
+		FileName: info.Name(),
+		FileSize: info.Size(),
+		FileMode: info.Mode(),
+		MTime:    info.ModTime().UnixNano(),
+	}
+}
+
+// Trace holds the results of the file system calls recorded
+// during a walk (see WithTraceRecording()), for replaying the walk
+// with WithTraceReplay(). Trace is read-only, so it can be used
+// by any number of walks at the same time.
+type Trace struct {
+	records map[string]*traceRecord // keyed by the operation and the path
+}
+
+// LoadTrace reads a trace written with WithTraceRecording()
+func LoadTrace(r io.Reader) (*Trace, error) {
+	t := &Trace{records: map[string]*traceRecord{}}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20) // listings of large directories are long lines
+	for line := 1; scanner.Scan(); line++ {
+		rec := &traceRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), rec); err != nil {
+			return nil, fmt.Errorf("Invalid trace record on line %d: %w", line, err)
+		}
+		t.records[rec.Op+":"+filepath.FromSlash(rec.Path)] = rec
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// WithTraceReplay makes the walk serve the directory listings, stat
+// and readlink calls from t instead of the file system, which is left
+// alone, so that the scheduling of walks can be benchmarked reproducibly.
+// The calls which were not recorded fail with ErrNotInTrace, including
+// all attempts to open files, so the enriched metadata read from open
+// files (e.g. the creation time on Linux) is not available either.
+func WithTraceReplay(t *Trace) Option {
+	return func(w *Walker) {
+		w.replay = t
+	}
+}
+
+// replayFS is a fileSystem serving the calls from a trace
+type replayFS struct {
+	trace *Trace
+}
+
+// lookup returns the recorded result of the call
+func (fsys replayFS) lookup(op, relpath string) (*traceRecord, error) {
+	rec, ok := fsys.trace.records[op+":"+relpath]
+	if !ok {
+		return nil, &os.PathError{Op: op, Path: relpath, Err: ErrNotInTrace}
+	}
+	if rec.Errno != 0 {
+		return rec, &os.PathError{Op: op, Path: relpath, Err: syscall.Errno(rec.Errno)}
+	}
+	if rec.Err != "" {
+		return rec, &os.PathError{Op: op, Path: relpath, Err: errors.New(rec.Err)}
+	}
+	return rec, nil
+}
+
+// ReadDirNames returns a copy of the recorded listing
+func (fsys replayFS) ReadDirNames(relpath string) ([]string, error) {
+	rec, err := fsys.lookup("readdir", relpath)
+	if rec == nil {
+		return nil, err
+	}
+	return append([]string(nil), rec.Names...), err
+}
+
+// Lstat returns the recorded file info
+func (fsys replayFS) Lstat(relpath string) (os.FileInfo, error) {
+	return fsys.stat("lstat", relpath)
+}
+
+// Stat returns the recorded file info of the symlink target
+func (fsys replayFS) Stat(relpath string) (os.FileInfo, error) {
+	return fsys.stat("stat", relpath)
+}
+
+// stat returns the file info recorded by the operation
+func (fsys replayFS) stat(op, relpath string) (os.FileInfo, error) {
+	rec, err := fsys.lookup(op, relpath)
+	if err != nil || rec.Info == nil {
+		return nil, err
+	}
+	return rec.Info, nil
+}
+
+// Readlink returns the recorded symlink target
+func (fsys replayFS) Readlink(relpath string) (string, error) {
+	rec, err := fsys.lookup("readlink", relpath)
+	if err != nil {
+		return "", err
+	}
+	return rec.Target, nil
+}
+
+// Open fails, as the contents of files are not recorded
+func (fsys replayFS) Open(relpath string, flag int) (*os.File, error) {
+	return nil, &os.PathError{Op: "open", Path: relpath, Err: ErrNotInTrace}
+}
@@ -0,0 +1,232 @@
+package cwalk
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrManifestSyntax indicates an invalid manifest passed to Verify()
+var ErrManifestSyntax = errors.New("Invalid manifest")
+
+// verifyProgressInterval is how often the progress of Verify() is reported
+const verifyProgressInterval = time.Second
+
+// VerifyReport is the result of Verify(); the paths are relative
+// to the root, with forward slashes, in lexical order
+type VerifyReport struct {
+	Verified int      // number of files matching the manifest
+	Missing  []string // files listed in the manifest, but not found
+	Extra    []string // regular files found, but not listed in the manifest
+	Modified []string // files which content differs, or which are no longer regular files
+	Bytes    int64    // number of bytes hashed
+}
+
+// VerifyProgress is the progress of Verify()
+type VerifyProgress struct {
+	Files int64 // number of files of the manifest hashed so far
+	Total int   // number of files in the manifest
+	Bytes int64 // number of bytes hashed so far
+}
+
+// Verify walks root, hashes the regular files listed in the manifest in
+// parallel (with the content stages, see WithContentStage()), and reports
+// the differences with the manifest, which has the format of the output
+// of sha256sum(1): a line per file with its hex digest, two spaces (or a
+// space and an asterisk) and its path relative to root (with forward
+// slashes, "./" prefixes allowed). The hash function is chosen by the
+// length of the digests: MD5, SHA-1, SHA-256 or SHA-512. The files which
+// are not listed are reported from their metadata, without being opened
+// (so the content stages passed in opts don't see them either). The files
+// which could not be read are reported as errors, and are neither verified
+// nor modified. Errors are returned after the walk along with the report.
+func Verify(root string, manifest io.Reader, opts ...Option) (VerifyReport, error) {
+	return VerifyWithProgress(root, manifest, nil, opts...)
+}
+
+// VerifyWithProgress is like Verify(), and calls progress every second
+// while the files are hashed, and once more when the walk is complete
+func VerifyWithProgress(root string, manifest io.Reader, progress func(VerifyProgress), opts ...Option) (VerifyReport, error) {
+	digests, newHash, err := readManifest(manifest)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+
+	var report VerifyReport
+	var mu sync.Mutex
+	found := map[string]bool{}
+	var hashed, hashedBytes int64
+	// only the files listed in the manifest are opened,
+	// the others are reported by the callback from their metadata
+	inManifest := func(entry *Entry) bool {
+		_, ok := digests[filepath.ToSlash(entry.Path)]
+		return ok
+	}
+	stage := func(entry *Entry, r io.Reader) error {
+		key := filepath.ToSlash(entry.Path)
+		want := digests[key]
+		h := newHash()
+		n, err := io.Copy(h, r)
+		atomic.AddInt64(&hashedBytes, n)
+		if err != nil {
+			return err
+		}
+		atomic.AddInt64(&hashed, 1)
+		mu.Lock()
+		defer mu.Unlock()
+		if bytes.Equal(h.Sum(nil), want) {
+			report.Verified++
+		} else {
+			report.Modified = append(report.Modified, key)
+		}
+		return nil
+	}
+
+	snapshot := func() VerifyProgress {
+		return VerifyProgress{
+			Files: atomic.LoadInt64(&hashed),
+			Total: len(digests),
+			Bytes: atomic.LoadInt64(&hashedBytes),
+		}
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	if progress != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t := time.NewTicker(verifyProgressInterval)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					progress(snapshot())
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	opts = append(opts[:len(opts):len(opts)], WithContentStage(stage), withContentFilter(inManifest))
+	err = walkTree(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(p)
+		_, listed := digests[key]
+		mu.Lock()
+		defer mu.Unlock()
+		if listed {
+			found[key] = true
+			if !info.Mode().IsRegular() {
+				report.Modified = append(report.Modified, key)
+			}
+		} else if info.Mode().IsRegular() {
+			report.Extra = append(report.Extra, key)
+		}
+		return nil
+	}, opts...)
+	close(done)
+	wg.Wait()
+	if progress != nil {
+		progress(snapshot())
+	}
+
+	for key := range digests {
+		if !found[key] {
+			report.Missing = append(report.Missing, key)
+		}
+	}
+	report.Bytes = hashedBytes
+	sort.Strings(report.Missing)
+	sort.Strings(report.Extra)
+	sort.Strings(report.Modified)
+	return report, err
+}
+
+// readManifest reads the digests of the manifest by path,
+// and returns the hash function they were computed with
+func readManifest(r io.Reader) (map[string][]byte, func() hash.Hash, error) {
+	digests := map[string][]byte{}
+	var newHash func() hash.Hash
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSuffix(s.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		// sha256sum escapes the lines of paths
+		// containing backslashes or newlines
+		escaped := strings.HasPrefix(line, "\\")
+		if escaped {
+			line = line[1:]
+		}
+		sep := strings.Index(line, " ")
+		if sep < 0 || sep+2 > len(line) || (line[sep+1] != ' ' && line[sep+1] != '*') {
+			return nil, nil, fmt.Errorf("%w: line %d: expected a digest and a path", ErrManifestSyntax, n)
+		}
+		digest, err := hex.DecodeString(line[:sep])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: line %d: bad digest", ErrManifestSyntax, n)
+		}
+		h := hashForDigest(len(digest))
+		if h == nil {
+			return nil, nil, fmt.Errorf("%w: line %d: unknown digest length", ErrManifestSyntax, n)
+		}
+		if newHash == nil {
+			newHash = h
+		} else if h().Size() != newHash().Size() {
+			return nil, nil, fmt.Errorf("%w: line %d: digests of different lengths", ErrManifestSyntax, n)
+		}
+		name := line[sep+2:]
+		if escaped {
+			name = strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(name)
+		}
+		name = path.Clean(name)
+		if name == "." || name == ".." || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			return nil, nil, fmt.Errorf("%w: line %d: path outside of the root", ErrManifestSyntax, n)
+		}
+		digests[name] = digest
+	}
+	if err := s.Err(); err != nil {
+		return nil, nil, err
+	}
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	return digests, newHash, nil
+}
+
+// hashForDigest returns the hash function producing
+// digests of the given size, nil if unknown
+func hashForDigest(size int) func() hash.Hash {
+	switch size {
+	case md5.Size:
+		return md5.New
+	case sha1.Size:
+		return sha1.New
+	case sha256.Size:
+		return sha256.New
+	case sha512.Size:
+		return sha512.New
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package cwalk
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestVerifyUnlisted(t *testing.T) {
+	root := pageTree(t, "a", "b", "d/c")
+	if err := os.WriteFile(filepath.Join(root, "a"), []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest := fmt.Sprintf("%x  ./a\n%x  missing\n", sha256.Sum256([]byte("abc")), sha256.Sum256(nil))
+
+	// the files which are not listed are never opened
+	var mu sync.Mutex
+	var opened []string
+	report, err := Verify(root, strings.NewReader(manifest), WithContentStage(func(entry *Entry, r io.Reader) error {
+		mu.Lock()
+		opened = append(opened, filepath.ToSlash(entry.Path))
+		mu.Unlock()
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(opened) != "[a]" {
+		t.Errorf("opened %v, want [a]", opened)
+	}
+	if got := fmt.Sprintf("%d %v %v %v", report.Verified, report.Missing, report.Extra, report.Modified); got != "1 [missing] [b d/c] []" {
+		t.Errorf("got report %s", got)
+	}
+}
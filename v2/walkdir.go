@@ -0,0 +1,69 @@
+// Package cwalk is the second major version of the concurrent
+// directory walker. It holds the walker engine, which v1 wraps, and
+// its API is built around options rather than package variables:
+//
+//   - every walk takes a context, and is canceled along with it;
+//   - the callback gets fs.DirEntry values and full paths, like the
+//     one of filepath.WalkDir;
+//   - the number of workers and the error limit are options (or Config
+//     fields) with fixed defaults, so walks in different parts of
+//     a program don't affect each other;
+//   - errors are returned as *WalkError, which holds *PathError values.
+//
+// The Walker and the functions built on it (such as DiskUsage())
+// work just like in v1, except for the defaults.
+package cwalk
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+)
+
+// Walk walks the tree rooted at root, calling fn for each file or
+// directory in the tree, including root; see filepath.WalkDir for the
+// meaning of the arguments and return values of fn. fn is called
+// from multiple goroutines, in no particular order. If the root can't
+// be read, the error returned by fn for it is returned; otherwise the
+// errors for the paths under the root are returned as a *WalkError
+// after the walk is complete.
+func Walk(ctx context.Context, root string, fn fs.WalkDirFunc, opts ...Option) error {
+	return WalkEntries(ctx, root, func(entry *Entry, err error) error {
+		var d fs.DirEntry
+		if entry.Info != nil {
+			var ok bool
+			if d, ok = entry.Info.(fs.DirEntry); !ok { // see WithLazyStat()
+				d = fs.FileInfoToDirEntry(entry.Info)
+			}
+		}
+		return fn(filepath.Join(root, entry.Path), d, err)
+	}, opts...)
+}
+
+// WalkConfig is like Walk, but takes the settings from cfg
+// (the options are applied after them)
+func WalkConfig(ctx context.Context, root string, cfg Config, fn fs.WalkDirFunc, opts ...Option) error {
+	cfgOpts, err := cfg.Options()
+	if err != nil {
+		return err
+	}
+	return Walk(ctx, root, fn, append(cfgOpts, opts...)...)
+}
+
+// WalkEntries is like Walk, but passes Entry values to fn, with the
+// paths relative to the root ("" for the root itself), which carry
+// the order tokens and the extended metadata
+func WalkEntries(ctx context.Context, root string, fn EntryFunc, opts ...Option) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := WalkerOf(ctx, root, opts...).WalkEntries("", fn)
+	return convertError(err)
+}
+
+// WalkerOf returns a Walker for root, canceled along with ctx,
+// for the features only available as Walker methods
+// (such as Explain() and Stop())
+func WalkerOf(ctx context.Context, root string, opts ...Option) *Walker {
+	return NewWalker(root, append([]Option{WithContext(ctx)}, opts...)...)
+}
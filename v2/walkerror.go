@@ -0,0 +1,71 @@
+package cwalk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PathError is an error for a path under the root
+type PathError struct {
+	Path string // relative to the root
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// WalkError is returned by the walks which completed (or were
+// aborted, see WithMaxErrors()), but couldn't read some paths
+type WalkError struct {
+	Errors    []*PathError
+	Truncated int  // number of errors not stored because of the error limit
+	Aborted   bool // the walk was aborted because of the error limit
+}
+
+func (e *WalkError) Error() string {
+	out := make([]string, len(e.Errors))
+	for i, pe := range e.Errors {
+		out[i] = pe.Error()
+	}
+	if e.Truncated > 0 {
+		out = append(out, fmt.Sprintf("%d more errors", e.Truncated))
+	}
+	return strings.Join(out, "\n")
+}
+
+// Is reports whether any of the errors matches target; ErrTooManyErrors
+// matches if the error limit was reached
+func (e *WalkError) Is(target error) bool {
+	if target == ErrTooManyErrors {
+		return e.Truncated > 0 || e.Aborted
+	}
+	for _, pe := range e.Errors {
+		if errors.Is(pe, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// convertError converts the errors returned by the Walker
+func convertError(err error) error {
+	list, ok := err.(WalkerErrorList)
+	if !ok {
+		return err
+	}
+	we := &WalkError{
+		Errors:    make([]*PathError, len(list.ErrorList)),
+		Truncated: list.Truncated,
+		Aborted:   list.Aborted,
+	}
+	for i, e := range list.ErrorList {
+		we.Errors[i] = &PathError{Path: e.Path(), Err: e.Unwrap()}
+	}
+	return we
+}
@@ -0,0 +1,31 @@
+package cwalk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrRootMoved indicates that the directory passed to WalkDirFile()
+// can no longer be found under its original name (only reported
+// on platforms where the walk can't be done relative to an open
+// file descriptor)
+var ErrRootMoved = errors.New("Root directory has been moved or replaced")
+
+// ErrConfineUnsupported indicates that the WithConfineToRoot()
+// option is not supported on this platform
+var ErrConfineUnsupported = errors.New("Confining the walk to the root is not supported on this platform")
+
+// WalkDirFile works like Walk(), but walks the directory f,
+// which the caller already holds open. On Linux, all paths are
+// opened relative to f using openat(2), so the root directory
+// can't be swapped between the moment the caller has checked it
+// and the traversal (note that symlinks inside the tree are still
+// followed when resolving intermediate path components, unless
+// the WithConfineToRoot() option is used).
+// The paths passed to walkFn are relative to f.
+func WalkDirFile(f *os.File, walkFn filepath.WalkFunc, opts ...Option) error {
+	w := NewWalker(f.Name(), opts...)
+	w.rootFile = f
+	return w.Walk("", walkFn)
+}
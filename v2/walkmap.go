@@ -0,0 +1,58 @@
+package cwalk
+
+import "io/fs"
+
+// WalkMap walks the tree like WalkEntries(), passing every entry that
+// was read without errors to fn, and collects the values for which fn
+// returns true into a map keyed by the entry path. Each worker fills
+// its own map, and the maps are merged once the walk is complete,
+// so fn needs no synchronization. fn may return filepath.SkipDir
+// to skip a directory; other errors are collected as usual
+// and returned along with the map.
+func WalkMap[T any](root string, fn func(path string, d fs.DirEntry) (T, bool, error), opts ...Option) (map[string]T, error) {
+	w := NewWalker(root, opts...)
+	rootValues := map[string]T{}
+	err := w.walk("", func(ws *workerState, entry *Entry, err error) error {
+		if err != nil || entry.Info == nil {
+			return err
+		}
+		values := rootValues
+		if ws != nil {
+			if ws.local == nil {
+				ws.local = map[string]T{}
+			}
+			values = ws.local.(map[string]T)
+		}
+		d, ok := entry.Info.(fs.DirEntry) // see WithLazyStat()
+		if !ok {
+			d = fs.FileInfoToDirEntry(entry.Info)
+		}
+		v, ok, err := fn(entry.Path, d)
+		if ok {
+			values[entry.Path] = v
+		}
+		return err
+	})
+
+	// the workers have exited, so their maps can be read here
+	w.mu.Lock()
+	workers := w.workers
+	w.mu.Unlock()
+	n := len(rootValues)
+	for _, ws := range workers {
+		if values, ok := ws.local.(map[string]T); ok {
+			n += len(values)
+		}
+	}
+	result := make(map[string]T, n)
+	for path, v := range rootValues {
+		result[path] = v
+	}
+	for _, ws := range workers {
+		values, _ := ws.local.(map[string]T)
+		for path, v := range values {
+			result[path] = v
+		}
+	}
+	return result, err
+}
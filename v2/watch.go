@@ -0,0 +1,158 @@
+package cwalk
+
+import (
+	"container/list"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrWatchUnsupported indicates that directory watches
+// are not supported on this platform
+var ErrWatchUnsupported = errors.New("Directory watches are not supported on this platform")
+
+// ErrWatchEvicted is the reason reported for the directories which
+// watch was removed to stay within the watch limit
+var ErrWatchEvicted = errors.New("Watch evicted to stay within the watch limit")
+
+// UnwatchedDir is a directory which is not watched,
+// so that changes of its entries go unnoticed
+type UnwatchedDir struct {
+	Path   string // as passed to the walk callback
+	Reason error  // ErrWatchEvicted, or the error of adding the watch
+}
+
+// Watches registers inotify watches on the directories as they are
+// discovered by walks (see WithWatches()), which is much faster
+// than adding them in a separate pass after the walk. The number of
+// watches is kept within a limit, which defaults to the system-wide
+// per-user limit: once it is reached (or the kernel refuses new watches),
+// the least recently used watch is removed. Directories become recently
+// used when they are watched and when Touch() is called for them,
+// typically when their events are handled.
+//
+// The events are read from the inotify descriptor returned by Fd(),
+// and Path() maps their watch descriptors back to the directories.
+// Watches is safe for concurrent use.
+type Watches struct {
+	mu        sync.Mutex
+	fd        int
+	mask      uint32
+	limit     int
+	lru       *list.List               // of *watch, least recently used first
+	byPath    map[string]*list.Element // keyed by the callback path
+	byWD      map[int]*list.Element
+	unwatched map[string]error
+}
+
+// watch is a single registered watch
+type watch struct {
+	path string
+	wd   int
+}
+
+// add registers the watch for the directory and evicts the least
+// recently used watches if needed; the directory is opened by absPath
+func (ws *Watches) add(path, absPath string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if e, ok := ws.byPath[path]; ok {
+		ws.lru.MoveToBack(e)
+		return
+	}
+	for {
+		for ws.lru.Len() >= ws.limit {
+			ws.evict()
+		}
+		wd, err := addWatch(ws.fd, absPath, ws.mask)
+		if err == errWatchLimit && ws.lru.Len() > 0 {
+			ws.evict()
+			continue
+		}
+		if err != nil {
+			ws.unwatched[path] = err
+			return
+		}
+		if e, ok := ws.byWD[wd]; ok {
+			// the same directory was found by another path (e.g. a bind
+			// mount); the watch is shared, and belongs to the latest path
+			ws.remove(e)
+		}
+		ws.byWD[wd] = ws.lru.PushBack(&watch{path: path, wd: wd})
+		ws.byPath[path] = ws.byWD[wd]
+		delete(ws.unwatched, path)
+		return
+	}
+}
+
+// evict removes the least recently used watch; ws.mu must be held
+func (ws *Watches) evict() {
+	e := ws.lru.Front()
+	removeWatch(ws.fd, e.Value.(*watch).wd)
+	ws.remove(e)
+	ws.unwatched[e.Value.(*watch).path] = ErrWatchEvicted
+}
+
+// remove forgets the watch; ws.mu must be held
+func (ws *Watches) remove(e *list.Element) {
+	w := e.Value.(*watch)
+	ws.lru.Remove(e)
+	delete(ws.byPath, w.path)
+	delete(ws.byWD, w.wd)
+}
+
+// Fd returns the inotify file descriptor to read the events from
+func (ws *Watches) Fd() int {
+	return ws.fd
+}
+
+// Path returns the directory watched by the watch descriptor
+func (ws *Watches) Path(wd int) (string, bool) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	e, ok := ws.byWD[wd]
+	if !ok {
+		return "", false
+	}
+	return e.Value.(*watch).path, true
+}
+
+// Touch marks the watched directory as recently used, so that
+// its watch is among the last ones to be evicted
+func (ws *Watches) Touch(path string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if e, ok := ws.byPath[path]; ok {
+		ws.lru.MoveToBack(e)
+	}
+}
+
+// Len returns the number of watched directories
+func (ws *Watches) Len() int {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.lru.Len()
+}
+
+// Unwatched returns the directories found by the walks which
+// are not watched, sorted by path. Note that only the entries of
+// these directories go unnoticed: their subdirectories are watched
+// on their own.
+func (ws *Watches) Unwatched() []UnwatchedDir {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	dirs := make([]UnwatchedDir, 0, len(ws.unwatched))
+	for path, reason := range ws.unwatched {
+		dirs = append(dirs, UnwatchedDir{Path: path, Reason: reason})
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Path < dirs[j].Path })
+	return dirs
+}
+
+// WithWatches makes the walk add a watch to ws for every directory
+// passed to the callback, unless the callback skips it
+func WithWatches(ws *Watches) Option {
+	return func(w *Walker) {
+		w.watches = ws
+	}
+}
@@ -0,0 +1,78 @@
+package cwalk
+
+import (
+	"container/list"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// WatchMask is the default set of inotify events watched
+// for the directories, see NewWatches()
+const WatchMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY |
+	syscall.IN_ATTRIB | syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// errWatchLimit is returned by addWatch when
+// the kernel refuses to add more watches
+var errWatchLimit = syscall.ENOSPC
+
+// NewWatches creates an inotify instance watching for the events
+// in mask (WatchMask if zero), with at most limit watches (the
+// system-wide limit from /proc/sys/fs/inotify/max_user_watches if
+// not positive). The instance must be closed with Close().
+func NewWatches(mask uint32, limit int) (*Watches, error) {
+	if mask == 0 {
+		mask = WatchMask
+	}
+	if limit <= 0 {
+		limit = maxUserWatches()
+	}
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	return &Watches{
+		fd:        fd,
+		mask:      mask | syscall.IN_ONLYDIR,
+		limit:     limit,
+		lru:       list.New(),
+		byPath:    map[string]*list.Element{},
+		byWD:      map[int]*list.Element{},
+		unwatched: map[string]error{},
+	}, nil
+}
+
+// Close removes all watches and closes the inotify descriptor
+func (ws *Watches) Close() error {
+	return syscall.Close(ws.fd)
+}
+
+// maxUserWatches returns the system-wide limit of inotify watches
+func maxUserWatches() int {
+	data, err := os.ReadFile("/proc/sys/fs/inotify/max_user_watches")
+	if err == nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 8192 // the historical default
+}
+
+// addWatch adds the inotify watch for the path
+func addWatch(fd int, path string, mask uint32) (int, error) {
+	wd, err := syscall.InotifyAddWatch(fd, path, mask)
+	if err == syscall.ENOSPC {
+		return 0, errWatchLimit
+	}
+	if err != nil {
+		return 0, &os.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+	}
+	return wd, nil
+}
+
+// removeWatch removes the inotify watch
+func removeWatch(fd, wd int) {
+	syscall.InotifyRmWatch(fd, uint32(wd))
+}
@@ -0,0 +1,32 @@
+//go:build !linux
+// +build !linux
+
+package cwalk
+
+import "errors"
+
+// WatchMask is the default set of events watched for the directories,
+// see NewWatches(); there are none on this platform
+const WatchMask = 0
+
+// errWatchLimit is never returned on this platform
+var errWatchLimit = errors.New("Watch limit reached")
+
+// NewWatches returns ErrWatchUnsupported on this platform
+func NewWatches(mask uint32, limit int) (*Watches, error) {
+	return nil, ErrWatchUnsupported
+}
+
+// Close does nothing on this platform
+func (ws *Watches) Close() error {
+	return nil
+}
+
+// addWatch returns ErrWatchUnsupported on this platform
+func addWatch(fd int, path string, mask uint32) (int, error) {
+	return 0, ErrWatchUnsupported
+}
+
+// removeWatch does nothing on this platform
+func removeWatch(fd, wd int) {
+}
@@ -0,0 +1,86 @@
+package walktest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/iafan/cwalk"
+)
+
+// fuzzMaxErrors is the error limit used to check
+// that errors are routed according to the error policy
+const fuzzMaxErrors = 8
+
+// Fuzz generates a tree from data (see GenerateTree()) in a temporary
+// directory and checks the traversal invariants on it with CheckInvariants().
+// Its signature allows to use it directly as a fuzz target, as FuzzWalk
+// in this package does:
+//
+//	func FuzzWalk(f *testing.F) {
+//		f.Fuzz(walktest.Fuzz)
+//	}
+func Fuzz(t *testing.T, data []byte) {
+	tree, err := GenerateTree(t.TempDir(), data)
+	if tree != nil {
+		t.Cleanup(func() { tree.RestorePermissions() })
+	}
+	if err != nil {
+		t.Skipf("can't generate the tree: %v", err)
+	}
+	CheckInvariants(t, tree.Root)
+}
+
+// CheckInvariants walks root and reports a test error if any
+// of the traversal invariants is violated:
+//
+//   - every path is passed to the callback exactly once
+//     (also verified by cwalk.WithConsistencyChecks());
+//   - cwalk visits the same paths, and reports the same
+//     error classes, as filepath.Walk (see Equivalence());
+//   - the concurrent engine visits the same paths, and reports the
+//     same error classes, as the sequential one, also with a filter
+//     (see Differential());
+//   - errors are reported for visited paths only, and no more
+//     than the limit set with cwalk.WithMaxErrors() are stored;
+//   - no goroutine outlives the walk (see cwalk.WithGoroutineCheck()).
+func CheckInvariants(t testing.TB, root string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	visits := map[string]int{}
+	err := cwalk.Walk(root, func(path string, info os.FileInfo, err error) error {
+		mu.Lock()
+		visits[filepath.ToSlash(path)]++
+		mu.Unlock()
+		return err
+	}, cwalk.WithMaxErrors(fuzzMaxErrors, cwalk.StopCollecting), cwalk.WithConsistencyChecks(), cwalk.WithGoroutineCheck())
+
+	for path, n := range visits {
+		if n != 1 {
+			t.Errorf("%q: visited %d times", path, n)
+		}
+	}
+
+	if list, ok := err.(cwalk.WalkerErrorList); ok {
+		if len(list.ErrorList) > fuzzMaxErrors {
+			t.Errorf("%d errors stored, the limit is %d", len(list.ErrorList), fuzzMaxErrors)
+		}
+		for _, e := range list.ErrorList {
+			var ce *cwalk.ConsistencyError
+			if errors.As(e, &ce) {
+				t.Errorf("%v", ce)
+			}
+			if visits[filepath.ToSlash(e.Path())] == 0 {
+				t.Errorf("%q: error reported for a path that was not visited: %v", e.Path(), e)
+			}
+		}
+	} else if err != nil {
+		t.Errorf("unexpected error type %T: %v", err, err)
+	}
+
+	Equivalence(t, root)
+	Differential(t, root, cwalk.WithSkipHidden())
+}
@@ -0,0 +1,22 @@
+package walktest
+
+import "testing"
+
+// FuzzWalk checks the traversal invariants on generated trees;
+// run it with go test -fuzz=FuzzWalk ./walktest
+func FuzzWalk(f *testing.F) {
+	// the bytes select the generator ops, see GenerateTree()
+	seeds := [][]byte{
+		{},
+		{opFile, opFile, opDir, opFile, opUp, opFile},
+		{opDir, opDir, opDir, opFile, opUp, opUp, opFile},
+		{opDir, opFile, opSymlink, opBrokenSymlink, opUp, opSymlink},
+		{opDeepDir, 40, opFile, opUp, opFile},
+		{opDir, opFile, opRestrict, opUp, opDir, opRestrict, opFile},
+		{opDir, opSymlink, opDeepDir, 200, opBrokenSymlink, opRestrict},
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(Fuzz)
+}
@@ -0,0 +1,130 @@
+package walktest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Tree describes a directory tree created by GenerateTree()
+type Tree struct {
+	Root       string
+	Paths      []string // slash-separated paths of all created entries, relative to Root
+	restricted []string // entries which had their permissions removed
+}
+
+// generator ops, selected by the input bytes
+const (
+	opFile = iota
+	opDir
+	opUp
+	opSymlink
+	opBrokenSymlink
+	opDeepDir
+	opRestrict
+	numOps
+)
+
+// maxDeepDir limits the length of a chain of directories
+// created by a single opDeepDir
+const maxDeepDir = 64
+
+// GenerateTree creates a directory tree inside the existing directory
+// root, deterministically derived from data (so that it can be driven
+// by a fuzzer). The tree may contain files, nested directories,
+// long chains of directories, symlinks (including broken ones
+// and ones pointing to their parent directories) and, on Unix,
+// entries with permissions removed; use RestorePermissions()
+// before removing such a tree.
+func GenerateTree(root string, data []byte) (*Tree, error) {
+	t := &Tree{Root: root}
+	cwd := "" // current directory, relative to root
+	seq := 0  // sequence number for unique names
+	next := func(i *int) int {
+		*i++
+		if *i < len(data) {
+			return int(data[*i])
+		}
+		return 0
+	}
+	newName := func(prefix string) string {
+		seq++
+		return filepath.Join(cwd, fmt.Sprintf("%s%d", prefix, seq))
+	}
+
+	for i := 0; i < len(data); i++ {
+		var err error
+		var created string
+		switch int(data[i]) % numOps {
+		case opFile:
+			created = newName("f")
+			err = os.WriteFile(filepath.Join(root, created), nil, 0644)
+		case opDir:
+			created = newName("d")
+			err = os.Mkdir(filepath.Join(root, created), 0755)
+			cwd = created
+		case opUp:
+			if cwd != "" {
+				cwd = filepath.Dir(cwd)
+				if cwd == "." {
+					cwd = ""
+				}
+			}
+		case opSymlink:
+			if len(t.Paths) == 0 {
+				continue
+			}
+			target := filepath.FromSlash(t.Paths[next(&i)%len(t.Paths)])
+			created = newName("l")
+			rel, relErr := filepath.Rel(filepath.Dir(created), target)
+			if relErr != nil {
+				return t, relErr
+			}
+			err = os.Symlink(rel, filepath.Join(root, created))
+		case opBrokenSymlink:
+			created = newName("b")
+			err = os.Symlink("does-not-exist", filepath.Join(root, created))
+		case opDeepDir:
+			depth := next(&i)%maxDeepDir + 1
+			for n := 0; n < depth && err == nil; n++ {
+				t.Paths = appendPath(t.Paths, created)
+				created = newName("c")
+				err = os.Mkdir(filepath.Join(root, created), 0755)
+				cwd = created
+			}
+		case opRestrict:
+			if len(t.Paths) == 0 {
+				continue
+			}
+			path := filepath.FromSlash(t.Paths[next(&i)%len(t.Paths)])
+			if os.Chmod(filepath.Join(root, path), 0) == nil {
+				t.restricted = append(t.restricted, path)
+			}
+		}
+		if err != nil {
+			return t, err
+		}
+		t.Paths = appendPath(t.Paths, created)
+	}
+	return t, nil
+}
+
+// appendPath adds a non-empty path to the list in slash-separated form
+func appendPath(paths []string, path string) []string {
+	if path == "" {
+		return paths
+	}
+	return append(paths, filepath.ToSlash(path))
+}
+
+// RestorePermissions gives back the permissions removed
+// while generating the tree, so that it can be removed
+func (t *Tree) RestorePermissions() error {
+	var firstErr error
+	for _, path := range t.restricted {
+		if err := os.Chmod(filepath.Join(t.Root, path), 0755); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
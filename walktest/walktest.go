@@ -0,0 +1,212 @@
+// Package walktest provides utilities for testing code built on cwalk,
+// most notably a check that cwalk visits the same paths, and reports
+// the same kinds of errors, as filepath.Walk does for a given tree.
+package walktest
+
+import (
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/iafan/cwalk"
+)
+
+// Error classes reported in Result.Error
+const (
+	ErrorNotExist   = "not-exist"
+	ErrorPermission = "permission"
+	ErrorOther      = "error"
+)
+
+// Result describes what a walker reported for a single path
+type Result struct {
+	Path  string      // slash-separated path relative to the root ("" for the root)
+	Type  os.FileMode // file type bits (os.ModeType), if the path was stat'ed
+	Error string      // error class, empty if no error was reported
+}
+
+// Results maps relative paths to the results reported for them
+type Results map[string]Result
+
+// add merges the file info and the error reported for the path
+// into the results (a path may be reported more than once,
+// e.g. when a directory can be stat'ed but not read)
+func (r Results) add(path string, info os.FileInfo, err error) {
+	path = filepath.ToSlash(path)
+	res := r[path]
+	res.Path = path
+	if info != nil {
+		res.Type = info.Mode() & os.ModeType
+	}
+	if err != nil {
+		res.Error = ErrorClass(err)
+	}
+	r[path] = res
+}
+
+// ErrorClass returns the class of the error, which is what
+// is compared between walkers (error messages may differ)
+func ErrorClass(err error) string {
+	switch {
+	case os.IsNotExist(err):
+		return ErrorNotExist
+	case os.IsPermission(err):
+		return ErrorPermission
+	}
+	return ErrorOther
+}
+
+// CollectStdlib walks root with filepath.Walk and returns
+// the results keyed by the path relative to root
+func CollectStdlib(root string) Results {
+	return collectStdlib(root, nil)
+}
+
+// collectStdlib is CollectStdlib, with the callback returning
+// SkipDir for the directories selected by skip, if not nil
+func collectStdlib(root string, skip func(path string) bool) Results {
+	res := Results{}
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			rel = path
+		}
+		if rel == "." {
+			rel = ""
+		}
+		res.add(rel, info, err)
+		return skipResult(skip, rel, info)
+	})
+	return res
+}
+
+// CollectCwalk walks root with cwalk.Walk and returns
+// the results keyed by the path relative to root
+func CollectCwalk(root string, opts ...cwalk.Option) Results {
+	return CollectEngine(cwalk.Concurrent(opts...), root)
+}
+
+// CollectEngine walks root with the engine and returns
+// the results keyed by the path relative to root
+func CollectEngine(e cwalk.TreeWalker, root string) Results {
+	return collectEngine(e, root, nil)
+}
+
+// collectEngine is CollectEngine, with the callback returning
+// SkipDir for the directories selected by skip, if not nil
+func collectEngine(e cwalk.TreeWalker, root string, skip func(path string) bool) Results {
+	var mu sync.Mutex
+	res := Results{}
+	err := e.Walk(root, func(path string, info os.FileInfo, err error) error {
+		mu.Lock()
+		res.add(path, info, err)
+		mu.Unlock()
+		return skipResult(skip, path, info)
+	})
+
+	if list, ok := err.(cwalk.WalkerErrorList); ok {
+		for _, e := range list.ErrorList {
+			res.add(e.Path(), nil, e)
+		}
+	} else if err != nil {
+		res.add("", nil, err)
+	}
+	return res
+}
+
+// skipResult returns SkipDir for the directories other
+// than the root which are selected by skip, if not nil
+func skipResult(skip func(path string) bool, path string, info os.FileInfo) error {
+	if skip != nil && path != "" && info != nil && info.IsDir() && skip(filepath.ToSlash(path)) {
+		return filepath.SkipDir
+	}
+	return nil
+}
+
+// skipHalf selects about half of the directories for the walks which
+// exercise SkipDir, based on their paths, so that the same directories
+// are selected whatever the order of the walk
+func skipHalf(path string) bool {
+	return crc32.ChecksumIEEE([]byte(path))%2 == 0
+}
+
+// Diff returns the paths for which the results differ, sorted
+func Diff(a, b Results) []string {
+	var paths []string
+	for path, res := range a {
+		if other, ok := b[path]; !ok || other != res {
+			paths = append(paths, path)
+		}
+	}
+	for path := range b {
+		if _, ok := a[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Equivalence walks root with both filepath.Walk and cwalk.Walk
+// (configured with opts) and reports a test error for every path
+// that was visited by only one of them, or got a different
+// file type or error class. The walks are done twice: the second
+// time, the callbacks return SkipDir for about half of the directories.
+func Equivalence(t testing.TB, root string, opts ...cwalk.Option) {
+	t.Helper()
+	for _, skip := range []func(string) bool{nil, skipHalf} {
+		want := collectStdlib(root, skip)
+		got := collectEngine(cwalk.Concurrent(opts...), root, skip)
+		for _, path := range Diff(want, got) {
+			w, inWant := want[path]
+			g, inGot := got[path]
+			switch {
+			case !inGot:
+				t.Errorf("%q: visited by filepath.Walk only (%+v)%s", path, w, skipNote(skip))
+			case !inWant:
+				t.Errorf("%q: visited by cwalk only (%+v)%s", path, g, skipNote(skip))
+			default:
+				t.Errorf("%q: filepath.Walk reported %+v, cwalk reported %+v%s", path, w, g, skipNote(skip))
+			}
+		}
+	}
+}
+
+// Differential walks root with both the sequential engine of cwalk,
+// which is built on filepath.Walk, and the concurrent one (configured
+// with opts), and reports a test error for every path that was visited
+// by only one of them, or got a different file type or error class.
+// Unlike Equivalence(), this also covers the filters (see
+// cwalk.WithFilter()), which filepath.Walk has no counterpart for.
+// Like Equivalence(), the walks are done twice, the second time
+// returning SkipDir for about half of the directories.
+func Differential(t testing.TB, root string, opts ...cwalk.Option) {
+	t.Helper()
+	for _, skip := range []func(string) bool{nil, skipHalf} {
+		want := collectEngine(cwalk.Sequential(opts...), root, skip)
+		got := collectEngine(cwalk.Concurrent(opts...), root, skip)
+		for _, path := range Diff(want, got) {
+			w, inWant := want[path]
+			g, inGot := got[path]
+			switch {
+			case !inGot:
+				t.Errorf("%q: visited by the sequential engine only (%+v)%s", path, w, skipNote(skip))
+			case !inWant:
+				t.Errorf("%q: visited by the concurrent engine only (%+v)%s", path, g, skipNote(skip))
+			default:
+				t.Errorf("%q: the sequential engine reported %+v, the concurrent one %+v%s", path, w, g, skipNote(skip))
+			}
+		}
+	}
+}
+
+// skipNote tells in the test errors whether SkipDir was returned
+func skipNote(skip func(string) bool) string {
+	if skip != nil {
+		return " when returning SkipDir for some directories"
+	}
+	return ""
+}